@@ -0,0 +1,310 @@
+package apiproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+type Config struct {
+	LogOutput io.Writer
+	Port      uint64
+
+	// Target is the URL of the API to proxy the requests to
+	Target string
+
+	// RecordFile is the path of the file where the requests and responses are recorded
+	RecordFile string
+
+	// Latency is an artificial delay added to the requests, with a random jitter of up to Jitter
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// FailRate is the probability (between 0 and 1) of failing a request without proxying it
+	FailRate float64
+
+	// Methods are the JSON-RPC methods (or prefixes of them, i.e. engine_getPayload) affected by the
+	// latency and failure injection. If empty, all the requests are affected.
+	Methods []string
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		LogOutput: os.Stdout,
+		Port:      5757,
+	}
+}
+
+// ApiProxy is a proxy for the Engine API and the Beacon API that records every request and
+// response as a JSON line in the record file
+type ApiProxy struct {
+	config *Config
+	log    *logrus.Entry
+	server *http.Server
+	client *http.Client
+
+	recordLock sync.Mutex
+	record     *os.File
+}
+
+func New(config *Config) (*ApiProxy, error) {
+	log := common.LogSetup(false, "info")
+	log.Logger.SetOutput(config.LogOutput)
+
+	record, err := os.OpenFile(config.RecordFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open record file: %w", err)
+	}
+
+	proxy := &ApiProxy{
+		config: config,
+		log:    log,
+		client: &http.Client{},
+		record: record,
+	}
+	return proxy, nil
+}
+
+// Run starts the HTTP server
+func (s *ApiProxy) Run() error {
+	mux := http.NewServeMux()
+	s.server = &http.Server{
+		Addr: fmt.Sprintf(":%d", s.config.Port),
+		// there is no write timeout since the beacon events are streamed
+		ReadTimeout: 10 * time.Second,
+		Handler:     mux,
+	}
+
+	mux.HandleFunc("/", s.handleRequest)
+
+	s.log.Infof("Starting server on port %d, proxy to %s", s.config.Port, s.config.Target)
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return fmt.Errorf("server error: %v", err)
+	}
+	return nil
+}
+
+// Close gracefully shuts down the server
+func (s *ApiProxy) Close() error {
+	s.log.Info("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("server shutdown error: %v", err)
+	}
+	return s.record.Close()
+}
+
+// Entry is a recorded request and its response
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Duration   string    `json:"duration"`
+	HTTPMethod string    `json:"httpMethod"`
+	Path       string    `json:"path"`
+
+	// Method is the JSON-RPC method for the Engine API requests (i.e. engine_newPayloadV3)
+	Method string `json:"method,omitempty"`
+
+	Request  json.RawMessage `json:"request,omitempty"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+
+	// Stream signals that the response is an event stream, which is not recorded
+	Stream bool `json:"stream,omitempty"`
+
+	// InjectedLatency and InjectedFailure describe the faults injected by the proxy
+	InjectedLatency string `json:"injectedLatency,omitempty"`
+	InjectedFailure bool   `json:"injectedFailure,omitempty"`
+}
+
+type jsonrpcMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+}
+
+func (s *ApiProxy) handleRequest(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	entry := &Entry{
+		Time:       start,
+		HTTPMethod: r.Method,
+		Path:       r.URL.RequestURI(),
+		Request:    toRawJSON(data),
+	}
+	var rpcRequest jsonrpcMessage
+	if json.Unmarshal(data, &rpcRequest) == nil {
+		entry.Method = rpcRequest.Method
+	}
+	defer func() {
+		entry.Duration = time.Since(start).String()
+		s.writeEntry(entry)
+	}()
+
+	if s.injectFaults(entry.Method) {
+		if delay := s.delay(); delay > 0 {
+			entry.InjectedLatency = delay.String()
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				entry.Error = r.Context().Err().Error()
+				return
+			}
+		}
+		if s.config.FailRate > 0 && rand.Float64() < s.config.FailRate {
+			entry.InjectedFailure = true
+			s.writeInjectedFailure(w, entry, rpcRequest)
+			return
+		}
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, strings.TrimSuffix(s.config.Target, "/")+r.URL.RequestURI(), bytes.NewReader(data))
+	if err != nil {
+		entry.Error = err.Error()
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	// It is important to copy the headers since the JWT token of the CL goes in them
+	req.Header = r.Header.Clone()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.log.Errorf("Error proxying request %s: %v", entry.Path, err)
+		entry.Error = err.Error()
+		entry.Status = http.StatusBadGateway
+		http.Error(w, "Bad gateway", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	entry.Status = resp.StatusCode
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		// stream the events (i.e. the payload attributes) as they arrive
+		entry.Stream = true
+		s.streamResponse(w, resp.Body)
+		return
+	}
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		entry.Error = err.Error()
+		return
+	}
+	entry.Response = toRawJSON(respData)
+	w.Write(respData)
+}
+
+// injectFaults returns whether the latency and failure injection applies to the method
+func (s *ApiProxy) injectFaults(method string) bool {
+	if len(s.config.Methods) == 0 {
+		return true
+	}
+	for _, prefix := range s.config.Methods {
+		if method != "" && strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ApiProxy) delay() time.Duration {
+	delay := s.config.Latency
+	if s.config.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(s.config.Jitter)))
+	}
+	return delay
+}
+
+// writeInjectedFailure replies with a JSON-RPC internal error for the Engine API
+// requests and with a 503 status code otherwise
+func (s *ApiProxy) writeInjectedFailure(w http.ResponseWriter, entry *Entry, rpcRequest jsonrpcMessage) {
+	s.log.Infof("Injecting failure: path=%s method=%s", entry.Path, entry.Method)
+
+	if entry.Method == "" {
+		entry.Status = http.StatusServiceUnavailable
+		http.Error(w, "Injected failure", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := rpcRequest.ID
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32603,"message":"injected failure"}}`, id)
+
+	entry.Status = http.StatusOK
+	entry.Response = json.RawMessage(resp)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(resp))
+}
+
+func (s *ApiProxy) streamResponse(w http.ResponseWriter, body io.Reader) {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 4096)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *ApiProxy) writeEntry(entry *Entry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		s.log.Errorf("Error encoding entry: %v", err)
+		return
+	}
+
+	s.recordLock.Lock()
+	defer s.recordLock.Unlock()
+
+	if _, err := s.record.Write(append(data, '\n')); err != nil {
+		s.log.Errorf("Error recording entry: %v", err)
+	}
+}
+
+// toRawJSON returns the data as raw JSON if it is valid JSON or as a JSON string otherwise
+func toRawJSON(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+	if json.Valid(data) {
+		return data
+	}
+	str, _ := json.Marshal(string(data))
+	return str
+}
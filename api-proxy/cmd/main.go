@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	apiproxy "github.com/ferranbt/builder-playground/api-proxy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	target     string
+	recordFile string
+	port       int
+	latency    time.Duration
+	jitter     time.Duration
+	failRate   float64
+	methods    []string
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "api-proxy",
+	Short: "",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runApiProxy()
+	},
+}
+
+func main() {
+	rootCmd.Flags().StringVar(&target, "target", "http://localhost:8551", "")
+	rootCmd.Flags().StringVar(&recordFile, "record", "requests.jsonl", "")
+	rootCmd.Flags().IntVar(&port, "port", 5757, "")
+	rootCmd.Flags().DurationVar(&latency, "latency", 0, "")
+	rootCmd.Flags().DurationVar(&jitter, "jitter", 0, "")
+	rootCmd.Flags().Float64Var(&failRate, "fail-rate", 0, "")
+	rootCmd.Flags().StringSliceVar(&methods, "methods", []string{}, "")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func runApiProxy() error {
+	cfg := &apiproxy.Config{
+		LogOutput:  os.Stdout,
+		Port:       uint64(port),
+		Target:     target,
+		RecordFile: recordFile,
+		Latency:    latency,
+		Jitter:     jitter,
+		FailRate:   failRate,
+		Methods:    methods,
+	}
+
+	proxy, err := apiproxy.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create api proxy: %w", err)
+	}
+	return proxy.Run()
+}
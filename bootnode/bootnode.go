@@ -0,0 +1,186 @@
+package bootnode
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	relaycommon "github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+type Config struct {
+	LogOutput io.Writer
+
+	// PrivateKey is the hex encoded discovery key of the bootnode
+	PrivateKey string
+
+	// DiscoveryPort is the UDP port of the discv4 and discv5 protocols
+	DiscoveryPort uint64
+
+	// HTTPPort serves the enode (/enode) and the ENR (/enr) of the bootnode
+	HTTPPort uint64
+
+	// ENRFile is an optional path where the ENR is written as a boot_enr.yaml list, the
+	// format of the boot nodes in the testnet folder of the consensus clients
+	ENRFile string
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		LogOutput:     os.Stdout,
+		DiscoveryPort: 30301,
+		HTTPPort:      8080,
+	}
+}
+
+// Bootnode is a discovery node that the execution clients (discv4 and discv5) and the consensus
+// clients (discv5) use to find each other. Both protocols share the same UDP socket.
+type Bootnode struct {
+	config *Config
+	log    *logrus.Entry
+	key    *ecdsa.PrivateKey
+
+	conn   *net.UDPConn
+	v4     *discover.UDPv4
+	v5     *discover.UDPv5
+	local  *enode.LocalNode
+	server *http.Server
+}
+
+func New(config *Config) (*Bootnode, error) {
+	log := relaycommon.LogSetup(false, "info")
+	log.Logger.SetOutput(config.LogOutput)
+
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(config.PrivateKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return &Bootnode{
+		config: config,
+		log:    log,
+		key:    key,
+	}, nil
+}
+
+// Run starts the discovery protocols and the HTTP server and blocks until the server is closed
+func (b *Bootnode) Run() error {
+	ip, err := localIP()
+	if err != nil {
+		return err
+	}
+
+	b.conn, err = net.ListenUDP("udp", &net.UDPAddr{Port: int(b.config.DiscoveryPort)})
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp port %d: %w", b.config.DiscoveryPort, err)
+	}
+
+	db, err := enode.OpenDB("")
+	if err != nil {
+		return err
+	}
+	b.local = enode.NewLocalNode(db, b.key)
+	b.local.SetStaticIP(ip)
+	b.local.SetFallbackUDP(int(b.config.DiscoveryPort))
+
+	// the packets that are not discv4 are handled by discv5
+	unhandled := make(chan discover.ReadPacket, 100)
+	if b.v4, err = discover.ListenV4(b.conn, b.local, discover.Config{PrivateKey: b.key, Unhandled: unhandled}); err != nil {
+		return fmt.Errorf("failed to start discv4: %w", err)
+	}
+	if b.v5, err = discover.ListenV5(&sharedUDPConn{UDPConn: b.conn, unhandled: unhandled}, b.local, discover.Config{PrivateKey: b.key}); err != nil {
+		return fmt.Errorf("failed to start discv5: %w", err)
+	}
+
+	node := b.local.Node()
+	b.log.Infof("Bootnode enode: %s", node.URLv4())
+	b.log.Infof("Bootnode ENR: %s", node.String())
+
+	if b.config.ENRFile != "" {
+		if err := os.WriteFile(b.config.ENRFile, []byte(fmt.Sprintf("- %s\n", node.String())), 0644); err != nil {
+			return fmt.Errorf("failed to write the ENR file: %w", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/enode", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, b.local.Node().URLv4())
+	})
+	mux.HandleFunc("/enr", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, b.local.Node().String())
+	})
+	b.server = &http.Server{
+		Addr:        fmt.Sprintf(":%d", b.config.HTTPPort),
+		ReadTimeout: 10 * time.Second,
+		Handler:     mux,
+	}
+
+	b.log.Infof("Starting server on port %d, discovery on udp port %d", b.config.HTTPPort, b.config.DiscoveryPort)
+	if err := b.server.ListenAndServe(); err != http.ErrServerClosed {
+		return fmt.Errorf("server error: %v", err)
+	}
+	return nil
+}
+
+// Close stops the HTTP server and the discovery protocols
+func (b *Bootnode) Close() error {
+	b.log.Info("Shutting down bootnode...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := b.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("server shutdown error: %v", err)
+	}
+	// discv4 closes the socket and the channel of the unhandled packets that discv5 reads
+	b.v4.Close()
+	b.v5.Close()
+	return nil
+}
+
+// localIP returns the first IPv4 address of the network interfaces that is not a loopback
+// address, which is the address of the container in the docker network
+func localIP() (net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the interface addresses: %w", err)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.To4() == nil {
+			continue
+		}
+		return ipNet.IP.To4(), nil
+	}
+	return nil, fmt.Errorf("no IPv4 address found")
+}
+
+// sharedUDPConn reads the packets that discv4 does not handle and writes to the shared socket
+type sharedUDPConn struct {
+	*net.UDPConn
+	unhandled chan discover.ReadPacket
+}
+
+func (s *sharedUDPConn) ReadFromUDPAddrPort(b []byte) (int, netip.AddrPort, error) {
+	packet, ok := <-s.unhandled
+	if !ok {
+		return 0, netip.AddrPort{}, errors.New("connection was closed")
+	}
+	return copy(b, packet.Data), packet.Addr, nil
+}
+
+// Close is a no-op, the socket is closed by discv4
+func (s *sharedUDPConn) Close() error {
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ferranbt/builder-playground/bootnode"
+	"github.com/spf13/cobra"
+)
+
+var (
+	privateKey string
+	port       int
+	httpPort   int
+	enrFile    string
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "bootnode",
+	Short: "",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBootnode()
+	},
+}
+
+func main() {
+	rootCmd.Flags().StringVar(&privateKey, "private-key", "", "")
+	rootCmd.Flags().IntVar(&port, "port", 30301, "udp port of the discovery protocols")
+	rootCmd.Flags().IntVar(&httpPort, "http-port", 8080, "")
+	rootCmd.Flags().StringVar(&enrFile, "enr-file", "", "file to write the ENR to as a boot_enr.yaml list")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func runBootnode() error {
+	cfg := &bootnode.Config{
+		LogOutput:     os.Stdout,
+		PrivateKey:    privateKey,
+		DiscoveryPort: uint64(port),
+		HTTPPort:      uint64(httpPort),
+		ENRFile:       enrFile,
+	}
+
+	b, err := bootnode.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create bootnode: %w", err)
+	}
+	return b.Run()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	enginemux "github.com/ferranbt/builder-playground/engine-mux"
+	"github.com/spf13/cobra"
+)
+
+var (
+	primary     string
+	secondaries []string
+	jwtSecrets  []string
+	policy      string
+	recordFile  string
+	port        int
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "engine-mux",
+	Short: "",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runEngineMux()
+	},
+}
+
+func main() {
+	rootCmd.Flags().StringVar(&primary, "primary", "http://localhost:8551", "")
+	rootCmd.Flags().StringArrayVar(&secondaries, "secondary", []string{}, "<name>=<url>")
+	rootCmd.Flags().StringArrayVar(&jwtSecrets, "secondary-jwt", []string{}, "<name>=<path>")
+	rootCmd.Flags().StringVar(&policy, "policy", string(enginemux.PolicyPrimary), "")
+	rootCmd.Flags().StringVar(&recordFile, "record", "", "")
+	rootCmd.Flags().IntVar(&port, "port", 5858, "")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func runEngineMux() error {
+	secrets := map[string]string{}
+	for _, item := range jwtSecrets {
+		name, path, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("invalid jwt secret '%s', expected <name>=<path>", item)
+		}
+		secrets[name] = path
+	}
+
+	cfg := &enginemux.Config{
+		LogOutput:  os.Stdout,
+		Port:       uint64(port),
+		Primary:    primary,
+		Policy:     enginemux.Policy(policy),
+		RecordFile: recordFile,
+	}
+	for _, item := range secondaries {
+		name, url, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("invalid secondary '%s', expected <name>=<url>", item)
+		}
+		secret, ok := secrets[name]
+		if !ok {
+			return fmt.Errorf("secondary %s has no jwt secret", name)
+		}
+		cfg.Secondaries = append(cfg.Secondaries, &enginemux.Target{Name: name, URL: url, JWTSecret: secret})
+	}
+
+	mux, err := enginemux.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create engine mux: %w", err)
+	}
+	return mux.Run()
+}
@@ -0,0 +1,535 @@
+package enginemux
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Policy selects the payload returned to the consensus client among the ones built by the targets
+type Policy string
+
+var (
+	// PolicyPrimary always returns the payload of the primary target, the other ones are only
+	// fetched to compare them
+	PolicyPrimary Policy = "primary"
+
+	// PolicyHighestValue returns the payload with the highest block value
+	PolicyHighestValue Policy = "highest-value"
+
+	// PolicyRoundRobin returns the payload of each target in turn
+	PolicyRoundRobin Policy = "round-robin"
+)
+
+func (p Policy) Validate() error {
+	switch p {
+	case PolicyPrimary, PolicyHighestValue, PolicyRoundRobin:
+		return nil
+	}
+	return fmt.Errorf("invalid policy '%s', expected primary, highest-value or round-robin", p)
+}
+
+// primaryName is the name of the primary target in the logs and the record file
+const primaryName = "primary"
+
+// maxPayloads is the number of payload ids tracked, the ones of the payloads that the
+// consensus client never requested are dropped
+const maxPayloads = 64
+
+// Target is an execution node driven by the multiplexer besides the primary one
+type Target struct {
+	Name string
+	URL  string
+
+	// JWTSecret is the path of the JWT secret of the target. The requests to the primary
+	// target keep the authentication of the consensus client instead.
+	JWTSecret string
+
+	secret []byte
+}
+
+type Config struct {
+	LogOutput io.Writer
+	Port      uint64
+
+	// Primary is the URL of the execution node that the consensus client authenticates with.
+	// Its responses are returned to the consensus client, except for getPayload.
+	Primary     string
+	Secondaries []*Target
+	Policy      Policy
+
+	// RecordFile is the path of the file where the payloads built by the targets are recorded
+	RecordFile string
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		LogOutput: os.Stdout,
+		Port:      5858,
+		Policy:    PolicyPrimary,
+	}
+}
+
+// EngineMux lets one consensus client drive multiple execution nodes (or builders) on the same
+// chain. It broadcasts newPayload and forkchoiceUpdated to all of them, so that all of them
+// follow the chain and build a payload every slot, and returns the payload of the one selected
+// by the policy in getPayload. The other Engine API methods are only sent to the primary.
+type EngineMux struct {
+	config *Config
+	log    *logrus.Entry
+	server *http.Server
+	client *http.Client
+
+	lock sync.Mutex
+	// payloads maps the payload ids of the primary to the ones of the secondaries
+	payloads map[string][]string
+	turn     int
+
+	recordLock sync.Mutex
+	record     *os.File
+}
+
+func New(config *Config) (*EngineMux, error) {
+	if err := config.Policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	log := common.LogSetup(false, "info")
+	log.Logger.SetOutput(config.LogOutput)
+
+	for _, target := range config.Secondaries {
+		data, err := os.ReadFile(target.JWTSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the jwt secret of %s: %w", target.Name, err)
+		}
+		secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwt secret of %s: %w", target.Name, err)
+		}
+		target.secret = secret
+	}
+
+	mux := &EngineMux{
+		config:   config,
+		log:      log,
+		client:   &http.Client{Timeout: 8 * time.Second},
+		payloads: map[string][]string{},
+	}
+	if config.RecordFile != "" {
+		record, err := os.OpenFile(config.RecordFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open record file: %w", err)
+		}
+		mux.record = record
+	}
+	return mux, nil
+}
+
+// Run starts the HTTP server
+func (s *EngineMux) Run() error {
+	mux := http.NewServeMux()
+	s.server = &http.Server{
+		Addr:         fmt.Sprintf(":%d", s.config.Port),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		Handler:      mux,
+	}
+
+	mux.HandleFunc("/", s.handleRequest)
+
+	names := []string{}
+	for _, target := range s.config.Secondaries {
+		names = append(names, target.Name)
+	}
+	s.log.Infof("Starting server on port %d, primary %s, secondaries [%s], policy %s", s.config.Port, s.config.Primary, strings.Join(names, ", "), s.config.Policy)
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return fmt.Errorf("server error: %v", err)
+	}
+	return nil
+}
+
+// Close gracefully shuts down the server
+func (s *EngineMux) Close() error {
+	s.log.Info("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("server shutdown error: %v", err)
+	}
+	if s.record != nil {
+		return s.record.Close()
+	}
+	return nil
+}
+
+type jsonrpcMessage struct {
+	Version string            `json:"jsonrpc,omitempty"`
+	ID      json.RawMessage   `json:"id,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Params  []json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage   `json:"result,omitempty"`
+	Error   json.RawMessage   `json:"error,omitempty"`
+}
+
+// response is the response of a target to a request
+type response struct {
+	status int
+	data   []byte
+	msg    *jsonrpcMessage
+	err    error
+}
+
+func (r *response) failed() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.msg == nil {
+		return fmt.Errorf("unexpected status code %d", r.status)
+	}
+	if len(r.msg.Error) != 0 && string(r.msg.Error) != "null" {
+		return fmt.Errorf("error response: %s", string(r.msg.Error))
+	}
+	return nil
+}
+
+func (s *EngineMux) handleRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	var req jsonrpcMessage
+	if err := json.Unmarshal(data, &req); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var resp *response
+	switch {
+	case strings.HasPrefix(req.Method, "engine_forkchoiceUpdated"):
+		resp = s.forkchoiceUpdated(r, data, &req)
+	case strings.HasPrefix(req.Method, "engine_newPayload"):
+		// the response of the primary is returned, the secondaries only follow the chain
+		resp = s.sendAll(r, func(i int) []byte { return data })[0]
+	case strings.HasPrefix(req.Method, "engine_getPayload"):
+		resp = s.getPayload(r, &req)
+	default:
+		resp = s.send(s.config.Primary, r.Header, data)
+	}
+
+	if resp.err != nil {
+		s.log.Errorf("Error proxying %s: %v", req.Method, resp.err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.status)
+	w.Write(resp.data)
+}
+
+// sendAll sends a request to each target, built by body from the index of the target (0 is
+// the primary), and returns their responses in the same order
+func (s *EngineMux) sendAll(r *http.Request, body func(i int) []byte) []*response {
+	responses := make([]*response, 1+len(s.config.Secondaries))
+
+	var wg sync.WaitGroup
+	for i := range responses {
+		data := body(i)
+		if data == nil {
+			responses[i] = &response{err: fmt.Errorf("no request for the target")}
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i == 0 {
+				responses[i] = s.send(s.config.Primary, r.Header, data)
+				return
+			}
+			target := s.config.Secondaries[i-1]
+			header, err := target.authHeader()
+			if err != nil {
+				responses[i] = &response{err: err}
+				return
+			}
+			responses[i] = s.send(target.URL, header, data)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, resp := range responses[1:] {
+		if err := resp.failed(); err != nil {
+			s.log.Warnf("Request to %s failed: %v", s.config.Secondaries[i].Name, err)
+		}
+	}
+	return responses
+}
+
+func (s *EngineMux) forkchoiceUpdated(r *http.Request, data []byte, req *jsonrpcMessage) *response {
+	responses := s.sendAll(r, func(i int) []byte { return data })
+	primary := responses[0]
+	if len(req.Params) < 2 || string(req.Params[1]) == "null" || primary.failed() != nil {
+		return primary
+	}
+
+	// the payload attributes start a payload on every target, the consensus client only
+	// knows the id of the one of the primary
+	primaryID := payloadID(primary)
+	if primaryID == "" {
+		return primary
+	}
+	ids := make([]string, len(s.config.Secondaries))
+	for i, resp := range responses[1:] {
+		if resp.failed() == nil {
+			ids[i] = payloadID(resp)
+		}
+	}
+
+	s.lock.Lock()
+	if len(s.payloads) >= maxPayloads {
+		s.payloads = map[string][]string{}
+	}
+	s.payloads[primaryID] = ids
+	s.lock.Unlock()
+	return primary
+}
+
+func payloadID(resp *response) string {
+	var result struct {
+		PayloadID *string `json:"payloadId"`
+	}
+	if err := json.Unmarshal(resp.msg.Result, &result); err != nil || result.PayloadID == nil {
+		return ""
+	}
+	return *result.PayloadID
+}
+
+// Candidate is a payload built by a target for a getPayload request
+type Candidate struct {
+	Target      string `json:"target"`
+	BlockHash   string `json:"blockHash,omitempty"`
+	BlockNumber string `json:"blockNumber,omitempty"`
+	NumTx       int    `json:"numTx"`
+	GasUsed     string `json:"gasUsed,omitempty"`
+
+	// BlockValue is the value of the payload in wei, it is not returned by getPayloadV1
+	BlockValue string `json:"blockValue,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	value *big.Int
+}
+
+// Entry is a getPayload request with the payloads of all the targets, a line of the record file
+type Entry struct {
+	Time      time.Time    `json:"time"`
+	Method    string       `json:"method"`
+	PayloadID string       `json:"payloadId"`
+	Selected  string       `json:"selected"`
+	Payloads  []*Candidate `json:"payloads"`
+}
+
+func (s *EngineMux) getPayload(r *http.Request, req *jsonrpcMessage) *response {
+	var primaryID string
+	if len(req.Params) != 0 {
+		json.Unmarshal(req.Params[0], &primaryID)
+	}
+	s.lock.Lock()
+	ids, ok := s.payloads[primaryID]
+	delete(s.payloads, primaryID)
+	s.lock.Unlock()
+
+	responses := s.sendAll(r, func(i int) []byte {
+		id := primaryID
+		if i != 0 {
+			if !ok || ids[i-1] == "" {
+				return nil
+			}
+			id = ids[i-1]
+		}
+		param, _ := json.Marshal(id)
+		msg := *req
+		msg.Params = []json.RawMessage{param}
+		data, err := json.Marshal(&msg)
+		if err != nil {
+			return nil
+		}
+		return data
+	})
+
+	entry := &Entry{Time: time.Now().UTC(), Method: req.Method, PayloadID: primaryID, Payloads: []*Candidate{}}
+	for i, resp := range responses {
+		name := primaryName
+		if i != 0 {
+			name = s.config.Secondaries[i-1].Name
+		}
+		entry.Payloads = append(entry.Payloads, newCandidate(name, resp))
+	}
+
+	selected := s.selectPayload(entry.Payloads)
+	entry.Selected = entry.Payloads[selected].Target
+
+	summary := []string{}
+	for _, candidate := range entry.Payloads {
+		if candidate.Error != "" {
+			summary = append(summary, fmt.Sprintf("%s=error", candidate.Target))
+		} else {
+			summary = append(summary, fmt.Sprintf("%s=%s (%d txs)", candidate.Target, candidate.BlockValue, candidate.NumTx))
+		}
+	}
+	s.log.Infof("Payload %s: selected %s, %s", primaryID, entry.Selected, strings.Join(summary, ", "))
+	s.writeRecord(entry)
+
+	resp := responses[selected]
+	if selected != 0 {
+		// the consensus client expects the id of its request
+		msg := *resp.msg
+		msg.ID = req.ID
+		data, err := json.Marshal(&msg)
+		if err != nil {
+			return responses[0]
+		}
+		resp = &response{status: resp.status, data: data, msg: &msg}
+	}
+	return resp
+}
+
+func newCandidate(name string, resp *response) *Candidate {
+	candidate := &Candidate{Target: name}
+	if err := resp.failed(); err != nil {
+		candidate.Error = err.Error()
+		return candidate
+	}
+
+	type executionPayload struct {
+		BlockHash    string            `json:"blockHash"`
+		BlockNumber  string            `json:"blockNumber"`
+		GasUsed      string            `json:"gasUsed"`
+		Transactions []json.RawMessage `json:"transactions"`
+	}
+	var result struct {
+		ExecutionPayload *executionPayload `json:"executionPayload"`
+		BlockValue       string            `json:"blockValue"`
+	}
+	if err := json.Unmarshal(resp.msg.Result, &result); err != nil {
+		candidate.Error = fmt.Sprintf("invalid payload: %v", err)
+		return candidate
+	}
+	payload := result.ExecutionPayload
+	if payload == nil {
+		// getPayloadV1 returns the execution payload alone
+		payload = &executionPayload{}
+		if err := json.Unmarshal(resp.msg.Result, payload); err != nil {
+			candidate.Error = fmt.Sprintf("invalid payload: %v", err)
+			return candidate
+		}
+	}
+	candidate.BlockHash, candidate.BlockNumber, candidate.GasUsed = payload.BlockHash, payload.BlockNumber, payload.GasUsed
+	candidate.NumTx = len(payload.Transactions)
+	if value, ok := new(big.Int).SetString(strings.TrimPrefix(result.BlockValue, "0x"), 16); ok {
+		candidate.value = value
+		candidate.BlockValue = value.String()
+	}
+	return candidate
+}
+
+// selectPayload returns the index of the payload returned to the consensus client. The
+// primary is used if the selected target failed to build a payload.
+func (s *EngineMux) selectPayload(candidates []*Candidate) int {
+	selected := 0
+	switch s.config.Policy {
+	case PolicyHighestValue:
+		for i, candidate := range candidates {
+			if candidate.Error != "" || candidate.value == nil {
+				continue
+			}
+			if best := candidates[selected]; best.Error != "" || best.value == nil || candidate.value.Cmp(best.value) > 0 {
+				selected = i
+			}
+		}
+	case PolicyRoundRobin:
+		s.lock.Lock()
+		selected = s.turn % len(candidates)
+		s.turn++
+		s.lock.Unlock()
+	}
+	if candidates[selected].Error != "" {
+		return 0
+	}
+	return selected
+}
+
+func (s *EngineMux) send(dst string, header http.Header, data []byte) *response {
+	req, err := http.NewRequest(http.MethodPost, dst, bytes.NewBuffer(data))
+	if err != nil {
+		return &response{err: err}
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return &response{err: err}
+	}
+	defer resp.Body.Close()
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &response{err: err}
+	}
+	result := &response{status: resp.StatusCode, data: respData}
+	var msg jsonrpcMessage
+	if err := json.Unmarshal(respData, &msg); err == nil {
+		result.msg = &msg
+	}
+	return result
+}
+
+// authHeader returns the authentication of a request to the Engine API of the target
+func (t *Target) authHeader() (http.Header, error) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iat": time.Now().Unix(),
+	}).SignedString(t.secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the jwt token of %s: %w", t.Name, err)
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	return header, nil
+}
+
+func (s *EngineMux) writeRecord(entry *Entry) {
+	if s.record == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		s.log.Errorf("Error marshalling record entry: %v", err)
+		return
+	}
+
+	s.recordLock.Lock()
+	defer s.recordLock.Unlock()
+	if _, err := s.record.Write(append(data, '\n')); err != nil {
+		s.log.Errorf("Error writing record entry: %v", err)
+	}
+}
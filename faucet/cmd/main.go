@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ferranbt/builder-playground/faucet"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rpc        string
+	privateKey string
+	port       int
+	amount     string
+	cooldown   time.Duration
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "faucet",
+	Short: "",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runFaucet()
+	},
+}
+
+func main() {
+	rootCmd.Flags().StringVar(&rpc, "rpc", "http://localhost:8545", "")
+	rootCmd.Flags().StringVar(&privateKey, "private-key", "", "")
+	rootCmd.Flags().IntVar(&port, "port", 8090, "")
+	rootCmd.Flags().StringVar(&amount, "amount", "10", "amount of ether sent on each request")
+	rootCmd.Flags().DurationVar(&cooldown, "cooldown", 0, "")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func runFaucet() error {
+	ether, ok := new(big.Float).SetString(amount)
+	if !ok {
+		return fmt.Errorf("invalid amount '%s'", amount)
+	}
+	wei, _ := new(big.Float).Mul(ether, big.NewFloat(1e18)).Int(nil)
+
+	cfg := &faucet.Config{
+		LogOutput:  os.Stdout,
+		Port:       uint64(port),
+		RPC:        rpc,
+		PrivateKey: privateKey,
+		Amount:     wei,
+		Cooldown:   cooldown,
+	}
+
+	f, err := faucet.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create faucet: %w", err)
+	}
+	return f.Run()
+}
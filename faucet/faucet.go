@@ -0,0 +1,300 @@
+package faucet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	relaycommon "github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+type Config struct {
+	LogOutput io.Writer
+	Port      uint64
+
+	// RPC is the URL of the JSON-RPC API of the execution node that receives the transfers
+	RPC string
+
+	// PrivateKey is the hex encoded key of the prefunded account that funds the transfers
+	PrivateKey string
+
+	// Amount is the number of wei sent on each request
+	Amount *big.Int
+
+	// Cooldown is the minimum time between two transfers to the same address. There is
+	// no limit if it is zero.
+	Cooldown time.Duration
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		LogOutput: os.Stdout,
+		Port:      8090,
+		Amount:    new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18)),
+	}
+}
+
+// Faucet is an HTTP service that sends funds from a prefunded account to the addresses
+// that request them
+type Faucet struct {
+	config *Config
+	log    *logrus.Entry
+	server *http.Server
+	client *ethclient.Client
+
+	key     *ecdsa.PrivateKey
+	address common.Address
+
+	// lock serializes the transfers so that the nonces are sequential
+	lock    sync.Mutex
+	chainID *big.Int
+	nonce   *uint64
+	funded  map[common.Address]time.Time
+}
+
+func New(config *Config) (*Faucet, error) {
+	log := relaycommon.LogSetup(false, "info")
+	log.Logger.SetOutput(config.LogOutput)
+
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(config.PrivateKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	if config.Amount == nil || config.Amount.Sign() <= 0 {
+		return nil, fmt.Errorf("the amount must be positive")
+	}
+
+	client, err := ethclient.Dial(config.RPC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", config.RPC, err)
+	}
+
+	faucet := &Faucet{
+		config:  config,
+		log:     log,
+		client:  client,
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+		funded:  map[common.Address]time.Time{},
+	}
+	return faucet, nil
+}
+
+// Run starts the HTTP server
+func (f *Faucet) Run() error {
+	mux := http.NewServeMux()
+	f.server = &http.Server{
+		Addr:         fmt.Sprintf(":%d", f.config.Port),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: time.Minute,
+		Handler:      mux,
+	}
+
+	mux.HandleFunc("/info", f.handleInfo)
+	mux.HandleFunc("/fund", f.handleFund)
+
+	f.log.Infof("Starting faucet on port %d, funding from %s", f.config.Port, f.address)
+	if err := f.server.ListenAndServe(); err != http.ErrServerClosed {
+		return fmt.Errorf("server error: %v", err)
+	}
+	return nil
+}
+
+// Close gracefully shuts down the server
+func (f *Faucet) Close() error {
+	f.log.Info("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := f.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("server shutdown error: %v", err)
+	}
+	f.client.Close()
+	return nil
+}
+
+type infoResponse struct {
+	Address  common.Address `json:"address"`
+	Balance  string         `json:"balance"`
+	Amount   string         `json:"amount"`
+	Cooldown string         `json:"cooldown"`
+}
+
+func (f *Faucet) handleInfo(w http.ResponseWriter, r *http.Request) {
+	balance, err := f.client.BalanceAt(r.Context(), f.address, nil)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to get the faucet balance: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, &infoResponse{
+		Address:  f.address,
+		Balance:  balance.String(),
+		Amount:   f.config.Amount.String(),
+		Cooldown: f.config.Cooldown.String(),
+	})
+}
+
+type fundRequest struct {
+	Address string `json:"address"`
+	// Wait signals to respond once the transfer is included in a block
+	Wait bool `json:"wait"`
+}
+
+type fundResponse struct {
+	TxHash      common.Hash `json:"txHash"`
+	Amount      string      `json:"amount"`
+	BlockNumber uint64      `json:"blockNumber,omitempty"`
+}
+
+// handleFund sends the amount to the address of the request, either in the JSON body of
+// a POST request ({"address": "0x...", "wait": true}) or in the query of a GET request
+// (/fund?address=0x...&wait=true)
+func (f *Faucet) handleFund(w http.ResponseWriter, r *http.Request) {
+	var req fundRequest
+	switch r.Method {
+	case http.MethodGet:
+		req.Address = r.URL.Query().Get("address")
+		req.Wait = r.URL.Query().Get("wait") == "true"
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	if !common.IsHexAddress(req.Address) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid address '%s'", req.Address))
+		return
+	}
+	to := common.HexToAddress(req.Address)
+
+	tx, err := f.send(r.Context(), to)
+	if err != nil {
+		status := http.StatusBadGateway
+		if err == errCooldown {
+			status = http.StatusTooManyRequests
+		}
+		writeError(w, status, err)
+		return
+	}
+	f.log.WithField("to", to.Hex()).WithField("tx", tx.Hash().Hex()).Info("Funded address")
+
+	resp := &fundResponse{TxHash: tx.Hash(), Amount: f.config.Amount.String()}
+	if req.Wait {
+		receipt, err := f.waitForReceipt(r.Context(), tx.Hash())
+		if err != nil {
+			writeError(w, http.StatusGatewayTimeout, fmt.Errorf("transaction %s not included: %w", tx.Hash(), err))
+			return
+		}
+		resp.BlockNumber = receipt.BlockNumber.Uint64()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+var errCooldown = errors.New("the address was funded recently, try again later")
+
+func (f *Faucet) send(ctx context.Context, to common.Address) (*types.Transaction, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if last, ok := f.funded[to]; ok && f.config.Cooldown != 0 && time.Since(last) < f.config.Cooldown {
+		return nil, errCooldown
+	}
+
+	if f.chainID == nil {
+		chainID, err := f.client.ChainID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the chain id: %w", err)
+		}
+		f.chainID = chainID
+	}
+	if f.nonce == nil {
+		nonce, err := f.client.PendingNonceAt(ctx, f.address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the faucet nonce: %w", err)
+		}
+		f.nonce = &nonce
+	}
+
+	tip, err := f.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the gas tip: %w", err)
+	}
+	head, err := f.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the latest block: %w", err)
+	}
+	// the recipient can be a contract (i.e. a smart wallet) that runs code on transfers
+	gas, err := f.client.EstimateGas(ctx, ethereum.CallMsg{From: f.address, To: &to, Value: f.config.Amount})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate the gas of the transfer: %w", err)
+	}
+	// leave room for the base fee to double before the transfer is included
+	feeCap := new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+
+	tx, err := types.SignNewTx(f.key, types.LatestSignerForChainID(f.chainID), &types.DynamicFeeTx{
+		ChainID:   f.chainID,
+		Nonce:     *f.nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       gas,
+		To:        &to,
+		Value:     f.config.Amount,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the transfer: %w", err)
+	}
+	if err := f.client.SendTransaction(ctx, tx); err != nil {
+		// the nonce is fetched again in the next transfer in case it is out of sync
+		f.nonce = nil
+		return nil, fmt.Errorf("failed to send the transfer: %w", err)
+	}
+	*f.nonce++
+	f.funded[to] = time.Now()
+	return tx, nil
+}
+
+func (f *Faucet) waitForReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	for {
+		receipt, err := f.client.TransactionReceipt(ctx, hash)
+		if err == nil {
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(obj)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -13,6 +13,7 @@ var (
 	apiListenPort        uint64
 	beaconClientAddr     string
 	validationServerAddr string
+	optimisticBuilders   []string
 )
 
 var rootCmd = &cobra.Command{
@@ -29,6 +30,7 @@ func main() {
 	rootCmd.Flags().Uint64Var(&apiListenPort, "api-listen-port", 5555, "")
 	rootCmd.Flags().StringVar(&beaconClientAddr, "beacon-client-addr", "http://localhost:3500", "")
 	rootCmd.Flags().StringVar(&validationServerAddr, "validation-server-addr", "", "")
+	rootCmd.Flags().StringSliceVar(&optimisticBuilders, "optimistic-builders", []string{}, "")
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -42,6 +44,7 @@ func runMevBoostRelay() error {
 	cfg.ApiListenPort = apiListenPort
 	cfg.BeaconClientAddr = beaconClientAddr
 	cfg.ValidationServerAddr = validationServerAddr
+	cfg.OptimisticBuilders = optimisticBuilders
 
 	relay, err := mevboostrelay.New(cfg)
 	if err != nil {
@@ -21,6 +21,7 @@ import (
 	"github.com/flashbots/mev-boost-relay/datastore"
 	"github.com/flashbots/mev-boost-relay/services/api"
 	"github.com/flashbots/mev-boost-relay/services/housekeeper"
+	"github.com/holiman/uint256"
 	"github.com/sirupsen/logrus"
 )
 
@@ -34,6 +35,10 @@ type Config struct {
 	LogOutput        io.Writer
 
 	ValidationServerAddr string
+
+	// OptimisticBuilders are the BLS public keys of the builders whose blocks are accepted
+	// before they are validated (optimistic relaying). They have enough collateral for any bid.
+	OptimisticBuilders []string
 }
 
 func DefaultConfig() *Config {
@@ -110,6 +115,16 @@ func New(config *Config) (*MevBoostRelay, error) {
 
 	// create the mockDB
 	pqDB := newInmemoryDB()
+	for _, pubkey := range config.OptimisticBuilders {
+		pqDB.Builders[pubkey] = &database.BlockBuilderEntry{
+			BuilderPubkey: pubkey,
+			IsOptimistic:  true,
+			Collateral:    optimisticCollateral,
+		}
+	}
+	if len(config.OptimisticBuilders) != 0 {
+		log.Infof("Optimistic relaying enabled for %d builders", len(config.OptimisticBuilders))
+	}
 
 	// datastore
 	ds, err := datastore.NewDatastore(redis, nil, pqDB)
@@ -287,13 +302,21 @@ type inmemoryDB struct {
 
 	deliveredPayloadsLock sync.Mutex
 	deliveredPayloads     []*database.DeliveredPayloadEntry
+
+	blockSubmissionsLock sync.Mutex
+	blockSubmissions     []*database.BuilderBlockSubmissionEntry
 }
 
+// optimisticCollateral is the collateral of the optimistic builders in wei (1M ETH), so that
+// all their bids are accepted optimistically
+var optimisticCollateral = "1000000000000000000000000"
+
 func newInmemoryDB() *inmemoryDB {
 	return &inmemoryDB{
-		MockDB:                   &database.MockDB{},
+		MockDB:                   &database.MockDB{Builders: map[string]*database.BlockBuilderEntry{}},
 		validatorRegistryEntries: make(map[string]*database.ValidatorRegistrationEntry),
 		deliveredPayloads:        make([]*database.DeliveredPayloadEntry, 0),
+		blockSubmissions:         make([]*database.BuilderBlockSubmissionEntry, 0),
 	}
 }
 
@@ -413,6 +436,12 @@ func (i *inmemoryDB) GetRecentDeliveredPayloads(filters database.GetPayloadsFilt
 }
 
 func filterPayload(entry *database.DeliveredPayloadEntry, filter database.GetPayloadsFilters) bool {
+	if filter.Slot != 0 {
+		if entry.Slot != uint64(filter.Slot) {
+			return true
+		}
+	}
+
 	if filter.BlockNumber != 0 {
 		if entry.BlockNumber != uint64(filter.BlockNumber) {
 			return true
@@ -428,6 +457,91 @@ func filterPayload(entry *database.DeliveredPayloadEntry, filter database.GetPay
 	return false
 }
 
+// -- endpoints for the builder block submissions ---
+
+func (i *inmemoryDB) SaveBuilderBlockSubmission(payload *common.VersionedSubmitBlockRequest, requestError, validationError error, receivedAt, eligibleAt time.Time, wasSimulated, saveExecPayload bool, profile common.Profile, optimisticSubmission bool, blockValue *uint256.Int) (*database.BuilderBlockSubmissionEntry, error) {
+	submission, err := common.GetBlockSubmissionInfo(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	simErrStr := ""
+	if validationError != nil {
+		simErrStr = validationError.Error()
+	}
+	requestErrStr := ""
+	if requestError != nil {
+		requestErrStr = requestError.Error()
+	}
+
+	entry := &database.BuilderBlockSubmissionEntry{
+		InsertedAt: time.Now(),
+		ReceivedAt: database.NewNullTime(receivedAt),
+		EligibleAt: database.NewNullTime(eligibleAt),
+
+		WasSimulated: wasSimulated,
+		SimSuccess:   wasSimulated && validationError == nil,
+		SimError:     simErrStr,
+		SimReqError:  requestErrStr,
+
+		Signature: submission.Signature.String(),
+
+		Slot:       submission.BidTrace.Slot,
+		BlockHash:  submission.BidTrace.BlockHash.String(),
+		ParentHash: submission.BidTrace.ParentHash.String(),
+
+		BuilderPubkey:        submission.BidTrace.BuilderPubkey.String(),
+		ProposerPubkey:       submission.BidTrace.ProposerPubkey.String(),
+		ProposerFeeRecipient: submission.BidTrace.ProposerFeeRecipient.String(),
+
+		GasUsed:  submission.GasUsed,
+		GasLimit: submission.GasLimit,
+
+		NumTx: uint64(len(submission.Transactions)),
+		Value: submission.BidTrace.Value.Dec(),
+
+		Epoch:       submission.BidTrace.Slot / common.SlotsPerEpoch,
+		BlockNumber: submission.BlockNumber,
+
+		OptimisticSubmission: optimisticSubmission,
+	}
+
+	i.blockSubmissionsLock.Lock()
+	defer i.blockSubmissionsLock.Unlock()
+
+	entry.ID = int64(len(i.blockSubmissions) + 1)
+	i.blockSubmissions = append(i.blockSubmissions, entry)
+	return entry, nil
+}
+
+// GetBuilderSubmissions returns the submissions that passed the simulation, like the
+// postgres implementation of the relay
+func (i *inmemoryDB) GetBuilderSubmissions(filters database.GetBuilderSubmissionsFilters) ([]*database.BuilderBlockSubmissionEntry, error) {
+	i.blockSubmissionsLock.Lock()
+	defer i.blockSubmissionsLock.Unlock()
+
+	entries := []*database.BuilderBlockSubmissionEntry{}
+	for _, entry := range i.blockSubmissions {
+		if !entry.SimSuccess && !entry.OptimisticSubmission {
+			continue
+		}
+		if filters.Slot != 0 && entry.Slot != uint64(filters.Slot) {
+			continue
+		}
+		if filters.BlockNumber != 0 && entry.BlockNumber != uint64(filters.BlockNumber) {
+			continue
+		}
+		if filters.BlockHash != "" && entry.BlockHash != filters.BlockHash {
+			continue
+		}
+		if filters.BuilderPubkey != "" && entry.BuilderPubkey != filters.BuilderPubkey {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
 type Spec struct {
 	SecondsPerSlot                  uint64 `json:"SECONDS_PER_SLOT,string"`            //nolint:tagliatelle
 	DepositContractAddress          string `json:"DEPOSIT_CONTRACT_ADDRESS"`           //nolint:tagliatelle
@@ -0,0 +1,1650 @@
+package cli
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ferranbt/builder-playground/pkg/playground"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var playgroundLog = playground.Logger("playground")
+
+var outputFlag string
+var genesisDelayFlag uint64
+var withOverrides []string
+var watchdog bool
+var dryRun bool
+var interactive bool
+var timeout time.Duration
+var logLevelFlag string
+var logFormatFlag string
+var deployFlags []string
+var slotTimeFlag uint64
+var platformOverrides []string
+var envFilesFlag []string
+var replayTargetFlag string
+var replayJWTSecretFlag string
+var chaosDepthFlag uint64
+var chaosTimeoutFlag time.Duration
+var validatorsCountFlag uint64
+var keysOutputFlag string
+var keysRestartFlag bool
+var verifyJSONFlag bool
+var verifyTimeoutFlag time.Duration
+var recipeFileFlag string
+var sessionNameFlag string
+var graphFormats []string
+var forkRPCFlag string
+var forkBlockFlag uint64
+var forkAccountsFlag []string
+var uiFlag bool
+var uiPortFlag uint64
+var healthPortFlag uint64
+var offlineFlag bool
+var bundleFlag string
+var statsIntervalFlag time.Duration
+var crashDumpPprofFlag time.Duration
+var recordBidsFlag bool
+var hostNamesFlag bool
+var allowEgressFlag []string
+var logMaxSizeFlag uint64
+var containerEngineFlag string
+var numValidatorsFlag uint64
+var prefundedBalanceFlag string
+var genesisBaseFeeFlag uint64
+var prefundedNonceFlag uint64
+var dutiesEpochsFlag uint64
+var watchdogFinalityEpochsFlag uint64
+var insecureKeysFlag bool
+var logRetentionFlag int
+var followLogsFlag bool
+var followLogsLevelFlag string
+var cleanOlderThanFlag time.Duration
+var cleanDryRunFlag bool
+var pruneDryRunFlag bool
+var rotateJWTSecretsFlag time.Duration
+var clConfigFlag string
+var disableSystemContractsFlag []string
+var forkEpochFlags []string
+var configFlag string
+var pullPolicyFlag string
+var lockedFlag string
+var bindFlag []string
+var userFlag []string
+var readyTimeoutFlag []string
+var clockSkewFlag []string
+var remoteFlag string
+var restartPolicyFlag []string
+var exportFlag string
+var templatesFlag string
+var withExplorerFlag []string
+var withFaucetFlag bool
+var withGrafanaFlag bool
+var attachFlag []string
+var requireSignatureFlag bool
+var downloadProxyFlag string
+var downloadConnectionsFlag int
+var platformFlag []string
+var releasePlatformFlag string
+var onBlockFlag string
+var onSlotFlag string
+var otelEndpointFlag string
+var errorFormatFlag string
+var scenarioJSONFlag bool
+var planDiffJSONFlag bool
+var logsSinceFlag time.Duration
+var logsGrepFlag string
+var logsLevelFlag string
+
+var rootCmd = &cobra.Command{
+	Use:   "playground",
+	Short: "",
+	Long:  ``,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := playground.ErrorFormat(errorFormatFlag).Validate(); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+		if playground.ErrorFormat(errorFormatFlag) == playground.ErrorFormatJSON {
+			// the final error is the only thing written to stderr
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		}
+		return playground.ConfigureContainerEngine(playground.ContainerEngine(containerEngineFlag))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+}
+
+var cookCmd = &cobra.Command{
+	Use:   "cook",
+	Short: "Cook a recipe",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if recipeFileFlag != "" {
+			recipe, err := playground.NewYamlRecipe(recipeFileFlag)
+			if err != nil {
+				return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+			}
+			if err := loadFlagConfig(cmd, recipe); err != nil {
+				return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+			}
+			return runIt(recipe)
+		}
+
+		recipeNames := []string{}
+		for _, recipe := range recipes {
+			recipeNames = append(recipeNames, recipe.Name())
+		}
+		return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("please specify a recipe to cook or a recipe file with --file. Available recipes: %s", recipeNames))
+	},
+}
+
+var manifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "Print the normalized manifest and artifacts of a recipe as JSON",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if recipeFileFlag != "" {
+			recipe, err := playground.NewYamlRecipe(recipeFileFlag)
+			if err != nil {
+				return err
+			}
+			return printManifest(recipe)
+		}
+		return fmt.Errorf("please specify a recipe or a recipe file with --file")
+	},
+}
+
+func printManifest(recipe playground.Recipe) error {
+	data, err := playground.RenderSnapshot(recipe)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+// loadFlagConfig sets the cook flags from the PLAYGROUND_* environment variables and the config file
+func loadFlagConfig(cmd *cobra.Command, recipe playground.Recipe) error {
+	path := configFlag
+	if path == "" {
+		path = os.Getenv(playground.FlagEnvName("config"))
+	}
+	recipeNames := []string{}
+	for _, recipe := range recipes {
+		recipeNames = append(recipeNames, recipe.Name())
+	}
+	return playground.LoadFlagConfig(cmd.Flags(), recipe.Name(), recipeNames, path)
+}
+
+var describeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "List the services of a recipe and the values that can be overridden",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if recipeFileFlag != "" {
+			recipe, err := playground.NewYamlRecipe(recipeFileFlag)
+			if err != nil {
+				return err
+			}
+			return describeRecipe(recipe)
+		}
+		return fmt.Errorf("please specify a recipe or a recipe file with --file")
+	},
+}
+
+func describeRecipe(recipe playground.Recipe) error {
+	manifest, err := playground.ApplyRecipe(recipe)
+	if err != nil {
+		return err
+	}
+	fmt.Print(manifest.Describe())
+	return nil
+}
+
+var artifactsCmd = &cobra.Command{
+	Use:   "artifacts",
+	Short: "List available artifacts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("please specify a service name")
+		}
+		serviceName := args[0]
+		component := playground.FindComponent(serviceName)
+		if component == nil {
+			return fmt.Errorf("service %s not found", serviceName)
+		}
+		releaseService, ok := component.(playground.ReleaseService)
+		if !ok {
+			return fmt.Errorf("service %s is not a release service", serviceName)
+		}
+		output := outputFlag
+		if output == "" {
+			homeDir, err := playground.GetHomeDir()
+			if err != nil {
+				return fmt.Errorf("failed to get home directory: %w", err)
+			}
+			output = homeDir
+		}
+		opts, err := releaseDownloadOptions("")
+		if err != nil {
+			return err
+		}
+		platforms := platformFlag
+		if len(platforms) == 0 {
+			// the host
+			platforms = []string{""}
+		}
+		for _, platform := range platforms {
+			if platform == "" {
+				continue
+			}
+			if _, _, err := playground.ParseReleasePlatform(platform); err != nil {
+				return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+			}
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		locations, err := playground.DownloadReleasePlatforms(ctx, output, releaseService.ReleaseArtifact(), platforms, opts)
+		if err != nil {
+			return fmt.Errorf("failed to download release: %w", err)
+		}
+		for _, location := range locations {
+			fmt.Println(location)
+		}
+		return nil
+	},
+}
+
+// releaseDownloadOptions returns the options of the downloads of the releases from the flags
+func releaseDownloadOptions(platform string) (*playground.ReleaseDownloadOptions, error) {
+	if downloadProxyFlag != "" {
+		if _, err := playground.ParseDownloadProxy(downloadProxyFlag); err != nil {
+			return nil, playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+	if downloadConnectionsFlag <= 0 {
+		return nil, playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid --download-connections %d, it must be positive", downloadConnectionsFlag))
+	}
+	if platform != "" {
+		if _, _, err := playground.ParseReleasePlatform(platform); err != nil {
+			return nil, playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+	return &playground.ReleaseDownloadOptions{
+		RequireSignature: requireSignatureFlag,
+		Platform:         platform,
+		Proxy:            downloadProxyFlag,
+		Connections:      downloadConnectionsFlag,
+	}, nil
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the running sessions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessions, err := playground.ListSessions()
+		if err != nil {
+			return err
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No sessions running")
+			return nil
+		}
+		for _, session := range sessions {
+			crashed := ""
+			if session.Crashed {
+				crashed = ", crashed: run 'playground prune' to remove it"
+			}
+			fmt.Printf("- %s (recipe: %s, containers: %d, started: %s, output: %s%s)\n",
+				session.Name, session.Recipe, session.Containers, session.StartedAt.Format(time.RFC3339), session.Output, crashed)
+		}
+		return nil
+	},
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay the traffic recorded by the API proxies",
+}
+
+var replayEngineCmd = &cobra.Command{
+	Use:   "engine <session-file>",
+	Short: "Replay the recorded Engine API requests against an EL and compare the responses",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := playground.LoadEngineSession(args[0])
+		if err != nil {
+			return err
+		}
+		secret, err := playground.ReadJWTSecret(replayJWTSecretFlag)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		report, err := playground.ReplayEngineSession(ctx, entries, replayTargetFlag, secret)
+		if err != nil {
+			return err
+		}
+		for _, diff := range report.Diffs {
+			fmt.Printf("- #%d %s\n  expected: %s\n  actual:   %s\n", diff.Index, diff.Method, truncate(diff.Expected, 300), truncate(diff.Actual, 300))
+		}
+		fmt.Printf("Replayed %d requests, %d responses differ\n", report.Requests, len(report.Diffs))
+		if len(report.Diffs) != 0 {
+			return fmt.Errorf("the responses of the target differ from the recorded ones")
+		}
+		return nil
+	},
+}
+
+var chaosCmd = &cobra.Command{
+	Use:   "chaos",
+	Short: "Inject faults in a running session",
+}
+
+var chaosReorgCmd = &cobra.Command{
+	Use:   "reorg",
+	Short: "Partition the minority node of the L1 to create a reorg and report the fork choice events",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if chaosDepthFlag == 0 {
+			return fmt.Errorf("the depth must be at least one slot")
+		}
+		session, err := playground.FindSession(sessionNameFlag)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return fmt.Errorf("session '%s' is not running", sessionNameFlag)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		report, err := playground.RunReorg(ctx, os.Stdout, session.Session, chaosDepthFlag, chaosTimeoutFlag)
+		if err != nil {
+			return err
+		}
+		if report.MajorityHead != nil && report.MinorityHead != nil {
+			fmt.Printf("Heads before the rejoin: majority %d (%s), minority %d (%s)\n",
+				report.MajorityHead.Slot, report.MajorityHead.Root, report.MinorityHead.Slot, report.MinorityHead.Root)
+		}
+		fmt.Printf("%d reorgs during the partition of %d slots\n", len(report.Events), report.Slots)
+		for _, event := range report.Events {
+			fmt.Printf("- %s: slot %d, depth %d, %s -> %s\n", event.Node, event.Slot, event.Depth, event.OldHead, event.NewHead)
+		}
+		if !report.Converged {
+			return fmt.Errorf("the minority node did not converge with the majority after %s", chaosTimeoutFlag)
+		}
+		fmt.Println("The minority node follows the chain of the majority")
+		return nil
+	},
+}
+
+var validatorsCmd = &cobra.Command{
+	Use:   "validators",
+	Short: "Manage the lifecycle of the validators of a running session",
+}
+
+var validatorsExitCmd = &cobra.Command{
+	Use:   "exit",
+	Short: "Submit voluntary exits for the last active validators of the L1",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		session, err := findRunningSession()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		exits, err := playground.ExitValidators(ctx, session, validatorsCountFlag)
+		if err != nil {
+			return err
+		}
+		for _, exit := range exits {
+			fmt.Printf("- validator %d (%s): exit submitted at epoch %d\n", exit.Index, exit.Pubkey, exit.Epoch)
+		}
+		return nil
+	},
+}
+
+var validatorsDepositCmd = &cobra.Command{
+	Use:   "deposit",
+	Short: "Create new validators for the L1 and send their deposits",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		session, err := findRunningSession()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		deposits, err := playground.DepositValidators(ctx, session, validatorsCountFlag)
+		if err != nil {
+			return err
+		}
+		for _, deposit := range deposits {
+			fmt.Printf("- validator %s: deposit %s\n", deposit.Pubkey, deposit.TxHash)
+		}
+		return nil
+	},
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the validator keys of a running session",
+}
+
+var keysExportSlashingProtectionCmd = &cobra.Command{
+	Use:   "export-slashing-protection [service]",
+	Short: "Stop a validator client and export its slashing protection database",
+	Long:  "Stop a validator client of a running session (validator by default) and export its slashing protection database in the interchange format of EIP-3076, so that its keys can be moved to another validator client without signing slashable messages. The validator client stays stopped unless --restart is set.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service := "validator"
+		if len(args) == 1 {
+			service = args[0]
+		}
+		session, err := playground.FindSession(sessionNameFlag)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return fmt.Errorf("session '%s' is not running", sessionNameFlag)
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		interchange, err := playground.ExportSessionSlashingProtection(ctx, session.Session, service, keysRestartFlag)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(interchange, "", "  ")
+		if err != nil {
+			return err
+		}
+		if keysOutputFlag == "" {
+			fmt.Println(string(data))
+			return nil
+		}
+		if err := os.WriteFile(keysOutputFlag, data, 0644); err != nil {
+			return fmt.Errorf("failed to write the interchange file: %w", err)
+		}
+		fmt.Printf("Exported the slashing protection history of %d validators of %s to %s\n", len(interchange.Data), service, keysOutputFlag)
+		return nil
+	},
+}
+
+var scaleCmd = &cobra.Command{
+	Use:   "scale [group=instances]...",
+	Short: "Change the number of instances of the scalable services of a running session",
+	Long:  "Change the number of instances of the scalable services of a running session (i.e. scale cl-node=3). It prints the instances of each group, without arguments it only prints them.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scale := map[string]int{}
+		for _, arg := range args {
+			name, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				return fmt.Errorf("invalid scale '%s', expected <group>=<instances>", arg)
+			}
+			instances, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid number of instances for '%s': %w", name, err)
+			}
+			scale[name] = instances
+		}
+
+		session, err := playground.FindSession(sessionNameFlag)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return fmt.Errorf("session '%s' is not running", sessionNameFlag)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		groups, err := playground.ScaleSession(ctx, session.Session, scale)
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(groups))
+		for name := range groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("- %s: %d instances\n", name, groups[name])
+		}
+		return nil
+	},
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [session]",
+	Short: "Run consistency checks against a running session and report which ones pass",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := playground.DefaultSessionName
+		if len(args) == 1 {
+			name = args[0]
+		}
+		session, err := playground.FindSession(name)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return fmt.Errorf("session '%s' is not running", name)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		report := playground.Verify(ctx, session.Session, verifyTimeoutFlag)
+		if verifyJSONFlag {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else {
+			for _, check := range report.Checks {
+				line := fmt.Sprintf("[%s] %s (%s)", check.Status, check.Name, check.Service)
+				if check.Message != "" {
+					line += ": " + check.Message
+				}
+				fmt.Println(line)
+			}
+		}
+		if !report.Passed {
+			return fmt.Errorf("verification of session '%s' failed", name)
+		}
+		return nil
+	},
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Inspect the plans written by the dry runs",
+}
+
+var planDiffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Compare the plans of two dry runs (images, args, config files and genesis hashes)",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		diff, err := playground.DiffPlans(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		if planDiffJSONFlag {
+			data, err := json.MarshalIndent(diff, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		diff.Print(os.Stdout)
+		return nil
+	},
+}
+
+var runScenarioCmd = &cobra.Command{
+	Use:   "run-scenario <scenario.yaml>",
+	Short: "Run the steps of a scenario file against a running session",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scenario, err := playground.LoadScenario(args[0])
+		if err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+		session, err := playground.FindSession(sessionNameFlag)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return fmt.Errorf("session '%s' is not running", sessionNameFlag)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		// the progress goes to stderr with --json so that stdout is only the report
+		progress := os.Stdout
+		if scenarioJSONFlag {
+			progress = os.Stderr
+		}
+		report := playground.RunScenario(ctx, progress, session.Session, scenario)
+		if scenarioJSONFlag {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		}
+		if !report.Passed {
+			return playground.NewClassifiedError(playground.ErrorClassScenarioFailed, fmt.Errorf("scenario %s failed", args[0]))
+		}
+		if !scenarioJSONFlag {
+			fmt.Printf("Scenario passed (%d steps)\n", len(report.Steps))
+		}
+		return nil
+	},
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <service>...",
+	Short: "Query the log files of the services, normalized from the formats of the clients",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := &playground.LogQuery{Since: logsSinceFlag}
+		if err := query.Level.Unmarshal(logsLevelFlag); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid --level: %w", err))
+		}
+		if logsGrepFlag != "" {
+			re, err := regexp.Compile(logsGrepFlag)
+			if err != nil {
+				return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid --grep: %w", err))
+			}
+			query.Grep = re
+		}
+
+		outputDir, err := sessionOutputDir()
+		if err != nil {
+			return err
+		}
+		entries, err := playground.QueryLogs(outputDir, args, query)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			fmt.Println(entry.Format(len(args) > 1))
+		}
+		return nil
+	},
+}
+
+// sessionOutputDir returns the output folder of --output, or the one of the session in
+// --name if it is running (it may have been started with --output), or the default one
+// of the session otherwise (i.e. to read the logs after the session ended)
+func sessionOutputDir() (string, error) {
+	if outputFlag != "" {
+		return outputFlag, nil
+	}
+	// the output folder of a session that is not running is the default one, so the errors
+	// of the container engine do not prevent reading it
+	if session, err := playground.FindSession(sessionNameFlag); err == nil && session != nil {
+		return session.Output, nil
+	}
+	homeDir, err := playground.GetHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, sessionNameFlag), nil
+}
+
+func findRunningSession() (*playground.Session, error) {
+	if validatorsCountFlag == 0 {
+		return nil, fmt.Errorf("the count must be at least one validator")
+	}
+	session, err := playground.FindSession(sessionNameFlag)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, fmt.Errorf("session '%s' is not running", sessionNameFlag)
+	}
+	return session.Session, nil
+}
+
+func truncate(str string, size int) string {
+	if len(str) <= size {
+		return str
+	}
+	return str[:size] + "..."
+}
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove the output folders of old devnets",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputs, err := playground.FindStaleOutputs(cleanOlderThanFlag)
+		if err != nil {
+			return err
+		}
+		if len(outputs) == 0 {
+			fmt.Println("No output folders to remove")
+			return nil
+		}
+		for _, output := range outputs {
+			if cleanDryRunFlag {
+				fmt.Printf("- %s (last modified: %s)\n", output.Path, output.ModTime.Format(time.RFC3339))
+				continue
+			}
+			if err := os.RemoveAll(output.Path); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", output.Path, err)
+			}
+			fmt.Printf("Removed %s\n", output.Path)
+		}
+		return nil
+	},
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove the containers, networks and volumes leaked by the sessions that crashed",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		resources, err := playground.LeakedResources(ctx)
+		if err != nil {
+			return err
+		}
+		if len(resources) == 0 {
+			fmt.Println("No leaked resources to remove")
+			return nil
+		}
+		for _, res := range resources {
+			fmt.Printf("- %s %s (session: %s)\n", res.Kind, res.Name, res.Session)
+		}
+		if pruneDryRunFlag {
+			return nil
+		}
+		if err := playground.PruneResources(ctx, resources); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d resources\n", len(resources))
+		return nil
+	},
+}
+
+var recipes = []playground.Recipe{
+	&playground.L1Recipe{},
+	&playground.RelayRecipe{},
+	&playground.OpRecipe{},
+	&playground.OpInteropRecipe{},
+	&playground.DevRecipe{},
+}
+
+// Execute runs the playground command line with the built-in recipes, the extra recipes of a
+// downstream project and the recipes of the plugins. It exits the process on failure, with
+// the exit code of the class of the error.
+func Execute(extraRecipes ...playground.Recipe) {
+	pluginRecipes, err := playground.LoadPluginRecipes()
+	if err != nil {
+		playgroundLog.Warn("failed to load the plugins", "err", err)
+	}
+	for _, recipe := range pluginRecipes {
+		extraRecipes = append(extraRecipes, recipe)
+	}
+	for _, recipe := range extraRecipes {
+		if slices.ContainsFunc(recipes, func(r playground.Recipe) bool { return r.Name() == recipe.Name() }) {
+			playgroundLog.Warn("recipe has the name of another recipe, skipping it", "recipe", recipe.Name())
+			continue
+		}
+		recipes = append(recipes, recipe)
+	}
+
+	for _, recipe := range recipes {
+		recipeCmd := &cobra.Command{
+			Use:   recipe.Name(),
+			Short: recipe.Description(),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if err := loadFlagConfig(cmd, recipe); err != nil {
+					return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+				}
+				return runIt(recipe)
+			},
+		}
+		// add the flags from the recipe
+		recipeCmd.Flags().AddFlagSet(recipe.Flags())
+
+		cookCmd.AddCommand(recipeCmd)
+
+		manifestRecipeCmd := &cobra.Command{
+			Use:   recipe.Name(),
+			Short: recipe.Description(),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return printManifest(recipe)
+			},
+		}
+		manifestRecipeCmd.Flags().AddFlagSet(recipe.Flags())
+		manifestCmd.AddCommand(manifestRecipeCmd)
+
+		describeRecipeCmd := &cobra.Command{
+			Use:   recipe.Name(),
+			Short: recipe.Description(),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return describeRecipe(recipe)
+			},
+		}
+		describeRecipeCmd.Flags().AddFlagSet(recipe.Flags())
+		describeCmd.AddCommand(describeRecipeCmd)
+	}
+
+	// add the common flags, shared by all the recipes
+	cookCmd.PersistentFlags().StringVar(&configFlag, "config", "", "TOML (or .env) file with the values of the flags")
+	cookCmd.PersistentFlags().StringVar(&outputFlag, "output", "", "Output folder for the artifacts (defaults to $HOME/.playground/<name>)")
+	cookCmd.PersistentFlags().StringVar(&sessionNameFlag, "name", playground.DefaultSessionName, "name of the session, used to run multiple devnets on the same host")
+	cookCmd.PersistentFlags().BoolVar(&watchdog, "watchdog", false, "enable watchdog")
+	cookCmd.PersistentFlags().StringArrayVar(&withOverrides, "override", []string{}, "override a service's config (<service>.image=, <service>.tag=, <service>.args+= or <service>.env.<name>=)")
+	cookCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "dry run the recipe")
+	cookCmd.PersistentFlags().BoolVar(&dryRun, "mise-en-place", false, "mise en place mode")
+	cookCmd.PersistentFlags().Uint64Var(&genesisDelayFlag, "genesis-delay", playground.MinimumGenesisDelay, "")
+	cookCmd.PersistentFlags().StringVar(&forkRPCFlag, "fork-rpc", "", "archive node RPC of a live network to shadow fork the L1 state from")
+	cookCmd.PersistentFlags().Uint64Var(&forkBlockFlag, "fork-block", 0, "block to shadow fork from (defaults to the latest block)")
+	cookCmd.PersistentFlags().StringSliceVar(&forkAccountsFlag, "fork-accounts", []string{}, "extra accounts to copy from the forked network")
+	cookCmd.PersistentFlags().StringSliceVar(&graphFormats, "graph-format", []string{"dot"}, "formats of the topology graph (dot, mermaid, json)")
+	cookCmd.PersistentFlags().BoolVar(&uiFlag, "ui", false, "serve a web dashboard with the status of the services")
+	cookCmd.PersistentFlags().Uint64Var(&uiPortFlag, "ui-port", 8088, "port of the web dashboard")
+	cookCmd.PersistentFlags().Uint64Var(&healthPortFlag, "health-port", 0, "serve /healthz and /status with the health of the devnet on this local port (0 disables it)")
+	cookCmd.PersistentFlags().StringArrayVar(&platformOverrides, "platform", []string{}, "override the image platform of a service (i.e. el=linux/amd64)")
+	cookCmd.PersistentFlags().StringArrayVar(&envFilesFlag, "env-file", []string{}, "load environment variables of a service from a .env file (i.e. builder=secrets.env)")
+	cookCmd.PersistentFlags().Uint64Var(&slotTimeFlag, "slot-time", playground.DefaultSlotTime, "number of seconds per slot in the L1 chain")
+	cookCmd.PersistentFlags().StringVar(&clConfigFlag, "cl-config", "", "beacon chain config.yaml to use instead of the embedded one")
+	cookCmd.PersistentFlags().StringArrayVar(&forkEpochFlags, "fork", []string{}, "schedule a fork of the L1 at an epoch (i.e. electra=2), it can be repeated")
+	cookCmd.PersistentFlags().StringSliceVar(&disableSystemContractsFlag, "disable-system-contracts", []string{}, "system contracts to leave out of the L1 genesis ("+strings.Join(playground.SystemContractNames(), ", ")+")")
+	cookCmd.PersistentFlags().Uint64Var(&numValidatorsFlag, "num-validators", playground.DefaultNumValidators, "number of validators in the L1 genesis")
+	cookCmd.PersistentFlags().StringVar(&prefundedBalanceFlag, "prefunded-balance", "10000", "balance in ETH of the prefunded accounts in the L1 and L2 genesis")
+	cookCmd.PersistentFlags().Uint64Var(&prefundedNonceFlag, "prefunded-nonce", 0, "nonce of the prefunded accounts in the L1 and L2 genesis")
+	cookCmd.PersistentFlags().Uint64Var(&genesisBaseFeeFlag, "genesis-base-fee", 0, "base fee in wei of the L1 and L2 genesis blocks (defaults to 1 gwei)")
+	cookCmd.PersistentFlags().BoolVar(&insecureKeysFlag, "insecure-keys", false, "encrypt the validator keystores with a fast but insecure key derivation")
+	cookCmd.PersistentFlags().StringSliceVar(&withExplorerFlag, "with-explorer", []string{}, "deploy block explorers for the L1 (blockscout, dora), --with-explorer alone deploys blockscout")
+	cookCmd.PersistentFlags().Lookup("with-explorer").NoOptDefVal = string(playground.ExplorerBlockscout)
+	cookCmd.PersistentFlags().BoolVar(&withFaucetFlag, "with-faucet", false, "deploy a faucet that funds the addresses that request it from a prefunded account of the L1")
+	cookCmd.PersistentFlags().StringArrayVar(&attachFlag, "attach", []string{}, "YAML file with extra services to add to the recipe, described like the services of a YAML recipe (repeatable)")
+	cookCmd.PersistentFlags().BoolVar(&withGrafanaFlag, "with-grafana", false, "deploy prometheus and grafana with the dashboards of the services that expose metrics")
+	cookCmd.PersistentFlags().StringVar(&otelEndpointFlag, "otel-endpoint", "", "export the traces of the artifacts generation and the services startup to this OTLP/HTTP endpoint (i.e. http://localhost:4318)")
+	cookCmd.PersistentFlags().BoolVar(&requireSignatureFlag, "require-signature", false, "fail if the signature of a release downloaded for the services that run on the host cannot be verified")
+	cookCmd.PersistentFlags().StringVar(&releasePlatformFlag, "release-platform", "", "<os>/<arch> of the release binaries of the services that run on the host instead of the host (i.e. darwin/amd64 to run them with Rosetta)")
+	cookCmd.PersistentFlags().StringVar(&downloadProxyFlag, "download-proxy", "", "http, https or socks5 proxy of the release downloads, instead of the proxy of HTTPS_PROXY")
+	cookCmd.PersistentFlags().IntVar(&downloadConnectionsFlag, "download-connections", 4, "number of concurrent range requests of each release download")
+	cookCmd.PersistentFlags().StringVar(&pullPolicyFlag, "pull-policy", string(playground.PullPolicyMissing), "when to pull the images before the services start (always, missing, never)")
+	cookCmd.PersistentFlags().StringArrayVar(&bindFlag, "bind", []string{}, "IP of the host interface the published ports bind to (127.0.0.1 by default), for all the services or for one (i.e. el=0.0.0.0)")
+	cookCmd.PersistentFlags().StringVar(&remoteFlag, "remote", "", "ssh destination (i.e. user@host) of a remote host with docker to run the containers on, with the output folder synced and the ports forwarded")
+	cookCmd.PersistentFlags().StringArrayVar(&readyTimeoutFlag, "ready-timeout", []string{}, "time to wait for the services to be ready instead of the defaults of their checks, for all the services or for one (i.e. beacon=2m)")
+	cookCmd.PersistentFlags().StringArrayVar(&clockSkewFlag, "clock-skew", []string{}, "run a service with its clock shifted ahead or behind the others with libfaketime (i.e. beacon=+500ms)")
+	cookCmd.PersistentFlags().StringArrayVar(&userFlag, "user", []string{}, "<uid>[:<gid>] the containers run as, or host for the host user, for all the services or for one (i.e. beacon=1000:1000)")
+	cookCmd.PersistentFlags().StringArrayVar(&restartPolicyFlag, "restart-policy", []string{}, "restart policy of the containers when they exit (never, always, on-failure, on-failure:<retries>), for all the services or for one (i.e. el=on-failure:3)")
+	cookCmd.PersistentFlags().StringVar(&templatesFlag, "templates", "", "folder with *.tmpl files rendered to the output folder with the endpoints of the services, the chain ids and the prefunded keys")
+	cookCmd.PersistentFlags().StringVar(&exportFlag, "export", "", "write the services as a package for another runner (kurtosis) to the output folder instead of starting them")
+	cookCmd.PersistentFlags().StringVar(&lockedFlag, "locked", "", "run the images (by digest) and the release binaries of the playground.lock file of a previous run")
+	cookCmd.PersistentFlags().BoolVar(&offlineFlag, "offline", false, "run the services in a network without external egress")
+	cookCmd.PersistentFlags().StringSliceVar(&allowEgressFlag, "allow-egress", []string{}, "services that can reach the outside world with --offline")
+	cookCmd.PersistentFlags().StringVar(&bundleFlag, "bundle", "", "write a tar.gz bundle with the logs, manifest, genesis files and run summary when the session ends")
+	cookCmd.PersistentFlags().BoolVar(&hostNamesFlag, "host-names", false, "services running on the host reach the other services by name (requires the hosts file of the output folder in /etc/hosts)")
+	cookCmd.PersistentFlags().Uint64Var(&logMaxSizeFlag, "log-max-size", 0, "rotate the log files of the services once they reach this size in MB (0 disables the rotation)")
+	cookCmd.PersistentFlags().IntVar(&logRetentionFlag, "log-retention", 3, "number of rotated log files to keep for each service")
+	cookCmd.PersistentFlags().BoolVar(&followLogsFlag, "follow-logs", false, "stream the logs of the services to the console, prefixed by the service name, besides the log files")
+	cookCmd.PersistentFlags().StringVar(&followLogsLevelFlag, "follow-logs-level", "trace", "minimum level of the log lines streamed by --follow-logs (trace, debug, info, warn, error)")
+	cookCmd.PersistentFlags().DurationVar(&statsIntervalFlag, "stats-interval", 0, "sample the network and block IO, memory and disk usage of the containers at this interval and add them to the run summary (0 disables it)")
+	cookCmd.PersistentFlags().Uint64Var(&watchdogFinalityEpochsFlag, "watchdog-finality-epochs", 0, "with --watchdog, fail if the beacon chain does not finalize for this many epochs (0 only logs the epoch summaries)")
+	cookCmd.PersistentFlags().Uint64Var(&dutiesEpochsFlag, "duties-epochs", 0, "write the proposer and attester duties of the validators in the next epochs to duties.json once the services are ready, refreshed every epoch with --watchdog (0 disables it)")
+	cookCmd.PersistentFlags().DurationVar(&crashDumpPprofFlag, "crash-dump-pprof", 0, "take a goroutine dump of the services with a pprof port at this interval, the last one is added to their crash dumps (0 disables it)")
+	cookCmd.PersistentFlags().BoolVar(&recordBidsFlag, "record-bids", false, "record the builder bids received by the relay every slot in bids.jsonl, with a summary of the builders when the session ends")
+	cookCmd.PersistentFlags().DurationVar(&rotateJWTSecretsFlag, "rotate-jwt-secrets", 0, "rotate the JWT secrets of the execution nodes after this time to test the Engine API auth failures")
+	cookCmd.PersistentFlags().BoolVar(&interactive, "interactive", false, "interactive mode")
+	cookCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "") // Used for CI
+	cookCmd.PersistentFlags().StringVar(&logLevelFlag, "log-level", "info", "log level, with optional levels by module (i.e. info,runner=debug,artifacts=warn)")
+	cookCmd.PersistentFlags().StringVar(&logFormatFlag, "log-format", "text", "format of the logs of the playground (text, json)")
+	cookCmd.PersistentFlags().StringVar(&onBlockFlag, "on-block", "", "command to run (with sh -c) on every new block of the L1 EL")
+	cookCmd.PersistentFlags().StringVar(&onSlotFlag, "on-slot", "", "command to run (with sh -c) on every new head of the L1 beacon chain")
+	cookCmd.PersistentFlags().StringArrayVar(&deployFlags, "deploy", []string{}, "folder with contracts, or contract file with its ABI encoded constructor arguments (<file>=<args>), to deploy on the L1 EL once it is ready")
+	cookCmd.Flags().StringVar(&recipeFileFlag, "file", "", "YAML file with the recipe to cook")
+
+	// reuse the same output flag for the artifacts command
+	artifactsCmd.Flags().StringVar(&outputFlag, "output", "", "Output folder for the artifacts")
+	artifactsCmd.Flags().BoolVar(&requireSignatureFlag, "require-signature", false, "fail if the signature of the release cannot be verified")
+	artifactsCmd.Flags().StringSliceVar(&platformFlag, "platform", []string{}, "<os>/<arch> of the binaries to download instead of the host, downloaded concurrently (i.e. linux/amd64,darwin/arm64)")
+	artifactsCmd.Flags().StringVar(&downloadProxyFlag, "download-proxy", "", "http, https or socks5 proxy of the downloads, instead of the proxy of HTTPS_PROXY")
+	artifactsCmd.Flags().IntVar(&downloadConnectionsFlag, "download-connections", 4, "number of concurrent range requests of each download")
+
+	rootCmd.PersistentFlags().StringVar(&errorFormatFlag, "error-format", string(playground.ErrorFormatText), "format of the final error: text or json (a JSON object with the error class and the exit code, written to stderr)")
+	rootCmd.PersistentFlags().StringVar(&containerEngineFlag, "container-engine", string(playground.ContainerEngineAuto), "container engine to use (auto, docker, podman)")
+
+	rootCmd.AddCommand(cookCmd)
+	rootCmd.AddCommand(artifactsCmd)
+	rootCmd.AddCommand(listCmd)
+
+	manifestCmd.Flags().StringVar(&recipeFileFlag, "file", "", "YAML file with the recipe")
+	rootCmd.AddCommand(manifestCmd)
+
+	describeCmd.Flags().StringVar(&recipeFileFlag, "file", "", "YAML file with the recipe")
+	rootCmd.AddCommand(describeCmd)
+
+	cleanCmd.Flags().DurationVar(&cleanOlderThanFlag, "older-than", 7*24*time.Hour, "remove the output folders not modified for this long")
+	cleanCmd.Flags().BoolVar(&cleanDryRunFlag, "dry-run", false, "list the output folders to remove without removing them")
+	rootCmd.AddCommand(cleanCmd)
+	pruneCmd.Flags().BoolVar(&pruneDryRunFlag, "dry-run", false, "list the leaked resources without removing them")
+	rootCmd.AddCommand(pruneCmd)
+
+	replayEngineCmd.Flags().StringVar(&replayTargetFlag, "target", "http://localhost:8551", "Engine API of the EL to replay the requests against")
+	replayEngineCmd.Flags().StringVar(&replayJWTSecretFlag, "jwt-secret", "", "JWT secret of the Engine API of the target")
+	replayEngineCmd.MarkFlagRequired("jwt-secret")
+	replayCmd.AddCommand(replayEngineCmd)
+	rootCmd.AddCommand(replayCmd)
+
+	chaosCmd.PersistentFlags().StringVar(&sessionNameFlag, "name", playground.DefaultSessionName, "name of the session")
+	chaosReorgCmd.Flags().Uint64Var(&chaosDepthFlag, "depth", 2, "number of slots the minority node builds its own fork")
+	chaosReorgCmd.Flags().DurationVar(&chaosTimeoutFlag, "timeout", 5*time.Minute, "maximum time to wait for the minority node to converge after the rejoin")
+	chaosCmd.AddCommand(chaosReorgCmd)
+	rootCmd.AddCommand(chaosCmd)
+
+	validatorsCmd.PersistentFlags().StringVar(&sessionNameFlag, "name", playground.DefaultSessionName, "name of the session")
+	validatorsCmd.PersistentFlags().Uint64Var(&validatorsCountFlag, "count", 1, "number of validators")
+	validatorsCmd.AddCommand(validatorsExitCmd)
+	validatorsCmd.AddCommand(validatorsDepositCmd)
+	rootCmd.AddCommand(validatorsCmd)
+
+	verifyCmd.Flags().BoolVar(&verifyJSONFlag, "json", false, "print the report as JSON")
+	verifyCmd.Flags().DurationVar(&verifyTimeoutFlag, "timeout", time.Minute, "maximum time to wait for the op-nodes to derive a new safe block")
+	rootCmd.AddCommand(verifyCmd)
+
+	keysCmd.PersistentFlags().StringVar(&sessionNameFlag, "name", playground.DefaultSessionName, "name of the session")
+	keysExportSlashingProtectionCmd.Flags().StringVar(&keysOutputFlag, "output", "", "file to write the interchange to (defaults to stdout)")
+	keysExportSlashingProtectionCmd.Flags().BoolVar(&keysRestartFlag, "restart", false, "start the validator client again after the export")
+	keysCmd.AddCommand(keysExportSlashingProtectionCmd)
+	rootCmd.AddCommand(keysCmd)
+
+	scaleCmd.Flags().StringVar(&sessionNameFlag, "name", playground.DefaultSessionName, "name of the session")
+	rootCmd.AddCommand(scaleCmd)
+
+	runScenarioCmd.Flags().StringVar(&sessionNameFlag, "name", playground.DefaultSessionName, "name of the session")
+	runScenarioCmd.Flags().BoolVar(&scenarioJSONFlag, "json", false, "print the report as JSON")
+	rootCmd.AddCommand(runScenarioCmd)
+
+	planDiffCmd.Flags().BoolVar(&planDiffJSONFlag, "json", false, "print the diff as JSON")
+	planCmd.AddCommand(planDiffCmd)
+	rootCmd.AddCommand(planCmd)
+
+	logsCmd.Flags().StringVar(&sessionNameFlag, "name", playground.DefaultSessionName, "name of the session")
+	logsCmd.Flags().StringVar(&outputFlag, "output", "", "output folder of the session (defaults to the one of the session)")
+	logsCmd.Flags().DurationVar(&logsSinceFlag, "since", 0, "only show the entries of this last period (i.e. 5m)")
+	logsCmd.Flags().StringVar(&logsGrepFlag, "grep", "", "only show the entries that match the regular expression")
+	logsCmd.Flags().StringVar(&logsLevelFlag, "level", "trace", "minimum level of the entries (trace, debug, info, warn, error)")
+	rootCmd.AddCommand(logsCmd)
+
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		if playground.ErrorFormat(errorFormatFlag) == playground.ErrorFormatJSON {
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		}
+		return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+	})
+
+	registerCompletions()
+
+	if err := rootCmd.Execute(); err != nil {
+		// the errors are printed as before in the text format, and to stderr in the JSON
+		// format so that they are not mixed with the output of the commands
+		format := playground.ErrorFormat(errorFormatFlag)
+		out := os.Stdout
+		if format == playground.ErrorFormatJSON {
+			out = os.Stderr
+		}
+		os.Exit(playground.WriteError(out, err, format))
+	}
+}
+
+func runIt(recipe playground.Recipe) error {
+	logConfig, err := playground.ParseLogConfig(logLevelFlag)
+	if err != nil {
+		return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("failed to parse log level: %w", err))
+	}
+	if err := playground.SetupLogging(logConfig, logFormatFlag); err != nil {
+		return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+	}
+	playgroundLog.Debug("log level", "level", logConfig)
+
+	if err := playground.ValidateSessionName(sessionNameFlag); err != nil {
+		return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+	}
+	if err := playground.PullPolicy(pullPolicyFlag).Validate(); err != nil {
+		return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+	}
+	if logRetentionFlag < 0 {
+		return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid log retention %d", logRetentionFlag))
+	}
+	var followLogsLevel playground.LogLevel
+	if err := followLogsLevel.Unmarshal(followLogsLevelFlag); err != nil {
+		return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid --follow-logs-level: %w", err))
+	}
+	if followLogsFlag && interactive {
+		return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("--follow-logs cannot be used with --interactive"))
+	}
+	if exportFlag != "" && exportFlag != "kurtosis" {
+		return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid export format '%s', expected kurtosis", exportFlag))
+	}
+	explorers := []playground.Explorer{}
+	for _, str := range withExplorerFlag {
+		explorer := playground.Explorer(str)
+		if err := explorer.Validate(); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+		explorers = append(explorers, explorer)
+	}
+	downloadOpts, err := releaseDownloadOptions(releasePlatformFlag)
+	if err != nil {
+		return err
+	}
+	attached := []*playground.AttachedServices{}
+	for _, path := range attachFlag {
+		services, err := playground.LoadAttachedServices(path)
+		if err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+		attached = append(attached, services)
+	}
+	overrides := []*playground.Override{}
+	for _, str := range withOverrides {
+		override, err := playground.ParseOverride(str)
+		if err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+		overrides = append(overrides, override)
+	}
+	for _, account := range forkAccountsFlag {
+		if !gethcommon.IsHexAddress(account) {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid fork account '%s'", account))
+		}
+	}
+	if !dryRun && exportFlag == "" {
+		// the artifacts builder removes the output folder, so we have to check before
+		// building that we are not going to clobber a running session
+		running, err := playground.FindSession(sessionNameFlag)
+		if err != nil {
+			return err
+		}
+		// the resources of a session that crashed are removed by the runner before it starts
+		if running != nil && !running.Crashed {
+			return fmt.Errorf("session '%s' is already running, use --name to start another one", sessionNameFlag)
+		}
+	}
+
+	// handle the interrupts from the start so that the artifacts generation and the
+	// release downloads can be cancelled too
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	// classify sets the class of a startup error, which is an interruption if the
+	// context was cancelled by the interrupt
+	classify := func(class playground.ErrorClass, err error) error {
+		if ctx.Err() != nil {
+			class = playground.ErrorClassInterrupted
+		}
+		return playground.NewClassifiedError(class, err)
+	}
+
+	shutdownTracing, err := playground.SetupTracing(ctx, otelEndpointFlag)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// flush the pending spans even if the session was interrupted
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			playgroundLog.Error("failed to export traces", "err", err)
+		}
+	}()
+
+	// the startup span covers everything until the outputs are available
+	ctx, startupSpan := playground.StartSpan(ctx, "startup", attribute.String("recipe", recipe.Name()), attribute.String("session", sessionNameFlag))
+	defer startupSpan.End()
+
+	outputDir := outputFlag
+	if outputDir == "" {
+		homeDir, err := playground.GetHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get home directory: %w", err)
+		}
+		outputDir = filepath.Join(homeDir, sessionNameFlag)
+	}
+
+	if err := playground.ValidateRecipe(recipe); err != nil {
+		return err
+	}
+	// the contracts are loaded before the artifacts are built to fail early on invalid files
+	var deployments []*playground.Deployment
+	for _, spec := range deployFlags {
+		specDeployments, err := playground.LoadDeployments(spec)
+		if err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+		deployments = append(deployments, specDeployments...)
+	}
+
+	builder := recipe.Artifacts()
+	builder.OutputDir(outputDir)
+	builder.GenesisDelay(genesisDelayFlag)
+	builder.SlotTime(slotTimeFlag)
+	builder.NumValidators(numValidatorsFlag)
+	prefundedBalance, err := playground.ParseEther(prefundedBalanceFlag)
+	if err != nil {
+		return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid --prefunded-balance: %w", err))
+	}
+	builder.PrefundedBalance(prefundedBalance)
+	builder.PrefundedNonce(prefundedNonceFlag)
+	builder.GenesisBaseFee(genesisBaseFeeFlag)
+	builder.CLConfig(clConfigFlag)
+	builder.DisableSystemContracts(disableSystemContractsFlag)
+	for _, forkEpoch := range forkEpochFlags {
+		fork, epochStr, ok := strings.Cut(forkEpoch, "=")
+		if !ok {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid fork '%s', expected <fork>=<epoch>", forkEpoch))
+		}
+		epoch, err := strconv.ParseUint(epochStr, 10, 64)
+		if err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid epoch '%s' of fork %s: %w", epochStr, fork, err))
+		}
+		builder.ForkEpoch(fork, epoch)
+	}
+	builder.InsecureKeys(insecureKeysFlag)
+	builder.LogRotation(logMaxSizeFlag, logRetentionFlag)
+	builder.ForkState(forkRPCFlag, forkBlockFlag, forkAccountsFlag)
+	artifacts, err := builder.Build(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return classify(playground.ErrorClassInterrupted, fmt.Errorf("interrupted while building the artifacts"))
+		}
+		return classify(playground.ErrorClassArtifactsFailed, err)
+	}
+
+	svcManager := recipe.Apply(&playground.ExContext{LogLevel: logConfig.Level("services"), SlotTime: artifacts.SlotTime}, artifacts)
+	if err := playground.AddDeployments(svcManager, deployments); err != nil {
+		return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+	}
+	// extraOutputs are the outputs of the services added with the flags
+	extraOutputs, err := playground.AddExplorers(svcManager, explorers)
+	if err != nil {
+		return err
+	}
+	if withFaucetFlag {
+		faucetOutputs, err := playground.AddFaucet(svcManager)
+		if err != nil {
+			return err
+		}
+		for name, output := range faucetOutputs {
+			extraOutputs[name] = output
+		}
+	}
+	if withGrafanaFlag {
+		// after the other services so that it scrapes all of them
+		grafanaOutputs, err := playground.AddGrafana(svcManager)
+		if err != nil {
+			return err
+		}
+		for name, output := range grafanaOutputs {
+			extraOutputs[name] = output
+		}
+	}
+	for _, services := range attached {
+		if err := services.Attach(svcManager); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+	hooks := map[playground.ChainEventKind]string{}
+	var events *playground.EventStream
+	if onBlockFlag != "" || onSlotFlag != "" {
+		var beaconNode, executionNode string
+		if onSlotFlag != "" {
+			beaconNode = "beacon"
+			hooks[playground.ChainEventSlot] = onSlotFlag
+		}
+		if onBlockFlag != "" {
+			executionNode = "el"
+			hooks[playground.ChainEventBlock] = onBlockFlag
+		}
+		if events, err = playground.NewEventStream(svcManager, beaconNode, executionNode); err != nil {
+			return err
+		}
+	}
+	for _, override := range overrides {
+		if err := svcManager.ApplyOverride(override); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+	for _, override := range platformOverrides {
+		name, platform, ok := strings.Cut(override, "=")
+		if !ok {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid platform override '%s', expected <service>=<os>/<arch>", override))
+		}
+		svc, ok := svcManager.GetService(name)
+		if !ok {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("platform override for unknown service '%s'", name))
+		}
+		svc.WithPlatform(platform)
+	}
+	for _, entry := range readyTimeoutFlag {
+		name, timeoutStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			name, timeoutStr = "", entry
+		}
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid ready timeout '%s': %w", entry, err))
+		}
+		if err := svcManager.SetReadyTimeout(name, timeout); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+	for _, entry := range clockSkewFlag {
+		name, skewStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid clock skew '%s', expected <service>=<skew>", entry))
+		}
+		skew, err := time.ParseDuration(skewStr)
+		if err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid clock skew '%s': %w", entry, err))
+		}
+		if err := svcManager.SetClockSkew(name, skew); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+	for _, envFile := range envFilesFlag {
+		name, path, ok := strings.Cut(envFile, "=")
+		if !ok || path == "" {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("invalid env file '%s', expected <service>=<path>", envFile))
+		}
+		svc, ok := svcManager.GetService(name)
+		if !ok {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("env file for unknown service '%s'", name))
+		}
+		svc.WithEnvFromFile(path)
+	}
+
+	if err := svcManager.Validate(); err != nil {
+		return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("failed to validate manifest: %w", err))
+	}
+	if templatesFlag != "" {
+		if err := svcManager.LoadTemplates(templatesFlag); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+	if err := svcManager.RenderTemplates(); err != nil {
+		return classify(playground.ErrorClassArtifactsFailed, err)
+	}
+	if err := svcManager.DownloadReleases(ctx, downloadOpts); err != nil {
+		if ctx.Err() != nil {
+			return classify(playground.ErrorClassInterrupted, fmt.Errorf("interrupted while downloading the release artifacts"))
+		}
+		return classify(playground.ErrorClassArtifactsFailed, err)
+	}
+
+	// generate the topology graphs
+	if err := svcManager.WriteGraphs(graphFormats); err != nil {
+		return classify(playground.ErrorClassArtifactsFailed, err)
+	}
+
+	var bidCollector *playground.BidCollector
+	if recordBidsFlag {
+		if bidCollector, err = playground.NewBidCollector(svcManager); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, fmt.Errorf("--record-bids: %w", err))
+		}
+	}
+
+	if exportFlag == "kurtosis" {
+		if err := svcManager.ExportKurtosis(recipe.Name()); err != nil {
+			return classify(playground.ErrorClassArtifactsFailed, fmt.Errorf("failed to export the kurtosis package: %w", err))
+		}
+		fmt.Printf("Kurtosis package written to %s, run it with 'kurtosis run %s'\n", filepath.Join(outputDir, "kurtosis"), filepath.Join(outputDir, "kurtosis"))
+		return nil
+	}
+	var remote *playground.RemoteHost
+	if remoteFlag != "" && !dryRun {
+		// the docker client of the runner uses the forwarded socket of the remote host
+		if remote, err = playground.ConnectRemoteHost(ctx, remoteFlag, sessionNameFlag); err != nil {
+			return classify(playground.ErrorClassStartFailed, err)
+		}
+		defer remote.Close()
+	}
+
+	session := playground.NewSession(sessionNameFlag, recipe.Name())
+	var dockerRunner *playground.LocalRunner
+	if dryRun {
+		// the dry run plans the services with the same settings of the runner, without docker
+		dockerRunner, err = playground.NewPlanRunner(artifacts.Out, svcManager, nil, session)
+	} else {
+		dockerRunner, err = playground.NewLocalRunner(artifacts.Out, svcManager, nil, interactive, session)
+	}
+	if err != nil {
+		return classify(playground.ErrorClassStartFailed, fmt.Errorf("failed to create docker runner: %w", err))
+	}
+	if remote != nil {
+		if err := dockerRunner.SetRemoteHost(remote); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+
+	for _, bind := range bindFlag {
+		name, addr, ok := strings.Cut(bind, "=")
+		if !ok {
+			name, addr = "", bind
+		}
+		if err := dockerRunner.SetBindAddress(name, addr); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+
+	for _, entry := range userFlag {
+		name, user, ok := strings.Cut(entry, "=")
+		if !ok {
+			name, user = "", entry
+		}
+		if err := dockerRunner.SetUser(name, user); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+
+	for _, entry := range restartPolicyFlag {
+		name, policyStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			name, policyStr = "", entry
+		}
+		policy, err := playground.ParseRestartPolicy(policyStr)
+		if err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+		if err := dockerRunner.SetRestartPolicy(name, policy); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+
+	if lockedFlag != "" {
+		lock, err := playground.ReadLockfile(lockedFlag)
+		if err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+		if err := dockerRunner.UseLockfile(lock); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+
+	if offlineFlag {
+		if err := dockerRunner.EnableOffline(allowEgressFlag); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+
+	if hostNamesFlag {
+		if err := dockerRunner.EnableHostNames(); err != nil {
+			return playground.NewClassifiedError(playground.ErrorClassUsage, err)
+		}
+	}
+
+	if dryRun {
+		if err := dockerRunner.WritePlan(); err != nil {
+			return classify(playground.ErrorClassArtifactsFailed, fmt.Errorf("failed to write the plan: %w", err))
+		}
+		fmt.Printf("Plan written to %s\n", filepath.Join(outputDir, "plan"))
+		return nil
+	}
+
+	if followLogsFlag {
+		dockerRunner.FollowLogs(os.Stdout, followLogsLevel)
+	}
+
+	if uiFlag {
+		uiServer := playground.NewUIServer(fmt.Sprintf("127.0.0.1:%d", uiPortFlag), svcManager, dockerRunner)
+		go func() {
+			if err := uiServer.Run(); err != nil {
+				playgroundLog.Error("dashboard failed", "err", err)
+			}
+		}()
+		defer uiServer.Close()
+		fmt.Printf("Dashboard available at http://127.0.0.1:%d\n", uiPortFlag)
+	}
+
+	var watchdogStatus *playground.WatchdogStatus
+	if watchdog {
+		watchdogStatus = playground.NewWatchdogStatus()
+	}
+	if healthPortFlag != 0 {
+		healthServer := playground.NewHealthServer(fmt.Sprintf("127.0.0.1:%d", healthPortFlag), svcManager, dockerRunner, watchdogStatus)
+		go func() {
+			if err := healthServer.Run(); err != nil {
+				playgroundLog.Error("health server failed", "err", err)
+			}
+		}()
+		defer healthServer.Close()
+		fmt.Printf("Health endpoint available at http://127.0.0.1:%d/healthz\n", healthPortFlag)
+	}
+
+	// the control server receives the requests of the commands of the session that change
+	// the services ('scale' and 'keys export-slashing-protection')
+	controlServer, err := playground.NewControlServer(dockerRunner)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := controlServer.Run(); err != nil {
+			playgroundLog.Error("control server failed", "err", err)
+		}
+	}()
+	defer controlServer.Close()
+
+	// stop stops the services and writes the run summary (and the bundle if enabled) with
+	// the reason and the error that ended the session
+	summary := playground.NewRunSummary(session, watchdog)
+	stop := func(reason playground.ExitReason, runErr error) error {
+		if runErr != nil {
+			// no-op if the startup is already done
+			playground.EndSpan(startupSpan, runErr)
+		}
+		summary.Finish(svcManager, dockerRunner, reason, runErr)
+		stopErr := dockerRunner.Stop()
+
+		if summary.Bids != nil {
+			fmt.Printf("\n========= Builder bids =========\n")
+			summary.Bids.Print(os.Stdout)
+		}
+
+		if err := summary.Write(svcManager); err != nil {
+			playgroundLog.Error("failed to write summary", "err", err)
+		}
+		if bundleFlag != "" {
+			if err := playground.WriteBundle(svcManager, bundleFlag); err != nil {
+				playgroundLog.Error("failed to write bundle", "err", err)
+			} else {
+				fmt.Printf("Bundle written to %s\n", bundleFlag)
+			}
+		}
+
+		if stopErr != nil {
+			return fmt.Errorf("failed to stop docker: %w", stopErr)
+		}
+		return nil
+	}
+
+	if err := dockerRunner.PullImages(ctx, playground.PullPolicy(pullPolicyFlag)); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("interrupted while pulling the images")
+			stop(playground.ExitReasonInterrupted, err)
+			return classify(playground.ErrorClassInterrupted, err)
+		}
+		stop(playground.ExitReasonStartFailed, err)
+		return classify(playground.ErrorClassPullFailed, err)
+	}
+
+	if err := dockerRunner.WriteLockfile(ctx); err != nil {
+		stop(playground.ExitReasonStartFailed, err)
+		return classify(playground.ErrorClassStartFailed, err)
+	}
+
+	if err := dockerRunner.Run(ctx); err != nil {
+		err = fmt.Errorf("failed to run docker: %w", err)
+		stop(playground.ExitReasonStartFailed, err)
+		return classify(playground.ErrorClassStartFailed, err)
+	}
+
+	if statsIntervalFlag > 0 {
+		collector := playground.NewStatsCollector(dockerRunner)
+		summary.CollectStats(collector)
+		go collector.Run(ctx, statsIntervalFlag)
+	}
+	if crashDumpPprofFlag > 0 {
+		go dockerRunner.SamplePprof(ctx, crashDumpPprofFlag)
+	}
+	if bidCollector != nil {
+		summary.CollectBids(bidCollector)
+		go bidCollector.Run(ctx)
+	}
+
+	if !interactive {
+		// print services info
+		fmt.Printf("\n========= Services started =========\n")
+		for _, ss := range svcManager.Services() {
+			ports := ss.Ports()
+			sort.Slice(ports, func(i, j int) bool {
+				return ports[i].Name < ports[j].Name
+			})
+
+			portsStr := []string{}
+			for _, p := range ports {
+				portsStr = append(portsStr, fmt.Sprintf("%s: %d/%d", p.Name, p.Port, p.HostPort))
+			}
+			fmt.Printf("- %s (%s)\n", ss.Name, strings.Join(portsStr, ", "))
+		}
+	}
+
+	if err := playground.WaitForReady(ctx, svcManager); err != nil {
+		err = fmt.Errorf("failed to wait for service readiness: %w", err)
+		stop(playground.ExitReasonNotReady, err)
+		return classify(playground.ErrorClassNotReady, err)
+	}
+
+	addresses, err := playground.RunDeployments(ctx, svcManager)
+	if err != nil {
+		err = fmt.Errorf("failed to run deployments: %w", err)
+		stop(playground.ExitReasonDeploymentFailed, err)
+		return classify(playground.ErrorClassDeploymentFailed, err)
+	}
+
+	// get the output from the recipe
+	outputs := recipe.Output(svcManager)
+	for name, output := range extraOutputs {
+		outputs[name] = output
+	}
+	for name, addr := range addresses {
+		outputs[name] = playground.OutputAddress(addr.Hex())
+	}
+	output, err := svcManager.ResolveOutputs(outputs)
+	if err != nil {
+		err = fmt.Errorf("failed to resolve recipe outputs: %w", err)
+		stop(playground.ExitReasonOutputFailed, err)
+		return classify(playground.ErrorClassOutputFailed, err)
+	}
+	summary.Outputs = output
+	if len(output) > 0 {
+		if err := svcManager.WriteOutputEnv(output); err != nil {
+			err = fmt.Errorf("failed to write output.env: %w", err)
+			stop(playground.ExitReasonOutputFailed, err)
+			return classify(playground.ErrorClassOutputFailed, err)
+		}
+
+		names := make([]string, 0, len(output))
+		for name := range output {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Printf("\n========= Output =========\n")
+		for _, name := range names {
+			fmt.Printf("- %s: %v\n", name, output[name])
+		}
+	}
+	startupSpan.End()
+
+	if events != nil {
+		go playground.RunEventHooks(ctx, events.Subscribe(), hooks, output)
+		go events.Run(ctx)
+	}
+
+	if dutiesEpochsFlag > 0 {
+		duties := playground.NewDutiesWriter(svcManager, dutiesEpochsFlag)
+		if err := duties.Write(ctx); err != nil {
+			playgroundLog.Warn("failed to write the validator duties", "err", err)
+		} else if watchdog {
+			go duties.Run(ctx)
+		}
+	}
+
+	watchdogErr := make(chan error, 2)
+	if watchdog {
+		go func() {
+			if err := playground.RunWatchdog(svcManager, watchdogStatus); err != nil {
+				watchdogErr <- fmt.Errorf("watchdog failed: %w", err)
+			}
+		}()
+		go func() {
+			finality := playground.NewFinalityWatchdog(svcManager, watchdogFinalityEpochsFlag)
+			if err := finality.Run(ctx, watchdogStatus); err != nil {
+				watchdogErr <- fmt.Errorf("finality watchdog failed: %w", err)
+			}
+		}()
+	}
+
+	if rotateJWTSecretsFlag > 0 {
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-time.After(rotateJWTSecretsFlag):
+				playgroundLog.Info("rotating the JWT secrets of the execution nodes")
+				if err := dockerRunner.RotateJWTSecrets(); err != nil {
+					playgroundLog.Error("failed to rotate the JWT secrets", "err", err)
+				}
+			}
+		}()
+	}
+
+	var timerCh <-chan time.Time
+	if timeout > 0 {
+		timerCh = time.After(timeout)
+	}
+
+	var reason playground.ExitReason
+	var exitErr error
+	select {
+	case <-ctx.Done():
+		fmt.Println("Stopping...")
+		reason = playground.ExitReasonInterrupted
+	case err := <-dockerRunner.ExitErr():
+		playgroundLog.Error("service failed", "err", err)
+		var failedErr *playground.ServiceFailedError
+		if errors.As(err, &failedErr) && len(failedErr.Logs) > 0 {
+			fmt.Printf("\n========= Last logs of %s =========\n", failedErr.Service)
+			for _, line := range failedErr.Logs {
+				fmt.Println(line)
+			}
+		}
+		reason, exitErr = playground.ExitReasonServiceFailed, playground.NewClassifiedError(playground.ErrorClassServiceFailed, err)
+	case err := <-watchdogErr:
+		playgroundLog.Error("watchdog failed", "err", err)
+		reason, exitErr = playground.ExitReasonWatchdogFailed, playground.NewClassifiedError(playground.ErrorClassWatchdogFailed, err)
+	case <-timerCh:
+		playgroundLog.Info("timeout reached")
+		reason = playground.ExitReasonTimeout
+	}
+
+	if err := stop(reason, exitErr); err != nil {
+		return err
+	}
+	// the session ended by a failure exits with the code of its class
+	return exitErr
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ferranbt/builder-playground/pkg/playground"
+	"github.com/spf13/cobra"
+)
+
+// registerCompletions adds the dynamic shell completions (see 'playground completion') of the
+// args and the flags: the recipes are the subcommands of cook, manifest and describe, and the
+// services of a recipe are the ones of its manifest with the flags typed so far.
+func registerCompletions() {
+	cookCmd.RegisterFlagCompletionFunc("override", completeOverrides)
+	for _, name := range []string{"platform", "env-file", "bind", "restart-policy", "user", "ready-timeout", "clock-skew"} {
+		// the flags of a service are <service>=<value>
+		cookCmd.RegisterFlagCompletionFunc(name, completeServiceValues)
+	}
+
+	cookCmd.RegisterFlagCompletionFunc("pull-policy", cobra.FixedCompletions([]string{
+		string(playground.PullPolicyAlways), string(playground.PullPolicyMissing), string(playground.PullPolicyNever),
+	}, cobra.ShellCompDirectiveNoFileComp))
+	cookCmd.RegisterFlagCompletionFunc("export", cobra.FixedCompletions([]string{"kurtosis"}, cobra.ShellCompDirectiveNoFileComp))
+	cookCmd.RegisterFlagCompletionFunc("follow-logs-level", cobra.FixedCompletions([]string{
+		string(playground.LevelTrace), string(playground.LevelDebug), string(playground.LevelInfo), string(playground.LevelWarn), string(playground.LevelError),
+	}, cobra.ShellCompDirectiveNoFileComp))
+	cookCmd.RegisterFlagCompletionFunc("log-format", cobra.FixedCompletions([]string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	cookCmd.RegisterFlagCompletionFunc("graph-format", cobra.FixedCompletions([]string{"dot", "mermaid", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	cookCmd.RegisterFlagCompletionFunc("with-explorer", cobra.FixedCompletions([]string{
+		string(playground.ExplorerBlockscout), string(playground.ExplorerDora),
+	}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.RegisterFlagCompletionFunc("error-format", cobra.FixedCompletions([]string{
+		string(playground.ErrorFormatText), string(playground.ErrorFormatJSON),
+	}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.RegisterFlagCompletionFunc("container-engine", cobra.FixedCompletions([]string{
+		string(playground.ContainerEngineAuto), string(playground.ContainerEngineDocker), string(playground.ContainerEnginePodman),
+	}, cobra.ShellCompDirectiveNoFileComp))
+
+	for _, cmd := range []*cobra.Command{cookCmd, manifestCmd, describeCmd} {
+		cmd.MarkFlagFilename("file", "yaml", "yml")
+	}
+	cookCmd.MarkPersistentFlagFilename("config", "toml", "env")
+	cookCmd.MarkPersistentFlagDirname("templates")
+	cookCmd.MarkPersistentFlagFilename("deploy")
+	cookCmd.MarkPersistentFlagFilename("attach", "yaml", "yml")
+
+	// the commands of a running session
+	for _, cmd := range []*cobra.Command{chaosCmd, validatorsCmd, keysCmd, runScenarioCmd, logsCmd} {
+		cmd.RegisterFlagCompletionFunc("name", completeSessions)
+	}
+	scaleCmd.RegisterFlagCompletionFunc("name", completeSessions)
+	verifyCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeSessions(cmd, args, toComplete)
+	}
+	runScenarioCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt
+	}
+	logsCmd.RegisterFlagCompletionFunc("level", cobra.FixedCompletions([]string{
+		"trace", "debug", "info", "warn", "error",
+	}, cobra.ShellCompDirectiveNoFileComp))
+	logsCmd.MarkFlagDirname("output")
+	logsCmd.ValidArgsFunction = completeLogServices
+	keysExportSlashingProtectionCmd.MarkFlagFilename("output", "json")
+	artifactsCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return playground.ReleaseComponents(), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeSessions completes the names of the sessions started on the host
+func completeSessions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, err := playground.SessionNames()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeLogServices completes the services with a log file in the output folder of the session
+func completeLogServices(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	outputDir, err := sessionOutputDir()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	files, err := filepath.Glob(filepath.Join(outputDir, "logs", "*.log"))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := []string{}
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".log"))
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeOverrides completes the override keys of the services of the recipe (i.e. el.tag=)
+func completeOverrides(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	manifest, err := completionManifest(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return manifest.OverrideKeys(), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeServiceValues completes the names of the services of the recipe followed by '='
+func completeServiceValues(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	manifest, err := completionManifest(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := []string{}
+	for _, svc := range manifest.Services() {
+		names = append(names, svc.Name+"=")
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completionManifest applies the recipe of the command (a subcommand of cook or cook --file)
+// with the flags typed so far, like 'describe'. The logs are muted since the shell shows them.
+func completionManifest(cmd *cobra.Command) (manifest *playground.Manifest, err error) {
+	logConfig, err := playground.ParseLogConfig("error")
+	if err != nil {
+		return nil, err
+	}
+	if err := playground.SetupLogging(logConfig, "text"); err != nil {
+		return nil, err
+	}
+
+	var recipe playground.Recipe
+	if cmd == cookCmd && recipeFileFlag != "" {
+		if recipe, err = playground.NewYamlRecipe(recipeFileFlag); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range recipes {
+		if cmd.Parent() == cookCmd && r.Name() == cmd.Name() {
+			recipe = r
+		}
+	}
+	if recipe == nil {
+		return nil, fmt.Errorf("no recipe to complete")
+	}
+
+	// the recipes panic on invalid flags
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to apply the recipe: %v", r)
+		}
+	}()
+	return playground.ApplyRecipe(recipe)
+}
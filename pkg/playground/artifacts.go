@@ -0,0 +1,1437 @@
+package playground
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	_ "embed"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	ecrypto "github.com/ethereum/go-ethereum/crypto"
+	gethparams "github.com/ethereum/go-ethereum/params"
+	"github.com/hashicorp/go-uuid"
+	"github.com/prysmaticlabs/prysm/v5/config/params"
+	"github.com/prysmaticlabs/prysm/v5/consensus-types/primitives"
+	"github.com/prysmaticlabs/prysm/v5/crypto/bls/common"
+	"github.com/prysmaticlabs/prysm/v5/runtime/interop"
+	"github.com/prysmaticlabs/prysm/v5/runtime/version"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/pbkdf2"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	defaultDiscoveryPrivKey = "a11ac89899cd86e36b6fb881ec1255b8a92a688790b7d950f8b7d8dd626671fb"
+	defaultDiscoveryEnodeID = "3479db4d9217fb5d7a8ed4d61ac36e120b05d36c2eefb795dc42ff2e971f251a2315f5649ea1833271e020b9adc98d5db9973c7ed92d6b2f1f2223088c3d852f"
+)
+
+// minimumGenesisDelay is the minimum delay for the genesis time. This is required
+// because lighthouse takes some time to start and we need to make sure it is ready
+// otherwise, some blocks are missed.
+var MinimumGenesisDelay uint64 = 10
+
+// DefaultSlotTime is the default number of seconds per slot in the L1 chain
+var DefaultSlotTime uint64 = 12
+
+// DefaultNumValidators is the default number of validators in the L1 genesis
+var DefaultNumValidators uint64 = 100
+
+// DefaultPrefundedBalance is the default balance of the prefunded accounts, 10000 ETH
+var DefaultPrefundedBalance = new(big.Int).Mul(big.NewInt(10000), big.NewInt(gethparams.Ether))
+
+// maxExtraDataSize is the maximum size of the extra data of a block in the consensus rules
+const maxExtraDataSize = 32
+
+// chain ids of the L1 and L2 genesis files. The L2 chain id of the opstack recipe can be changed
+// with L2ChainID, the default one is the chain id of the embedded op-deployer state.
+const (
+	l1ChainID        uint64 = 1337
+	defaultL2ChainID uint64 = 13
+)
+
+//go:embed utils/rollup.json
+var opRollupConfig []byte
+
+//go:embed utils/genesis.json
+var opGenesis []byte
+
+//go:embed utils/state.json
+var opState []byte
+
+//go:embed config.yaml.tmpl
+var clConfigContent []byte
+
+var artifactsLog = Logger("artifacts")
+
+type ArtifactsBuilder struct {
+	outputDir         string
+	applyLatestL1Fork bool
+	genesisDelay      uint64
+	slotTime          uint64
+	fork              *forkConfig
+	logMaxSize        uint64
+	logRetention      int
+	numValidators     uint64
+	insecureKeys      bool
+	minorityKeys      bool
+	executionOnly     bool
+	opInteropDir      string
+	clConfigPath      string
+	l2ChainID         uint64
+	opDeployer        *OpDeployerConfig
+	elSeedDataDir     string
+	clSeedDataDir     string
+	genesisGasLimit   uint64
+	genesisExtraData  string
+	genesisBaseFee    uint64
+	l2GenesisGasLimit uint64
+	prefundedBalance  *big.Int
+	prefundedNonce    uint64
+
+	disabledSystemContracts []string
+	forkEpochs              map[string]uint64
+
+	// extraFiles are written to the output folder with the artifacts, by relative path
+	extraFiles map[string]string
+}
+
+func NewArtifactsBuilder() *ArtifactsBuilder {
+	return &ArtifactsBuilder{
+		outputDir:         "",
+		applyLatestL1Fork: false,
+		genesisDelay:      MinimumGenesisDelay,
+		slotTime:          DefaultSlotTime,
+		numValidators:     DefaultNumValidators,
+		l2ChainID:         defaultL2ChainID,
+		prefundedBalance:  DefaultPrefundedBalance,
+	}
+}
+
+func (b *ArtifactsBuilder) OutputDir(outputDir string) *ArtifactsBuilder {
+	b.outputDir = outputDir
+	return b
+}
+
+func (b *ArtifactsBuilder) ApplyLatestL1Fork(applyLatestL1Fork bool) *ArtifactsBuilder {
+	b.applyLatestL1Fork = applyLatestL1Fork
+	return b
+}
+
+func (b *ArtifactsBuilder) GenesisDelay(genesisDelaySeconds uint64) *ArtifactsBuilder {
+	b.genesisDelay = genesisDelaySeconds
+	return b
+}
+
+func (b *ArtifactsBuilder) NumValidators(numValidators uint64) *ArtifactsBuilder {
+	b.numValidators = numValidators
+	return b
+}
+
+// GenesisBlock sets the gas limit and the extra data of the L1 genesis block. The gas limit of
+// the genesis state of prysm is used if it is 0.
+func (b *ArtifactsBuilder) GenesisBlock(gasLimit uint64, extraData string) *ArtifactsBuilder {
+	b.genesisGasLimit = gasLimit
+	b.genesisExtraData = extraData
+	return b
+}
+
+// L2GenesisGasLimit sets the gas limit of the L2 genesis block and of the system config of the
+// rollup. The one of the L2 genesis is used if it is 0.
+func (b *ArtifactsBuilder) L2GenesisGasLimit(gasLimit uint64) *ArtifactsBuilder {
+	b.l2GenesisGasLimit = gasLimit
+	return b
+}
+
+// GenesisBaseFee sets the base fee, in wei, of the L1 and the L2 genesis blocks. The initial
+// base fee of EIP-1559 (1 gwei) is used if it is 0.
+func (b *ArtifactsBuilder) GenesisBaseFee(baseFee uint64) *ArtifactsBuilder {
+	b.genesisBaseFee = baseFee
+	return b
+}
+
+// PrefundedNonce sets the nonce of the prefunded accounts in the L1 and the L2 genesis, 0 by
+// default like the accounts that never sent a transaction
+func (b *ArtifactsBuilder) PrefundedNonce(nonce uint64) *ArtifactsBuilder {
+	b.prefundedNonce = nonce
+	return b
+}
+
+// PrefundedBalance sets the balance, in wei, of the prefunded accounts in the L1 and the L2 genesis
+func (b *ArtifactsBuilder) PrefundedBalance(balance *big.Int) *ArtifactsBuilder {
+	b.prefundedBalance = balance
+	return b
+}
+
+// InsecureKeys encrypts the validator keystores with a single round of the key derivation
+// function. The keystores are still valid but they can be generated much faster, which
+// matters for large validator sets.
+func (b *ArtifactsBuilder) InsecureKeys(insecureKeys bool) *ArtifactsBuilder {
+	b.insecureKeys = insecureKeys
+	return b
+}
+
+// MinorityValidators moves a third of the validator keys to data_validator_minority for the
+// validator client of the minority node of the L1 recipe (--minority-node)
+func (b *ArtifactsBuilder) MinorityValidators(enabled bool) *ArtifactsBuilder {
+	b.minorityKeys = enabled
+	return b
+}
+
+// ExecutionOnly only generates the artifacts of the execution chain (the genesis, the prefunded
+// accounts and the JWT secret), without the genesis state of the beacon chain and the keys of
+// the validators, for the chains that run without a consensus client (see DevRecipe)
+func (b *ArtifactsBuilder) ExecutionOnly(enabled bool) *ArtifactsBuilder {
+	b.executionOnly = enabled
+	return b
+}
+
+// LogRotation rotates the log files of the services once they reach maxSize megabytes,
+// keeping the last retention rotated files
+func (b *ArtifactsBuilder) LogRotation(maxSize uint64, retention int) *ArtifactsBuilder {
+	b.logMaxSize = maxSize * 1024 * 1024
+	b.logRetention = retention
+	return b
+}
+
+func (b *ArtifactsBuilder) SlotTime(slotTimeSeconds uint64) *ArtifactsBuilder {
+	b.slotTime = slotTimeSeconds
+	return b
+}
+
+// L2ChainID sets the chain id of the OP chain. The L2 genesis and the rollup config are regenerated
+// for the chain id, with the batch inbox derived from it like op-deployer does. Unless OpDeployer
+// is used, the L1 contracts are the ones of the embedded state, so the dispute games keep the
+// default chain id.
+func (b *ArtifactsBuilder) L2ChainID(chainID uint64) *ArtifactsBuilder {
+	b.l2ChainID = chainID
+	return b
+}
+
+// OpDeployer deploys the OP chain with op-deployer instead of using the embedded state, which
+// regenerates the L1 contracts, the L2 genesis and the rollup config for the L2 chain id
+// with the given contracts version (see OpDeployerConfig).
+func (b *ArtifactsBuilder) OpDeployer(config *OpDeployerConfig) *ArtifactsBuilder {
+	b.opDeployer = config
+	return b
+}
+
+// SeedDataDirs builds the L1 genesis of the chain of existing EL and CL data folders (either can
+// be empty) so that the nodes start from them mid-chain. The folders must be data folders of a
+// playground output folder: its genesis time is reused and the new genesis must match its
+// genesis, otherwise the chain config changed and the build fails.
+func (b *ArtifactsBuilder) SeedDataDirs(el, cl string) *ArtifactsBuilder {
+	b.elSeedDataDir, b.clSeedDataDir = el, cl
+	return b
+}
+
+// CLConfig replaces the embedded beacon chain config with the config.yaml in the given path. The
+// slot time and the fork active at genesis are taken from the config.
+func (b *ArtifactsBuilder) CLConfig(path string) *ArtifactsBuilder {
+	b.clConfigPath = path
+	return b
+}
+
+// OpInterop uses the OP chains deployed with op-deployer in the given folder instead of the
+// embedded one. The folder has the state.json of op-deployer and the genesis-<chain id>.json
+// and rollup-<chain id>.json files of each chain (see InteropChainIDs).
+func (b *ArtifactsBuilder) OpInterop(dir string) *ArtifactsBuilder {
+	b.opInteropDir = dir
+	return b
+}
+
+// ForkState pre-seeds the L1 genesis with the state of a live network at the given
+// block (zero for latest) so that the devnet runs as a shadow fork.
+func (b *ArtifactsBuilder) ForkState(rpcURL string, block uint64, accounts []string) *ArtifactsBuilder {
+	if rpcURL == "" {
+		b.fork = nil
+		return b
+	}
+	addrs := []gethcommon.Address{}
+	for _, account := range accounts {
+		addrs = append(addrs, gethcommon.HexToAddress(account))
+	}
+	b.fork = &forkConfig{rpcURL: rpcURL, block: block, accounts: addrs}
+	return b
+}
+
+// ForkEpoch schedules the fork of the beacon chain (altair, bellatrix, capella, deneb, electra or
+// fulu) at the given epoch, which takes precedence over ApplyLatestL1Fork and the CLConfig. The
+// matching execution forks are scheduled at the timestamp of the epoch.
+func (b *ArtifactsBuilder) ForkEpoch(fork string, epoch uint64) *ArtifactsBuilder {
+	if b.forkEpochs == nil {
+		b.forkEpochs = map[string]uint64{}
+	}
+	b.forkEpochs[fork] = epoch
+	return b
+}
+
+// DisableSystemContracts leaves the given system contracts (see SystemContractNames) out of the
+// L1 genesis to test the behavior of the clients when they are missing
+func (b *ArtifactsBuilder) DisableSystemContracts(names []string) *ArtifactsBuilder {
+	b.disabledSystemContracts = names
+	return b
+}
+
+// ExtraFiles writes more files to the output folder, by path relative to the output folder
+func (b *ArtifactsBuilder) ExtraFiles(files map[string]string) *ArtifactsBuilder {
+	b.extraFiles = files
+	return b
+}
+
+// validateGenesisParams checks the parameters of the L1 and L2 genesis blocks against the
+// consensus rules, so that the clients do not reject the genesis files
+func (b *ArtifactsBuilder) validateGenesisParams() error {
+	if b.prefundedBalance == nil || b.prefundedBalance.Sign() <= 0 {
+		return fmt.Errorf("the balance of the prefunded accounts must be positive")
+	}
+	for name, gasLimit := range map[string]uint64{"L1": b.genesisGasLimit, "L2": b.l2GenesisGasLimit} {
+		if gasLimit != 0 && (gasLimit < gethparams.MinGasLimit || gasLimit > gethparams.MaxGasLimit) {
+			return fmt.Errorf("the gas limit of the %s genesis block is %d, it must be between %d and %d", name, gasLimit, gethparams.MinGasLimit, gethparams.MaxGasLimit)
+		}
+	}
+	if len(b.genesisExtraData) > maxExtraDataSize {
+		return fmt.Errorf("the extra data of the genesis block is %d bytes, the maximum is %d", len(b.genesisExtraData), maxExtraDataSize)
+	}
+	return nil
+}
+
+type Artifacts struct {
+	Out *output
+
+	// SlotTime is the number of seconds per slot of the L1 chain. It is different from the
+	// one of the builder if the beacon chain config sets another one.
+	SlotTime uint64
+
+	// L2ChainID is the chain id of the OP chain (see L2ChainID)
+	L2ChainID uint64
+
+	// OpDeployment are the L1 contracts of the OP chain, from the embedded state or the one
+	// of op-deployer. It is nil for the interop chains.
+	OpDeployment *opChainDeployment
+}
+
+// Build generates the artifacts in the output folder. Cancelling the context stops the
+// generation, which can take a while for large validator sets or shadow forks.
+func (b *ArtifactsBuilder) Build(ctx context.Context) (_ *Artifacts, err error) {
+	ctx, span := StartSpan(ctx, "build artifacts", attribute.Int("num_validators", int(b.numValidators)))
+	defer func() {
+		EndSpan(span, err)
+	}()
+
+	homeDir, err := GetHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	if b.outputDir == "" {
+		// Use the $HOMEDIR/devnet as the default output
+		b.outputDir = filepath.Join(homeDir, "devnet")
+	}
+
+	out := &output{dst: b.outputDir, homeDir: homeDir, logMaxSize: b.logMaxSize, logRetention: b.logRetention}
+
+	// the genesis of the seeded data folders is read before the output folder is deleted
+	var elSeed, clSeed *seedChain
+	if b.elSeedDataDir != "" {
+		if elSeed, err = loadSeedChain(b.elSeedDataDir, b.outputDir); err != nil {
+			return nil, err
+		}
+	}
+	if b.clSeedDataDir != "" {
+		if clSeed, err = loadSeedChain(b.clSeedDataDir, b.outputDir); err != nil {
+			return nil, err
+		}
+	}
+	if elSeed != nil && clSeed != nil && elSeed.genesisTime != clSeed.genesisTime {
+		return nil, fmt.Errorf("the EL and CL datadirs are from different chains")
+	}
+
+	// check if the output directory exists
+	if out.Exists("") {
+		artifactsLog.Info("deleting existing output directory", "path", b.outputDir)
+		if err := out.Remove(""); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.genesisDelay < MinimumGenesisDelay {
+		artifactsLog.Warn("genesis delay too low, using the minimum", "seconds", MinimumGenesisDelay)
+		b.genesisDelay = MinimumGenesisDelay
+	}
+
+	// enable the latest fork in config.yaml or not
+	var latestForkEpoch string
+	if b.applyLatestL1Fork {
+		latestForkEpoch = "0"
+	} else {
+		latestForkEpoch = "18446744073709551615"
+	}
+	clConfigContentStr := strings.Replace(string(clConfigContent), "{{.LatestForkEpoch}}", latestForkEpoch, 1)
+
+	if b.slotTime == 0 {
+		return nil, fmt.Errorf("slot time must be at least 1 second")
+	}
+	if b.numValidators == 0 {
+		return nil, fmt.Errorf("the number of validators must be at least 1")
+	}
+	if b.l2ChainID == 0 || b.l2ChainID == l1ChainID {
+		return nil, fmt.Errorf("invalid L2 chain id %d, it must be non zero and different from the L1 chain id %d", b.l2ChainID, l1ChainID)
+	}
+	if err := validateSystemContractNames(b.disabledSystemContracts); err != nil {
+		return nil, err
+	}
+	clConfigContentStr = strings.Replace(clConfigContentStr, "{{.SecondsPerSlot}}", fmt.Sprintf("%d", b.slotTime), 1)
+
+	// load the config.yaml file
+	var clConfig *params.BeaconChainConfig
+	if b.clConfigPath != "" {
+		if clConfig, err = loadCLConfig(b.clConfigPath); err != nil {
+			return nil, err
+		}
+		if clConfig.SecondsPerSlot != b.slotTime {
+			artifactsLog.Info("using the slot time of the beacon chain config", "seconds", clConfig.SecondsPerSlot)
+			b.slotTime = clConfig.SecondsPerSlot
+		}
+	} else {
+		if clConfig, err = params.UnmarshalConfig([]byte(clConfigContentStr), nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(b.forkEpochs) != 0 {
+		if err := applyForkEpochs(clConfig, b.forkEpochs); err != nil {
+			return nil, err
+		}
+	}
+
+	genesisTime := uint64(time.Now().Add(time.Duration(b.genesisDelay) * time.Second).Unix())
+	if seed := cmp.Or(elSeed, clSeed); seed != nil {
+		artifactsLog.Info("using the genesis time of the seeded datadir", "genesis", seed.dir)
+		genesisTime = seed.genesisTime
+	}
+
+	// the genesis state is generated directly, align the genesis parameters with its genesis time
+	clConfig.GenesisDelay = b.genesisDelay
+	clConfig.MinGenesisTime = genesisTime - b.genesisDelay
+
+	if err := params.SetActive(clConfig); err != nil {
+		return nil, err
+	}
+	config := params.BeaconConfig()
+
+	gen := interop.GethTestnetGenesis(genesisTime, config)
+	// HACK: fix this in prysm?
+	gen.Config.DepositContractAddress = gethcommon.HexToAddress(config.DepositContractAddress)
+	if err := b.validateGenesisParams(); err != nil {
+		return nil, err
+	}
+	if b.genesisGasLimit != 0 {
+		gen.GasLimit = b.genesisGasLimit
+	}
+	if b.genesisBaseFee != 0 {
+		gen.BaseFee = new(big.Int).SetUint64(b.genesisBaseFee)
+	}
+	if b.genesisExtraData != "" {
+		gen.ExtraData = []byte(b.genesisExtraData)
+	}
+
+	if b.fork != nil {
+		forkAlloc, err := fetchForkState(ctx, b.fork)
+		if err != nil {
+			return nil, err
+		}
+		// the accounts of the devnet genesis (i.e. the deposit contract) take precedence
+		for addr, account := range forkAlloc {
+			if _, ok := gen.Alloc[addr]; !ok {
+				gen.Alloc[addr] = account
+			}
+		}
+	}
+
+	// add pre-funded accounts
+	funded, err := b.fundedAccounts()
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range funded {
+		gen.Alloc[account.Address] = types.Account{
+			Balance: new(big.Int).Set(b.prefundedBalance),
+			Nonce:   account.Nonce,
+		}
+	}
+
+	opDeployerState, l2Genesis, l2Rollup := opState, opGenesis, opRollupConfig
+	var interopChainIDs []uint64
+	if b.opInteropDir != "" {
+		if interopChainIDs, err = InteropChainIDs(b.opInteropDir); err != nil {
+			return nil, err
+		}
+		if opDeployerState, err = os.ReadFile(filepath.Join(b.opInteropDir, "state.json")); err != nil {
+			return nil, fmt.Errorf("failed to read the op-deployer state: %w", err)
+		}
+	} else if b.opDeployer != nil {
+		result, err := runOpDeployer(ctx, out, b.opDeployer, b.l2ChainID)
+		if err != nil {
+			return nil, err
+		}
+		opDeployerState, l2Genesis, l2Rollup = result.state, result.genesis, result.rollup
+	}
+	opChainState, err := parseOpDeployerState(opDeployerState)
+	if err != nil {
+		return nil, err
+	}
+	var opDeployment *opChainDeployment
+	if b.opInteropDir == "" {
+		if opDeployment, err = opChainState.chainDeployment(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply Optimism pre-state
+	{
+		decoded, err := base64.StdEncoding.DecodeString(opChainState.L1StateDump)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode opState: %w", err)
+		}
+
+		// Create gzip reader from the base64 decoded data
+		gr, err := gzip.NewReader(bytes.NewReader(decoded))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		defer gr.Close()
+
+		// Read and decode the contents
+		contents, err := io.ReadAll(gr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read opState: %w", err)
+		}
+
+		var alloc types.GenesisAlloc
+		if err := json.Unmarshal(contents, &alloc); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal opState: %w", err)
+		}
+		for addr, account := range alloc {
+			gen.Alloc[addr] = account
+		}
+	}
+
+	// the system contracts are last so that no other state overrides them
+	addSystemContracts(gen, b.disabledSystemContracts)
+	if err := validateGenesis(gen, genesisTime, config, b.disabledSystemContracts); err != nil {
+		return nil, fmt.Errorf("invalid L1 genesis: %w", err)
+	}
+
+	block := gen.ToBlock()
+	artifactsLog.Info("genesis block", "hash", block.Hash())
+
+	if b.executionOnly {
+		err = out.WriteBatch(map[string]interface{}{
+			"genesis.json":        gen,
+			prefundedAccountsFile: funded,
+			"jwtsecret":           defaultJWTToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := b.writeExtraFiles(out); err != nil {
+			return nil, err
+		}
+		return &Artifacts{Out: out, SlotTime: b.slotTime, L2ChainID: b.l2ChainID, OpDeployment: opDeployment}, nil
+	}
+
+	// the fork of the genesis state
+	var v int
+	if config.ElectraForkEpoch == 0 {
+		v = version.Electra
+	} else {
+		v = version.Deneb
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	priv, pub, err := interop.DeterministicallyGenerateKeys(0, b.numValidators)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, minorityKeys := priv, []common.SecretKey{}
+	if b.minorityKeys {
+		if b.numValidators < 3 {
+			return nil, fmt.Errorf("the minority node requires at least 3 validators")
+		}
+		split := len(priv) - len(priv)/3
+		keys, minorityKeys = priv[:split], priv[split:]
+	}
+
+	// the keystores only depend on the keys, encrypt them while the genesis state is generated
+	keystoreErr := make(chan error, 1)
+	go func() {
+		if err := out.WriteFile("data_validator/", &lighthouseKeystore{ctx: ctx, privKeys: keys, insecure: b.insecureKeys}); err != nil {
+			keystoreErr <- err
+			return
+		}
+		if len(minorityKeys) != 0 {
+			keystoreErr <- out.WriteFile("data_validator_minority/", &lighthouseKeystore{ctx: ctx, privKeys: minorityKeys, insecure: b.insecureKeys})
+			return
+		}
+		keystoreErr <- nil
+	}()
+
+	depositData, roots, err := interop.DepositDataFromKeysWithExecCreds(priv, pub, b.numValidators)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	opts := make([]interop.PremineGenesisOpt, 0)
+	opts = append(opts, interop.WithDepositData(depositData, roots))
+
+	_, genesisSpan := StartSpan(ctx, "generate genesis state")
+	state, err := interop.NewPreminedGenesis(ctx, genesisTime, 0, b.numValidators, v, block, opts...)
+	EndSpan(genesisSpan, err)
+	if err != nil {
+		return nil, err
+	}
+	if elSeed != nil {
+		if err := elSeed.checkExecution(block.Hash()); err != nil {
+			return nil, err
+		}
+	}
+	if clSeed != nil {
+		genesisSSZ, err := state.MarshalSSZ()
+		if err != nil {
+			return nil, err
+		}
+		if err := clSeed.checkConsensus(genesisSSZ); err != nil {
+			return nil, err
+		}
+	}
+	if err := <-keystoreErr; err != nil {
+		return nil, fmt.Errorf("failed to write validator keystores: %w", err)
+	}
+
+	validatorsRoot := state.GenesisValidatorsRoot()
+	artifacts := map[string]interface{}{
+		"testnet/config.yaml":                 func() ([]byte, error) { return convert(config) },
+		"testnet/genesis.ssz":                 state,
+		"genesis.json":                        gen,
+		prefundedAccountsFile:                 funded,
+		"jwtsecret":                           defaultJWTToken,
+		"testnet/boot_enr.yaml":               "[]",
+		"testnet/deploy_block.txt":            "0",
+		"testnet/deposit_contract_block.txt":  "0",
+		"testnet/genesis_validators_root.txt": hex.EncodeToString(validatorsRoot),
+		"deterministic_p2p_key.txt":           defaultDiscoveryPrivKey,
+
+		// the validator clients start from the empty slashing protection history of their keys
+		"data_validator/" + slashingProtectionFile: newSlashingProtectionInterchange(validatorsRoot, keys),
+	}
+	if len(minorityKeys) != 0 {
+		artifacts["data_validator_minority/"+slashingProtectionFile] = newSlashingProtectionInterchange(validatorsRoot, minorityKeys)
+	}
+	if err := out.WriteBatch(artifacts); err != nil {
+		return nil, err
+	}
+
+	if b.opInteropDir == "" {
+		if b.opDeployer == nil {
+			// op-deployer already generates them for the chain id
+			if l2Genesis, l2Rollup, err = opChainConfig(l2Genesis, l2Rollup, opChainState, b.l2ChainID); err != nil {
+				return nil, err
+			}
+		}
+		if err := b.writeOpChain(out, l2Genesis, l2Rollup, block.Hash(), genesisTime, "l2-genesis.json", "rollup.json", false); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, chainID := range interopChainIDs {
+			genesis, err := os.ReadFile(filepath.Join(b.opInteropDir, fmt.Sprintf("genesis-%d.json", chainID)))
+			if err != nil {
+				return nil, err
+			}
+			rollup, err := os.ReadFile(filepath.Join(b.opInteropDir, fmt.Sprintf("rollup-%d.json", chainID)))
+			if err != nil {
+				return nil, err
+			}
+			if err := b.writeOpChain(out, genesis, rollup, block.Hash(), genesisTime, fmt.Sprintf("l2-genesis-%d.json", chainID), fmt.Sprintf("rollup-%d.json", chainID), true); err != nil {
+				return nil, fmt.Errorf("failed to write the artifacts of chain %d: %w", chainID, err)
+			}
+		}
+		if err := out.WriteFile("dependency_set.json", newInteropDependencySet(interopChainIDs)); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.writeExtraFiles(out); err != nil {
+		return nil, err
+	}
+
+	return &Artifacts{Out: out, SlotTime: b.slotTime, L2ChainID: b.l2ChainID, OpDeployment: opDeployment}, nil
+}
+
+// writeExtraFiles writes the extra files last, so that they can replace the generated artifacts
+func (b *ArtifactsBuilder) writeExtraFiles(out *output) error {
+	for path, content := range b.extraFiles {
+		if !filepath.IsLocal(path) {
+			return fmt.Errorf("file %s must be a path inside the output folder", path)
+		}
+		if err := out.WriteFile(path, content); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// opChainDeployment are the addresses of the L1 contracts of the OP chain deployed in the
+// L1 genesis state, including the dispute game contracts used by the fault proofs
+type opChainDeployment struct {
+	SystemConfig            string `json:"systemConfigProxyAddress"`
+	OptimismPortal          string `json:"optimismPortalProxyAddress"`
+	DisputeGameFactory      string `json:"disputeGameFactoryProxyAddress"`
+	AnchorStateRegistry     string `json:"anchorStateRegistryProxyAddress"`
+	PermissionedDisputeGame string `json:"permissionedDisputeGameAddress"`
+}
+
+// loadCLConfig loads and validates a beacon chain config.yaml provided by the user. The execution
+// genesis is generated for the playground L1 chain, so the deposit chain must be the same, and the
+// genesis state must start at least from Deneb.
+func loadCLConfig(path string) (*params.BeaconChainConfig, error) {
+	config, err := params.UnmarshalConfigFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load beacon chain config: %w", err)
+	}
+
+	if config.DepositChainID != l1ChainID || config.DepositNetworkID != l1ChainID {
+		return nil, fmt.Errorf("the deposit chain and network id of the beacon chain config must be %d", l1ChainID)
+	}
+	if config.SecondsPerSlot == 0 {
+		return nil, fmt.Errorf("the slot time of the beacon chain config must be at least 1 second")
+	}
+	if err := validateCLForks(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// validateCLForks checks that the fork schedule of the beacon chain config is supported. The
+// genesis state starts from Deneb or Electra.
+func validateCLForks(config *params.BeaconChainConfig) error {
+	forks := []struct {
+		name  string
+		epoch primitives.Epoch
+	}{
+		{"ALTAIR_FORK_EPOCH", config.AltairForkEpoch},
+		{"BELLATRIX_FORK_EPOCH", config.BellatrixForkEpoch},
+		{"CAPELLA_FORK_EPOCH", config.CapellaForkEpoch},
+		{"DENEB_FORK_EPOCH", config.DenebForkEpoch},
+	}
+	for _, fork := range forks {
+		if fork.epoch != 0 {
+			return fmt.Errorf("the beacon chain config must start from Deneb, %s is %d", fork.name, fork.epoch)
+		}
+	}
+	if config.FuluForkEpoch == 0 {
+		return fmt.Errorf("fulu at genesis is not supported")
+	}
+	if config.ElectraForkEpoch > config.FuluForkEpoch {
+		return fmt.Errorf("the electra fork epoch must be before the fulu fork epoch")
+	}
+	return nil
+}
+
+// applyForkEpochs schedules the forks of the beacon chain config at the given epochs
+func applyForkEpochs(config *params.BeaconChainConfig, forkEpochs map[string]uint64) error {
+	epochs := map[string]*primitives.Epoch{
+		"altair":    &config.AltairForkEpoch,
+		"bellatrix": &config.BellatrixForkEpoch,
+		"capella":   &config.CapellaForkEpoch,
+		"deneb":     &config.DenebForkEpoch,
+		"electra":   &config.ElectraForkEpoch,
+		"fulu":      &config.FuluForkEpoch,
+	}
+	for fork, epoch := range forkEpochs {
+		ptr, ok := epochs[fork]
+		if !ok {
+			return fmt.Errorf("unknown fork '%s', expected one of altair, bellatrix, capella, deneb, electra, fulu", fork)
+		}
+		*ptr = primitives.Epoch(epoch)
+	}
+	if err := validateCLForks(config); err != nil {
+		return fmt.Errorf("invalid fork schedule: %w", err)
+	}
+	return nil
+}
+
+// mustOpChainDeployment returns the deployment of the OP chain from the embedded state
+func mustOpChainDeployment() *opChainDeployment {
+	state, err := parseOpDeployerState(opState)
+	if err != nil {
+		panic(fmt.Sprintf("BUG: %v", err))
+	}
+	deployment, err := state.chainDeployment()
+	if err != nil {
+		panic(fmt.Sprintf("BUG: %v", err))
+	}
+	return deployment
+}
+
+// opChainConfig regenerates the embedded L2 genesis and rollup config for the chain id. The
+// addresses of the L1 contracts in the rollup config are the ones of the op-deployer state and
+// the batch inbox is derived from the chain id, like op-deployer does.
+func opChainConfig(genesis, rollup []byte, state *opDeployerState, chainID uint64) ([]byte, []byte, error) {
+	deployment, err := state.chainDeployment()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chainIDHash := gethcommon.BigToHash(new(big.Int).SetUint64(chainID))
+	var batchInbox gethcommon.Address
+	copy(batchInbox[1:], ecrypto.Keccak256(chainIDHash[:])[:19])
+
+	newGenesis, err := overrideJSON(genesis, map[string]interface{}{
+		"config": map[string]interface{}{
+			"chainId": chainID,
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	newRollup, err := overrideJSON(rollup, map[string]interface{}{
+		"l2_chain_id":               chainID,
+		"batch_inbox_address":       strings.ToLower(batchInbox.Hex()),
+		"deposit_contract_address":  deployment.OptimismPortal,
+		"l1_system_config_address":  deployment.SystemConfig,
+		"protocol_versions_address": state.SuperchainDeployment.ProtocolVersions,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return newGenesis, newRollup, nil
+}
+
+// writeOpChain writes the genesis and the rollup config of an OP chain, with the timestamps
+// and the L1 genesis block of the devnet. If interop is set, the interop fork is active from the
+// L2 genesis.
+func (b *ArtifactsBuilder) writeOpChain(out *output, genesis, rollup []byte, l1Hash gethcommon.Hash, genesisTime uint64, genesisFile, rollupFile string, interop bool) error {
+	// the L2 block time cannot be larger than the L1 slot time
+	opBlockTime := min(uint64(2), b.slotTime)
+	opTimestamp := genesisTime + opBlockTime
+
+	// override l2 genesis, make the timestamp start one L2 block after the L1 genesis
+	genesisOverrides := map[string]interface{}{
+		"timestamp": hexutil.Uint64(opTimestamp).String(),
+	}
+	if b.genesisBaseFee != 0 {
+		genesisOverrides["baseFeePerGas"] = hexutil.EncodeUint64(b.genesisBaseFee)
+	}
+	if b.l2GenesisGasLimit != 0 {
+		genesisOverrides["gasLimit"] = hexutil.EncodeUint64(b.l2GenesisGasLimit)
+	}
+	alloc, err := b.prefundedL2Alloc(genesis)
+	if err != nil {
+		return err
+	}
+	genesisOverrides["alloc"] = alloc
+	if interop {
+		genesisOverrides["config"] = map[string]interface{}{
+			"interopTime": opTimestamp,
+		}
+	}
+	newOpGenesis, err := overrideJSON(genesis, genesisOverrides)
+	if err != nil {
+		return err
+	}
+
+	// the hash of the genesis has changed beause of the timestamp so we need to account for that
+	var opGenesisObj core.Genesis
+	if err := json.Unmarshal(newOpGenesis, &opGenesisObj); err != nil {
+		return fmt.Errorf("failed to unmarshal opGenesis: %w", err)
+	}
+
+	opGenesisHash := opGenesisObj.ToBlock().Hash()
+
+	// override rollup.json with the real values for the L1 chain and the correct timestamp
+	rollupOverrides := map[string]interface{}{
+		"block_time": opBlockTime,
+		"genesis": map[string]interface{}{
+			"l2_time": opTimestamp, // this one not in hex
+			"l1": map[string]interface{}{
+				"hash":   l1Hash.String(),
+				"number": 0,
+			},
+			"l2": map[string]interface{}{
+				"hash":   opGenesisHash.String(),
+				"number": 0,
+			},
+		},
+		"chain_op_config": map[string]interface{}{ // TODO: Read this from somewhere (genesis??)
+			"eip1559Elasticity":        6,
+			"eip1559Denominator":       50,
+			"eip1559DenominatorCanyon": 250,
+		},
+	}
+	if b.l2GenesisGasLimit != 0 {
+		// the gas limit of the L2 blocks is the one of the system config, not of the parent block
+		rollupOverrides["genesis"].(map[string]interface{})["system_config"] = map[string]interface{}{
+			"gasLimit": b.l2GenesisGasLimit,
+		}
+	}
+	if interop {
+		rollupOverrides["interop_time"] = opTimestamp
+	}
+	newOpRollup, err := overrideJSON(rollup, rollupOverrides)
+	if err != nil {
+		return err
+	}
+
+	if err := out.WriteFile(genesisFile, newOpGenesis); err != nil {
+		return err
+	}
+	if err := out.WriteFile(rollupFile, newOpRollup); err != nil {
+		return err
+	}
+	return nil
+}
+
+// prefundedAccountsFile is the file of the output folder with the prefunded accounts, so that
+// the tools do not have to hard-code their keys
+const prefundedAccountsFile = "prefunded-accounts.json"
+
+// PrefundedAccount is an account funded in the L1 and the L2 genesis, an entry of
+// prefunded-accounts.json
+type PrefundedAccount struct {
+	Address    gethcommon.Address `json:"address"`
+	PrivateKey string             `json:"privateKey"`
+
+	// Balance is the balance in the genesis, in wei
+	Balance string `json:"balance"`
+	Nonce   uint64 `json:"nonce"`
+}
+
+// fundedAccounts returns the prefunded accounts with the balance and the nonce of the builder
+func (b *ArtifactsBuilder) fundedAccounts() ([]*PrefundedAccount, error) {
+	accounts := []*PrefundedAccount{}
+	for _, privStr := range prefundedAccounts {
+		priv, err := getPrivKey(privStr)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, &PrefundedAccount{
+			Address:    ecrypto.PubkeyToAddress(priv.PublicKey),
+			PrivateKey: privStr,
+			Balance:    b.prefundedBalance.String(),
+			Nonce:      b.prefundedNonce,
+		})
+	}
+	return accounts, nil
+}
+
+// prefundedL2Alloc returns the overrides of the alloc of an L2 genesis with the balance and the
+// nonce of the prefunded accounts. The accounts that are already in the alloc keep their code
+// and storage.
+func (b *ArtifactsBuilder) prefundedL2Alloc(genesis []byte) (map[string]interface{}, error) {
+	var existing struct {
+		Alloc map[string]json.RawMessage `json:"alloc"`
+	}
+	if err := json.Unmarshal(genesis, &existing); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the L2 genesis: %w", err)
+	}
+	// the keys of the alloc are not always prefixed or lowercase
+	keys := map[gethcommon.Address]string{}
+	for key := range existing.Alloc {
+		keys[gethcommon.HexToAddress(key)] = key
+	}
+
+	funded, err := b.fundedAccounts()
+	if err != nil {
+		return nil, err
+	}
+	alloc := map[string]interface{}{}
+	for _, account := range funded {
+		key, ok := keys[account.Address]
+		if !ok {
+			key = strings.ToLower(account.Address.Hex())
+		}
+		alloc[key] = map[string]interface{}{
+			"balance": hexutil.EncodeBig(b.prefundedBalance),
+			"nonce":   hexutil.EncodeUint64(account.Nonce),
+		}
+	}
+	return alloc, nil
+}
+
+// ParseEther parses an amount of ether in decimal (i.e. 10000 or 0.5) and returns it in wei
+func ParseEther(amount string) (*big.Int, error) {
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok || strings.ContainsAny(amount, "/eE") {
+		return nil, fmt.Errorf("invalid amount of ether '%s'", amount)
+	}
+	value.Mul(value, new(big.Rat).SetInt64(gethparams.Ether))
+	if !value.IsInt() {
+		return nil, fmt.Errorf("the amount of ether '%s' has more than 18 decimals", amount)
+	}
+	return value.Num(), nil
+}
+
+func overrideJSON(jsonData []byte, overrides map[string]interface{}) ([]byte, error) {
+	// Parse original JSON into a map
+	var original map[string]interface{}
+	if err := json.Unmarshal(jsonData, &original); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal original JSON: %w", err)
+	}
+
+	// Recursively merge the overrides into the original
+	mergeMap(original, overrides)
+
+	// Marshal back to JSON
+	result, err := json.Marshal(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal modified JSON: %w", err)
+	}
+
+	return result, nil
+}
+
+// mergeMap recursively merges src into dst
+func mergeMap(dst, src map[string]interface{}) {
+	for key, srcVal := range src {
+		if dstVal, exists := dst[key]; exists {
+			// If both values are maps, merge them recursively
+			if dstMap, ok := dstVal.(map[string]interface{}); ok {
+				if srcMap, ok := srcVal.(map[string]interface{}); ok {
+					mergeMap(dstMap, srcMap)
+					continue
+				}
+			}
+		}
+		// For all other cases, override the value
+		dst[key] = srcVal
+	}
+}
+
+func getPrivKey(privStr string) (*ecdsa.PrivateKey, error) {
+	privBuf, err := hex.DecodeString(strings.TrimPrefix(privStr, "0x"))
+	if err != nil {
+		return nil, err
+	}
+
+	priv, err := ecrypto.ToECDSA(privBuf)
+	if err != nil {
+		return nil, err
+	}
+	return priv, nil
+}
+
+func Connect(service, port string) string {
+	return fmt.Sprintf(`{{Service "%s" "%s"}}`, service, port)
+}
+
+// ConnectAddr is like Connect for the connection strings that are not http URLs, it resolves
+// to the host:port of the port of the service
+func ConnectAddr(service, port string) string {
+	return fmt.Sprintf(`{{Addr "%s" "%s"}}`, service, port)
+}
+
+type output struct {
+	dst string
+
+	homeDir string
+	lock    sync.Mutex
+
+	// logMaxSize is the size in bytes at which the log files are rotated (disabled if zero)
+	// and logRetention is the number of rotated files to keep
+	logMaxSize   uint64
+	logRetention int
+}
+
+func (o *output) AbsoluteDstPath() (string, error) {
+	return filepath.Abs(o.dst)
+}
+
+func (o *output) Exists(path string) bool {
+	_, err := os.Stat(filepath.Join(o.dst))
+	return err == nil
+}
+
+func (o *output) Remove(path string) error {
+	return os.RemoveAll(filepath.Join(o.dst, path))
+}
+
+func (o *output) CopyFile(src string, dst string) error {
+	// Open the source file
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer sourceFile.Close()
+
+	// Create the destination directory if it doesn't exist
+	dstPath := filepath.Join(o.dst, dst)
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	// Create the destination file
+	destFile, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer destFile.Close()
+
+	// Copy the contents
+	if _, err := io.Copy(destFile, sourceFile); err != nil {
+		return fmt.Errorf("failed to copy file contents: %w", err)
+	}
+
+	// Copy file permissions from source to destination
+	sourceInfo, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("failed to get source file info: %w", err)
+	}
+
+	if err := os.Chmod(dstPath, sourceInfo.Mode()); err != nil {
+		return fmt.Errorf("failed to set destination file permissions: %w", err)
+	}
+
+	return nil
+}
+
+func (o *output) WriteBatch(data map[string]interface{}) error {
+	for dst, data := range data {
+		if err := o.WriteFile(dst, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *output) LogOutput(name string) (*logFile, error) {
+	// lock this because some services might be trying to access this in parallel
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	path := filepath.Join(o.dst, "logs", name+".log")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	logOutput, err := newLogFile(path, o.logMaxSize, o.logRetention)
+	if err != nil {
+		return nil, err
+	}
+	return logOutput, nil
+}
+
+func (o *output) WriteFile(dst string, data interface{}) error {
+	dst = filepath.Join(o.dst, dst)
+
+	var dataRaw []byte
+	var err error
+
+	if raw, ok := data.([]byte); ok {
+		dataRaw = raw
+	} else if raw, ok := data.(string); ok {
+		dataRaw = []byte(raw)
+	} else if ssz, ok := data.(sszObject); ok {
+		if dataRaw, err = ssz.MarshalSSZ(); err != nil {
+			return err
+		}
+	} else if encObj, ok := data.(encObject); ok {
+		// create a new output for this sub-object and delegate the full encoding to it
+		if err = encObj.Encode(&output{dst: dst}); err != nil {
+			return err
+		}
+		return nil
+	} else if encFn, ok := data.(func() ([]byte, error)); ok {
+		if dataRaw, err = encFn(); err != nil {
+			return err
+		}
+	} else {
+		// figure out how to decode the object given the file extension
+		ext := filepath.Ext(dst)
+		if ext == ".json" {
+			if dataRaw, err = json.MarshalIndent(data, "", "\t"); err != nil {
+				return err
+			}
+		} else if ext == ".yaml" {
+			if dataRaw, err = yaml.Marshal(data); err != nil {
+				return err
+			}
+		} else {
+			return fmt.Errorf("unsupported file extension: %s", ext)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, dataRaw, 0644); err != nil {
+		return err
+	}
+	return nil
+}
+
+var secret = "secret"
+
+type lighthouseKeystore struct {
+	// ctx stops the encryption of the remaining keys if cancelled
+	ctx      context.Context
+	privKeys []common.SecretKey
+	insecure bool
+}
+
+func (l *lighthouseKeystore) Encode(o *output) error {
+	// the keystore encryption is cpu intensive, spread the keys across a pool of workers
+	workers := min(runtime.NumCPU(), len(l.privKeys))
+
+	keysCh := make(chan common.SecretKey, len(l.privKeys))
+	for _, key := range l.privKeys {
+		keysCh <- key
+	}
+	close(keysCh)
+
+	progress := newProgress("encrypting validator keystores", len(l.privKeys))
+	errCh := make(chan error, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keysCh {
+				if err := l.ctx.Err(); err != nil {
+					errCh <- err
+					return
+				}
+				if err := encodeValidatorKeystore(o, key, l.insecure); err != nil {
+					errCh <- err
+					return
+				}
+				progress.Inc()
+			}
+		}()
+	}
+	wg.Wait()
+
+	close(errCh)
+	for err := range errCh {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeValidatorKeystore(o *output, key common.SecretKey, insecure bool) error {
+	var cryptoFields map[string]interface{}
+	var err error
+	if insecure {
+		cryptoFields, err = encryptInsecureKeystore(key.Marshal(), secret)
+	} else {
+		cryptoFields, err = keystorev4.New().Encrypt(key.Marshal(), secret)
+	}
+	if err != nil {
+		return err
+	}
+
+	id, _ := uuid.GenerateUUID()
+
+	pubKeyHex := "0x" + hex.EncodeToString(key.PublicKey().Marshal())
+	item := map[string]interface{}{
+		"crypto":      cryptoFields,
+		"uuid":        id,
+		"pubkey":      pubKeyHex[2:], // without 0x in the json file
+		"version":     4,
+		"description": "",
+	}
+	valJSON, err := json.MarshalIndent(item, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return o.WriteBatch(map[string]interface{}{
+		"validators/" + pubKeyHex + "/voting-keystore.json": valJSON,
+		"secrets/" + pubKeyHex:                              secret,
+	})
+}
+
+// encryptInsecureKeystore encrypts the data as an EIP-2335 keystore using pbkdf2 with a single
+// iteration instead of the 262144 used by keystorev4, which takes most of the artifacts build time
+func encryptInsecureKeystore(data []byte, passphrase string) (map[string]interface{}, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	iv := make([]byte, 16)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	decryptionKey := pbkdf2.Key([]byte(passphrase), salt, 1, 32, sha256.New)
+
+	aesCipher, err := aes.NewCipher(decryptionKey[:16])
+	if err != nil {
+		return nil, err
+	}
+	cipherMsg := make([]byte, len(data))
+	cipher.NewCTR(aesCipher, iv).XORKeyStream(cipherMsg, data)
+
+	checksum := sha256.Sum256(append(append([]byte{}, decryptionKey[16:32]...), cipherMsg...))
+
+	return map[string]interface{}{
+		"kdf": map[string]interface{}{
+			"function": "pbkdf2",
+			"params": map[string]interface{}{
+				"dklen": 32,
+				"c":     1,
+				"prf":   "hmac-sha256",
+				"salt":  hex.EncodeToString(salt),
+			},
+			"message": "",
+		},
+		"checksum": map[string]interface{}{
+			"function": "sha256",
+			"params":   map[string]interface{}{},
+			"message":  hex.EncodeToString(checksum[:]),
+		},
+		"cipher": map[string]interface{}{
+			"function": "aes-128-ctr",
+			"params": map[string]interface{}{
+				"iv": hex.EncodeToString(iv),
+			},
+			"message": hex.EncodeToString(cipherMsg),
+		},
+	}, nil
+}
+
+// progress logs the progress of a long running task every 10% of the items
+type progress struct {
+	lock  sync.Mutex
+	name  string
+	total int
+	done  int
+	step  int
+}
+
+func newProgress(name string, total int) *progress {
+	return &progress{name: name, total: total, step: max(total/10, 1)}
+}
+
+func (p *progress) Inc() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.done++
+	if p.done%p.step == 0 || p.done == p.total {
+		artifactsLog.Info(p.name, "done", p.done, "total", p.total)
+	}
+}
+
+type encObject interface {
+	Encode(o *output) error
+}
+
+type sszObject interface {
+	MarshalSSZ() ([]byte, error)
+}
+
+func GetHomeDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting user home directory: %w", err)
+	}
+
+	// Define the path for our custom home directory
+	customHomeDir := filepath.Join(homeDir, ".playground")
+
+	// Create output directory if it doesn't exist
+	if err := os.MkdirAll(customHomeDir, 0755); err != nil {
+		return "", fmt.Errorf("error creating output directory: %v", err)
+	}
+
+	return customHomeDir, nil
+}
+
+func convert(config *params.BeaconChainConfig) ([]byte, error) {
+	val := reflect.ValueOf(config).Elem()
+
+	vals := []string{}
+	for i := 0; i < val.NumField(); i++ {
+		// only encode the public fields with tag 'yaml'
+		tag := val.Type().Field(i).Tag.Get("yaml")
+		if tag == "" {
+			continue
+		}
+
+		// decode the type of the value
+		typ := val.Field(i).Type()
+
+		var resTyp string
+		if isByteArray(typ) || isByteSlice(typ) {
+			resTyp = "0x" + hex.EncodeToString(val.Field(i).Bytes())
+		} else {
+			// basic types
+			switch typ.Kind() {
+			case reflect.String:
+				resTyp = val.Field(i).String()
+			case reflect.Uint8, reflect.Uint64:
+				resTyp = fmt.Sprintf("%d", val.Field(i).Uint())
+			case reflect.Int:
+				resTyp = fmt.Sprintf("%d", val.Field(i).Int())
+			default:
+				panic(fmt.Sprintf("BUG: unsupported type, tag '%s', err: '%s'", tag, val.Field(i).Kind()))
+			}
+		}
+
+		vals = append(vals, fmt.Sprintf("%s: %s", tag, resTyp))
+	}
+
+	return []byte(strings.Join(vals, "\n")), nil
+}
+
+func isByteArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8
+}
+
+func isByteSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+var prefundedAccounts = []string{
+	"0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80",
+	"0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d",
+	"0x5de4111afa1a4b94908f83103eb1f1706367c2e68ca870fc3fb9a804cdab365a",
+	"0x7c852118294e51e653712a81e05800f419141751be58f605c371e15141b007a6",
+	"0x47e179ec197488593b187f80a00eb0da91f1b9d0b13f8733639f19c30a34926a",
+	"0x8b3a350cf5c34c9194ca85829a2df0ec3153be0318b5e2d3348e872092edffba",
+	"0x92db14e403b83dfe3df233f83dfa3a0d7096f21ca9b0d6d6b8d88b2b4ec1564e",
+	"0x4bbbf85ce3377467afe5d46f804f221813b2bb87f24d81f60f1fcdbf7cbf4356",
+	"0xdbda1821b80551c9d65939329250298aa3472ba22feea921c0cf5d620ea67b97",
+	"0x2a871d0798f97d79848a013d4936a73bf4cc922c825d33c1cf7073dff6d409c6",
+}
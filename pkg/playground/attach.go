@@ -0,0 +1,63 @@
+package playground
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// AttachedServices are extra services added to the manifest of any recipe (--attach), described
+// like the services of a YAML recipe. They join the network of the recipe, so they reach its
+// services with the {{Service}} and {{Addr}} templates and depend on them with depends_on.
+type AttachedServices struct {
+	path   string
+	config *YamlAttachConfig
+}
+
+// YamlAttachConfig is the schema of a file of attached services
+type YamlAttachConfig struct {
+	// Services is the list of services to attach, by name. They are added to the manifest
+	// after the services of the recipe, in alphabetical order.
+	Services map[string]*YamlServiceConfig `yaml:"services"`
+
+	// Templates are config files rendered to the output folder with the endpoints of the services,
+	// by path relative to the output folder (see RenderTemplates)
+	Templates map[string]string `yaml:"templates"`
+}
+
+// LoadAttachedServices reads and validates a file of attached services, before the recipe
+// is applied
+func LoadAttachedServices(path string) (*AttachedServices, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attached services file: %w", err)
+	}
+
+	var config YamlAttachConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode attached services file %s: %w", path, err)
+	}
+	if len(config.Services) == 0 {
+		return nil, fmt.Errorf("attached services file %s does not define any services", path)
+	}
+	if err := validateYamlServices(config.Services); err != nil {
+		return nil, fmt.Errorf("attached services file %s: %w", path, err)
+	}
+	return &AttachedServices{path: path, config: &config}, nil
+}
+
+// Attach adds the services to the manifest. The names of the services cannot be the ones of
+// the services of the recipe, the dependencies on them are checked when the manifest is validated.
+func (a *AttachedServices) Attach(manifest *Manifest) error {
+	for name := range a.config.Services {
+		if _, ok := manifest.GetService(name); ok {
+			return fmt.Errorf("attached service %s of %s is already a service of the recipe", name, a.path)
+		}
+	}
+	applyYamlServices(manifest, a.config.Services)
+	for name, content := range a.config.Templates {
+		manifest.AddTemplate(name, content)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	mevRCommon "github.com/flashbots/mev-boost-relay/common"
+)
+
+// bidsFile is the file of the output folder with the bids collected by the BidCollector
+const bidsFile = "bids.jsonl"
+
+// BidRecord is a builder bid received by the relay, a line of bids.jsonl
+type BidRecord struct {
+	Slot        uint64 `json:"slot"`
+	Builder     string `json:"builder"`
+	Value       string `json:"value"`
+	BlockHash   string `json:"blockHash"`
+	BlockNumber uint64 `json:"blockNumber"`
+	NumTx       uint64 `json:"numTx"`
+	GasUsed     uint64 `json:"gasUsed"`
+
+	// LatencyMs is the time from the start of the slot to the bid, negative for the bids sent
+	// before the slot starts
+	LatencyMs  int64 `json:"latencyMs"`
+	Optimistic bool  `json:"optimistic"`
+
+	// Winner signals that the relay delivered the payload of the bid to the proposer
+	Winner bool `json:"winner"`
+}
+
+// BuilderBidsSummary is the summary of the bids of a builder during the run
+type BuilderBidsSummary struct {
+	Builder string `json:"builder"`
+	Bids    int    `json:"bids"`
+	Slots   int    `json:"slots"`
+	Wins    int    `json:"wins"`
+
+	// WonValue is the total value of the delivered payloads of the builder, in wei
+	WonValue string `json:"wonValue"`
+
+	// AvgLatencyMs and MaxLatencyMs are the latencies of the bids from the start of the slot
+	AvgLatencyMs int64 `json:"avgLatencyMs"`
+	MaxLatencyMs int64 `json:"maxLatencyMs"`
+}
+
+// BidsSummary is the summary of the bids received by the relay during the run
+type BidsSummary struct {
+	Slots         int                   `json:"slots"`
+	SlotsWithBids int                   `json:"slotsWithBids"`
+	Delivered     int                   `json:"delivered"`
+	Builders      []*BuilderBidsSummary `json:"builders"`
+}
+
+type builderBids struct {
+	bids, slots, wins int
+	wonValue          *big.Int
+	totalLatencyMs    int64
+	maxLatencyMs      int64
+}
+
+// BidCollector records the bids received by the relay of the devnet every slot in bids.jsonl,
+// with their latency from the start of the slot and whether they won the slot
+type BidCollector struct {
+	relay    *ServiceSpec
+	beacon   *ServiceSpec
+	slotTime time.Duration
+	path     string
+
+	relayURL string
+
+	lock          sync.Mutex
+	slots         int
+	slotsWithBids int
+	delivered     int
+	builders      map[string]*builderBids
+}
+
+// NewBidCollector returns the collector of the relay of the manifest, which must have one. It
+// can be created before the services start.
+func NewBidCollector(manifest *Manifest) (*BidCollector, error) {
+	for _, svc := range manifest.Services() {
+		var beaconClient string
+		var slotTime time.Duration
+		switch relay := svc.component.(type) {
+		case *MevBoostRelay:
+			beaconClient, slotTime = relay.BeaconClient, relay.slotTime
+		case *FlashbotsRelayAPI:
+			beaconClient, slotTime = relay.BeaconClient, relay.slotTime
+		default:
+			continue
+		}
+		return &BidCollector{
+			relay:    svc,
+			beacon:   manifest.MustGetService(beaconClient),
+			slotTime: slotTime,
+			path:     filepath.Join(manifest.out.dst, bidsFile),
+			builders: map[string]*builderBids{},
+		}, nil
+	}
+	return nil, fmt.Errorf("the recipe has no relay to record the bids of")
+}
+
+// Run records the bids of every slot once the next slot starts, until the context is done
+func (b *BidCollector) Run(ctx context.Context) {
+	// the host ports are assigned when the services start
+	b.relayURL = "http://" + b.relay.hostAddr("http")
+	beaconURL := "http://" + b.beacon.hostAddr("http")
+
+	file, err := os.Create(b.path)
+	if err != nil {
+		runnerLog.Error("failed to create the bids file", "err", err)
+		return
+	}
+	defer file.Close()
+
+	var genesisTime time.Time
+	for genesisTime.IsZero() {
+		var genesis struct {
+			Data struct {
+				GenesisTime string `json:"genesis_time"`
+			} `json:"data"`
+		}
+		if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/beacon/genesis", &genesis); err == nil {
+			if secs, err := strconv.ParseInt(genesis.Data.GenesisTime, 10, 64); err == nil {
+				genesisTime = time.Unix(secs, 0)
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+
+	// start from the current slot, the previous ones were before the services started
+	next := uint64(0)
+	if now := time.Now(); now.After(genesisTime) {
+		next = uint64(now.Sub(genesisTime) / b.slotTime)
+	}
+	for {
+		// a slot is recorded once the next one starts, when the proposer already got the payload
+		wait := time.Until(genesisTime.Add(time.Duration(next+2) * b.slotTime))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		slotStart := genesisTime.Add(time.Duration(next) * b.slotTime)
+		records, err := b.slotBids(next, slotStart)
+		if err != nil {
+			// the relay might not be ready yet, the slot is retried
+			runnerLog.Debug("failed to get the bids of the slot", "slot", next, "err", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for _, record := range records {
+			data, err := json.Marshal(record)
+			if err != nil {
+				continue
+			}
+			file.Write(append(data, '\n'))
+		}
+		b.record(records)
+		next++
+	}
+}
+
+// slotBids returns the bids of a slot received by the relay
+func (b *BidCollector) slotBids(slot uint64, slotStart time.Time) ([]*BidRecord, error) {
+	query := url.Values{"slot": []string{fmt.Sprintf("%d", slot)}}
+
+	var bids []*mevRCommon.BidTraceV2WithTimestampJSON
+	if err := getBeaconJSON(context.Background(), b.relayURL+"/relay/v1/data/bidtraces/builder_blocks_received?"+query.Encode(), &bids); err != nil {
+		return nil, err
+	}
+	delivered, err := getRelayBidTraces(b.relayURL, "proposer_payload_delivered", query)
+	if err != nil {
+		return nil, err
+	}
+	winners := map[string]bool{}
+	for _, payload := range delivered {
+		winners[payload.BlockHash] = true
+	}
+
+	records := []*BidRecord{}
+	for _, bid := range bids {
+		timestampMs := bid.TimestampMs
+		if timestampMs == 0 {
+			timestampMs = bid.Timestamp * 1000
+		}
+		records = append(records, &BidRecord{
+			Slot:        bid.Slot,
+			Builder:     bid.BuilderPubkey,
+			Value:       bid.Value,
+			BlockHash:   bid.BlockHash,
+			BlockNumber: bid.BlockNumber,
+			NumTx:       bid.NumTx,
+			GasUsed:     bid.GasUsed,
+			LatencyMs:   timestampMs - slotStart.UnixMilli(),
+			Optimistic:  bid.OptimisticSubmission,
+			Winner:      winners[bid.BlockHash],
+		})
+	}
+	// the relay returns the most recent bids first
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].LatencyMs < records[j].LatencyMs
+	})
+	return records, nil
+}
+
+func (b *BidCollector) record(records []*BidRecord) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.slots++
+	if len(records) != 0 {
+		b.slotsWithBids++
+	}
+	slots := map[string]bool{}
+	for _, record := range records {
+		builder, ok := b.builders[record.Builder]
+		if !ok {
+			builder = &builderBids{wonValue: new(big.Int)}
+			b.builders[record.Builder] = builder
+		}
+		builder.bids++
+		builder.totalLatencyMs += record.LatencyMs
+		if builder.bids == 1 || record.LatencyMs > builder.maxLatencyMs {
+			builder.maxLatencyMs = record.LatencyMs
+		}
+		if !slots[record.Builder] {
+			slots[record.Builder] = true
+			builder.slots++
+		}
+		if record.Winner {
+			builder.wins++
+			b.delivered++
+			if value, ok := new(big.Int).SetString(record.Value, 10); ok {
+				builder.wonValue.Add(builder.wonValue, value)
+			}
+		}
+	}
+}
+
+// Summary returns the summary of the bids recorded so far
+func (b *BidCollector) Summary() *BidsSummary {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	summary := &BidsSummary{Slots: b.slots, SlotsWithBids: b.slotsWithBids, Delivered: b.delivered, Builders: []*BuilderBidsSummary{}}
+	for pubkey, builder := range b.builders {
+		summary.Builders = append(summary.Builders, &BuilderBidsSummary{
+			Builder:      pubkey,
+			Bids:         builder.bids,
+			Slots:        builder.slots,
+			Wins:         builder.wins,
+			WonValue:     builder.wonValue.String(),
+			AvgLatencyMs: builder.totalLatencyMs / int64(builder.bids),
+			MaxLatencyMs: builder.maxLatencyMs,
+		})
+	}
+	sort.Slice(summary.Builders, func(i, j int) bool {
+		return summary.Builders[i].Builder < summary.Builders[j].Builder
+	})
+	return summary
+}
+
+// Print writes the summary as a table of the builders
+func (s *BidsSummary) Print(out io.Writer) {
+	fmt.Fprintf(out, "Slots: %d, slots with bids: %d, delivered payloads: %d\n", s.Slots, s.SlotsWithBids, s.Delivered)
+	if len(s.Builders) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BUILDER\tBIDS\tSLOTS\tWINS\tWON VALUE (ETH)\tAVG LATENCY\tMAX LATENCY")
+	for _, builder := range s.Builders {
+		value, _ := new(big.Int).SetString(builder.WonValue, 10)
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%dms\t%dms\n", truncatePubkey(builder.Builder), builder.Bids, builder.Slots, builder.Wins, weiToEther(value), builder.AvgLatencyMs, builder.MaxLatencyMs)
+	}
+	w.Flush()
+}
+
+func truncatePubkey(pubkey string) string {
+	if len(pubkey) <= 18 {
+		return pubkey
+	}
+	return pubkey[:10] + "..." + pubkey[len(pubkey)-6:]
+}
+
+// weiToEther formats an amount of wei in ether with 6 decimals
+func weiToEther(wei *big.Int) string {
+	return new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Text('f', 6)
+}
@@ -0,0 +1,69 @@
+package playground
+
+var components = []Service{}
+
+// RegisterComponent adds a component to the catalog, so that the YAML recipes can use it by
+// name. The downstream projects register their own components before running the CLI.
+func RegisterComponent(component Service) {
+	components = append(components, component)
+}
+
+func init() {
+	RegisterComponent(&OpBatcher{})
+	RegisterComponent(&OpProposer{})
+	RegisterComponent(&OpChallenger{})
+	RegisterComponent(&OpSupervisor{})
+	RegisterComponent(&OpGeth{})
+	RegisterComponent(&OpReth{})
+	RegisterComponent(&OpNode{})
+	RegisterComponent(&RethEL{})
+	RegisterComponent(&GethDev{})
+	RegisterComponent(&Anvil{})
+	RegisterComponent(&ValidationNode{})
+	RegisterComponent(&LighthouseBeaconNode{})
+	RegisterComponent(&LighthouseValidator{})
+	RegisterComponent(&ClProxy{})
+	RegisterComponent(&ApiProxy{})
+	RegisterComponent(&EngineMux{})
+	RegisterComponent(&RpcGateway{})
+	RegisterComponent(&Faucet{})
+	RegisterComponent(&Faketime{})
+	RegisterComponent(&Bootnode{})
+	RegisterComponent(&MevBoostRelay{})
+	RegisterComponent(&MevBoost{})
+	RegisterComponent(&FlashbotsRelayHousekeeper{})
+	RegisterComponent(&FlashbotsRelayAPI{})
+	RegisterComponent(&FlashbotsRelayWebsite{})
+	RegisterComponent(&RollupBoost{})
+	RegisterComponent(&FlashbotsBuilder{})
+	RegisterComponent(&Rbuilder{})
+	RegisterComponent(&Postgres{})
+	RegisterComponent(&Redis{})
+	RegisterComponent(&BlockscoutPostgres{})
+	RegisterComponent(&Blockscout{})
+	RegisterComponent(&BlockscoutFrontend{})
+	RegisterComponent(&Dora{})
+	RegisterComponent(&Prometheus{})
+	RegisterComponent(&Grafana{})
+}
+
+func FindComponent(name string) Service {
+	for _, component := range components {
+		if component.Name() == name {
+			return component
+		}
+	}
+	return nil
+}
+
+// ReleaseComponents returns the names of the components with a release binary that can run
+// on the host (see the artifacts command)
+func ReleaseComponents() []string {
+	names := []string{}
+	for _, component := range components {
+		if _, ok := component.(ReleaseService); ok {
+			names = append(names, component.Name())
+		}
+	}
+	return names
+}
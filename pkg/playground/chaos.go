@@ -0,0 +1,361 @@
+package playground
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// minorityServices are the services of the minority node of the L1 recipe (--minority-node)
+// that are partitioned from the rest of the network to create a reorg
+var minorityServices = []string{"el-minority", "beacon-minority", "validator-minority"}
+
+// ReorgEvent is a chain_reorg event of a beacon node
+type ReorgEvent struct {
+	Node    string
+	Slot    uint64
+	Depth   uint64
+	OldHead string
+	NewHead string
+}
+
+// BeaconHead is the head block of a beacon node
+type BeaconHead struct {
+	Slot uint64
+	Root string
+}
+
+type ReorgReport struct {
+	// Slots is the number of slots the minority node was partitioned
+	Slots uint64
+
+	// MajorityHead and MinorityHead are the heads of both sides before the rejoin
+	MajorityHead *BeaconHead
+	MinorityHead *BeaconHead
+
+	// Events are the chain_reorg events of the beacon nodes during the experiment
+	Events []*ReorgEvent
+
+	// Converged signals that the minority node follows the head of the majority (on the
+	// beacon chain and the execution chain) after the rejoin
+	Converged bool
+}
+
+// RunReorg creates a reorg in the L1 devnet of a running session. The minority node (an EL/CL
+// pair with its own validators) is moved to an isolated network for the given number of slots,
+// where it builds its own fork. Once it rejoins the network, its beacon node reorgs to the chain
+// of the majority. The timeout is the maximum time to wait for the minority node to converge.
+func RunReorg(ctx context.Context, out io.Writer, session *Session, depth uint64, timeout time.Duration) (*ReorgReport, error) {
+	for _, name := range minorityServices {
+		if _, ok := session.Services[name]; !ok {
+			return nil, fmt.Errorf("session '%s' has no minority node, start the l1 recipe with --minority-node", session.Name)
+		}
+	}
+	beaconURL := sessionServiceURL(session, "beacon", "http")
+	minorityURL := sessionServiceURL(session, "beacon-minority", "http")
+
+	clt, err := session.dockerClient()
+	if err != nil {
+		return nil, err
+	}
+	defer clt.Close()
+
+	containers := map[string]string{}
+	for _, name := range minorityServices {
+		id, err := serviceContainer(ctx, clt, session, name)
+		if err != nil {
+			return nil, err
+		}
+		containers[name] = id
+	}
+
+	slotTime, err := getSlotTime(ctx, beaconURL)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReorgReport{Slots: depth}
+
+	// follow the reorgs of both beacon nodes for the whole experiment
+	var eventsLock sync.Mutex
+	var followers sync.WaitGroup
+	followCtx, cancelFollow := context.WithCancel(ctx)
+	defer cancelFollow()
+	for name, url := range map[string]string{"beacon": beaconURL, "beacon-minority": minorityURL} {
+		followers.Add(1)
+		go func() {
+			defer followers.Done()
+			for followCtx.Err() == nil {
+				// the connection drops while the node is partitioned or restarted
+				followReorgs(followCtx, url, func(event *ReorgEvent) {
+					event.Node = name
+					fmt.Fprintf(out, "Reorg in %s at slot %d (depth %d): %s -> %s\n", name, event.Slot, event.Depth, event.OldHead, event.NewHead)
+
+					eventsLock.Lock()
+					report.Events = append(report.Events, event)
+					eventsLock.Unlock()
+				})
+				select {
+				case <-followCtx.Done():
+				case <-time.After(time.Second):
+				}
+			}
+		}()
+	}
+
+	forkHead, err := getBeaconHead(ctx, beaconURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the head of the beacon node: %w", err)
+	}
+	fmt.Fprintf(out, "Partitioning the minority node at slot %d for %d slots\n", forkHead.Slot, depth)
+
+	partition := &networkPartition{
+		clt:        clt,
+		network:    networkPrefix + "-" + session.Name,
+		partition:  networkPrefix + "-" + session.Name + "-partition",
+		labels:     session.labels(),
+		containers: containers,
+	}
+	if err := partition.isolate(ctx); err != nil {
+		// leave the services in the session network if the partition is incomplete
+		if rejoinErr := partition.rejoin(context.Background()); rejoinErr != nil {
+			fmt.Fprintf(out, "Failed to rejoin the minority node: %v\n", rejoinErr)
+		}
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Duration(depth) * slotTime):
+	}
+
+	report.MajorityHead, _ = getBeaconHead(context.Background(), beaconURL)
+	report.MinorityHead, _ = getBeaconHead(context.Background(), minorityURL)
+
+	// the network is restored even if the command is interrupted
+	fmt.Fprintf(out, "Rejoining the minority node\n")
+	if err := partition.rejoin(context.Background()); err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	// the beacon node does not dial its peers again on its own after it is reconnected
+	if err := clt.ContainerRestart(ctx, containers["beacon-minority"], container.StopOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to restart the minority beacon node: %w", err)
+	}
+
+	elURL := sessionServiceURL(session, "el", "http")
+	minorityELURL := sessionServiceURL(session, "el-minority", "http")
+
+	timeoutCh := time.After(timeout)
+wait:
+	for !report.Converged {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeoutCh:
+			break wait
+		case <-time.After(slotTime):
+		}
+		report.Converged = isConverged(ctx, beaconURL, minorityURL, elURL, minorityELURL)
+	}
+
+	// the events of the last head changes can arrive after the heads match
+	time.Sleep(time.Second)
+	cancelFollow()
+	followers.Wait()
+	return report, nil
+}
+
+// networkPartition moves the containers from the network of the session to an isolated one
+// where they can only reach each other
+type networkPartition struct {
+	clt        *client.Client
+	network    string
+	partition  string
+	labels     map[string]string
+	containers map[string]string
+}
+
+func (p *networkPartition) isolate(ctx context.Context) error {
+	if _, err := p.clt.NetworkCreate(ctx, p.partition, network.CreateOptions{
+		Internal: true,
+		// the partition network is removed with the session if the chaos command is interrupted
+		Labels: p.labels,
+	}); err != nil {
+		return fmt.Errorf("failed to create partition network: %w", err)
+	}
+	for name, id := range p.containers {
+		// the services keep resolving each other by name
+		if err := p.clt.NetworkConnect(ctx, p.partition, id, &network.EndpointSettings{Aliases: []string{name}}); err != nil {
+			return fmt.Errorf("failed to connect %s to the partition network: %w", name, err)
+		}
+		if err := p.clt.NetworkDisconnect(ctx, p.network, id, true); err != nil {
+			return fmt.Errorf("failed to disconnect %s from the session network: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// rejoin moves the containers back to the network of the session and removes the partition
+// network. It can be called after a partial isolate.
+func (p *networkPartition) rejoin(ctx context.Context) error {
+	for name, id := range p.containers {
+		info, err := p.clt.ContainerInspect(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s: %w", name, err)
+		}
+		if _, ok := info.NetworkSettings.Networks[p.network]; !ok {
+			if err := p.clt.NetworkConnect(ctx, p.network, id, &network.EndpointSettings{Aliases: []string{name}}); err != nil {
+				return fmt.Errorf("failed to connect %s to the session network: %w", name, err)
+			}
+		}
+		if _, ok := info.NetworkSettings.Networks[p.partition]; ok {
+			if err := p.clt.NetworkDisconnect(ctx, p.partition, id, true); err != nil {
+				return fmt.Errorf("failed to disconnect %s from the partition network: %w", name, err)
+			}
+		}
+	}
+	if err := p.clt.NetworkRemove(ctx, p.partition); err != nil && !client.IsErrNotFound(err) {
+		return fmt.Errorf("failed to remove partition network: %w", err)
+	}
+	return nil
+}
+
+// isConverged checks whether the minority node follows the same head as the majority
+func isConverged(ctx context.Context, beaconURL, minorityURL, elURL, minorityELURL string) bool {
+	head, err := getBeaconHead(ctx, beaconURL)
+	if err != nil {
+		return false
+	}
+	minorityHead, err := getBeaconHead(ctx, minorityURL)
+	if err != nil || minorityHead.Root != head.Root {
+		return false
+	}
+
+	hashes := []string{}
+	for _, url := range []string{elURL, minorityELURL} {
+		clt, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			return false
+		}
+		header, err := clt.HeaderByNumber(ctx, nil)
+		clt.Close()
+		if err != nil {
+			return false
+		}
+		hashes = append(hashes, header.Hash().Hex())
+	}
+	return hashes[0] == hashes[1]
+}
+
+// followReorgs reads the chain_reorg events from the event stream (SSE) of the beacon node
+func followReorgs(ctx context.Context, beaconURL string, handler func(*ReorgEvent)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, beaconURL+"/eth/v1/events?topics=chain_reorg", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event struct {
+			Slot         string `json:"slot"`
+			Depth        string `json:"depth"`
+			OldHeadBlock string `json:"old_head_block"`
+			NewHeadBlock string `json:"new_head_block"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			return fmt.Errorf("failed to decode chain_reorg event: %w", err)
+		}
+		slot, _ := strconv.ParseUint(event.Slot, 10, 64)
+		depth, _ := strconv.ParseUint(event.Depth, 10, 64)
+		handler(&ReorgEvent{Slot: slot, Depth: depth, OldHead: event.OldHeadBlock, NewHead: event.NewHeadBlock})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func getBeaconHead(ctx context.Context, beaconURL string) (*BeaconHead, error) {
+	var resp struct {
+		Data struct {
+			Root   string `json:"root"`
+			Header struct {
+				Message struct {
+					Slot string `json:"slot"`
+				} `json:"message"`
+			} `json:"header"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/beacon/headers/head", &resp); err != nil {
+		return nil, err
+	}
+	slot, err := strconv.ParseUint(resp.Data.Header.Message.Slot, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid head slot: %w", err)
+	}
+	return &BeaconHead{Slot: slot, Root: resp.Data.Root}, nil
+}
+
+func getSlotTime(ctx context.Context, beaconURL string) (time.Duration, error) {
+	var resp struct {
+		Data struct {
+			SecondsPerSlot string `json:"SECONDS_PER_SLOT"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/config/spec", &resp); err != nil {
+		return 0, fmt.Errorf("failed to get the beacon chain spec: %w", err)
+	}
+	seconds, err := strconv.ParseUint(resp.Data.SecondsPerSlot, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid slot time in the beacon chain spec: %w", err)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func getBeaconJSON(ctx context.Context, url string, obj interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(obj)
+}
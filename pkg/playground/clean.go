@@ -0,0 +1,95 @@
+package playground
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// StaleOutput is a devnet output folder under the playground home directory
+type StaleOutput struct {
+	Path    string
+	ModTime time.Time
+}
+
+// FindStaleOutputs returns the devnet output folders under the playground home directory
+// that have not been modified in the last olderThan duration. The output folders of the
+// running sessions, the sessions metadata and the downloaded release binaries are never
+// included.
+func FindStaleOutputs(olderThan time.Duration) ([]*StaleOutput, error) {
+	homeDir, err := GetHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := ListSessions()
+	if err != nil {
+		return nil, err
+	}
+	running := map[string]struct{}{}
+	for _, session := range sessions {
+		running[filepath.Clean(session.Output)] = struct{}{}
+	}
+
+	entries, err := os.ReadDir(homeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*StaleOutput{}
+	for _, entry := range entries {
+		// release binaries are files and sessions holds the metadata of the running sessions
+		if !entry.IsDir() || entry.Name() == "sessions" {
+			continue
+		}
+		path := filepath.Join(homeDir, entry.Name())
+		if _, ok := running[path]; ok {
+			continue
+		}
+		// all the output folders have the jwt secret, skip any other folder
+		if _, err := os.Stat(filepath.Join(path, "jwtsecret")); err != nil {
+			continue
+		}
+
+		modTime, err := lastModified(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check output %s: %w", path, err)
+		}
+		if time.Since(modTime) < olderThan {
+			continue
+		}
+		res = append(res, &StaleOutput{Path: path, ModTime: modTime})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ModTime.Before(res[j].ModTime)
+	})
+	return res, nil
+}
+
+// lastModified returns the most recent modification time of the output folder
+// and of its logs, which are the files written while the session is running
+func lastModified(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	modTime := info.ModTime()
+
+	logs, err := os.ReadDir(filepath.Join(path, "logs"))
+	if err != nil && !os.IsNotExist(err) {
+		return time.Time{}, err
+	}
+	for _, entry := range logs {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime, nil
+}
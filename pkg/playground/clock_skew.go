@@ -0,0 +1,41 @@
+package playground
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// faketimeService is the name of the job that installs libfaketime for the skewed services
+const faketimeService = "faketime"
+
+// SetClockSkew runs the service with its clock shifted by skew (i.e. +500ms ahead or -1s behind
+// the other services), to test how the clients tolerate the deadlines of the attestations
+// and the bids. The clock of the container is faked by preloading libfaketime, installed by the
+// faketime job of the manifest. It only shifts the wall clock read through the C library of
+// glibc images, it has no effect on the Go binaries which read the clock from the kernel.
+func (s *Manifest) SetClockSkew(name string, skew time.Duration) error {
+	svc, ok := s.GetService(name)
+	if !ok {
+		return fmt.Errorf("clock skew for unknown service '%s'", name)
+	}
+	if name == faketimeService {
+		return fmt.Errorf("the clock of service '%s' cannot be skewed", name)
+	}
+	if _, ok := s.GetService(faketimeService); !ok {
+		s.AddService(faketimeService, &Faketime{})
+	}
+
+	offset := strconv.FormatFloat(skew.Seconds(), 'f', -1, 64)
+	if skew >= 0 {
+		offset = "+" + offset
+	}
+	svc.
+		WithEnv("LD_PRELOAD", "{{.Dir}}/faketime/libfaketime.so.1").
+		WithEnv("FAKETIME", offset).
+		// the timers and the timeouts of the clients use the monotonic clock, which is not skewed
+		WithEnv("DONT_FAKE_MONOTONIC", "1").
+		WithEnv("FAKETIME_DONT_RESET", "1").
+		DependsOnCompleted(faketimeService)
+	return nil
+}
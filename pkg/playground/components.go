@@ -0,0 +1,2369 @@
+package playground
+
+import (
+	"cmp"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flashbots/go-boost-utils/bls"
+)
+
+var defaultJWTToken = "04592280e1778419b7aa954d43871cb2cfb2ebda754fb735e8adeb293a88f9bf"
+
+type RollupBoost struct {
+	ELNode  string
+	Builder string
+}
+
+func (r *RollupBoost) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("docker.io/flashbots/rollup-boost").
+		WithTag("0.4rc1").
+		WithArgs(
+			"--rpc-port", `{{Port "authrpc" 8551}}`,
+			"--l2-jwt-path", JWTSecret(r.ELNode),
+			"--l2-url", Connect(r.ELNode, "authrpc"),
+			// the external builder uses the shared secret of the output folder
+			"--builder-jwt-path", "{{.Dir}}/jwtsecret",
+			"--builder-url", r.Builder,
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "authrpc"}).
+		DependsOnHealthy(r.ELNode)
+}
+
+func (r *RollupBoost) Name() string {
+	return "rollup-boost"
+}
+
+// opDevAccountKey is the private key of the batcher, proposer and challenger roles of the
+// OP chain deployed in the L1 genesis state (utils/state.json)
+var opDevAccountKey = "0x2a871d0798f97d79848a013d4936a73bf4cc922c825d33c1cf7073dff6d409c6"
+
+type OpBatcher struct {
+	L1Node     string
+	L2Node     string
+	RollupNode string
+
+	// MaxChannelDuration is the maximum number of L1 blocks a channel stays open (defaults to 2)
+	MaxChannelDuration uint64
+
+	// PollInterval is how often the batcher loads the new L2 blocks and submits them (defaults to 1s)
+	PollInterval time.Duration
+
+	// DataAvailabilityType is where the batches are posted: calldata (the default), blobs or auto
+	DataAvailabilityType string
+}
+
+func (o *OpBatcher) Run(service *ServiceSpec, ctx *ExContext) {
+	maxChannelDuration := o.MaxChannelDuration
+	if maxChannelDuration == 0 {
+		maxChannelDuration = 2
+	}
+	pollInterval := o.PollInterval
+	if pollInterval == 0 {
+		pollInterval = time.Second
+	}
+
+	service.
+		WithImage("us-docker.pkg.dev/oplabs-tools-artifacts/images/op-batcher").
+		WithTag("v1.11.1").
+		WithEntrypoint("op-batcher").
+		WithArgs(
+			"--l1-eth-rpc", Connect(o.L1Node, "http"),
+			"--l2-eth-rpc", Connect(o.L2Node, "http"),
+			"--rollup-rpc", Connect(o.RollupNode, "http"),
+			fmt.Sprintf("--max-channel-duration=%d", maxChannelDuration),
+			"--sub-safety-margin=4",
+			"--poll-interval="+pollInterval.String(),
+			"--num-confirmations=1",
+			"--private-key="+opDevAccountKey,
+		).
+		DependsOnHealthy(o.L1Node).
+		DependsOnHealthy(o.L2Node).
+		DependsOnHealthy(o.RollupNode)
+
+	if o.DataAvailabilityType != "" {
+		service.WithArgs("--data-availability-type=" + o.DataAvailabilityType)
+	}
+}
+
+func (o *OpBatcher) Name() string {
+	return "op-batcher"
+}
+
+// OpProposer submits the L2 output roots to the dispute game factory on the L1
+type OpProposer struct {
+	L1Node     string
+	RollupNode string
+
+	// ProposalInterval is the interval between proposals (defaults to 12s)
+	ProposalInterval time.Duration
+
+	// GameFactory is the address of the dispute game factory (defaults to the one of the
+	// embedded op-deployer state)
+	GameFactory string
+}
+
+func (o *OpProposer) Run(service *ServiceSpec, ctx *ExContext) {
+	proposalInterval := o.ProposalInterval
+	if proposalInterval == 0 {
+		proposalInterval = 12 * time.Second
+	}
+	gameFactory := o.GameFactory
+	if gameFactory == "" {
+		gameFactory = mustOpChainDeployment().DisputeGameFactory
+	}
+
+	service.
+		WithImage("us-docker.pkg.dev/oplabs-tools-artifacts/images/op-proposer").
+		WithTag("v1.10.0").
+		WithEntrypoint("op-proposer").
+		WithArgs(
+			"--l1-eth-rpc", Connect(o.L1Node, "http"),
+			"--rollup-rpc", Connect(o.RollupNode, "http"),
+			"--game-factory-address", gameFactory,
+			// the chain only has the permissioned dispute game
+			"--game-type", "1",
+			"--proposal-interval", proposalInterval.String(),
+			"--poll-interval=1s",
+			"--num-confirmations=1",
+			"--allow-non-finalized=true",
+			// the proposer role is the same account as the batcher, the tx manager
+			// of each service resets its nonce if the other one used it
+			"--private-key="+opDevAccountKey,
+		).
+		DependsOnHealthy(o.L1Node).
+		DependsOnHealthy(o.RollupNode)
+}
+
+func (o *OpProposer) Name() string {
+	return "op-proposer"
+}
+
+// OpSupervisor validates the cross-chain messages between the OP chains of the dependency set
+// (dependency_set.json in the output folder)
+type OpSupervisor struct {
+	L1Node string
+
+	// Nodes are the op-node services of the chains, with the interop RPC enabled
+	Nodes []string
+}
+
+func (o *OpSupervisor) Run(service *ServiceSpec, ctx *ExContext) {
+	nodes := []string{}
+	for _, node := range o.Nodes {
+		nodes = append(nodes, Connect(node, "interop"))
+	}
+
+	service.
+		WithImage("us-docker.pkg.dev/oplabs-tools-artifacts/images/op-supervisor").
+		WithTag("v0.3.0").
+		WithEntrypoint("op-supervisor").
+		WithArgs(
+			"--l1-rpc", Connect(o.L1Node, "http"),
+			"--l2-consensus.nodes", strings.Join(nodes, ","),
+			"--l2-consensus.jwt-secret", "{{.Dir}}/jwtsecret",
+			"--datadir", "{{.Dir}}/data_op_supervisor",
+			"--dependency-set", "{{.Dir}}/dependency_set.json",
+			"--rpc.addr", "0.0.0.0",
+			"--rpc.port", `{{Port "http" 8545}}`,
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http"}).
+		DependsOnHealthy(o.L1Node)
+
+	for _, node := range o.Nodes {
+		service.DependsOnHealthy(node)
+	}
+}
+
+func (o *OpSupervisor) Name() string {
+	return "op-supervisor"
+}
+
+var _ ServiceWatchdog = &OpSupervisor{}
+
+func (o *OpSupervisor) Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	supervisorURL := "http://" + service.hostAddr("http")
+	return watchSupervisorSafeHead(out, supervisorURL, 30*time.Second)
+}
+
+// OpChallenger monitors the dispute games of the OP chain and challenges the invalid claims
+// with the cannon fault proof VM
+type OpChallenger struct {
+	L1Node     string
+	L1Beacon   string
+	L2Node     string
+	RollupNode string
+
+	// PrestatesURL is where the cannon absolute prestates are downloaded from, by hash
+	PrestatesURL string
+
+	// GameFactory is the address of the dispute game factory (defaults to the one of the
+	// embedded op-deployer state)
+	GameFactory string
+}
+
+func (o *OpChallenger) Run(service *ServiceSpec, ctx *ExContext) {
+	prestatesURL := o.PrestatesURL
+	if prestatesURL == "" {
+		prestatesURL = "https://storage.googleapis.com/oplabs-network-data/proofs/op-program/cannon"
+	}
+	gameFactory := o.GameFactory
+	if gameFactory == "" {
+		gameFactory = mustOpChainDeployment().DisputeGameFactory
+	}
+
+	service.
+		WithImage("us-docker.pkg.dev/oplabs-tools-artifacts/images/op-challenger").
+		WithTag("v1.3.1").
+		WithEntrypoint("op-challenger").
+		WithArgs(
+			"--l1-eth-rpc", Connect(o.L1Node, "http"),
+			"--l1-beacon", Connect(o.L1Beacon, "http"),
+			"--l2-eth-rpc", Connect(o.L2Node, "http"),
+			"--rollup-rpc", Connect(o.RollupNode, "http"),
+			"--game-factory-address", gameFactory,
+			"--trace-type", "permissioned",
+			"--datadir", "{{.Dir}}/data_op_challenger",
+			"--cannon-bin", "/usr/local/bin/cannon",
+			"--cannon-server", "/usr/local/bin/op-program",
+			"--cannon-prestates-url", prestatesURL,
+			"--cannon-rollup-config", "{{.Dir}}/rollup.json",
+			"--cannon-l2-genesis", "{{.Dir}}/l2-genesis.json",
+			"--num-confirmations=1",
+			"--metrics.enabled",
+			"--metrics.port", `{{Port "metrics" 7305}}`,
+			"--private-key="+opDevAccountKey,
+		).
+		DependsOnHealthy(o.L1Node).
+		DependsOnHealthy(o.L1Beacon).
+		DependsOnHealthy(o.L2Node).
+		DependsOnHealthy(o.RollupNode)
+}
+
+func (o *OpChallenger) Name() string {
+	return "op-challenger"
+}
+
+type OpNode struct {
+	L1Node   string
+	L1Beacon string
+	L2Node   string
+
+	// RollupConfig is the name of the rollup config in the output folder. Defaults to rollup.json
+	RollupConfig string
+
+	// DataDir is the name of the safe db folder inside the output folder. It must be
+	// unique if there are multiple op-node services. Defaults to db.
+	DataDir string
+
+	// Interop exposes the interop RPC used by op-supervisor (port "interop")
+	Interop bool
+}
+
+func (o *OpNode) Run(service *ServiceSpec, ctx *ExContext) {
+	rollupConfig := o.RollupConfig
+	if rollupConfig == "" {
+		rollupConfig = "rollup.json"
+	}
+	dataDir := o.DataDir
+	if dataDir == "" {
+		dataDir = "db"
+	}
+	tag := "v1.11.0"
+	if o.Interop {
+		// the interop RPC is only available in the newer releases
+		tag = "v1.13.0"
+	}
+
+	service.
+		WithImage("us-docker.pkg.dev/oplabs-tools-artifacts/images/op-node").
+		WithTag(tag).
+		WithEntrypoint("op-node").
+		WithArgs(
+			"--l1", Connect(o.L1Node, "http"),
+			"--l1.beacon", Connect(o.L1Beacon, "http"),
+			"--l1.epoch-poll-interval", ctx.slotDuration().String(),
+			"--l1.http-poll-interval", (ctx.slotDuration() / 2).String(),
+			"--l2", Connect(o.L2Node, "authrpc"),
+			"--l2.jwt-secret", JWTSecret(o.L2Node),
+			"--sequencer.enabled",
+			"--sequencer.l1-confs", "0",
+			"--verifier.l1-confs", "0",
+			"--p2p.sequencer.key", "8b3a350cf5c34c9194ca85829a2df0ec3153be0318b5e2d3348e872092edffba",
+			"--rollup.config", "{{.Dir}}/"+rollupConfig,
+			"--rpc.addr", "0.0.0.0",
+			"--rpc.port", `{{Port "http" 8549}}`,
+			"--p2p.listen.ip", "0.0.0.0",
+			"--p2p.listen.tcp", `{{Port "p2p" 9003}}`,
+			"--p2p.listen.udp", `{{Port "p2p" 9003}}`,
+			"--p2p.scoring.peers", "light",
+			"--p2p.ban.peers", "true",
+			"--metrics.enabled",
+			"--metrics.addr", "0.0.0.0",
+			"--metrics.port", `{{Port "metrics" 7300}}`,
+			"--pprof.enabled",
+			"--pprof.addr", "0.0.0.0",
+			"--pprof.port", `{{Port "pprof" 6060}}`,
+			"--rpc.enable-admin",
+			"--safedb.path", "{{.Dir}}/"+dataDir,
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http"}).
+		DependsOnHealthy(o.L1Node).
+		DependsOnHealthy(o.L1Beacon).
+		DependsOnHealthy(o.L2Node)
+
+	if o.Interop {
+		service.WithArgs(
+			"--interop.rpc.addr", "0.0.0.0",
+			"--interop.rpc.port", `{{Port "interop" 9645}}`,
+			"--interop.jwt-secret", "{{.Dir}}/jwtsecret",
+		)
+	}
+}
+
+func (o *OpNode) Name() string {
+	return "op-node"
+}
+
+func (o *OpNode) MetricsPath() string {
+	return "/metrics"
+}
+
+func (o *OpNode) MetricsPanels() []*MetricsPanel {
+	return []*MetricsPanel{
+		{Title: "L2 unsafe head", Expr: `op_node_default_refs_number{%s,layer="l2",type="l2_unsafe"}`, ChainHead: true},
+		{Title: "L2 safe head", Expr: `op_node_default_refs_number{%s,layer="l2",type="l2_safe"}`},
+		{Title: "L1 head", Expr: `op_node_default_refs_number{%s,layer="l1",type="l1_head"}`},
+		{Title: "Peers", Expr: "op_node_default_peer_count{%s}"},
+	}
+}
+
+type OpGeth struct {
+	UseDeterministicP2PKey bool
+
+	// Genesis is the name of the L2 genesis in the output folder. Defaults to l2-genesis.json
+	Genesis string
+
+	// DataDir is the name of the data folder inside the output folder. It must be
+	// unique if there are multiple op-geth nodes. Defaults to data_opgeth.
+	DataDir string
+
+	// Supervisor is the op-supervisor service that validates the interop
+	// messages of the transactions
+	Supervisor string
+
+	// outputs
+	Enode string
+}
+
+func logLevelToGethVerbosity(logLevel LogLevel) string {
+	switch logLevel {
+	case LevelTrace:
+		return "5"
+	case LevelDebug:
+		return "4"
+	case LevelInfo:
+		return "3"
+	case LevelWarn:
+		return "2"
+	case LevelError:
+		return "1"
+	default:
+		return "3"
+	}
+}
+
+func (o *OpGeth) Run(service *ServiceSpec, ctx *ExContext) {
+	var nodeKeyFlag string
+	if o.UseDeterministicP2PKey {
+		nodeKeyFlag = "--nodekey {{.Dir}}/deterministic_p2p_key.txt "
+	}
+	genesis := o.Genesis
+	if genesis == "" {
+		genesis = "l2-genesis.json"
+	}
+	dataDir := o.DataDir
+	if dataDir == "" {
+		dataDir = "data_opgeth"
+	}
+	tag := "v1.101500.0"
+	var interopFlag string
+	if o.Supervisor != "" {
+		// the interop RPC is only available in the newer releases
+		tag = "v1.101503.1"
+		interopFlag = "--rollup.interoprpc " + Connect(o.Supervisor, "http") + " "
+	}
+
+	service.
+		WithImage("us-docker.pkg.dev/oplabs-tools-artifacts/images/op-geth").
+		WithTag(tag).
+		WithEntrypoint("/bin/sh").
+		WithArgs(
+			"-c",
+			"geth init --datadir {{.Dir}}/"+dataDir+" --state.scheme hash {{.Dir}}/"+genesis+" && "+
+				"exec geth "+
+				"--datadir {{.Dir}}/"+dataDir+" "+
+				"--verbosity "+logLevelToGethVerbosity(ctx.LogLevel)+" "+
+				"--http "+
+				"--http.corsdomain \"*\" "+
+				"--http.vhosts \"*\" "+
+				"--http.addr 0.0.0.0 "+
+				"--http.port "+`{{Port "http" 8545}} `+
+				"--http.api web3,debug,eth,txpool,net,engine,miner "+
+				"--ws "+
+				"--ws.addr 0.0.0.0 "+
+				"--ws.port "+`{{Port "ws" 8546}} `+
+				"--ws.origins \"*\" "+
+				"--ws.api debug,eth,txpool,net,engine,miner "+
+				"--syncmode full "+
+				"--nodiscover "+
+				"--maxpeers 0 "+
+				"--rpc.allow-unprotected-txs "+
+				"--authrpc.addr 0.0.0.0 "+
+				"--authrpc.port "+`{{Port "authrpc" 8551}} `+
+				"--authrpc.vhosts \"*\" "+
+				"--authrpc.jwtsecret "+JWTSecret(service.Name)+" "+
+				"--gcmode archive "+
+				"--state.scheme hash "+
+				"--port "+`{{Port "rpc" 30303}} `+
+				nodeKeyFlag+
+				interopFlag+
+				"--metrics "+
+				"--metrics.addr 0.0.0.0 "+
+				"--metrics.port "+`{{Port "metrics" 6061}}`,
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "authrpc"})
+}
+
+func (o *OpGeth) Name() string {
+	return "op-geth"
+}
+
+func (o *OpGeth) MetricsPath() string {
+	return "/debug/metrics/prometheus"
+}
+
+func (o *OpGeth) MetricsPanels() []*MetricsPanel {
+	return gethMetricsPanels
+}
+
+// gethMetricsPanels are the dashboard panels of the geth nodes
+var gethMetricsPanels = []*MetricsPanel{
+	{Title: "Chain head", Expr: "chain_head_block{%s}", ChainHead: true},
+	{Title: "Pending transactions", Expr: "txpool_pending{%s}"},
+	{Title: "Peers", Expr: "p2p_peers{%s}"},
+}
+
+var _ ServiceReady = &OpGeth{}
+
+func (o *OpGeth) Ready(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	logs := service.logs
+
+	if err := logs.WaitForLog("HTTP server started", readyTimeoutOf(service, 5*time.Second)); err != nil {
+		return err
+	}
+
+	enodeLine, err := logs.FindLog("enode://")
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(enodeLine, "enode://")[1]
+	enodeID := strings.Split(parts, "@")[0]
+
+	// the enode needs the IP of the interface the p2p port binds to
+	ip := service.hostIP
+	if ip == "" {
+		ip = "127.0.0.1"
+	}
+	enode := fmt.Sprintf("enode://%s@%s?discport=0", enodeID, net.JoinHostPort(ip, strconv.Itoa(service.MustGetPort("rpc").HostPort)))
+	o.Enode = enode
+	return nil
+}
+
+var _ ServiceWatchdog = &OpGeth{}
+
+func (o *OpGeth) Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	rethURL := "http://" + service.hostAddr("http")
+	return watchChainHead(out, rethURL, 2*time.Second)
+}
+
+// OpReth is the OP stack flavour of reth as the L2 execution client
+type OpReth struct {
+}
+
+func (o *OpReth) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("ghcr.io/paradigmxyz/op-reth").
+		WithTag("v1.3.1").
+		WithEntrypoint("/usr/local/bin/op-reth").
+		WithArgs(
+			"node",
+			// op-reth does not need a separate init step, the genesis is loaded on the first run
+			"--chain", "{{.Dir}}/l2-genesis.json",
+			"--datadir", "{{.Dir}}/data_op_reth",
+			"--color", "never",
+			"--ipcpath", "{{.Dir}}/op_reth.ipc",
+			"--disable-discovery",
+			"--port", `{{Port "rpc" 30303}}`,
+			"--http",
+			"--http.addr", "0.0.0.0",
+			"--http.api", "admin,eth,web3,net,rpc,debug,txpool,miner",
+			"--http.port", `{{Port "http" 8545}}`,
+			"--ws",
+			"--ws.addr", "0.0.0.0",
+			"--ws.api", "eth,web3,net,txpool",
+			"--ws.port", `{{Port "ws" 8546}}`,
+			"--authrpc.addr", "0.0.0.0",
+			"--authrpc.port", `{{Port "authrpc" 8551}}`,
+			"--authrpc.jwtsecret", JWTSecret(service.Name),
+			"--metrics", `0.0.0.0:{{Port "metrics" 9090}}`,
+			logLevelToRethVerbosity(ctx.LogLevel),
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "authrpc"})
+}
+
+func (o *OpReth) Name() string {
+	return "op-reth"
+}
+
+func (o *OpReth) MetricsPath() string {
+	return "/metrics"
+}
+
+func (o *OpReth) MetricsPanels() []*MetricsPanel {
+	return rethMetricsPanels
+}
+
+var _ ServiceWatchdog = &OpReth{}
+
+func (o *OpReth) Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	rethURL := "http://" + service.hostAddr("http")
+	return watchChainHead(out, rethURL, 2*time.Second)
+}
+
+type RethEL struct {
+	UseRethForValidation bool
+	UseNativeReth        bool
+
+	// DataDir is the name of the data folder inside the output folder. It must be
+	// unique if there are multiple reth nodes. Defaults to data_reth.
+	DataDir string
+
+	// DataVolume is the name of a shared volume (see WithVolume) for the database instead of the
+	// data folder, so that other services can mount it (i.e. rbuilder). The IPC socket is still
+	// named after DataDir.
+	DataVolume string
+
+	// Bootnode is the discovery bootnode of the network (see Bootnode)
+	Bootnode string
+
+	// Peers are the execution nodes to connect to as trusted peers. Every reth node
+	// has the deterministic p2p key of its service name, so the enodes are known.
+	Peers []string
+
+	// TrustedOnly only connects the node to its trusted Peers, so that the transactions of its
+	// mempool are only propagated to them (a private mempool)
+	TrustedOnly bool
+
+	// Pruning is the pruning mode of the node, archive if empty
+	Pruning RethPruning
+
+	// StaticFilesDir is the name of the folder of the static files (the headers, transactions
+	// and receipts of the finalized blocks) inside the output folder. Defaults to the static_files
+	// folder of the data folder.
+	StaticFilesDir string
+
+	// Metrics exposes the Prometheus metrics of the node on the metrics port
+	Metrics bool
+
+	// Seed is an existing data folder of the chain to start from (see WithDataDirSeed).
+	// The init step checks that its genesis is the one of the chain.
+	Seed *DataDirSeed
+
+	// GasLimit is the target gas limit of the blocks built by the node and ExtraData their
+	// extra data. The defaults of reth are used if they are not set.
+	GasLimit  uint64
+	ExtraData string
+
+	// VerifyBlocks makes the watchdog check that the new blocks move their gas limit towards
+	// GasLimit, and that they have ExtraData if it is set (see watchBlockParams)
+	VerifyBlocks bool
+
+	// ExpectPeers makes the watchdog check that the node is connected to the nodes of its
+	// topology: its Peers and the reth nodes that have it as a peer (see watchPeers)
+	ExpectPeers bool
+
+	// slotTime is the block time expected by the watchdog
+	slotTime time.Duration
+}
+
+// RethPruning is the pruning mode of a reth node
+type RethPruning string
+
+const (
+	// RethPruningArchive keeps the full history of the chain
+	RethPruningArchive RethPruning = "archive"
+
+	// RethPruningFull keeps the state and the history of the recent blocks only
+	RethPruningFull RethPruning = "full"
+)
+
+func (p RethPruning) Validate() error {
+	switch p {
+	case "", RethPruningArchive, RethPruningFull:
+		return nil
+	}
+	return fmt.Errorf("invalid reth pruning mode '%s', expected archive or full", p)
+}
+
+func (r *RethEL) ReleaseArtifact() *release {
+	return &release{
+		Name:    "reth",
+		Org:     "paradigmxyz",
+		Version: "v1.3.1",
+		Arch: func(goos, goarch string) string {
+			if goos == "linux" && goarch == "amd64" {
+				return "x86_64-unknown-linux-gnu"
+			} else if goos == "linux" && goarch == "arm64" {
+				return "aarch64-unknown-linux-gnu"
+			} else if goos == "darwin" && goarch == "arm64" { // Apple M1
+				return "aarch64-apple-darwin"
+			} else if goos == "darwin" && goarch == "amd64" {
+				return "x86_64-apple-darwin"
+			} else if goos == "windows" && goarch == "amd64" {
+				return "x86_64-pc-windows-gnu"
+			}
+			return ""
+		},
+		// the archives are signed by the release key of reth
+		Signature: &releaseSignature{
+			GPGKey: "50FB7CC55B2E8AFA59FE03B7AA5ED56A7FBF253E",
+		},
+	}
+}
+
+func logLevelToRethVerbosity(logLevel LogLevel) string {
+	switch logLevel {
+	case LevelTrace:
+		return "-vvvvv"
+	case LevelDebug:
+		return "-vvvv"
+	case LevelWarn:
+		return "-vv"
+	case LevelError:
+		return "-v"
+	case LevelInfo:
+		fallthrough
+	default:
+		return "-vvv"
+	}
+}
+
+func (r *RethEL) Run(svc *ServiceSpec, ctx *ExContext) {
+	r.slotTime = ctx.slotDuration()
+
+	dataDir, ipcPath := "data_reth", "reth.ipc"
+	if r.DataDir != "" {
+		dataDir, ipcPath = r.DataDir, r.DataDir+".ipc"
+	}
+	// the peers in the docker network connect to the p2p port of the container
+	p2pAddr := "0.0.0.0"
+	if r.UseNativeReth {
+		p2pAddr = "127.0.0.1"
+	}
+
+	if err := r.Pruning.Validate(); err != nil {
+		panic(fmt.Sprintf("BUG: %s, it is checked by the recipe", err))
+	}
+	datadir := "{{.Dir}}/" + dataDir
+	if r.DataVolume != "" {
+		if r.Seed != nil {
+			panic("BUG: the datadir of a reth node with a data volume cannot be seeded")
+		}
+		svc.WithVolume(r.DataVolume)
+		datadir = fmt.Sprintf(`{{Volume "%s"}}`, r.DataVolume)
+	}
+	if r.Seed != nil {
+		svc.WithDataDirSeed(dataDir, r.Seed)
+	}
+	datadirArgs := func() []string {
+		args := []string{"--datadir", datadir}
+		if r.StaticFilesDir != "" {
+			args = append(args, "--datadir.static-files", "{{.Dir}}/"+r.StaticFilesDir)
+		}
+		return args
+	}
+
+	// start the reth el client
+	svc.
+		WithImage("ghcr.io/paradigmxyz/reth").
+		WithTag("v1.3.1").
+		WithEntrypoint("/usr/local/bin/reth").
+		// write the genesis to the database before the node starts, so that an invalid genesis
+		// (or a data folder of another chain) fails the init step instead of the running node
+		WithInit(append([]string{
+			"init",
+			"--chain", "{{.Dir}}/genesis.json",
+			"--color", "never",
+		}, datadirArgs()...)...).
+		WithArgs(
+			"node",
+			"--chain", "{{.Dir}}/genesis.json",
+		).
+		WithArgs(datadirArgs()...).
+		WithArgs(
+			"--color", "never",
+			"--ipcpath", "{{.Dir}}/"+ipcPath,
+			"--addr", p2pAddr,
+			"--port", `{{Port "rpc" 30303}}`,
+			"--p2p-secret-key", "{{.Dir}}/p2p/"+svc.Name+".key",
+			// "--disable-discovery",
+			// http config
+			"--http",
+			"--http.addr", "0.0.0.0",
+			"--http.api", "admin,eth,web3,net,rpc,mev,flashbots",
+			"--http.port", `{{Port "http" 8545}}`,
+			// ws config
+			"--ws",
+			"--ws.addr", "0.0.0.0",
+			"--ws.api", "eth,web3,net,txpool",
+			"--ws.port", `{{Port "ws" 8546}}`,
+			"--authrpc.port", `{{Port "authrpc" 8551}}`,
+			"--authrpc.addr", "0.0.0.0",
+			"--authrpc.jwtsecret", JWTSecret(svc.Name),
+			// For reth version 1.2.0 the "legacy" engine was removed, so we now require these arguments:
+			"--engine.persistence-threshold", "0", "--engine.memory-block-buffer-target", "0",
+			logLevelToRethVerbosity(ctx.LogLevel),
+		).
+		WithFile("p2p/"+svc.Name+".key", nodeKeyHex(svc.Name)).
+		WithReadyCheck(&ReadyCheck{PortLabel: "authrpc"})
+
+	if r.Pruning == RethPruningFull {
+		svc.WithArgs("--full")
+	}
+	if r.Metrics {
+		svc.WithArgs("--metrics", `0.0.0.0:{{Port "metrics" 9001}}`)
+	}
+	if r.GasLimit != 0 {
+		svc.WithArgs("--builder.gaslimit", strconv.FormatUint(r.GasLimit, 10))
+	}
+	if r.ExtraData != "" {
+		svc.WithArgs("--builder.extradata", r.ExtraData)
+	}
+
+	if r.Bootnode != "" {
+		svc.
+			WithArgs(
+				"--bootnodes", nodeEnode(r.Bootnode, bootnodeDiscoveryPort),
+				"--enable-discv5-discovery",
+			).
+			DependsOnHealthy(r.Bootnode)
+	}
+	if len(r.Peers) != 0 {
+		enodes := []string{}
+		for _, peer := range r.Peers {
+			// the name of the peer only resolves once its container exists
+			enodes = append(enodes, nodeEnode(peer, 30303))
+			svc.DependsOnStarted(peer)
+		}
+		svc.WithArgs("--trusted-peers", strings.Join(enodes, ","))
+	}
+	if r.TrustedOnly {
+		svc.WithArgs("--trusted-only")
+	}
+
+	if r.UseNativeReth {
+		// we need to use this otherwise the db cannot be binded
+		svc.UseHostExecution()
+	}
+}
+
+func (r *RethEL) Name() string {
+	return "reth"
+}
+
+func (r *RethEL) MetricsPath() string {
+	return "/metrics"
+}
+
+func (r *RethEL) MetricsPanels() []*MetricsPanel {
+	return rethMetricsPanels
+}
+
+// rethMetricsPanels are the dashboard panels of the reth nodes
+var rethMetricsPanels = []*MetricsPanel{
+	{Title: "Chain head", Expr: "reth_blockchain_tree_canonical_chain_height{%s}", ChainHead: true},
+	{Title: "Gas per second", Expr: "reth_sync_execution_gas_per_second{%s}"},
+	{Title: "Pending transactions", Expr: "reth_transaction_pool_pending_pool_transactions{%s}"},
+	{Title: "Peers", Expr: "reth_network_connected_peers{%s}"},
+}
+
+var _ ServiceWatchdog = &RethEL{}
+
+func (r *RethEL) Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	rethURL := "http://" + service.hostAddr("http")
+	if !r.VerifyBlocks && !r.ExpectPeers {
+		return watchChainHead(out, rethURL, r.slotTime)
+	}
+
+	watchGroup := newWatchGroup()
+	watchGroup.watch(func() error {
+		return watchChainHead(out, rethURL, r.slotTime)
+	})
+	if r.VerifyBlocks {
+		watchGroup.watch(func() error {
+			return watchBlockParams(out, rethURL, r.GasLimit, r.ExtraData)
+		})
+	}
+	if r.ExpectPeers {
+		// the links are bidirectional, the nodes that connect to this one are peers too
+		expected := append([]string{}, r.Peers...)
+		for _, svc := range service.manifest.Services() {
+			if other, ok := svc.component.(*RethEL); ok && slices.Contains(other.Peers, service.Name) {
+				expected = append(expected, svc.Name)
+			}
+		}
+		minPeers := len(expected)
+		if r.Bootnode != "" {
+			minPeers = max(minPeers, 1)
+		}
+		watchGroup.watch(func() error {
+			return watchPeers(out, service.Name, r.slotTime, minPeers, expected, func() (int, map[string]bool, error) {
+				return getELPeers(rethURL, expected)
+			})
+		})
+	}
+	return watchGroup.wait()
+}
+
+// GethDev is a geth node in developer mode, which builds the blocks by itself without a beacon
+// node. It starts from the genesis of the playground, with its prefunded accounts.
+type GethDev struct {
+	// BlockTime is the number of seconds between the blocks. If zero, a block is mined as soon
+	// as there is a pending transaction.
+	BlockTime uint64
+
+	// DataDir is the name of the data folder inside the output folder. Defaults to data_geth.
+	DataDir string
+}
+
+func (g *GethDev) Run(service *ServiceSpec, ctx *ExContext) {
+	dataDir := g.DataDir
+	if dataDir == "" {
+		dataDir = "data_geth"
+	}
+
+	service.
+		WithImage("docker.io/ethereum/client-go").
+		WithTag("v1.15.11").
+		WithEntrypoint("/bin/sh").
+		WithArgs(
+			"-c",
+			"geth init --datadir {{.Dir}}/"+dataDir+" {{.Dir}}/genesis.json && "+
+				"exec geth "+
+				"--dev "+
+				"--dev.period "+strconv.FormatUint(g.BlockTime, 10)+" "+
+				"--datadir {{.Dir}}/"+dataDir+" "+
+				"--verbosity "+logLevelToGethVerbosity(ctx.LogLevel)+" "+
+				"--miner.gaslimit "+strconv.FormatUint(defaultGasLimit, 10)+" "+
+				"--http "+
+				"--http.corsdomain \"*\" "+
+				"--http.vhosts \"*\" "+
+				"--http.addr 0.0.0.0 "+
+				"--http.port "+`{{Port "http" 8545}} `+
+				"--http.api web3,debug,eth,txpool,net "+
+				"--ws "+
+				"--ws.addr 0.0.0.0 "+
+				"--ws.port "+`{{Port "ws" 8546}} `+
+				"--ws.origins \"*\" "+
+				"--ws.api debug,eth,txpool,net "+
+				"--rpc.allow-unprotected-txs "+
+				"--metrics "+
+				"--metrics.addr 0.0.0.0 "+
+				"--metrics.port "+`{{Port "metrics" 6061}}`,
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http"})
+}
+
+func (g *GethDev) Name() string {
+	return "geth-dev"
+}
+
+func (g *GethDev) MetricsPath() string {
+	return "/debug/metrics/prometheus"
+}
+
+func (g *GethDev) MetricsPanels() []*MetricsPanel {
+	return gethMetricsPanels
+}
+
+var _ ServiceWatchdog = &GethDev{}
+
+func (g *GethDev) Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	if g.BlockTime == 0 {
+		// the blocks are only mined with the transactions
+		return nil
+	}
+	gethURL := "http://" + service.hostAddr("http")
+	return watchChainHead(out, gethURL, time.Duration(g.BlockTime)*time.Second)
+}
+
+// anvilMnemonic is the default mnemonic of anvil, whose first accounts are the prefunded
+// accounts of the playground
+const anvilMnemonic = "test test test test test test test test test test test junk"
+
+// Anvil is the local node of foundry, either as an execution-only chain that starts from the
+// genesis of the playground or as a fork of another chain (i.e. the EL of the devnet) to simulate
+// transactions and bundles against its state. The unlocked accounts of anvil are the prefunded
+// accounts of the playground in both cases.
+type Anvil struct {
+	// ForkNode is the EL service of the manifest to fork and ForkURL the RPC endpoint of an
+	// external chain. Without them, anvil starts from the genesis of the playground.
+	ForkNode string
+	ForkURL  string
+
+	// ForkBlock is the block of the fork, the latest one if zero
+	ForkBlock uint64
+
+	// BlockTime is the number of seconds between the blocks. If zero, a block is mined for
+	// each transaction.
+	BlockTime uint64
+}
+
+func (a *Anvil) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("ghcr.io/foundry-rs/foundry").
+		WithTag("v1.2.3").
+		WithEntrypoint("anvil").
+		WithArgs(
+			"--host", "0.0.0.0",
+			"--port", `{{Port "http" 8545}}`,
+			"--chain-id", strconv.FormatUint(l1ChainID, 10),
+			"--mnemonic", anvilMnemonic,
+			"--accounts", strconv.Itoa(len(prefundedAccounts)),
+			// the balance of the accounts is in ether
+			"--balance", new(big.Int).Div(DefaultPrefundedBalance, big.NewInt(1e18)).String(),
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http"})
+
+	if a.ForkNode != "" {
+		service.
+			WithArgs("--fork-url", Connect(a.ForkNode, "http")).
+			DependsOnHealthy(a.ForkNode)
+	} else if a.ForkURL != "" {
+		service.WithArgs("--fork-url", a.ForkURL)
+	}
+	if a.ForkNode != "" || a.ForkURL != "" {
+		if a.ForkBlock != 0 {
+			service.WithArgs("--fork-block-number", strconv.FormatUint(a.ForkBlock, 10))
+		}
+	} else {
+		service.WithArgs("--init", "{{.Dir}}/genesis.json")
+	}
+	if a.BlockTime != 0 {
+		service.WithArgs("--block-time", strconv.FormatUint(a.BlockTime, 10))
+	}
+}
+
+func (a *Anvil) Name() string {
+	return "anvil"
+}
+
+var _ ServiceWatchdog = &Anvil{}
+
+func (a *Anvil) Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	if a.BlockTime == 0 {
+		// the blocks are only mined with the transactions
+		return nil
+	}
+	anvilURL := "http://" + service.hostAddr("http")
+	return watchChainHead(out, anvilURL, time.Duration(a.BlockTime)*time.Second)
+}
+
+// ValidationNode is a reth node dedicated to validate the block submissions of the relay with
+// the flashbots_validateBuilderSubmission API, so that the validation does not load the EL of
+// the proposer. It needs its own beacon node to follow the chain.
+type ValidationNode struct {
+	// DataDir is the name of the data folder inside the output folder. Defaults to data_reth_validation.
+	DataDir string
+
+	// Bootnode is the discovery bootnode of the network (see Bootnode)
+	Bootnode string
+}
+
+func (v *ValidationNode) Run(service *ServiceSpec, ctx *ExContext) {
+	dataDir := v.DataDir
+	if dataDir == "" {
+		dataDir = "data_reth_validation"
+	}
+	(&RethEL{DataDir: dataDir, Bootnode: v.Bootnode}).Run(service, ctx)
+}
+
+func (v *ValidationNode) Name() string {
+	return "validation-node"
+}
+
+// bootnodeDiscoveryPort is the UDP port of the discovery protocols of the bootnode
+const bootnodeDiscoveryPort = 30301
+
+// Bootnode is the discovery bootnode of the L1 for the execution clients (discv4 and discv5) and
+// the beacon nodes (discv5). Its key is the deterministic p2p key of the service name, so the
+// enode is known in advance, and it writes its ENR to the testnet folder for the beacon nodes.
+type Bootnode struct {
+}
+
+func (b *Bootnode) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("docker.io/flashbots/playground-utils").
+		WithTag("latest").
+		WithEntrypoint("bootnode").
+		WithArgs(
+			"--private-key", nodeKeyHex(service.Name),
+			"--port", fmt.Sprintf("%d", bootnodeDiscoveryPort),
+			"--http-port", `{{Port "http" 8080}}`,
+			"--enr-file", "{{.Dir}}/testnet/boot_enr.yaml",
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http", Path: "/enr"})
+}
+
+func (b *Bootnode) Name() string {
+	return "bootnode"
+}
+
+type LighthouseBeaconNode struct {
+	ExecutionNode string
+	MevBoostNode  string
+
+	// DataDir is the name of the data folder inside the output folder. It must be
+	// unique if there are multiple beacon nodes. Defaults to data_beacon_node.
+	DataDir string
+
+	// TargetPeers is the number of beacon nodes expected to peer with this node. Discovery
+	// is disabled without a Bootnode, so the peers connect to each other with PeerNodes.
+	TargetPeers uint64
+
+	// PeerNodes are the beacon nodes to connect to over p2p to follow the chain
+	PeerNodes []string
+
+	// Bootnode is the discovery bootnode of the network (see Bootnode). It enables the
+	// discovery, which finds the other beacon nodes besides the PeerNodes.
+	Bootnode string
+
+	// CheckpointSyncNode is the beacon node to checkpoint sync from on startup
+	// instead of syncing from genesis
+	CheckpointSyncNode string
+
+	// Seed is an existing data folder of the chain to start from (see WithDataDirSeed)
+	Seed *DataDirSeed
+
+	// ExpectPeers makes the watchdog check that the node has as many peers as the links of its
+	// topology: its PeerNodes and the beacon nodes that have it as a peer, up to TargetPeers
+	// (see watchPeers). The peer ids of lighthouse are random, so only the count is checked.
+	ExpectPeers bool
+
+	// slotTime is the time between the peer checks of the watchdog
+	slotTime time.Duration
+}
+
+func (l *LighthouseBeaconNode) Run(svc *ServiceSpec, ctx *ExContext) {
+	l.slotTime = ctx.slotDuration()
+
+	dataDir := "data_beacon_node"
+	if l.DataDir != "" {
+		dataDir = l.DataDir
+	}
+	if l.Seed != nil {
+		svc.WithDataDirSeed(dataDir, l.Seed)
+	}
+
+	svc.
+		WithImage("sigp/lighthouse").
+		WithTag("v7.0.0-beta.0").
+		WithEntrypoint("lighthouse").
+		WithArgs(
+			"bn",
+			"--datadir", "{{.Dir}}/"+dataDir,
+			"--testnet-dir", "{{.Dir}}/testnet",
+			"--disable-peer-scoring",
+			"--staking",
+			"--disable-upnp",
+			"--disable-packet-filter",
+			"--target-peers", fmt.Sprintf("%d", l.TargetPeers),
+			"--debug-level", "error",
+			"--logfile-debug-level", "error",
+			"--enr-udp-port", `{{Port "p2p" 9000}}`,
+			"--enr-tcp-port", `{{Port "p2p" 9000}}`,
+			"--enr-quic-port", `{{Port "quic-p2p" 9100}}`,
+			"--port", `{{Port "p2p" 9000}}`,
+			"--quic-port", `{{Port "quic-p2p" 9100}}`,
+			"--metrics",
+			"--metrics-address", "0.0.0.0",
+			"--metrics-port", `{{Port "metrics" 5054}}`,
+			"--http",
+			"--http-port", `{{Port "http" 3500}}`,
+			"--http-address", "0.0.0.0",
+			"--http-allow-origin", "*",
+			"--execution-endpoint", Connect(l.ExecutionNode, "authrpc"),
+			"--execution-jwt", JWTSecret(l.ExecutionNode),
+			"--always-prepare-payload",
+			// prepare the payload 2/3 of the slot in advance
+			"--prepare-payload-lookahead", fmt.Sprintf("%d", ctx.slotDuration().Milliseconds()*2/3),
+			"--suggested-fee-recipient", defaultFeeRecipient,
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http", Path: "/eth/v1/node/version"}).
+		DependsOnHealthy(l.ExecutionNode)
+
+	if l.MevBoostNode != "" {
+		svc.WithArgs(
+			"--builder", Connect(l.MevBoostNode, "http"),
+			"--builder-fallback-epochs-since-finalization", "0",
+			"--builder-fallback-disable-checks",
+		)
+	}
+	if l.Bootnode != "" {
+		// the bootnode writes its ENR to the boot_enr.yaml of the testnet folder before it is
+		// healthy, which lighthouse uses as the boot nodes. The ENR of the node has the address
+		// of its container, that the bootnode reports back.
+		svc.
+			WithArgs("--enable-enr-auto-update").
+			DependsOnHealthy(l.Bootnode)
+	} else {
+		svc.WithArgs(
+			"--disable-discovery",
+			"--boot-nodes", "",
+			"--enr-address", "127.0.0.1",
+		)
+	}
+	if len(l.PeerNodes) != 0 {
+		// the p2p ports are not remapped inside the docker network
+		addrs := []string{}
+		for _, peer := range l.PeerNodes {
+			addrs = append(addrs, fmt.Sprintf("/dns4/%s/tcp/9000", peer))
+			svc.DependsOnHealthy(peer)
+		}
+		svc.WithArgs("--libp2p-addresses", strings.Join(addrs, ","))
+	}
+	if l.CheckpointSyncNode != "" {
+		svc.
+			WithArgs(
+				"--checkpoint-sync-url", Connect(l.CheckpointSyncNode, "http"),
+				// the devnet starts at genesis, there is no history to backfill
+				"--disable-backfill-rate-limiting",
+			).
+			DependsOnHealthy(l.CheckpointSyncNode)
+	}
+}
+
+func (l *LighthouseBeaconNode) Name() string {
+	return "lighthouse-beacon-node"
+}
+
+func (l *LighthouseBeaconNode) MetricsPath() string {
+	return "/metrics"
+}
+
+func (l *LighthouseBeaconNode) MetricsPanels() []*MetricsPanel {
+	return []*MetricsPanel{
+		{Title: "Head slot", Expr: "beacon_head_state_slot{%s}", ChainHead: true},
+		{Title: "Finalized epoch", Expr: "beacon_head_state_finalized_epoch{%s}"},
+		{Title: "Peers", Expr: "libp2p_peers{%s}"},
+	}
+}
+
+var _ ServiceWatchdog = &LighthouseBeaconNode{}
+
+func (l *LighthouseBeaconNode) Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	if !l.ExpectPeers {
+		return nil
+	}
+	beaconNodeURL := "http://" + service.hostAddr("http")
+
+	expected := append([]string{}, l.PeerNodes...)
+	for _, svc := range service.manifest.Services() {
+		if other, ok := svc.component.(*LighthouseBeaconNode); ok && slices.Contains(other.PeerNodes, service.Name) {
+			expected = append(expected, svc.Name)
+		}
+	}
+	minPeers := len(expected)
+	if l.TargetPeers != 0 {
+		// lighthouse prunes the peers above the target
+		minPeers = min(minPeers, int(l.TargetPeers))
+	}
+	if l.Bootnode != "" {
+		minPeers = max(minPeers, 1)
+	}
+	return watchPeers(out, service.Name, l.slotTime, minPeers, expected, func() (int, map[string]bool, error) {
+		count, err := getBeaconPeerCount(beaconNodeURL)
+		return count, nil, err
+	})
+}
+
+var _ ServiceReady = &LighthouseBeaconNode{}
+
+func (l *LighthouseBeaconNode) Ready(logOutput io.Writer, service *ServiceSpec, ctx context.Context) error {
+	beaconNodeURL := "http://" + service.hostAddr("http")
+
+	if err := waitForChainAlive(ctx, logOutput, beaconNodeURL, readyTimeoutOf(service, 30*time.Second)); err != nil {
+		return err
+	}
+	return nil
+}
+
+type LighthouseValidator struct {
+	BeaconNode string
+
+	// DataDir is the name of the folder with the validator keys inside the output folder.
+	// Defaults to data_validator.
+	DataDir string
+
+	// FeeRecipient and GasLimit are the preferences of the validators for the blocks they
+	// propose and the registrations with the relay. They default to the ones of the devnet.
+	FeeRecipient string
+	GasLimit     uint64
+
+	slotTime time.Duration
+}
+
+func (l *LighthouseValidator) dataDir() string {
+	if l.DataDir != "" {
+		return l.DataDir
+	}
+	return "data_validator"
+}
+
+func (l *LighthouseValidator) Run(service *ServiceSpec, ctx *ExContext) {
+	l.slotTime = ctx.slotDuration()
+	dataDir := l.dataDir()
+	feeRecipient, gasLimit := l.FeeRecipient, l.GasLimit
+	if feeRecipient == "" {
+		feeRecipient = defaultFeeRecipient
+	}
+	if gasLimit == 0 {
+		gasLimit = defaultGasLimit
+	}
+
+	// start validator client
+	service.
+		WithImage("sigp/lighthouse").
+		WithTag("v7.0.0-beta.0").
+		WithEntrypoint("lighthouse").
+		WithArgs(
+			"vc",
+			"--datadir", "{{.Dir}}/"+dataDir,
+			"--testnet-dir", "{{.Dir}}/testnet",
+			"--init-slashing-protection",
+			"--beacon-nodes", Connect(l.BeaconNode, "http"),
+			"--suggested-fee-recipient", feeRecipient,
+			"--gas-limit", strconv.FormatUint(gasLimit, 10),
+			"--builder-proposals",
+			"--prefer-builder-proposals",
+		).
+		// the slashing protection history of the keys (see slashing_protection.go), the one
+		// of another validator client if they were moved from it
+		WithInit(
+			"account", "validator", "slashing-protection", "import",
+			"{{.Dir}}/"+dataDir+"/"+slashingProtectionFile,
+			"--datadir", "{{.Dir}}/"+dataDir,
+			"--testnet-dir", "{{.Dir}}/testnet",
+		).
+		DependsOnHealthy(l.BeaconNode)
+}
+
+func (l *LighthouseValidator) SlashingProtectionExport(path string) []string {
+	return []string{
+		"account", "validator", "slashing-protection", "export", path,
+		"--datadir", "{{.Dir}}/" + l.dataDir(),
+		"--testnet-dir", "{{.Dir}}/testnet",
+	}
+}
+
+func (l *LighthouseValidator) Name() string {
+	return "lighthouse-validator"
+}
+
+var _ ServiceWatchdog = &LighthouseValidator{}
+
+func (l *LighthouseValidator) Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	beaconNode := service.manifest.MustGetService(l.BeaconNode)
+	beaconNodeURL := "http://" + beaconNode.hostAddr("http")
+	keysDir := filepath.Join(service.manifest.out.dst, l.dataDir(), "validators")
+
+	return watchValidatorLifecycle(out, beaconNodeURL, keysDir, l.slotTime)
+}
+
+type ClProxy struct {
+	PrimaryBuilder   string
+	SecondaryBuilder string
+}
+
+func (c *ClProxy) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("docker.io/flashbots/playground-utils").
+		WithTag("latest").
+		WithEntrypoint("cl-proxy").
+		WithArgs(
+			"--primary-builder", Connect(c.PrimaryBuilder, "authrpc"),
+			"--secondary-builder", c.SecondaryBuilder,
+			"--port", `{{Port "authrpc" 5656}}`,
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "authrpc"}).
+		DependsOnHealthy(c.PrimaryBuilder)
+}
+
+func (c *ClProxy) Name() string {
+	return "cl-proxy"
+}
+
+// ApiProxy sits in front of the Engine API or the Beacon API of a service and records every
+// request and response to logs/<name>-requests.jsonl. The proxy exposes the same port
+// label as the target so that it can replace it in the connections.
+type ApiProxy struct {
+	Service   string
+	PortLabel string
+
+	// Latency (plus a random Jitter) and FailRate inject faults in the requests
+	// to the JSON-RPC Methods (or prefixes of them). If Methods is empty, they
+	// apply to all the requests.
+	Latency  time.Duration
+	Jitter   time.Duration
+	FailRate float64
+	Methods  []string
+}
+
+func (a *ApiProxy) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("docker.io/flashbots/playground-utils").
+		WithTag("latest").
+		WithEntrypoint("api-proxy").
+		WithArgs(
+			"--target", Connect(a.Service, a.PortLabel),
+			"--port", fmt.Sprintf(`{{Port "%s" 5757}}`, a.PortLabel),
+			"--record", "{{.Dir}}/logs/"+service.Name+"-requests.jsonl",
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: a.PortLabel}).
+		DependsOnHealthy(a.Service)
+
+	if a.Latency != 0 {
+		service.WithArgs("--latency", a.Latency.String())
+	}
+	if a.Jitter != 0 {
+		service.WithArgs("--jitter", a.Jitter.String())
+	}
+	if a.FailRate != 0 {
+		service.WithArgs("--fail-rate", strconv.FormatFloat(a.FailRate, 'f', -1, 64))
+	}
+	if len(a.Methods) != 0 {
+		service.WithArgs("--methods", strings.Join(a.Methods, ","))
+	}
+}
+
+func (a *ApiProxy) Name() string {
+	return "api-proxy"
+}
+
+// EngineMux lets one beacon node drive multiple execution nodes or builders on the same chain,
+// to compare them side by side. It exposes the Engine API of the Primary EL (with its JWT
+// secret) and broadcasts newPayload and forkchoiceUpdated to the Secondaries, which are the
+// services with an 'authrpc' port or the host ports of external ELs (authenticated with the
+// fixed jwtsecret of the output folder). getPayload returns the payload selected by the Policy
+// (primary, highest-value or round-robin) and the payloads of all the targets are recorded to
+// logs/<name>-payloads.jsonl.
+type EngineMux struct {
+	Primary     string
+	Secondaries []string
+	Policy      string
+}
+
+func (e *EngineMux) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("docker.io/flashbots/playground-utils").
+		WithTag("latest").
+		WithEntrypoint("engine-mux").
+		WithArgs(
+			"--primary", Connect(e.Primary, "authrpc"),
+			"--port", `{{Port "authrpc" 5858}}`,
+			"--record", "{{.Dir}}/logs/"+service.Name+"-payloads.jsonl",
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "authrpc"}).
+		DependsOnHealthy(e.Primary)
+
+	if e.Policy != "" {
+		service.WithArgs("--policy", e.Policy)
+	}
+	for _, secondary := range e.Secondaries {
+		if port, err := strconv.ParseUint(secondary, 10, 16); err == nil {
+			name := fmt.Sprintf("host-%d", port)
+			service.WithArgs(
+				"--secondary", fmt.Sprintf("%s=http://host.docker.internal:%d", name, port),
+				"--secondary-jwt", name+"={{.Dir}}/jwtsecret",
+			)
+			continue
+		}
+		service.WithArgs(
+			"--secondary", secondary+"="+Connect(secondary, "authrpc"),
+			"--secondary-jwt", secondary+"="+JWTSecret(secondary),
+		).DependsOnHealthy(secondary)
+	}
+}
+
+func (e *EngineMux) Name() string {
+	return "engine-mux"
+}
+
+// RpcGateway aggregates the JSON-RPC endpoints of multiple services behind one URL and routes
+// the requests by method, like the RPC setups of the searchers in production (i.e. the bundles
+// to the builder and eth_call to the full node). Routes maps a method, or a prefix ending
+// with '*' (i.e. mev_*), to the service with the 'http' port that serves it. The other
+// methods are routed to Default.
+type RpcGateway struct {
+	Default string
+	Routes  map[string]string
+}
+
+func (r *RpcGateway) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("docker.io/flashbots/playground-utils").
+		WithTag("latest").
+		WithEntrypoint("rpc-gateway").
+		WithArgs(
+			"--port", `{{Port "http" 8545}}`,
+			"--default", Connect(r.Default, "http"),
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http"})
+
+	methods := make([]string, 0, len(r.Routes))
+	for method := range r.Routes {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		service.WithArgs("--route", method+"="+Connect(r.Routes[method], "http"))
+	}
+}
+
+func (r *RpcGateway) Name() string {
+	return "rpc-gateway"
+}
+
+// Faucet sends funds from a prefunded account of the genesis to the addresses that request them
+// in /fund, either with ?address=0x... or with a JSON body {"address": "0x..."}
+type Faucet struct {
+	ExecutionNode string
+
+	// Amount is the number of ether sent on each request (10 by default)
+	Amount string
+
+	// Cooldown is the minimum time between two transfers to the same address
+	Cooldown time.Duration
+}
+
+func (f *Faucet) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("docker.io/flashbots/playground-utils").
+		WithTag("latest").
+		WithEntrypoint("faucet").
+		WithArgs(
+			"--rpc", Connect(f.ExecutionNode, "http"),
+			"--private-key", faucetAccount,
+			"--port", `{{Port "http" 8090}}`,
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http", Path: "/info"}).
+		DependsOnHealthy(f.ExecutionNode)
+
+	if f.Amount != "" {
+		service.WithArgs("--amount", f.Amount)
+	}
+	if f.Cooldown != 0 {
+		service.WithArgs("--cooldown", f.Cooldown.String())
+	}
+}
+
+func (f *Faucet) Name() string {
+	return "faucet"
+}
+
+// Faketime is the job that installs libfaketime in the output folder for the services that run
+// with a skewed clock (see SetClockSkew). The library is built for glibc, so the images of those
+// services must be based on glibc.
+type Faketime struct{}
+
+func (f *Faketime) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("docker.io/library/debian").
+		WithTag("bookworm-slim").
+		WithEntrypoint("/bin/sh").
+		WithArgs("-c", faketimeScript).
+		AsJob()
+}
+
+func (f *Faketime) Name() string {
+	return "faketime"
+}
+
+// faketimeScript copies libfaketime to the faketime folder of the output folder, which the
+// skewed services preload from /artifacts
+var faketimeScript = `set -e
+if [ -f /artifacts/faketime/libfaketime.so.1 ]; then
+	exit 0
+fi
+apt-get update -qq
+apt-get install -y -qq --no-install-recommends libfaketime > /dev/null
+mkdir -p /artifacts/faketime
+cp /usr/lib/*/faketime/libfaketime.so.1 /artifacts/faketime/
+`
+
+type MevBoostRelay struct {
+	BeaconClient     string
+	ValidationServer string
+
+	// OptimisticBuilders are the public keys of the builders whose bids are accepted before
+	// they are validated by the ValidationServer (optimistic relaying)
+	OptimisticBuilders []string
+
+	// ExpectBids enables the watchdog assertions on the relay data API. Every slot
+	// must have validated builder bids and a delivered payload.
+	ExpectBids bool
+
+	// Registration registers the validators of the devnet with the relay once it is ready.
+	// If nil, the validators are registered by the validator client on its own schedule.
+	Registration *ValidatorRegistration
+
+	slotTime time.Duration
+}
+
+func (m *MevBoostRelay) Run(service *ServiceSpec, ctx *ExContext) {
+	m.slotTime = ctx.slotDuration()
+
+	service.
+		WithImage("docker.io/flashbots/playground-utils").
+		WithTag("latest").
+		WithEntrypoint("mev-boost-relay").
+		WithArgs(
+			"--api-listen-addr", "0.0.0.0",
+			"--api-listen-port", `{{Port "http" 5555}}`,
+			"--beacon-client-addr", Connect(m.BeaconClient, "http"),
+		).
+		// the relay reads the slot time from the environment
+		WithEnv("SEC_PER_SLOT", fmt.Sprintf("%d", uint64(ctx.slotDuration().Seconds()))).
+		DependsOnHealthy(m.BeaconClient)
+
+	if m.ValidationServer != "" {
+		service.
+			WithArgs("--validation-server-addr", Connect(m.ValidationServer, "http")).
+			DependsOnHealthy(m.ValidationServer)
+	}
+	if len(m.OptimisticBuilders) != 0 {
+		service.WithArgs("--optimistic-builders", strings.Join(m.OptimisticBuilders, ","))
+	}
+}
+
+func (m *MevBoostRelay) Name() string {
+	return "mev-boost-relay"
+}
+
+var _ ServiceReady = &MevBoostRelay{}
+
+func (m *MevBoostRelay) Ready(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	if m.Registration == nil {
+		return nil
+	}
+	beaconNode := service.manifest.MustGetService(m.BeaconClient)
+	beaconNodeURL := "http://" + beaconNode.hostAddr("http")
+	relayURL := "http://" + service.hostAddr("http")
+
+	return registerValidators(ctx, out, beaconNodeURL, relayURL, m.Registration, readyTimeoutOf(service, time.Minute))
+}
+
+var _ ServiceWatchdog = &MevBoostRelay{}
+
+func (m *MevBoostRelay) Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	beaconNodeURL := "http://" + service.hostAddr("http")
+
+	watchGroup := newWatchGroup()
+	watchGroup.watch(func() error {
+		return watchProposerPayloads(beaconNodeURL)
+	})
+	watchGroup.watch(func() error {
+		return validateProposerPayloads(out, beaconNodeURL)
+	})
+	if m.ExpectBids {
+		watchGroup.watch(func() error {
+			return watchRelayBids(out, beaconNodeURL, m.slotTime)
+		})
+	}
+
+	return watchGroup.wait()
+}
+
+// MevBoost is the mev-boost sidecar of a beacon node. It requests the bids of the relays for the
+// beacon node and forwards the registrations of the validators to them.
+type MevBoost struct {
+	// Relays are the relays that mev-boost connects to: the names of the relay services of the
+	// manifest, or the URLs of external relays with their public key (i.e. https://0xabc@relay.example)
+	Relays []string
+}
+
+func (m *MevBoost) Run(service *ServiceSpec, ctx *ExContext) {
+	if len(m.Relays) == 0 {
+		panic("mev-boost requires at least one relay")
+	}
+	relays := []string{}
+	for _, relay := range m.Relays {
+		if strings.Contains(relay, "://") {
+			relays = append(relays, relay)
+			continue
+		}
+		pubkey, err := relayPublicKey()
+		if err != nil {
+			panic(fmt.Sprintf("BUG: failed to get the relay public key: %s", err))
+		}
+		relays = append(relays, fmt.Sprintf("http://%s@%s", pubkey, ConnectAddr(relay, "http")))
+		service.DependsOnStarted(relay)
+	}
+
+	service.
+		WithImage("docker.io/flashbots/mev-boost").
+		WithTag("1.9").
+		WithFile("mev-boost.sh", mevBoostScript).
+		WithEntrypoint("/bin/sh").
+		WithArgs(
+			"{{.Dir}}/mev-boost.sh",
+			"-addr", `0.0.0.0:{{Port "http" 18550}}`,
+			"-relays", strings.Join(relays, ","),
+			"-loglevel", "info",
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http", Path: "/eth/v1/builder/status"})
+}
+
+func (m *MevBoost) Name() string {
+	return "mev-boost"
+}
+
+// mevBoostScript is the entrypoint of mev-boost. It passes the genesis fork version and the
+// genesis time of the devnet, which mev-boost needs for a custom network.
+var mevBoostScript = `#!/bin/sh
+set -e
+config={{.Dir}}/testnet/config.yaml
+fork_version=$(sed -n 's/^GENESIS_FORK_VERSION: *//p' $config)
+genesis_time=$(( $(sed -n 's/^MIN_GENESIS_TIME: *//p' $config) + $(sed -n 's/^GENESIS_DELAY: *//p' $config) ))
+exec /app/mev-boost -genesis-fork-version "$fork_version" -genesis-timestamp "$genesis_time" "$@"
+`
+
+// flashbotsRelayForkVersions are the fork versions (plus the genesis validators root) that the
+// Flashbots relay reads from the environment for a custom network
+var flashbotsRelayForkVersions = []string{
+	"GENESIS_FORK_VERSION",
+	"BELLATRIX_FORK_VERSION",
+	"CAPELLA_FORK_VERSION",
+	"DENEB_FORK_VERSION",
+	"ELECTRA_FORK_VERSION",
+}
+
+// relayDatabase is the config of the database of the Flashbots relay
+var relayDatabase = &Postgres{
+	User:     "relay",
+	Password: "relay",
+	Database: "relay",
+}
+
+// relayRedis is the config of the Redis server of the Flashbots relay
+var relayRedis = &Redis{}
+
+// flashbotsRelayScript is the entrypoint of the Flashbots relay containers. It exports the
+// genesis validators root and the fork versions of the devnet for the custom network.
+var flashbotsRelayScript = func() string {
+	script := "#!/bin/sh\nset -e\n"
+	script += "export GENESIS_VALIDATORS_ROOT=0x$(cat {{.Dir}}/testnet/genesis_validators_root.txt)\n"
+	for _, name := range flashbotsRelayForkVersions {
+		script += fmt.Sprintf("export %s=$(sed -n 's/^%s: *//p' {{.Dir}}/testnet/config.yaml)\n", name, name)
+	}
+	return script + "exec /app/mev-boost-relay \"$@\"\n"
+}()
+
+// withFlashbotsRelay runs a command (api, housekeeper or website) of the Flashbots mev-boost-relay
+// on the custom network of the devnet, with its Postgres database and Redis server
+func withFlashbotsRelay(service *ServiceSpec, ctx *ExContext, command string, database string, redis string) *ServiceSpec {
+	return service.
+		WithImage("docker.io/flashbots/mev-boost-relay").
+		WithTag("latest").
+		WithFile("flashbots-relay.sh", flashbotsRelayScript).
+		WithEntrypoint("/bin/sh").
+		WithArgs(
+			"{{.Dir}}/flashbots-relay.sh", command,
+			"--network", "custom",
+			"--db", PostgresURL(database, relayDatabase),
+			"--redis-uri", ConnectAddr(redis, "redis"),
+		).
+		// the relay reads the slot time from the environment
+		WithEnv("SEC_PER_SLOT", fmt.Sprintf("%d", uint64(ctx.slotDuration().Seconds()))).
+		DependsOnHealthy(database).
+		DependsOnHealthy(redis)
+}
+
+// FlashbotsRelayHousekeeper is the housekeeper of the Flashbots mev-boost-relay. It syncs the
+// validators and the proposer duties from the beacon node to Redis for the relay API.
+type FlashbotsRelayHousekeeper struct {
+	BeaconClient string
+	Database     string
+	Redis        string
+}
+
+func (f *FlashbotsRelayHousekeeper) Run(service *ServiceSpec, ctx *ExContext) {
+	withFlashbotsRelay(service, ctx, "housekeeper", f.Database, f.Redis).
+		WithArgs("--beacon-uris", Connect(f.BeaconClient, "http")).
+		DependsOnHealthy(f.BeaconClient)
+}
+
+func (f *FlashbotsRelayHousekeeper) Name() string {
+	return "flashbots-relay-housekeeper"
+}
+
+// FlashbotsRelayAPI is the API of the Flashbots mev-boost-relay: the builder API for the block
+// submissions, the proposer API for the beacon node and the data API. Unlike MevBoostRelay, it
+// needs the Housekeeper, a Postgres Database and a Redis server.
+type FlashbotsRelayAPI struct {
+	BeaconClient     string
+	ValidationServer string
+	Housekeeper      string
+	Database         string
+	Redis            string
+
+	// ExpectBids enables the watchdog assertions on the relay data API and Registration the
+	// registration of the validators once it is ready (see MevBoostRelay)
+	ExpectBids   bool
+	Registration *ValidatorRegistration
+
+	slotTime time.Duration
+}
+
+func (f *FlashbotsRelayAPI) Run(service *ServiceSpec, ctx *ExContext) {
+	f.slotTime = ctx.slotDuration()
+
+	withFlashbotsRelay(service, ctx, "api", f.Database, f.Redis).
+		WithArgs(
+			"--listen-addr", `0.0.0.0:{{Port "http" 9062}}`,
+			"--beacon-uris", Connect(f.BeaconClient, "http"),
+			"--blocksim", Connect(f.ValidationServer, "http"),
+			"--secret-key", flashbotsRelaySecretKey,
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http", Path: "/eth/v1/builder/status"}).
+		DependsOnHealthy(f.BeaconClient).
+		DependsOnHealthy(f.ValidationServer).
+		DependsOnStarted(f.Housekeeper)
+}
+
+func (f *FlashbotsRelayAPI) Name() string {
+	return "flashbots-relay-api"
+}
+
+var _ ServiceReady = &FlashbotsRelayAPI{}
+
+func (f *FlashbotsRelayAPI) Ready(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	if f.Registration == nil {
+		return nil
+	}
+	beaconNode := service.manifest.MustGetService(f.BeaconClient)
+	beaconNodeURL := "http://" + beaconNode.hostAddr("http")
+	relayURL := "http://" + service.hostAddr("http")
+
+	// the relay only accepts the registrations of the validators synced by the housekeeper
+	return registerValidators(ctx, out, beaconNodeURL, relayURL, f.Registration, readyTimeoutOf(service, time.Minute))
+}
+
+var _ ServiceWatchdog = &FlashbotsRelayAPI{}
+
+func (f *FlashbotsRelayAPI) Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	relay := &MevBoostRelay{ExpectBids: f.ExpectBids, slotTime: f.slotTime}
+	return relay.Watchdog(out, service, ctx)
+}
+
+// flashbotsRelaySecretKey is the BLS key used by the Flashbots relay to sign the bids, the same
+// one of the relay of playground-utils
+var flashbotsRelaySecretKey = "0x5eae315483f028b5cdd5d1090ff0c7618b18737ea9bf3c35047189db22835c48"
+
+// FlashbotsRelayWebsite is the website of the Flashbots mev-boost-relay with the recent payloads
+type FlashbotsRelayWebsite struct {
+	Database string
+	Redis    string
+}
+
+func (f *FlashbotsRelayWebsite) Run(service *ServiceSpec, ctx *ExContext) {
+	withFlashbotsRelay(service, ctx, "website", f.Database, f.Redis).
+		WithArgs("--listen-addr", `0.0.0.0:{{Port "http" 9060}}`).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http"})
+}
+
+func (f *FlashbotsRelayWebsite) Name() string {
+	return "flashbots-relay-website"
+}
+
+// defaultBuilderSecretKey is the BLS key used by the builder to sign the block submissions to the relay
+var defaultBuilderSecretKey = "0x2e0834786285daccd064ca17f1654f67b4aef298acbb82cef9ec422fb4975622"
+
+// FlashbotsBuilder is the Flashbots block builder (a fork of geth). It follows the chain as the
+// execution node of BeaconNode and submits the blocks it builds to the Relay.
+type FlashbotsBuilder struct {
+	BeaconNode string
+	Relay      string
+
+	// GasLimit is the target gas limit of the blocks it builds and ExtraData their extra data,
+	// the defaults of geth are used if they are not set
+	GasLimit  uint64
+	ExtraData string
+}
+
+func (f *FlashbotsBuilder) Run(service *ServiceSpec, ctx *ExContext) {
+	// The builder does not depend on the beacon node since the beacon node uses it as
+	// its execution node. It retries both the payload attributes subscription and the
+	// relay submissions until they are available.
+	service.
+		WithImage("docker.io/flashbots/builder").
+		WithTag("latest").
+		WithEntrypoint("/bin/sh").
+		WithArgs(
+			"-c",
+			"geth init --datadir {{.Dir}}/data_builder {{.Dir}}/genesis.json && "+
+				"exec geth "+
+				"--datadir {{.Dir}}/data_builder "+
+				"--verbosity "+logLevelToGethVerbosity(ctx.LogLevel)+" "+
+				"--http "+
+				"--http.vhosts \"*\" "+
+				"--http.addr 0.0.0.0 "+
+				"--http.port "+`{{Port "http" 8545}} `+
+				"--http.api web3,eth,txpool,net,flashbots "+
+				"--syncmode full "+
+				"--nodiscover "+
+				"--maxpeers 0 "+
+				"--authrpc.addr 0.0.0.0 "+
+				"--authrpc.port "+`{{Port "authrpc" 8551}} `+
+				"--authrpc.vhosts \"*\" "+
+				"--authrpc.jwtsecret "+JWTSecret(service.Name)+" "+
+				"--port "+`{{Port "rpc" 30303}} `+
+				"--builder "+
+				"--builder.algotype greedy "+
+				"--builder.beacon_endpoints "+Connect(f.BeaconNode, "http")+" "+
+				"--builder.remote_relay_endpoint "+Connect(f.Relay, "http")+" "+
+				"--builder.secret_key "+defaultBuilderSecretKey+" "+
+				// the builder signs with the builder domain, which only depends on the genesis fork version
+				"--builder.genesis_fork_version 0x20000089 "+
+				"--builder.bellatrix_fork_version 0x20000091"+
+				f.minerArgs(),
+		).
+		// the builder signs the proposer payment transaction with this key
+		WithEnv("BUILDER_TX_SIGNING_KEY", prefundedAccounts[1]).
+		WithReadyCheck(&ReadyCheck{PortLabel: "authrpc"})
+}
+
+// minerArgs are the args of the gas limit and the extra data of the blocks, which are
+// appended to the shell command
+func (f *FlashbotsBuilder) minerArgs() string {
+	args := []string{}
+	if f.GasLimit != 0 {
+		args = append(args, "--miner.gaslimit", strconv.FormatUint(f.GasLimit, 10))
+	}
+	if f.ExtraData != "" {
+		args = append(args, "--miner.extradata", f.ExtraData)
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	return " " + quoteShell(args)
+}
+
+func (f *FlashbotsBuilder) Name() string {
+	return "flashbots-builder"
+}
+
+// rbuilderConfig is the TOML config of rbuilder. It uses the same templates as the service args.
+var rbuilderConfig = `log_json = false
+log_level = "info,rbuilder=debug"
+redacted_telemetry_server_port = {{Port "redacted-metrics" 6061}}
+redacted_telemetry_server_ip = "0.0.0.0"
+full_telemetry_server_port = {{Port "metrics" 6060}}
+full_telemetry_server_ip = "0.0.0.0"
+
+chain = "{{.Dir}}/genesis.json"
+reth_datadir = "%s"
+el_node_ipc_path = "{{.Dir}}/%s.ipc"
+
+relay_secret_key = "%s"
+coinbase_secret_key = "%s"
+
+cl_node_url = ["{{Service "%s" "http"}}"]
+jsonrpc_server_port = {{Port "http" 8645}}
+jsonrpc_server_ip = "0.0.0.0"
+extra_data = %s
+
+ignore_cancellable_orders = true
+sbundle_mergeable_signers = []
+live_builders = ["mp-ordering"]
+enabled_relays = ["playground"]
+
+[[relays]]
+name = "playground"
+url = "{{Service "%s" "http"}}"
+priority = 0
+use_ssz_for_submit = false
+use_gzip_for_submit = false
+
+[[builders]]
+name = "mp-ordering"
+algo = "ordering-builder"
+discard_txs = true
+sorting = "max-profit"
+failed_order_retries = 1
+drop_failed_orders = true
+`
+
+// Rbuilder is the Flashbots Rust block builder. It reads the state directly from the database
+// of a reth node, so it needs the data folder of ExecutionNode, and it submits the blocks it
+// builds for the payload attributes of BeaconNode to the Relay.
+type Rbuilder struct {
+	ExecutionNode string
+	BeaconNode    string
+	Relay         string
+
+	// RethDataDir is the name of the data folder of the reth node inside the output folder, which
+	// is also the name of its IPC socket
+	RethDataDir string
+
+	// RethDataVolume is the shared volume with the database of the reth node, if it is not in
+	// RethDataDir (see RethEL.DataVolume)
+	RethDataVolume string
+
+	// ExtraData is the extra data of the blocks it builds, defaults to rbuilder. The gas limit
+	// of the blocks is the one registered by the validators.
+	ExtraData string
+
+	// slotTime is the time between bids expected by the watchdog
+	slotTime time.Duration
+}
+
+func (r *Rbuilder) Run(service *ServiceSpec, ctx *ExContext) {
+	r.slotTime = ctx.slotDuration()
+
+	rethDatadir := "{{.Dir}}/" + r.RethDataDir
+	if r.RethDataVolume != "" {
+		service.WithVolume(r.RethDataVolume)
+		rethDatadir = fmt.Sprintf(`{{Volume "%s"}}`, r.RethDataVolume)
+	}
+	config := fmt.Sprintf(rbuilderConfig,
+		rethDatadir, r.RethDataDir,
+		strings.TrimPrefix(defaultBuilderSecretKey, "0x"),
+		strings.TrimPrefix(prefundedAccounts[1], "0x"),
+		r.BeaconNode, strconv.Quote(cmp.Or(r.ExtraData, "rbuilder")), r.Relay,
+	)
+
+	service.
+		WithImage("docker.io/flashbots/rbuilder").
+		WithTag("latest").
+		WithEntrypoint("rbuilder").
+		WithFile("rbuilder.toml", config).
+		WithArgs("run", "{{.Dir}}/rbuilder.toml").
+		WithReadyCheck(&ReadyCheck{PortLabel: "http"}).
+		DependsOnHealthy(r.ExecutionNode).
+		DependsOnHealthy(r.BeaconNode)
+}
+
+func (r *Rbuilder) Name() string {
+	return "rbuilder"
+}
+
+var _ ServiceWatchdog = &Rbuilder{}
+
+func (r *Rbuilder) Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error {
+	relay := service.manifest.MustGetService(r.Relay)
+	relayURL := "http://" + relay.hostAddr("http")
+
+	builderPubkey, err := builderPublicKey()
+	if err != nil {
+		return err
+	}
+	return watchBuilderSubmissions(out, relayURL, builderPubkey, r.slotTime)
+}
+
+// builderPublicKey returns the BLS public key of the builders as reported by the relay
+func builderPublicKey() (string, error) {
+	return blsPublicKey(defaultBuilderSecretKey)
+}
+
+// relayPublicKey returns the BLS public key that the relays of the devnet sign the bids with
+func relayPublicKey() (string, error) {
+	return blsPublicKey(flashbotsRelaySecretKey)
+}
+
+// blsPublicKey returns the hex BLS public key of a hex secret key
+func blsPublicKey(secretKey string) (string, error) {
+	secret, err := hex.DecodeString(strings.TrimPrefix(secretKey, "0x"))
+	if err != nil {
+		return "", err
+	}
+	sk, err := bls.SecretKeyFromBytes(secret)
+	if err != nil {
+		return "", err
+	}
+	pk, err := bls.PublicKeyFromSecretKey(sk)
+	if err != nil {
+		return "", err
+	}
+	return "0x" + hex.EncodeToString(bls.PublicKeyToBytes(pk)), nil
+}
+
+// Postgres is a PostgreSQL database for the services that need one (i.e. a relay or an indexer).
+// The other services connect to it with the connection string of PostgresURL.
+type Postgres struct {
+	// User, Password and Database are the credentials and the database created on the
+	// first start. They default to postgres.
+	User     string
+	Password string
+	Database string
+
+	// InitSQL are the SQL scripts run in order on the first start of the database, after
+	// the database is created (i.e. a schema or fixtures)
+	InitSQL []string
+
+	// DataDir is the name of the data folder inside the output folder. Defaults to data_<service>.
+	DataDir string
+}
+
+func (p *Postgres) Run(service *ServiceSpec, ctx *ExContext) {
+	user, password, database := p.credentials()
+	dataDir := p.DataDir
+	if dataDir == "" {
+		dataDir = "data_" + service.Name
+	}
+
+	// the entrypoint of the image runs the scripts of /docker-entrypoint-initdb.d with the server
+	// listening only on the unix socket, so the TCP ready check passes once they are done
+	initDir := "postgres/" + service.Name
+	for i, sql := range p.InitSQL {
+		service.WithFile(fmt.Sprintf("%s/%02d.sql", initDir, i), sql)
+	}
+
+	service.
+		WithImage("docker.io/library/postgres").
+		WithTag("16-alpine").
+		WithEnv("POSTGRES_USER", user).
+		WithEnv("POSTGRES_PASSWORD", password).
+		WithEnv("POSTGRES_DB", database).
+		WithEnv("PGDATA", "{{.Dir}}/"+dataDir).
+		WithReadyCheck(&ReadyCheck{PortLabel: "postgres"})
+
+	if len(p.InitSQL) == 0 {
+		service.WithArgs("postgres", "-p", `{{Port "postgres" 5432}}`)
+	} else {
+		service.
+			WithEntrypoint("/bin/sh").
+			WithArgs("-c", "cp {{.Dir}}/"+initDir+"/*.sql /docker-entrypoint-initdb.d/ && "+
+				`exec docker-entrypoint.sh postgres -p {{Port "postgres" 5432}}`)
+	}
+}
+
+func (p *Postgres) credentials() (user, password, database string) {
+	user, password, database = p.User, p.Password, p.Database
+	if user == "" {
+		user = "postgres"
+	}
+	if password == "" {
+		password = "postgres"
+	}
+	if database == "" {
+		database = "postgres"
+	}
+	return user, password, database
+}
+
+func (p *Postgres) Name() string {
+	return "postgres"
+}
+
+// PostgresURL returns the template of the connection string of the database of a Postgres service
+func PostgresURL(service string, db *Postgres) string {
+	user, password, database := db.credentials()
+	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", user, password, ConnectAddr(service, "postgres"), database)
+}
+
+// Redis is a Redis server without persistence for the services that need a cache or a queue.
+// The other services connect to it with the connection string of RedisURL.
+type Redis struct {
+	// Password is the password of the default user, no authentication if empty
+	Password string
+}
+
+func (r *Redis) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("docker.io/library/redis").
+		WithTag("7-alpine").
+		WithArgs(
+			"redis-server",
+			"--port", `{{Port "redis" 6379}}`,
+			"--save", "",
+			"--appendonly", "no",
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "redis"})
+
+	if r.Password != "" {
+		service.WithArgs("--requirepass", r.Password)
+	}
+}
+
+func (r *Redis) Name() string {
+	return "redis"
+}
+
+// RedisURL returns the template of the connection string of a Redis service
+func RedisURL(service string, redis *Redis) string {
+	if redis.Password != "" {
+		return fmt.Sprintf("redis://:%s@%s", redis.Password, ConnectAddr(service, "redis"))
+	}
+	return "redis://" + ConnectAddr(service, "redis")
+}
+
+// blockscoutDatabase is the config of the database of the Blockscout indexer
+var blockscoutDatabase = &Postgres{
+	User:     "blockscout",
+	Password: "blockscout",
+	Database: "blockscout",
+	DataDir:  "data_blockscout_db",
+}
+
+// BlockscoutPostgres is the database of the Blockscout indexer
+type BlockscoutPostgres struct {
+}
+
+func (b *BlockscoutPostgres) Run(service *ServiceSpec, ctx *ExContext) {
+	blockscoutDatabase.Run(service, ctx)
+}
+
+func (b *BlockscoutPostgres) Name() string {
+	return "blockscout-postgres"
+}
+
+// Blockscout is the indexer and the API of the Blockscout explorer for the chain of ExecutionNode
+type Blockscout struct {
+	ExecutionNode string
+	Database      string
+	ChainID       uint64
+}
+
+func (b *Blockscout) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("ghcr.io/blockscout/blockscout").
+		WithTag("6.10.1").
+		WithEntrypoint("/bin/sh").
+		WithArgs(
+			"-c",
+			"bin/blockscout eval \"Elixir.Explorer.ReleaseTasks.create_and_migrate()\" && "+
+				"exec bin/blockscout start",
+		).
+		WithEnv("DATABASE_URL", "postgresql://blockscout:blockscout@"+ConnectAddr(b.Database, "postgres")+"/blockscout").
+		WithEnv("PORT", `{{Port "http" 4000}}`).
+		WithEnv("CHAIN_ID", fmt.Sprintf("%d", b.ChainID)).
+		WithEnv("COIN", "ETH").
+		WithEnv("ECTO_USE_SSL", "false").
+		WithEnv("ETHEREUM_JSONRPC_VARIANT", "geth").
+		WithEnv("ETHEREUM_JSONRPC_HTTP_URL", Connect(b.ExecutionNode, "http")).
+		WithEnv("ETHEREUM_JSONRPC_TRACE_URL", Connect(b.ExecutionNode, "http")).
+		WithEnv("INDEXER_DISABLE_PENDING_TRANSACTIONS_FETCHER", "true").
+		WithEnv("DISABLE_MARKET", "true").
+		WithEnv("API_V2_ENABLED", "true").
+		WithEnv("SECRET_KEY_BASE", "56NtB48ear7+wMSf0IQuWDAAazhpb31qyc7GiyspBP2vh7t5zlCsF5QDv76chXeN").
+		// the database migrations take a while on the first run
+		WithReadyCheck(&ReadyCheck{PortLabel: "http", Path: "/api/v2/stats", Timeout: 3 * time.Minute}).
+		DependsOnHealthy(b.Database).
+		DependsOnHealthy(b.ExecutionNode)
+}
+
+func (b *Blockscout) Name() string {
+	return "blockscout"
+}
+
+// BlockscoutFrontend is the web interface of the Blockscout explorer. It runs in the browser, so it
+// reaches the API of Backend and the RPC of ExecutionNode through their ports on the host.
+type BlockscoutFrontend struct {
+	Backend       string
+	ExecutionNode string
+	ChainID       uint64
+}
+
+func (b *BlockscoutFrontend) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("ghcr.io/blockscout/frontend").
+		WithTag("v1.37.4").
+		WithEnv("PORT", `{{Port "http" 3000}}`).
+		WithEnv("NEXT_PUBLIC_APP_HOST", fmt.Sprintf(`{{HostName "%s"}}`, service.Name)).
+		WithEnv("NEXT_PUBLIC_APP_PORT", fmt.Sprintf(`{{HostPort "%s" "http"}}`, service.Name)).
+		WithEnv("NEXT_PUBLIC_API_HOST", fmt.Sprintf(`{{HostName "%s"}}`, b.Backend)).
+		WithEnv("NEXT_PUBLIC_API_PORT", fmt.Sprintf(`{{HostPort "%s" "http"}}`, b.Backend)).
+		WithEnv("NEXT_PUBLIC_API_PROTOCOL", "http").
+		WithEnv("NEXT_PUBLIC_API_WEBSOCKET_PROTOCOL", "ws").
+		WithEnv("NEXT_PUBLIC_NETWORK_NAME", "Playground").
+		WithEnv("NEXT_PUBLIC_NETWORK_ID", fmt.Sprintf("%d", b.ChainID)).
+		WithEnv("NEXT_PUBLIC_NETWORK_RPC_URL", fmt.Sprintf(`http://{{HostName "%s"}}:{{HostPort "%s" "http"}}`, b.ExecutionNode, b.ExecutionNode)).
+		WithEnv("NEXT_PUBLIC_NETWORK_CURRENCY_NAME", "Ether").
+		WithEnv("NEXT_PUBLIC_NETWORK_CURRENCY_SYMBOL", "ETH").
+		WithEnv("NEXT_PUBLIC_NETWORK_CURRENCY_DECIMALS", "18").
+		WithEnv("NEXT_PUBLIC_IS_TESTNET", "true").
+		WithReadyCheck(&ReadyCheck{PortLabel: "http"}).
+		DependsOnHealthy(b.Backend)
+}
+
+func (b *BlockscoutFrontend) Name() string {
+	return "blockscout-frontend"
+}
+
+var doraConfig = `
+logging:
+  outputLevel: info
+chain:
+  displayName: Playground
+server:
+  host: 0.0.0.0
+  port: "{{Port "http" 8080}}"
+frontend:
+  enabled: true
+  siteName: Dora
+  siteSubtitle: Playground
+beaconapi:
+  localCacheSize: 10
+  endpoints:
+    - name: %s
+      url: {{Service "%s" "http"}}
+executionapi:
+  depositLogBatchSize: 1000
+  endpoints:
+    - name: %s
+      url: {{Service "%s" "http"}}
+indexer:
+  inMemoryEpochs: 3
+database:
+  engine: sqlite
+  sqlite:
+    file: {{.Dir}}/dora.sqlite
+`
+
+// Dora is a lightweight explorer of the beacon chain of BeaconNode
+type Dora struct {
+	BeaconNode    string
+	ExecutionNode string
+}
+
+func (d *Dora) Run(service *ServiceSpec, ctx *ExContext) {
+	config := fmt.Sprintf(doraConfig, d.BeaconNode, d.BeaconNode, d.ExecutionNode, d.ExecutionNode)
+
+	service.
+		WithImage("docker.io/ethpandaops/dora").
+		WithTag("latest").
+		WithFile("dora-config.yaml", config).
+		WithArgs("-config", "{{.Dir}}/dora-config.yaml").
+		WithReadyCheck(&ReadyCheck{PortLabel: "http"}).
+		DependsOnHealthy(d.BeaconNode)
+}
+
+func (d *Dora) Name() string {
+	return "dora"
+}
+
+// PrometheusTarget is a service whose metrics are scraped from its metrics port
+type PrometheusTarget struct {
+	Service string
+	Path    string
+}
+
+// Prometheus scrapes the metrics of the Targets, with the name of the service as the service
+// label of their series (see AddGrafana)
+type Prometheus struct {
+	Targets []*PrometheusTarget
+}
+
+func (p *Prometheus) Run(service *ServiceSpec, ctx *ExContext) {
+	var config strings.Builder
+	config.WriteString("global:\n  scrape_interval: 5s\nscrape_configs:\n")
+	for _, target := range p.Targets {
+		fmt.Fprintf(&config, "  - job_name: %s\n", target.Service)
+		fmt.Fprintf(&config, "    metrics_path: %s\n", target.Path)
+		config.WriteString("    static_configs:\n")
+		fmt.Fprintf(&config, "      - targets: ['%s']\n", ConnectAddr(target.Service, "metrics"))
+		fmt.Fprintf(&config, "        labels:\n          service: %s\n", target.Service)
+	}
+
+	service.
+		WithImage("docker.io/prom/prometheus").
+		WithTag("v3.4.1").
+		WithFile("prometheus/prometheus.yml", config.String()).
+		WithArgs(
+			"--config.file", "{{.Dir}}/prometheus/prometheus.yml",
+			"--storage.tsdb.path", "{{.Dir}}/data_prometheus",
+			"--web.listen-address", `0.0.0.0:{{Port "http" 9090}}`,
+		).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http", Path: "/-/ready"})
+}
+
+func (p *Prometheus) Name() string {
+	return "prometheus"
+}
+
+var grafanaDatasourceConfig = `apiVersion: 1
+datasources:
+  - name: Prometheus
+    uid: %s
+    type: prometheus
+    access: proxy
+    url: {{Service "%s" "http"}}
+    isDefault: true
+`
+
+var grafanaDashboardsConfig = `apiVersion: 1
+providers:
+  - name: playground
+    type: file
+    options:
+      path: {{.Dir}}/grafana/dashboards
+`
+
+// Grafana shows the Dashboards, by their name, with the metrics of the Prometheus service as
+// the datasource. The anonymous users are admins, there is no login.
+type Grafana struct {
+	Prometheus string
+	Dashboards map[string]string
+}
+
+func (g *Grafana) Run(service *ServiceSpec, ctx *ExContext) {
+	service.
+		WithImage("docker.io/grafana/grafana").
+		WithTag("12.0.2").
+		WithEnv("GF_SERVER_HTTP_PORT", `{{Port "http" 3000}}`).
+		WithEnv("GF_PATHS_DATA", "{{.Dir}}/data_grafana").
+		WithEnv("GF_PATHS_PROVISIONING", "{{.Dir}}/grafana/provisioning").
+		WithEnv("GF_AUTH_ANONYMOUS_ENABLED", "true").
+		WithEnv("GF_AUTH_ANONYMOUS_ORG_ROLE", "Admin").
+		WithEnv("GF_AUTH_DISABLE_LOGIN_FORM", "true").
+		WithFile("grafana/provisioning/datasources/prometheus.yaml", fmt.Sprintf(grafanaDatasourceConfig, grafanaDatasource, g.Prometheus)).
+		WithFile("grafana/provisioning/dashboards/playground.yaml", grafanaDashboardsConfig).
+		WithReadyCheck(&ReadyCheck{PortLabel: "http", Path: "/api/health"}).
+		DependsOnHealthy(g.Prometheus)
+
+	for name, dashboard := range g.Dashboards {
+		service.WithFile("grafana/dashboards/"+name+".json", dashboard)
+	}
+	if _, ok := g.Dashboards["playground"]; ok {
+		// the overview of AddGrafana
+		service.WithEnv("GF_DASHBOARDS_DEFAULT_HOME_DASHBOARD_PATH", "{{.Dir}}/grafana/dashboards/playground.json")
+	}
+}
+
+func (g *Grafana) Name() string {
+	return "grafana"
+}
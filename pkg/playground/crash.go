@@ -0,0 +1,206 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// crashDumpDir is the folder of the crash dumps in the output folder
+	crashDumpDir = "crash"
+
+	// crashDumpLogLines is the number of log lines of the service in its crash dump
+	crashDumpLogLines = 1000
+
+	// pprofGoroutinePath is the goroutine dump of the pprof handlers of go, with the stack of
+	// every goroutine
+	pprofGoroutinePath = "/debug/pprof/goroutine?debug=2"
+)
+
+// crashStackRe matches the first line of the panic of a go or a rust client
+var crashStackRe = regexp.MustCompile(`^(panic: |fatal error: |goroutine \d+ \[|thread '.*' panicked at|SIGSEGV|SIGABRT)`)
+
+// crashExit is the exit.json of a crash dump
+type crashExit struct {
+	Service   string    `json:"service"`
+	ExitCode  int       `json:"exitCode"`
+	OOMKilled bool      `json:"oomKilled"`
+	Error     string    `json:"error,omitempty"`
+	ExitedAt  time.Time `json:"exitedAt"`
+	Restarts  int       `json:"restarts"`
+
+	// Restarted signals that the restart policy restarted the container after the dump
+	Restarted bool `json:"restarted"`
+}
+
+// pprofDumps are the last goroutine dumps of the services with a pprof port, which are
+// sampled while they run since the endpoint is gone once the container exits
+type pprofDumps struct {
+	lock  sync.Mutex
+	dumps map[string][]byte
+}
+
+// SamplePprof takes a goroutine dump of the services with a pprof port every interval,
+// until the context is done. The last one is added to the crash dump of the service.
+func (d *LocalRunner) SamplePprof(ctx context.Context, interval time.Duration) {
+	d.pprof.lock.Lock()
+	d.pprof.dumps = map[string][]byte{}
+	d.pprof.lock.Unlock()
+
+	clt := &http.Client{Timeout: interval}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, svc := range d.manifest.Services() {
+			port, ok := svc.GetPort("pprof")
+			if !ok || d.isHostService(svc.Name) || d.TaskStatus(svc.Name) != taskStatusStarted {
+				continue
+			}
+			dump, err := fetchPprofDump(ctx, clt, "http://"+svc.hostAddr(port.Name)+pprofGoroutinePath)
+			if err != nil {
+				runnerLog.Debug("failed to take the goroutine dump", "service", svc.Name, "err", err)
+				continue
+			}
+			d.pprof.lock.Lock()
+			d.pprof.dumps[svc.Name] = dump
+			d.pprof.lock.Unlock()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func fetchPprofDump(ctx context.Context, clt *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := clt.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// captureCrashDump writes the crash dump of a container that exited to crash/<service>/ in the
+// output folder: the last lines of its logs, the panic in them, the docker inspect of the
+// container with its exit state and the last goroutine dump, if sampled. The dump of a
+// previous exit of the service is replaced. It must be called before the container is
+// restarted or removed.
+func (d *LocalRunner) captureCrashDump(name string, containerID string, exitCode int, restarted bool, logs *logFile, logsDone chan struct{}) {
+	waitLogs(logsDone)
+
+	dir := filepath.Join(d.out.dst, crashDumpDir, name)
+	if err := d.writeCrashDump(dir, name, containerID, exitCode, restarted, logs); err != nil {
+		runnerLog.Warn("failed to write the crash dump", "service", name, "err", err)
+		return
+	}
+	runnerLog.Info("crash dump captured", "service", name, "path", dir)
+}
+
+func (d *LocalRunner) writeCrashDump(dir string, name string, containerID string, exitCode int, restarted bool, logs *logFile) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	d.tasksMtx.Lock()
+	restarts := 0
+	if task, ok := d.tasks[name]; ok {
+		restarts = task.restartCount
+	}
+	d.tasksMtx.Unlock()
+
+	exit := &crashExit{Service: name, ExitCode: exitCode, ExitedAt: time.Now().UTC(), Restarts: restarts, Restarted: restarted}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if inspect, err := d.client.ContainerInspect(ctx, containerID); err != nil {
+		runnerLog.Warn("failed to inspect the container of the crash dump", "service", name, "err", err)
+	} else {
+		if inspect.State != nil {
+			exit.OOMKilled = inspect.State.OOMKilled
+			exit.Error = inspect.State.Error
+			if t, err := time.Parse(time.RFC3339Nano, inspect.State.FinishedAt); err == nil {
+				exit.ExitedAt = t
+			}
+		}
+		data, err := json.MarshalIndent(inspect, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "inspect.json"), data, 0644); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(exit, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "exit.json"), data, 0644); err != nil {
+		return err
+	}
+
+	if logs != nil {
+		lines := tailLines(logs.Name(), crashDumpLogLines)
+		if err := os.WriteFile(filepath.Join(dir, "logs.txt"), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			return err
+		}
+		if stack := crashStack(lines); stack != nil {
+			if err := os.WriteFile(filepath.Join(dir, "stacktrace.txt"), []byte(strings.Join(stack, "\n")+"\n"), 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.pprof.lock.Lock()
+	dump, ok := d.pprof.dumps[name]
+	d.pprof.lock.Unlock()
+	if ok {
+		if err := os.WriteFile(filepath.Join(dir, "goroutines.txt"), dump, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crashStack returns the lines of the logs from the first line of the last panic, if any
+func crashStack(lines []string) []string {
+	start := -1
+	for i := len(lines) - 1; i >= 0; i-- {
+		if !crashStackRe.MatchString(lines[i]) {
+			continue
+		}
+		start = i
+		// the goroutines of a go panic follow the panic line
+		if !strings.HasPrefix(lines[i], "goroutine ") {
+			break
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+	return lines[start:]
+}
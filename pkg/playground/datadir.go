@@ -0,0 +1,163 @@
+package playground
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+)
+
+// DataDirMode is how a seeded data folder is provided to the service
+type DataDirMode string
+
+var (
+	// DataDirModeCopy copies the data folder into the output folder, so the original is not modified
+	DataDirModeCopy DataDirMode = "copy"
+
+	// DataDirModeBind mounts the data folder in the service, which writes the new blocks to it
+	DataDirModeBind DataDirMode = "bind"
+)
+
+func (m DataDirMode) Validate() error {
+	switch m {
+	case DataDirModeCopy, DataDirModeBind:
+		return nil
+	}
+	return fmt.Errorf("invalid datadir mode '%s', expected copy or bind", m)
+}
+
+// DataDirSeed is an existing data folder that a service starts from instead of an empty one
+type DataDirSeed struct {
+	// Path is the absolute path of the folder on the host
+	Path string
+
+	Mode DataDirMode
+}
+
+// NewDataDirSeed validates the data folder of a service that starts mid-chain
+func NewDataDirSeed(path string, mode DataDirMode) (*DataDirSeed, error) {
+	if err := mode.Validate(); err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the datadir: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("the datadir %s is not a folder", abs)
+	}
+	return &DataDirSeed{Path: abs, Mode: mode}, nil
+}
+
+// WithDataDirSeed starts the service from the contents of an existing data folder, which the
+// runner copies or mounts as the given folder of the output folder before the service starts
+func (s *ServiceSpec) WithDataDirSeed(dataDir string, seed *DataDirSeed) *ServiceSpec {
+	if s.dataDirSeeds == nil {
+		s.dataDirSeeds = map[string]*DataDirSeed{}
+	}
+	s.dataDirSeeds[dataDir] = seed
+	return s
+}
+
+// seedChain is the L1 genesis of the playground output folder that a seeded data folder
+// comes from, which the new genesis has to match (see SeedDataDirs)
+type seedChain struct {
+	dir         string
+	genesisTime uint64
+	genesisHash gethcommon.Hash
+	genesisSSZ  []byte
+}
+
+// loadSeedChain loads the genesis of the output folder of a data folder, which is its parent
+func loadSeedChain(datadir string, outputDir string) (*seedChain, error) {
+	abs, err := filepath.Abs(datadir)
+	if err != nil {
+		return nil, err
+	}
+	absOutput, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(absOutput, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("the datadir %s is inside the output folder, which is deleted before the artifacts are built", abs)
+	}
+
+	dir := filepath.Dir(abs)
+	data, err := os.ReadFile(filepath.Join(dir, "genesis.json"))
+	if err != nil {
+		return nil, fmt.Errorf("the datadir %s must be a data folder of a playground output folder with the genesis of its chain: %w", abs, err)
+	}
+	var genesis core.Genesis
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the genesis of the datadir %s: %w", abs, err)
+	}
+	chain := &seedChain{
+		dir:         dir,
+		genesisTime: genesis.Timestamp,
+		genesisHash: genesis.ToBlock().Hash(),
+	}
+
+	// the genesis state of the beacon chain is only needed for the beacon nodes
+	if chain.genesisSSZ, err = os.ReadFile(filepath.Join(dir, "testnet", "genesis.ssz")); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// checkExecution checks that the new L1 genesis is the one of the chain of the data folder
+func (c *seedChain) checkExecution(hash gethcommon.Hash) error {
+	if c.genesisHash != hash {
+		return fmt.Errorf("the EL datadir is from another chain: the genesis of %s is %s, the new genesis is %s (the chain config changed)", c.dir, c.genesisHash, hash)
+	}
+	return nil
+}
+
+// checkConsensus checks that the new beacon chain genesis state is the one of the chain of the data folder
+func (c *seedChain) checkConsensus(genesisSSZ []byte) error {
+	if c.genesisSSZ == nil {
+		return fmt.Errorf("the CL datadir must be a data folder of a playground output folder with the beacon chain genesis (testnet/genesis.ssz)")
+	}
+	if !bytes.Equal(c.genesisSSZ, genesisSSZ) {
+		return fmt.Errorf("the CL datadir is from another chain: the beacon chain genesis of %s does not match the new one (the chain config changed)", c.dir)
+	}
+	return nil
+}
+
+// seedDataDirs copies (or links for the services on the host) the seeded data folders of the
+// service into the output folder. The copies are kept if the service is started again.
+func (d *LocalRunner) seedDataDirs(svc *ServiceSpec) error {
+	for dataDir, seed := range svc.dataDirSeeds {
+		dst := filepath.Join(d.out.dst, dataDir)
+		if _, err := os.Lstat(dst); err == nil {
+			continue
+		}
+
+		switch {
+		case seed.Mode == DataDirModeCopy:
+			runnerLog.Info("copying the datadir", "service", svc.Name, "path", seed.Path)
+			if err := os.CopyFS(dst, os.DirFS(seed.Path)); err != nil {
+				return fmt.Errorf("failed to copy the datadir of service %s: %w", svc.Name, err)
+			}
+		case d.isHostService(svc.Name):
+			if err := os.Symlink(seed.Path, dst); err != nil {
+				return fmt.Errorf("failed to link the datadir of service %s: %w", svc.Name, err)
+			}
+		default:
+			// the mount point of the bind mount (see toDockerComposeService), created here
+			// so that it does not belong to the docker daemon
+			if err := os.MkdirAll(dst, 0755); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
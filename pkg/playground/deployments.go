@@ -0,0 +1,296 @@
+package playground
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ecrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// deployerAccount is the prefunded account used to deploy the contracts
+var deployerAccount = prefundedAccounts[0]
+
+// Deployment is a contract to deploy once the execution node is ready
+type Deployment struct {
+	// Name is the name of the contract used in the recipe output
+	Name string
+
+	// Service is the name of the execution node service to deploy the contract on.
+	// It must expose an 'http' port.
+	Service string
+
+	// Bytecode is the creation bytecode of the contract
+	Bytecode []byte
+
+	// Args are the ABI encoded constructor arguments appended to the bytecode
+	Args []byte
+}
+
+func (s *Manifest) AddDeployment(deployment *Deployment) {
+	s.deployments = append(s.deployments, deployment)
+}
+
+func (s *Manifest) Deployments() []*Deployment {
+	return s.deployments
+}
+
+// LoadDeployments loads the contracts of a --deploy spec: a folder with the contracts, or a
+// contract file followed by the ABI encoded constructor arguments of the contract in hex
+// (<file>=<args>). Files with the '.json' extension are considered forge artifacts, and files
+// with the '.bin' or '.hex' extension are considered hex encoded creation bytecode. The name
+// of the deployment is the name of the file without the extension. The service of the
+// deployments is resolved when they are added to the manifest (see AddDeployments).
+func LoadDeployments(spec string) ([]*Deployment, error) {
+	// the arguments are hex, the path is the part before the last '='
+	path, argsStr, hasArgs := spec, "", false
+	if i := strings.LastIndex(spec, "="); i != -1 {
+		path, argsStr, hasArgs = spec[:i], spec[i+1:], true
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deployments: %w", err)
+	}
+
+	if !info.IsDir() {
+		deployment, err := loadDeployment(path)
+		if err != nil {
+			return nil, err
+		}
+		if hasArgs {
+			if deployment.Args, err = hexutil.Decode(argsStr); err != nil {
+				return nil, fmt.Errorf("invalid constructor arguments of %s '%s', expected ABI encoded hex: %w", deployment.Name, argsStr, err)
+			}
+		}
+		return []*Deployment{deployment}, nil
+	}
+	if hasArgs {
+		return nil, fmt.Errorf("invalid deployment '%s', the constructor arguments require a contract file instead of a folder", spec)
+	}
+
+	files, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deployments folder: %w", err)
+	}
+	deployments := []*Deployment{}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if ext := filepath.Ext(file.Name()); ext != ".json" && ext != ".bin" && ext != ".hex" {
+			continue
+		}
+		deployment, err := loadDeployment(filepath.Join(path, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		deployments = append(deployments, deployment)
+	}
+
+	sort.Slice(deployments, func(i, j int) bool {
+		return deployments[i].Name < deployments[j].Name
+	})
+	return deployments, nil
+}
+
+// loadDeployment loads the bytecode of a contract file, a forge artifact or hex encoded bytecode
+func loadDeployment(path string) (*Deployment, error) {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	if ext != ".json" && ext != ".bin" && ext != ".hex" {
+		return nil, fmt.Errorf("unknown deployment file %s, expected a forge artifact (.json) or bytecode (.bin, .hex)", name)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deployment file %s: %w", name, err)
+	}
+
+	bytecodeStr := string(data)
+	if ext == ".json" {
+		var artifact struct {
+			Bytecode struct {
+				Object string `json:"object"`
+			} `json:"bytecode"`
+		}
+		if err := json.Unmarshal(data, &artifact); err != nil {
+			return nil, fmt.Errorf("failed to decode forge artifact %s: %w", name, err)
+		}
+		bytecodeStr = artifact.Bytecode.Object
+	}
+
+	bytecodeStr = strings.TrimSpace(bytecodeStr)
+	if !strings.HasPrefix(bytecodeStr, "0x") {
+		bytecodeStr = "0x" + bytecodeStr
+	}
+	bytecode, err := hexutil.Decode(bytecodeStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode bytecode of %s: %w", name, err)
+	}
+	if len(bytecode) == 0 {
+		return nil, fmt.Errorf("deployment %s has empty bytecode", name)
+	}
+	return &Deployment{
+		Name:     strings.TrimSuffix(name, ext),
+		Bytecode: bytecode,
+	}, nil
+}
+
+// AddDeployments adds the deployments to the manifest. The deployments without a service are
+// deployed on the L1 execution node of the manifest (see l1ExecutionNode).
+func AddDeployments(manifest *Manifest, deployments []*Deployment) error {
+	for _, deployment := range deployments {
+		for _, existing := range manifest.deployments {
+			if existing.Name == deployment.Name {
+				return fmt.Errorf("deployment %s is defined more than once", deployment.Name)
+			}
+		}
+		if deployment.Service == "" {
+			svc, ok := manifest.l1ExecutionNode()
+			if !ok {
+				return fmt.Errorf("the recipe does not have an L1 execution node to deploy %s on", deployment.Name)
+			}
+			deployment.Service = svc.Name
+		}
+		svc, ok := manifest.GetService(deployment.Service)
+		if !ok {
+			return fmt.Errorf("deployment %s targets service %s, but it is not defined", deployment.Name, deployment.Service)
+		}
+		if _, ok := svc.GetPort("http"); !ok {
+			return fmt.Errorf("deployment %s targets service %s, but it does not expose port http", deployment.Name, deployment.Service)
+		}
+		manifest.AddDeployment(deployment)
+	}
+	return nil
+}
+
+// l1ExecutionNode returns the first execution node of the L1 chain in the order of the services
+// of the manifest, the one that the recipes add before the nodes that follow it
+func (s *Manifest) l1ExecutionNode() (*ServiceSpec, bool) {
+	for _, svc := range s.services {
+		switch svc.component.(type) {
+		case *RethEL, *GethDev, *Anvil:
+			return svc, true
+		}
+	}
+	return nil, false
+}
+
+// RunDeployments deploys all the contracts in the manifest and returns their addresses.
+// It has to run after the services are ready.
+func RunDeployments(ctx context.Context, manifest *Manifest) (map[string]gethcommon.Address, error) {
+	addresses := map[string]gethcommon.Address{}
+	if len(manifest.deployments) == 0 {
+		return addresses, nil
+	}
+
+	output, err := manifest.out.LogOutput("deployments")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log output: %w", err)
+	}
+
+	priv, err := getPrivKey(deployerAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, deployment := range manifest.deployments {
+		svc, ok := manifest.GetService(deployment.Service)
+		if !ok {
+			return nil, fmt.Errorf("deployment %s targets service %s, but it is not defined", deployment.Name, deployment.Service)
+		}
+		elURL := "http://" + svc.hostAddr("http")
+
+		addr, err := deployContract(ctx, output, elURL, priv, deployment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deploy %s: %w", deployment.Name, err)
+		}
+		addresses[deployment.Name] = addr
+	}
+
+	if err := manifest.out.WriteFile("deployments.json", addresses); err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+func deployContract(ctx context.Context, logOutput io.Writer, elURL string, key *ecdsa.PrivateKey, deployment *Deployment) (gethcommon.Address, error) {
+	from := ecrypto.PubkeyToAddress(key.PublicKey)
+
+	clt, err := ethclient.DialContext(ctx, elURL)
+	if err != nil {
+		return gethcommon.Address{}, err
+	}
+	defer clt.Close()
+
+	chainID, err := clt.ChainID(ctx)
+	if err != nil {
+		return gethcommon.Address{}, fmt.Errorf("failed to get chain id: %w", err)
+	}
+	nonce, err := clt.PendingNonceAt(ctx, from)
+	if err != nil {
+		return gethcommon.Address{}, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	gasPrice, err := clt.SuggestGasPrice(ctx)
+	if err != nil {
+		return gethcommon.Address{}, fmt.Errorf("failed to get gas price: %w", err)
+	}
+
+	data := append(append([]byte{}, deployment.Bytecode...), deployment.Args...)
+	gas, err := clt.EstimateGas(ctx, ethereum.CallMsg{From: from, Data: data})
+	if err != nil {
+		return gethcommon.Address{}, fmt.Errorf("failed to estimate gas: %w", err)
+	}
+
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(chainID), &types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      gas,
+		Value:    big.NewInt(0),
+		Data:     data,
+	})
+	if err != nil {
+		return gethcommon.Address{}, fmt.Errorf("failed to sign tx: %w", err)
+	}
+	if err := clt.SendTransaction(ctx, tx); err != nil {
+		return gethcommon.Address{}, fmt.Errorf("failed to send tx: %w", err)
+	}
+	fmt.Fprintf(logOutput, "Deploying %s on %s: tx %s\n", deployment.Name, deployment.Service, tx.Hash())
+
+	// wait for the receipt, blocks are produced every slot so this can take a while
+	timeoutCh := time.After(2 * time.Minute)
+	for {
+		receipt, err := clt.TransactionReceipt(ctx, tx.Hash())
+		if err == nil {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return gethcommon.Address{}, fmt.Errorf("deployment tx %s reverted", tx.Hash())
+			}
+			fmt.Fprintf(logOutput, "Deployed %s at %s\n", deployment.Name, receipt.ContractAddress)
+			return receipt.ContractAddress, nil
+		}
+		if err != ethereum.NotFound {
+			return gethcommon.Address{}, fmt.Errorf("failed to get receipt: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return gethcommon.Address{}, ctx.Err()
+		case <-timeoutCh:
+			return gethcommon.Address{}, fmt.Errorf("timeout waiting for deployment tx %s", tx.Hash())
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package playground
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+)
+
+// The containers resolve the other services by name with the DNS of the docker network.
+// The services running on the host (see --use-native-reth) use localhost and the host ports
+// instead. With host names enabled, every service is reachable by name from both sides:
+//   - From the host, the names resolve to localhost with the entries of the 'hosts' file
+//     written in the output folder, which has to be appended to /etc/hosts.
+//   - From the containers, the names of the host services resolve to the host machine
+//     with extra host entries.
+// In both cases the host ports are used since the names point to the host machine.
+
+// EnableHostNames makes the services running on the host reach the other services by name.
+// It fails if the names do not resolve to the loopback interface on the host.
+func (d *LocalRunner) EnableHostNames() error {
+	if err := d.writeHostsFile(); err != nil {
+		return err
+	}
+	for _, svc := range d.manifest.Services() {
+		addrs, err := net.LookupHost(svc.Name)
+		if err != nil || len(addrs) == 0 || !net.ParseIP(addrs[0]).IsLoopback() {
+			return fmt.Errorf("service '%s' does not resolve to localhost on the host, append the 'hosts' file of the output folder to /etc/hosts", svc.Name)
+		}
+	}
+	d.hostNames = true
+	return nil
+}
+
+// addrFromHost returns the address to reach a service from the host machine. The names resolve
+// to localhost, so the services whose ports bind to another interface are reached by its address.
+func (d *LocalRunner) addrFromHost(svc *ServiceSpec) string {
+	if ip := d.hostIP(svc); ip != "" {
+		return ip
+	}
+	if d.hostNames {
+		return svc.Name
+	}
+	return "localhost"
+}
+
+// hostServiceAddrFromDocker returns the address to reach a service running on the host from the containers
+func (d *LocalRunner) hostServiceAddrFromDocker(name string) string {
+	if d.hostNames {
+		return name
+	}
+	return "host.docker.internal"
+}
+
+// hostServiceEntries returns the extra host entries for the containers to resolve
+// the services running on the host by name
+func (d *LocalRunner) hostServiceEntries() map[string]string {
+	if !d.hostNames {
+		return nil
+	}
+
+	addr := "host-gateway"
+	if runtime.GOOS == "linux" && !d.dockerDesktop {
+		addr = "172.17.0.1"
+	}
+
+	entries := map[string]string{}
+	for name := range d.overrides {
+		entries[name] = addr
+	}
+	return entries
+}
+
+// writeHostsFile writes an /etc/hosts snippet that resolves all the services of the
+// session to localhost, where their ports are exposed.
+func (d *LocalRunner) writeHostsFile() error {
+	names := []string{}
+	for _, svc := range d.manifest.Services() {
+		names = append(names, svc.Name)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("# builder-playground session %s\n", d.session.Name))
+	b.WriteString(fmt.Sprintf("127.0.0.1 %s\n", strings.Join(names, " ")))
+	return d.out.WriteFile("hosts", b.String())
+}
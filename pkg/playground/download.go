@@ -0,0 +1,428 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultDownloadConnections is the number of concurrent range requests of a download
+const defaultDownloadConnections = 4
+
+// downloadChunkSize is the minimum size of a range of a download, the small files are
+// downloaded with a single request
+const downloadChunkSize = 4 << 20
+
+// downloadRetries is how many times a range is requested again after a failure, starting from
+// the bytes already downloaded
+var downloadRetries = 5
+
+// downloadRetryDelay is the delay before the first retry of a range, it grows with the retries
+var downloadRetryDelay = time.Second
+
+// downloader downloads files with concurrent range requests. The downloaded ranges are saved
+// next to the partial file, so that a download that fails (or is interrupted) resumes from them
+// the next time instead of from zero.
+type downloader struct {
+	client      *http.Client
+	connections int
+}
+
+// newDownloader returns a downloader through the proxy, or the proxy of the environment
+// (HTTPS_PROXY, NO_PROXY...) if it is empty
+func newDownloader(proxy string, connections int) (*downloader, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		proxyURL, err := ParseDownloadProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if connections <= 0 {
+		connections = defaultDownloadConnections
+	}
+	return &downloader{client: &http.Client{Transport: transport}, connections: connections}, nil
+}
+
+// ParseDownloadProxy parses the URL of the proxy of the downloads
+func ParseDownloadProxy(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy '%s': %w", proxy, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy '%s', expected an http, https or socks5 URL", proxy)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy '%s', it has no host", proxy)
+	}
+	return proxyURL, nil
+}
+
+// downloadState is the progress of a download, saved in <path>.part.json
+type downloadState struct {
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+
+	// ETag and LastModified identify the version of the file, the ranges are downloaded again
+	// if it changed
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+
+	Chunks []*downloadChunk `json:"chunks"`
+}
+
+// downloadChunk is a range of the file, Done is the number of its bytes already downloaded
+type downloadChunk struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Done  int64 `json:"done"`
+}
+
+func (c *downloadChunk) size() int64 {
+	return c.End - c.Start + 1
+}
+
+// downloadProgress is the downloaded and total size of a download, nil if it is not reported
+type downloadProgress struct {
+	lock    sync.Mutex
+	current int64
+	total   int64
+}
+
+func (p *downloadProgress) set(current, total int64) {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.current, p.total = current, total
+}
+
+func (p *downloadProgress) add(n int64) {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.current += n
+}
+
+func (p *downloadProgress) size() (int64, int64) {
+	if p == nil {
+		return 0, 0
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.current, p.total
+}
+
+// fetch downloads a small file (i.e. a signature) to path with a single request
+func (d *downloader) fetch(ctx context.Context, url string, path string) error {
+	return d.fetchProgress(ctx, url, path, nil)
+}
+
+func (d *downloader) fetchProgress(ctx context.Context, url string, path string, progress *downloadProgress) error {
+	resp, err := d.get(ctx, url, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating file: %v", err)
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, &progressReader{r: resp.Body, progress: progress}); err != nil {
+		return fmt.Errorf("error downloading file: %v", err)
+	}
+	return nil
+}
+
+// progressReader reports the bytes read to the progress
+type progressReader struct {
+	r        io.Reader
+	progress *downloadProgress
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.progress.add(int64(n))
+	return n, err
+}
+
+func (d *downloader) get(ctx context.Context, url string, byteRange string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	expected := http.StatusOK
+	if byteRange != "" {
+		req.Header.Set("Range", "bytes="+byteRange)
+		expected = http.StatusPartialContent
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading file: %v", err)
+	}
+	if resp.StatusCode != expected {
+		resp.Body.Close()
+		return nil, fmt.Errorf("error downloading file %s: status %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// download downloads the url to path with concurrent range requests if the server supports
+// them, resuming the ranges of a previous attempt. The progress is reported to progress.
+func (d *downloader) download(ctx context.Context, url string, path string, progress *downloadProgress) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading file: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading file %s: status %s", url, resp.Status)
+	}
+
+	partPath, statePath := path+".part", path+".part.json"
+	if resp.Header.Get("Accept-Ranges") != "bytes" || resp.ContentLength <= 0 {
+		// the download restarts from zero on failure
+		os.Remove(statePath)
+		progress.set(0, max(resp.ContentLength, 0))
+		if err := d.downloadSingle(ctx, url, partPath, progress); err != nil {
+			return err
+		}
+		return os.Rename(partPath, path)
+	}
+
+	state := &downloadState{
+		URL:          url,
+		Size:         resp.ContentLength,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if prev, ok := readDownloadState(statePath); ok && prev.resumes(state) && fileExists(partPath) {
+		state = prev
+		releasesLog.Info("resuming download", "url", url, "done", formatBytes(state.done()), "total", formatBytes(state.Size))
+	} else {
+		state.Chunks = newDownloadChunks(state.Size, d.connections)
+	}
+
+	file, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("error creating file: %v", err)
+	}
+	defer file.Close()
+	if err := file.Truncate(state.Size); err != nil {
+		return fmt.Errorf("error creating file: %v", err)
+	}
+	progress.set(state.done(), state.Size)
+
+	var lock sync.Mutex
+	save := func() {
+		lock.Lock()
+		defer lock.Unlock()
+		if data, err := json.Marshal(state); err == nil {
+			os.WriteFile(statePath, data, 0644)
+		}
+	}
+
+	// the ranges are also saved while they download, in case the playground does not exit
+	// cleanly
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				save()
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(state.Chunks))
+	for i, chunk := range state.Chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = d.downloadChunk(ctx, url, file, chunk, &lock, progress)
+			save()
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-stopped
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w (the download resumes from %s of %s)", err, formatBytes(state.done()), formatBytes(state.Size))
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return fmt.Errorf("error moving output file: %v", err)
+	}
+	os.Remove(statePath)
+	return nil
+}
+
+// downloadSingle downloads the url with a single request, retrying from zero
+func (d *downloader) downloadSingle(ctx context.Context, url string, path string, progress *downloadProgress) error {
+	var err error
+	for attempt := 0; attempt <= downloadRetries; attempt++ {
+		if attempt > 0 {
+			releasesLog.Warn("download failed, retrying", "url", url, "attempt", attempt, "err", err)
+			if err := sleepContext(ctx, time.Duration(attempt)*downloadRetryDelay); err != nil {
+				return err
+			}
+		}
+		_, total := progress.size()
+		progress.set(0, total)
+		if err = d.fetchProgress(ctx, url, path, progress); err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// downloadChunk downloads the remaining bytes of the chunk into the file, retrying from the
+// bytes downloaded by the previous attempts
+func (d *downloader) downloadChunk(ctx context.Context, url string, file *os.File, chunk *downloadChunk, lock *sync.Mutex, progress *downloadProgress) error {
+	var err error
+	for attempt := 0; attempt <= downloadRetries; attempt++ {
+		lock.Lock()
+		done := chunk.Done
+		lock.Unlock()
+		if done >= chunk.size() {
+			return nil
+		}
+		if attempt > 0 {
+			releasesLog.Warn("download of range failed, retrying", "url", url, "start", chunk.Start+done, "attempt", attempt, "err", err)
+			if err := sleepContext(ctx, time.Duration(attempt)*downloadRetryDelay); err != nil {
+				return err
+			}
+		}
+
+		var resp *http.Response
+		resp, err = d.get(ctx, url, strconv.FormatInt(chunk.Start+done, 10)+"-"+strconv.FormatInt(chunk.End, 10))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		err = copyChunk(file, resp.Body, chunk, lock, progress)
+		resp.Body.Close()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// copyChunk writes the body of a range request to the file at the offset of the chunk,
+// counting the bytes written in the chunk
+func copyChunk(file *os.File, body io.Reader, chunk *downloadChunk, lock *sync.Mutex, progress *downloadProgress) error {
+	buf := make([]byte, 32<<10)
+	for {
+		lock.Lock()
+		offset, remaining := chunk.Start+chunk.Done, chunk.size()-chunk.Done
+		lock.Unlock()
+		if remaining <= 0 {
+			return nil
+		}
+
+		n, err := body.Read(buf[:min(int64(len(buf)), remaining)])
+		if n > 0 {
+			if _, err := file.WriteAt(buf[:n], offset); err != nil {
+				return fmt.Errorf("error writing output file: %v", err)
+			}
+			lock.Lock()
+			chunk.Done += int64(n)
+			lock.Unlock()
+			progress.add(int64(n))
+		}
+		if err == io.EOF {
+			lock.Lock()
+			defer lock.Unlock()
+			if chunk.Done < chunk.size() {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func newDownloadChunks(size int64, connections int) []*downloadChunk {
+	chunkSize := max(size/int64(connections), downloadChunkSize)
+	chunks := []*downloadChunk{}
+	for start := int64(0); start < size; start += chunkSize {
+		chunks = append(chunks, &downloadChunk{Start: start, End: min(start+chunkSize, size) - 1})
+	}
+	return chunks
+}
+
+// resumes returns whether the state of a previous download is the one of the same file
+func (s *downloadState) resumes(next *downloadState) bool {
+	return s.URL == next.URL && s.Size == next.Size && s.ETag == next.ETag && s.LastModified == next.LastModified && len(s.Chunks) > 0
+}
+
+func (s *downloadState) done() (done int64) {
+	for _, chunk := range s.Chunks {
+		done += chunk.Done
+	}
+	return done
+}
+
+func readDownloadState(path string) (*downloadState, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var state downloadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, false
+	}
+	return &state, true
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,259 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dutiesFile is the file of the output folder with the duties of the validators of the devnet
+const dutiesFile = "duties.json"
+
+// ValidatorDuties are the duties of the validators of the devnet in the next epochs, the
+// duties.json of the output folder
+type ValidatorDuties struct {
+	UpdatedAt    time.Time      `json:"updatedAt"`
+	CurrentEpoch uint64         `json:"currentEpoch"`
+	Epochs       []*EpochDuties `json:"epochs"`
+}
+
+// EpochDuties are the proposals and the attestations of the validators of the devnet in an epoch
+type EpochDuties struct {
+	Epoch     uint64          `json:"epoch"`
+	Proposers []*ProposerDuty `json:"proposers"`
+	Attesters []*AttesterDuty `json:"attesters"`
+}
+
+// ProposerDuty is the slot a validator proposes a block in
+type ProposerDuty struct {
+	Slot           uint64 `json:"slot"`
+	ValidatorIndex uint64 `json:"validatorIndex"`
+	Pubkey         string `json:"pubkey"`
+
+	// Validator is the validator client service with the key of the validator
+	Validator string `json:"validator"`
+}
+
+// AttesterDuty is the slot and the committee a validator attests in
+type AttesterDuty struct {
+	Slot              uint64 `json:"slot"`
+	ValidatorIndex    uint64 `json:"validatorIndex"`
+	Pubkey            string `json:"pubkey"`
+	CommitteeIndex    uint64 `json:"committeeIndex"`
+	CommitteePosition uint64 `json:"committeePosition"`
+	Validator         string `json:"validator"`
+}
+
+// DutiesWriter writes the duties of the validators of the validator clients of a manifest to
+// duties.json, so that the tests can target the proposals of specific validators
+type DutiesWriter struct {
+	manifest *Manifest
+	epochs   uint64
+
+	// slotTime and slotsPerEpoch are known once the duties are written
+	slotTime      time.Duration
+	slotsPerEpoch uint64
+}
+
+// NewDutiesWriter returns a writer of the duties of the next epochs, starting with the current one
+func NewDutiesWriter(manifest *Manifest, epochs uint64) *DutiesWriter {
+	return &DutiesWriter{manifest: manifest, epochs: epochs}
+}
+
+// dutiesValidators are the validator clients that use a beacon node
+type dutiesValidators struct {
+	beaconURL string
+
+	// keys are the validator clients of the pubkeys of the keystores
+	keys map[string]string
+}
+
+func (d *DutiesWriter) validators() (map[string]*dutiesValidators, error) {
+	beacons := map[string]*dutiesValidators{}
+	for _, svc := range d.manifest.Services() {
+		validator, ok := svc.component.(*LighthouseValidator)
+		if !ok {
+			continue
+		}
+		d.slotTime = validator.slotTime
+		group, ok := beacons[validator.BeaconNode]
+		if !ok {
+			// the host ports are assigned when the services start
+			beaconNode := d.manifest.MustGetService(validator.BeaconNode)
+			group = &dutiesValidators{
+				beaconURL: "http://" + beaconNode.hostAddr("http"),
+				keys:      map[string]string{},
+			}
+			beacons[validator.BeaconNode] = group
+		}
+
+		// the keystores added by 'validators deposit' are included once they are in the folder
+		entries, err := os.ReadDir(filepath.Join(d.manifest.out.dst, validator.dataDir(), "validators"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the validator keys of %s: %w", svc.Name, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && strings.HasPrefix(entry.Name(), "0x") {
+				group.keys[entry.Name()] = svc.Name
+			}
+		}
+	}
+	return beacons, nil
+}
+
+// Write queries the duties from the beacon nodes and writes duties.json. The beacon nodes only
+// compute the duties of the current and the next epoch, the later epochs are left out until a
+// refresh.
+func (d *DutiesWriter) Write(ctx context.Context) error {
+	beacons, err := d.validators()
+	if err != nil {
+		return err
+	}
+	if len(beacons) == 0 {
+		return fmt.Errorf("the recipe has no validator client")
+	}
+
+	duties := &ValidatorDuties{UpdatedAt: time.Now().UTC(), Epochs: []*EpochDuties{}}
+	epochs := map[uint64]*EpochDuties{}
+	for _, group := range beacons {
+		current, err := d.beaconDuties(ctx, group, epochs)
+		if err != nil {
+			return err
+		}
+		duties.CurrentEpoch = max(duties.CurrentEpoch, current)
+	}
+	for _, epoch := range epochs {
+		sort.Slice(epoch.Proposers, func(i, j int) bool { return epoch.Proposers[i].Slot < epoch.Proposers[j].Slot })
+		sort.Slice(epoch.Attesters, func(i, j int) bool {
+			if epoch.Attesters[i].Slot != epoch.Attesters[j].Slot {
+				return epoch.Attesters[i].Slot < epoch.Attesters[j].Slot
+			}
+			return epoch.Attesters[i].ValidatorIndex < epoch.Attesters[j].ValidatorIndex
+		})
+		duties.Epochs = append(duties.Epochs, epoch)
+	}
+	sort.Slice(duties.Epochs, func(i, j int) bool { return duties.Epochs[i].Epoch < duties.Epochs[j].Epoch })
+
+	data, err := json.MarshalIndent(duties, "", "  ")
+	if err != nil {
+		return err
+	}
+	// the file is replaced at once since the tools might read it while it is refreshed
+	path := filepath.Join(d.manifest.out.dst, dutiesFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// beaconDuties adds the duties of the validators of a beacon node to the epochs and returns
+// the current epoch of the beacon node
+func (d *DutiesWriter) beaconDuties(ctx context.Context, group *dutiesValidators, epochs map[uint64]*EpochDuties) (uint64, error) {
+	var spec struct {
+		Data struct {
+			SlotsPerEpoch string `json:"SLOTS_PER_EPOCH"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, group.beaconURL+"/eth/v1/config/spec", &spec); err != nil {
+		return 0, fmt.Errorf("failed to get the beacon chain spec: %w", err)
+	}
+	slotsPerEpoch, err := strconv.ParseUint(spec.Data.SlotsPerEpoch, 10, 64)
+	if err != nil || slotsPerEpoch == 0 {
+		return 0, fmt.Errorf("invalid slots per epoch '%s'", spec.Data.SlotsPerEpoch)
+	}
+	head, err := getBeaconHead(ctx, group.beaconURL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the head: %w", err)
+	}
+	current := head.Slot / slotsPerEpoch
+	d.slotsPerEpoch = slotsPerEpoch
+
+	validators, err := getValidators(ctx, group.beaconURL)
+	if err != nil {
+		return 0, err
+	}
+	indices := []string{}
+	for _, validator := range validators {
+		if _, ok := group.keys[validator.Pubkey]; ok {
+			indices = append(indices, strconv.FormatUint(validator.Index, 10))
+		}
+	}
+
+	for epoch := current; epoch < current+d.epochs; epoch++ {
+		var proposers struct {
+			Data []struct {
+				Pubkey         string `json:"pubkey"`
+				ValidatorIndex string `json:"validator_index"`
+				Slot           string `json:"slot"`
+			} `json:"data"`
+		}
+		if err := getBeaconJSON(ctx, fmt.Sprintf("%s/eth/v1/validator/duties/proposer/%d", group.beaconURL, epoch), &proposers); err != nil {
+			if epoch == current {
+				return 0, fmt.Errorf("failed to get the proposer duties of epoch %d: %w", epoch, err)
+			}
+			// too far in the future for the beacon node
+			break
+		}
+		var attesters struct {
+			Data []struct {
+				Pubkey            string `json:"pubkey"`
+				ValidatorIndex    string `json:"validator_index"`
+				CommitteeIndex    string `json:"committee_index"`
+				CommitteePosition string `json:"validator_committee_index"`
+				Slot              string `json:"slot"`
+			} `json:"data"`
+		}
+		if err := postBeaconJSONResult(ctx, fmt.Sprintf("%s/eth/v1/validator/duties/attester/%d", group.beaconURL, epoch), indices, &attesters); err != nil {
+			if epoch == current {
+				return 0, fmt.Errorf("failed to get the attester duties of epoch %d: %w", epoch, err)
+			}
+			break
+		}
+
+		duties, ok := epochs[epoch]
+		if !ok {
+			duties = &EpochDuties{Epoch: epoch, Proposers: []*ProposerDuty{}, Attesters: []*AttesterDuty{}}
+			epochs[epoch] = duties
+		}
+		for _, item := range proposers.Data {
+			validator, ok := group.keys[item.Pubkey]
+			if !ok {
+				continue
+			}
+			duty := &ProposerDuty{Pubkey: item.Pubkey, Validator: validator}
+			duty.Slot, _ = strconv.ParseUint(item.Slot, 10, 64)
+			duty.ValidatorIndex, _ = strconv.ParseUint(item.ValidatorIndex, 10, 64)
+			duties.Proposers = append(duties.Proposers, duty)
+		}
+		for _, item := range attesters.Data {
+			duty := &AttesterDuty{Pubkey: item.Pubkey, Validator: group.keys[item.Pubkey]}
+			duty.Slot, _ = strconv.ParseUint(item.Slot, 10, 64)
+			duty.ValidatorIndex, _ = strconv.ParseUint(item.ValidatorIndex, 10, 64)
+			duty.CommitteeIndex, _ = strconv.ParseUint(item.CommitteeIndex, 10, 64)
+			duty.CommitteePosition, _ = strconv.ParseUint(item.CommitteePosition, 10, 64)
+			duties.Attesters = append(duties.Attesters, duty)
+		}
+	}
+	return current, nil
+}
+
+// Run refreshes duties.json every epoch, until the context is done. It must be called after
+// the first Write.
+func (d *DutiesWriter) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.slotTime * time.Duration(d.slotsPerEpoch)):
+		}
+		if err := d.Write(ctx); err != nil && ctx.Err() == nil {
+			runnerLog.Warn("failed to refresh the validator duties", "err", err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package playground
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// ContainerEngine is the runtime that runs the containers. Any runtime that implements the
+// Docker API can be used, the playground talks to it through the DOCKER_HOST socket.
+type ContainerEngine string
+
+var (
+	ContainerEngineAuto   ContainerEngine = "auto"
+	ContainerEngineDocker ContainerEngine = "docker"
+	ContainerEnginePodman ContainerEngine = "podman"
+)
+
+const defaultDockerSocket = "/var/run/docker.sock"
+
+// ConfigureContainerEngine points the Docker API clients (and the compose command) to the socket
+// of the container engine by setting DOCKER_HOST, unless it is already set. With auto, the docker
+// socket is preferred and the podman socket is used only if there is no docker socket.
+func ConfigureContainerEngine(engine ContainerEngine) error {
+	if os.Getenv(client.EnvOverrideHost) != "" {
+		return nil
+	}
+
+	switch engine {
+	case ContainerEngineAuto:
+		if runtime.GOOS == "windows" {
+			// docker uses a named pipe on windows
+			return nil
+		}
+		if _, err := os.Stat(defaultDockerSocket); err == nil {
+			return nil
+		}
+		if socket := findPodmanSocket(); socket != "" {
+			return os.Setenv(client.EnvOverrideHost, "unix://"+socket)
+		}
+		return nil
+	case ContainerEngineDocker:
+		return nil
+	case ContainerEnginePodman:
+		socket := findPodmanSocket()
+		if socket == "" {
+			return fmt.Errorf("podman socket not found, start it with 'systemctl --user start podman.socket' or set DOCKER_HOST")
+		}
+		return os.Setenv(client.EnvOverrideHost, "unix://"+socket)
+	default:
+		return fmt.Errorf("unknown container engine '%s', expected auto, docker or podman", engine)
+	}
+}
+
+// findPodmanSocket returns the path of the podman API socket, rootless first
+func findPodmanSocket() string {
+	candidates := []string{}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "podman", "podman.sock"))
+	}
+	if runtime.GOOS == "linux" {
+		candidates = append(candidates,
+			fmt.Sprintf("/run/user/%d/podman/podman.sock", os.Getuid()),
+			"/run/podman/podman.sock",
+		)
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// isPodmanEngine checks whether the daemon behind the Docker API is podman
+func isPodmanEngine(ctx context.Context, clt *client.Client) bool {
+	version, err := clt.ServerVersion(ctx)
+	if err != nil {
+		return false
+	}
+	for _, component := range version.Components {
+		if strings.Contains(strings.ToLower(component.Name), "podman") {
+			return true
+		}
+	}
+	return false
+}
+
+// composeCommand returns the command used to run docker compose. The podman compose
+// wrapper is used with podman if the docker cli is not available.
+func (d *LocalRunner) composeCommand(ctx context.Context, args ...string) *exec.Cmd {
+	if d.podman {
+		if _, err := exec.LookPath("docker"); err != nil {
+			return exec.CommandContext(ctx, "podman", append([]string{"compose"}, args...)...)
+		}
+	}
+	return exec.CommandContext(ctx, "docker", append([]string{"compose"}, args...)...)
+}
@@ -0,0 +1,88 @@
+package playground
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithEnvFromFile loads environment variables of the service from a .env file (KEY=VALUE lines).
+// The file is read when the runner starts and its variables take precedence over the ones set
+// with WithEnv. The values are never written to the generated manifests, so it is the place
+// for the secrets (i.e. signing keys).
+func (s *ServiceSpec) WithEnvFromFile(path string) *ServiceSpec {
+	s.envFiles = append(s.envFiles, path)
+	return s
+}
+
+// readEnvFile parses a .env file. It skips the empty lines and the comments and it accepts an
+// optional 'export' prefix and quoted values.
+func readEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	defer file.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid line %d in %s, expected KEY=VALUE", line, path)
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	return values, nil
+}
+
+// envFileValues reads the env files of the service. The later files take precedence.
+func (s *ServiceSpec) envFileValues() (map[string]string, error) {
+	values := map[string]string{}
+	for _, path := range s.envFiles {
+		fileValues, err := readEnvFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load env file of service %s: %w", s.Name, err)
+		}
+		for k, v := range fileValues {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+// composeEnv returns the environment and the env files of the docker compose service. The values
+// of the env files are left for compose to read, but the variables defined in the files are
+// removed from the environment because compose gives precedence to the environment.
+func (d *LocalRunner) composeEnv(s *ServiceSpec) (map[string]string, []string, error) {
+	env, err := d.resolveEnv(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	fileValues, err := s.envFileValues()
+	if err != nil {
+		return nil, nil, err
+	}
+	for k := range fileValues {
+		delete(env, k)
+	}
+
+	envFiles := []string{}
+	for _, path := range s.envFiles {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		envFiles = append(envFiles, abs)
+	}
+	return env, envFiles, nil
+}
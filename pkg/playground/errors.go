@@ -0,0 +1,138 @@
+package playground
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrorClass is the kind of failure that ended the playground. Each class has its own exit
+// code so that the CI jobs can tell, i.e., a readiness timeout from an image pull failure.
+type ErrorClass string
+
+var (
+	ErrorClassGeneric          ErrorClass = "error"
+	ErrorClassUsage            ErrorClass = "usage"
+	ErrorClassArtifactsFailed  ErrorClass = "artifacts-failed"
+	ErrorClassPullFailed       ErrorClass = "pull-failed"
+	ErrorClassStartFailed      ErrorClass = "start-failed"
+	ErrorClassNotReady         ErrorClass = "not-ready"
+	ErrorClassDeploymentFailed ErrorClass = "deployment-failed"
+	ErrorClassOutputFailed     ErrorClass = "output-failed"
+	ErrorClassServiceFailed    ErrorClass = "service-failed"
+	ErrorClassWatchdogFailed   ErrorClass = "watchdog-failed"
+	ErrorClassScenarioFailed   ErrorClass = "scenario-failed"
+	ErrorClassInterrupted      ErrorClass = "interrupted"
+)
+
+// errorExitCodes are the exit codes of the error classes. They are part of the interface of
+// the playground, so the existing codes must not change.
+var errorExitCodes = map[ErrorClass]int{
+	ErrorClassGeneric:          1,
+	ErrorClassUsage:            2,
+	ErrorClassArtifactsFailed:  3,
+	ErrorClassPullFailed:       4,
+	ErrorClassStartFailed:      5,
+	ErrorClassNotReady:         6,
+	ErrorClassDeploymentFailed: 7,
+	ErrorClassOutputFailed:     8,
+	ErrorClassServiceFailed:    9,
+	ErrorClassWatchdogFailed:   10,
+	ErrorClassScenarioFailed:   11,
+	// like the shells for the processes ended by SIGINT
+	ErrorClassInterrupted: 130,
+}
+
+func (c ErrorClass) ExitCode() int {
+	if code, ok := errorExitCodes[c]; ok {
+		return code
+	}
+	return errorExitCodes[ErrorClassGeneric]
+}
+
+// ClassifiedError is an error with the class of the failure
+type ClassifiedError struct {
+	Class ErrorClass
+	Err   error
+}
+
+// NewClassifiedError sets the class of the error. It returns nil if err is nil.
+func NewClassifiedError(class ErrorClass, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ClassifiedError{Class: class, Err: err}
+}
+
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorClassOf returns the class of the outermost classified error of the chain, or the
+// generic class if the error is not classified
+func ErrorClassOf(err error) ErrorClass {
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		return classified.Class
+	}
+	return ErrorClassGeneric
+}
+
+type ErrorFormat string
+
+var (
+	ErrorFormatText ErrorFormat = "text"
+	ErrorFormatJSON ErrorFormat = "json"
+)
+
+func (f ErrorFormat) Validate() error {
+	switch f {
+	case ErrorFormatText, ErrorFormatJSON:
+		return nil
+	}
+	return fmt.Errorf("invalid error format '%s', expected text or json", f)
+}
+
+// errorReport is the final error of the playground in the JSON error format
+type errorReport struct {
+	Class    ErrorClass `json:"class"`
+	ExitCode int        `json:"exitCode"`
+	Message  string     `json:"message"`
+
+	// Service and ServiceExitCode are the service that failed and its exit code,
+	// for the service-failed errors
+	Service         string `json:"service,omitempty"`
+	ServiceExitCode *int   `json:"serviceExitCode,omitempty"`
+}
+
+// WriteError writes the final error of the playground, as a JSON object in a single line with
+// the JSON format, and returns the exit code of the process
+func WriteError(w io.Writer, err error, format ErrorFormat) int {
+	class := ErrorClassOf(err)
+	if format != ErrorFormatJSON {
+		fmt.Fprintln(w, err)
+		return class.ExitCode()
+	}
+
+	report := &errorReport{
+		Class:    class,
+		ExitCode: class.ExitCode(),
+		Message:  err.Error(),
+	}
+	var failedErr *ServiceFailedError
+	if errors.As(err, &failedErr) {
+		report.Service, report.ServiceExitCode = failedErr.Service, &failedErr.ExitCode
+	}
+	data, marshalErr := json.Marshal(report)
+	if marshalErr != nil {
+		fmt.Fprintln(w, err)
+		return class.ExitCode()
+	}
+	fmt.Fprintln(w, string(data))
+	return class.ExitCode()
+}
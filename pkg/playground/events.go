@@ -0,0 +1,286 @@
+package playground
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var eventsLog = Logger("events")
+
+type ChainEventKind string
+
+var (
+	// ChainEventSlot is a new head of the beacon chain
+	ChainEventSlot ChainEventKind = "slot"
+	// ChainEventBlock is a new head of the execution chain
+	ChainEventBlock ChainEventKind = "block"
+)
+
+// ChainEvent is a new head of the beacon chain (slot) or of the execution chain (block)
+type ChainEvent struct {
+	Kind ChainEventKind
+
+	// Slot and BlockRoot are set for the slot events
+	Slot      uint64
+	BlockRoot string
+
+	// Number, Hash and Timestamp are set for the block events
+	Number    uint64
+	Hash      string
+	Timestamp uint64
+}
+
+// Env returns the environment variables that describe the event to the hooks
+func (e *ChainEvent) Env() []string {
+	env := []string{"PLAYGROUND_EVENT=" + string(e.Kind)}
+	switch e.Kind {
+	case ChainEventSlot:
+		env = append(env,
+			fmt.Sprintf("PLAYGROUND_SLOT=%d", e.Slot),
+			"PLAYGROUND_BLOCK_ROOT="+e.BlockRoot,
+		)
+	case ChainEventBlock:
+		env = append(env,
+			fmt.Sprintf("PLAYGROUND_BLOCK_NUMBER=%d", e.Number),
+			"PLAYGROUND_BLOCK_HASH="+e.Hash,
+			fmt.Sprintf("PLAYGROUND_BLOCK_TIMESTAMP=%d", e.Timestamp),
+		)
+	}
+	return env
+}
+
+// EventStream follows the head events of a beacon node and the new heads of an execution node
+// and delivers them to the subscribers
+type EventStream struct {
+	beacon *ServiceSpec
+	el     *ServiceSpec
+
+	lock sync.Mutex
+	subs []chan *ChainEvent
+}
+
+// NewEventStream creates the event stream of the beacon and execution nodes of the manifest.
+// Any of them can be empty to follow only the other chain.
+func NewEventStream(manifest *Manifest, beaconNode, executionNode string) (*EventStream, error) {
+	e := &EventStream{}
+	if beaconNode != "" {
+		beacon, ok := manifest.GetService(beaconNode)
+		if !ok {
+			return nil, fmt.Errorf("beacon node %s not found", beaconNode)
+		}
+		e.beacon = beacon
+	}
+	if executionNode != "" {
+		el, ok := manifest.GetService(executionNode)
+		if !ok {
+			return nil, fmt.Errorf("execution node %s not found", executionNode)
+		}
+		e.el = el
+	}
+	return e, nil
+}
+
+// Subscribe returns a channel with the events received after the call. The events are
+// dropped if the subscriber does not keep up with the chain.
+func (e *EventStream) Subscribe() <-chan *ChainEvent {
+	ch := make(chan *ChainEvent, 16)
+
+	e.lock.Lock()
+	e.subs = append(e.subs, ch)
+	e.lock.Unlock()
+	return ch
+}
+
+func (e *EventStream) publish(event *ChainEvent) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for _, ch := range e.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Run follows the chains until the context is cancelled. It must be called once the services
+// are running. The connections are retried if the nodes go away (i.e. they are restarted).
+func (e *EventStream) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	follow := func(name string, fn func(ctx context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if err := fn(ctx); err != nil && ctx.Err() == nil {
+					eventsLog.Debug("event stream disconnected", "source", name, "err", err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Second):
+				}
+			}
+		}()
+	}
+	if e.beacon != nil {
+		beaconURL := "http://" + e.beacon.hostAddr("http")
+		follow(e.beacon.Name, func(ctx context.Context) error {
+			return e.followBeacon(ctx, beaconURL)
+		})
+	}
+	if e.el != nil {
+		// the subscriptions need a websocket connection, otherwise the head is polled
+		elURL := "http://" + e.el.hostAddr("http")
+		if port, ok := e.el.GetPort("ws"); ok {
+			elURL = "ws://" + e.el.hostAddr(port.Name)
+		}
+		follow(e.el.Name, func(ctx context.Context) error {
+			return e.followExecution(ctx, elURL)
+		})
+	}
+	wg.Wait()
+}
+
+// followBeacon reads the head events from the event stream (SSE) of the beacon node
+func (e *EventStream) followBeacon(ctx context.Context, beaconURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, beaconURL+"/eth/v1/events?topics=head", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var head struct {
+			Slot  string `json:"slot"`
+			Block string `json:"block"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &head); err != nil {
+			return fmt.Errorf("failed to decode head event: %w", err)
+		}
+		slot, err := strconv.ParseUint(head.Slot, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid slot in head event: %w", err)
+		}
+		e.publish(&ChainEvent{Kind: ChainEventSlot, Slot: slot, BlockRoot: head.Block})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// followExecution subscribes to the new heads of the execution node or polls them
+// if there is no websocket endpoint
+func (e *EventStream) followExecution(ctx context.Context, elURL string) error {
+	clt, err := ethclient.DialContext(ctx, elURL)
+	if err != nil {
+		return err
+	}
+	defer clt.Close()
+
+	publish := func(header *types.Header) {
+		e.publish(&ChainEvent{
+			Kind:      ChainEventBlock,
+			Number:    header.Number.Uint64(),
+			Hash:      header.Hash().Hex(),
+			Timestamp: header.Time,
+		})
+	}
+
+	if strings.HasPrefix(elURL, "ws") {
+		ch := make(chan *types.Header)
+		sub, err := clt.SubscribeNewHead(ctx, ch)
+		if err != nil {
+			return err
+		}
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case err := <-sub.Err():
+				return err
+			case header := <-ch:
+				publish(header)
+			}
+		}
+	}
+
+	var latest uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(250 * time.Millisecond):
+			header, err := clt.HeaderByNumber(ctx, nil)
+			if err != nil {
+				return err
+			}
+			if num := header.Number.Uint64(); num > latest {
+				latest = num
+				publish(header)
+			}
+		}
+	}
+}
+
+// RunEventHooks runs the command of the hook of each event with 'sh -c' until the context
+// is cancelled. The command receives the outputs of the recipe (as in output.env) and the
+// details of the event as environment variables. The commands run concurrently so that a
+// slow hook does not delay the next events.
+func RunEventHooks(ctx context.Context, events <-chan *ChainEvent, hooks map[ChainEventKind]string, outputs map[string]string) {
+	env := os.Environ()
+	for name, value := range outputs {
+		env = append(env, fmt.Sprintf("%s=%s", outputEnvName(name), value))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			hook, ok := hooks[event.Kind]
+			if !ok {
+				continue
+			}
+			go func() {
+				cmd := exec.CommandContext(ctx, "sh", "-c", hook)
+				cmd.Env = append(append([]string{}, env...), event.Env()...)
+				cmd.Stdout = os.Stdout
+				cmd.Stderr = os.Stderr
+				if err := cmd.Run(); err != nil && ctx.Err() == nil {
+					eventsLog.Error("event hook failed", "event", event.Kind, "err", err)
+				}
+			}()
+		}
+	}
+}
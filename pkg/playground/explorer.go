@@ -0,0 +1,74 @@
+package playground
+
+import "fmt"
+
+// Explorer is a block explorer deployed next to the devnet with --with-explorer
+type Explorer string
+
+var (
+	// ExplorerBlockscout indexes the L1 execution chain
+	ExplorerBlockscout Explorer = "blockscout"
+	// ExplorerDora shows the L1 beacon chain
+	ExplorerDora Explorer = "dora"
+)
+
+func (e Explorer) Validate() error {
+	switch e {
+	case ExplorerBlockscout, ExplorerDora:
+		return nil
+	default:
+		return fmt.Errorf("invalid explorer '%s', expected blockscout or dora", e)
+	}
+}
+
+// AddExplorers adds the explorers to the manifest, connected to the el and beacon services
+// of the L1, and returns the outputs with their URLs
+func AddExplorers(manifest *Manifest, explorers []Explorer) (map[string]*RecipeOutput, error) {
+	outputs := map[string]*RecipeOutput{}
+	seen := map[Explorer]bool{}
+	for _, explorer := range explorers {
+		if seen[explorer] {
+			continue
+		}
+		seen[explorer] = true
+
+		var required []string
+		switch explorer {
+		case ExplorerBlockscout:
+			required = []string{"el"}
+		case ExplorerDora:
+			required = []string{"el", "beacon"}
+		default:
+			return nil, explorer.Validate()
+		}
+		for _, name := range required {
+			if _, ok := manifest.GetService(name); !ok {
+				return nil, fmt.Errorf("explorer %s requires the service %s in the recipe", explorer, name)
+			}
+		}
+
+		switch explorer {
+		case ExplorerBlockscout:
+			manifest.AddService("blockscout-db", &BlockscoutPostgres{})
+			manifest.AddService("blockscout-api", &Blockscout{
+				ExecutionNode: "el",
+				Database:      "blockscout-db",
+				ChainID:       l1ChainID,
+			})
+			manifest.AddService("blockscout", &BlockscoutFrontend{
+				Backend:       "blockscout-api",
+				ExecutionNode: "el",
+				ChainID:       l1ChainID,
+			})
+			outputs["blockscout-http"] = OutputURL("http", "blockscout", "http")
+			outputs["blockscout-api-http"] = OutputURL("http", "blockscout-api", "http")
+		case ExplorerDora:
+			manifest.AddService("dora", &Dora{
+				BeaconNode:    "beacon",
+				ExecutionNode: "el",
+			})
+			outputs["dora-http"] = OutputURL("http", "dora", "http")
+		}
+	}
+	return outputs, nil
+}
@@ -0,0 +1,24 @@
+package playground
+
+import "fmt"
+
+// faucetAccount is the prefunded account that funds the transfers of the faucet. It is not
+// used by any other service so that the faucet does not race them for the nonces.
+var faucetAccount = prefundedAccounts[len(prefundedAccounts)-1]
+
+// AddFaucet adds the faucet to the manifest (--with-faucet), connected to the el service
+// of the L1, and returns the output with its URL
+func AddFaucet(manifest *Manifest) (map[string]*RecipeOutput, error) {
+	if _, ok := manifest.GetService("el"); !ok {
+		return nil, fmt.Errorf("the faucet requires the service el in the recipe")
+	}
+	if _, ok := manifest.GetService("faucet"); !ok {
+		// the recipe may already deploy it (see DevRecipe)
+		manifest.AddService("faucet", &Faucet{
+			ExecutionNode: "el",
+		})
+	}
+	return map[string]*RecipeOutput{
+		"faucet-http": OutputURL("http", "faucet", "http"),
+	}, nil
+}
@@ -0,0 +1,238 @@
+package playground
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// FinalityWatchdog tracks the justification and the finalization of the beacon chain of a
+// manifest, logs a summary of each epoch once it ends and fails if the chain stops finalizing
+// (--watchdog-finality-epochs)
+type FinalityWatchdog struct {
+	manifest *Manifest
+
+	// maxEpochs is the number of epochs without finality tolerated, 0 only logs the summaries
+	maxEpochs uint64
+}
+
+// NewFinalityWatchdog returns a watchdog that fails after maxEpochs epochs without finality
+func NewFinalityWatchdog(manifest *Manifest, maxEpochs uint64) *FinalityWatchdog {
+	return &FinalityWatchdog{manifest: manifest, maxEpochs: maxEpochs}
+}
+
+// EpochSummary is the result of an epoch of the beacon chain
+type EpochSummary struct {
+	Epoch uint64
+
+	// Participation is the share of the active stake that attested to the target of the epoch
+	Participation float64
+
+	// MissedBlocks is the number of slots of the epoch without a block in the canonical chain
+	MissedBlocks uint64
+
+	CurrentJustified  *Checkpoint
+	PreviousJustified *Checkpoint
+	Finalized         *Checkpoint
+}
+
+// Checkpoint is a checkpoint of the beacon chain, the block root of the first slot of an epoch
+type Checkpoint struct {
+	Epoch uint64
+	Root  string
+}
+
+// finalityLag is the number of epochs between the current epoch and the finalized one in a
+// chain that finalizes every epoch: the previous epoch is justified at the start of an epoch
+// and the one before it is finalized
+const finalityLag = 2
+
+// beaconNode returns the http endpoint of the first beacon node of the manifest, all of them
+// follow the same chain
+func (f *FinalityWatchdog) beaconNode() (string, bool) {
+	for _, svc := range f.manifest.Services() {
+		if _, ok := svc.component.(*LighthouseBeaconNode); ok {
+			return "http://" + svc.hostAddr("http"), true
+		}
+	}
+	return "", false
+}
+
+// Run summarizes every epoch that ends until the context is done or the chain does not finalize
+// for more than the maximum number of epochs. The state of the watchdog is reported to status
+// as the finality watchdog, which can be nil. It returns right away if the recipe does not have
+// a beacon chain.
+func (f *FinalityWatchdog) Run(ctx context.Context, status *WatchdogStatus) error {
+	beaconURL, ok := f.beaconNode()
+	if !ok {
+		return nil
+	}
+	status.update("finality", nil)
+
+	genesis, slotTime, slotsPerEpoch, err := getBeaconTiming(ctx, beaconURL)
+	if err != nil {
+		return err
+	}
+	epochTime := slotTime * time.Duration(slotsPerEpoch)
+
+	// summarize from the epoch in progress, the first slot of the next epoch is given half a
+	// slot so that the head state includes the processing of the epoch
+	epoch := uint64(0)
+	if now := time.Now(); now.After(genesis) {
+		epoch = uint64(now.Sub(genesis) / epochTime)
+	}
+	for {
+		end := genesis.Add(time.Duration(epoch+1)*epochTime + slotTime/2)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(end)):
+		}
+
+		summary, err := getEpochSummary(ctx, beaconURL, epoch, slotsPerEpoch)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			watchdogLog.Warn("failed to summarize the epoch", "epoch", epoch, "err", err)
+			epoch++
+			continue
+		}
+		watchdogLog.Info("epoch summary",
+			"epoch", summary.Epoch,
+			"participation", fmt.Sprintf("%.1f%%", summary.Participation*100),
+			"missed blocks", summary.MissedBlocks,
+			"justified", summary.CurrentJustified.Epoch,
+			"finalized", summary.Finalized.Epoch,
+			"finalized root", summary.Finalized.Root,
+		)
+
+		// the epoch that started is the current one
+		current := epoch + 1
+		if stalled := current - min(current, summary.Finalized.Epoch+finalityLag); f.maxEpochs > 0 && stalled > f.maxEpochs {
+			err := fmt.Errorf("the chain did not finalize for %d epochs (current epoch %d, finalized epoch %d)", stalled, current, summary.Finalized.Epoch)
+			status.update("finality", err)
+			return err
+		}
+		epoch++
+	}
+}
+
+// getBeaconTiming returns the genesis time, the slot time and the number of slots per epoch
+// of the beacon chain
+func getBeaconTiming(ctx context.Context, beaconURL string) (time.Time, time.Duration, uint64, error) {
+	var genesis struct {
+		Data struct {
+			GenesisTime string `json:"genesis_time"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/beacon/genesis", &genesis); err != nil {
+		return time.Time{}, 0, 0, fmt.Errorf("failed to get the genesis: %w", err)
+	}
+	genesisTime, err := strconv.ParseInt(genesis.Data.GenesisTime, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, 0, fmt.Errorf("invalid genesis time '%s'", genesis.Data.GenesisTime)
+	}
+
+	var spec struct {
+		Data struct {
+			SecondsPerSlot string `json:"SECONDS_PER_SLOT"`
+			SlotsPerEpoch  string `json:"SLOTS_PER_EPOCH"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/config/spec", &spec); err != nil {
+		return time.Time{}, 0, 0, fmt.Errorf("failed to get the beacon chain spec: %w", err)
+	}
+	seconds, err := strconv.ParseUint(spec.Data.SecondsPerSlot, 10, 64)
+	if err != nil || seconds == 0 {
+		return time.Time{}, 0, 0, fmt.Errorf("invalid slot time '%s'", spec.Data.SecondsPerSlot)
+	}
+	slotsPerEpoch, err := strconv.ParseUint(spec.Data.SlotsPerEpoch, 10, 64)
+	if err != nil || slotsPerEpoch == 0 {
+		return time.Time{}, 0, 0, fmt.Errorf("invalid slots per epoch '%s'", spec.Data.SlotsPerEpoch)
+	}
+	return time.Unix(genesisTime, 0), time.Duration(seconds) * time.Second, slotsPerEpoch, nil
+}
+
+// getEpochSummary returns the summary of an epoch that ended, with the checkpoints of the
+// head state
+func getEpochSummary(ctx context.Context, beaconURL string, epoch uint64, slotsPerEpoch uint64) (*EpochSummary, error) {
+	summary := &EpochSummary{Epoch: epoch}
+
+	var checkpoints struct {
+		Data struct {
+			PreviousJustified beaconCheckpoint `json:"previous_justified"`
+			CurrentJustified  beaconCheckpoint `json:"current_justified"`
+			Finalized         beaconCheckpoint `json:"finalized"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/beacon/states/head/finality_checkpoints", &checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to get the finality checkpoints: %w", err)
+	}
+	summary.PreviousJustified = checkpoints.Data.PreviousJustified.checkpoint()
+	summary.CurrentJustified = checkpoints.Data.CurrentJustified.checkpoint()
+	summary.Finalized = checkpoints.Data.Finalized.checkpoint()
+
+	// the votes of the epoch are counted by lighthouse with the state at the end of the epoch
+	var inclusion struct {
+		Data struct {
+			ActiveGwei          uint64 `json:"current_epoch_active_gwei"`
+			TargetAttestingGwei uint64 `json:"current_epoch_target_attesting_gwei"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, fmt.Sprintf("%s/lighthouse/validator_inclusion/%d/global", beaconURL, epoch), &inclusion); err != nil {
+		return nil, fmt.Errorf("failed to get the participation of epoch %d: %w", epoch, err)
+	}
+	if inclusion.Data.ActiveGwei != 0 {
+		summary.Participation = float64(inclusion.Data.TargetAttestingGwei) / float64(inclusion.Data.ActiveGwei)
+	}
+
+	// the blocks of the epoch are the ones of the canonical chain, from the head back to the
+	// first slot of the epoch. The genesis block is not proposed, slot 0 is not counted.
+	start, end := epoch*slotsPerEpoch, (epoch+1)*slotsPerEpoch
+	slots := slotsPerEpoch
+	if start == 0 {
+		start, slots = 1, slotsPerEpoch-1
+	}
+	blocks := uint64(0)
+	blockID := "head"
+	for {
+		var header struct {
+			Data struct {
+				Header struct {
+					Message struct {
+						Slot       string `json:"slot"`
+						ParentRoot string `json:"parent_root"`
+					} `json:"message"`
+				} `json:"header"`
+			} `json:"data"`
+		}
+		if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/beacon/headers/"+blockID, &header); err != nil {
+			return nil, fmt.Errorf("failed to get the block %s: %w", blockID, err)
+		}
+		slot, err := strconv.ParseUint(header.Data.Header.Message.Slot, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slot of block %s: %w", blockID, err)
+		}
+		if slot < start {
+			break
+		}
+		if slot < end {
+			blocks++
+		}
+		blockID = header.Data.Header.Message.ParentRoot
+	}
+	summary.MissedBlocks = slots - blocks
+	return summary, nil
+}
+
+type beaconCheckpoint struct {
+	Epoch string `json:"epoch"`
+	Root  string `json:"root"`
+}
+
+func (c beaconCheckpoint) checkpoint() *Checkpoint {
+	epoch, _ := strconv.ParseUint(c.Epoch, 10, 64)
+	return &Checkpoint{Epoch: epoch, Root: c.Root}
+}
@@ -0,0 +1,144 @@
+package playground
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	flag "github.com/spf13/pflag"
+)
+
+// flagEnvPrefix is the prefix of the environment variables that set the flags
+const flagEnvPrefix = "PLAYGROUND_"
+
+// FlagEnvName returns the environment variable of a flag (i.e. PLAYGROUND_SLOT_TIME for --slot-time)
+func FlagEnvName(name string) string {
+	return flagEnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// LoadFlagConfig sets the flags that are not set in the command line from the PLAYGROUND_* environment
+// variables and, with a lower precedence, from the config file (if any). The config file is either a
+// TOML file with the flag names as keys (the flags of a recipe can also be set in a table with the
+// recipe name) or a .env file with the PLAYGROUND_* variables. The recipes are used to ignore the
+// tables of the other recipes.
+func LoadFlagConfig(flags *flag.FlagSet, recipe string, recipes []string, path string) error {
+	values := map[string][]string{}
+	if path != "" {
+		var err error
+		if filepath.Ext(path) == ".env" {
+			values, err = readFlagEnvFile(flags, path)
+		} else {
+			values, err = readFlagTOMLFile(flags, recipe, recipes, path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// the environment variables take precedence over the config file
+	flags.VisitAll(func(f *flag.Flag) {
+		if value, ok := os.LookupEnv(FlagEnvName(f.Name)); ok {
+			values[f.Name] = splitFlagValue(f, value)
+		}
+	})
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := flags.Lookup(name)
+		if f.Changed {
+			// the command line takes precedence
+			continue
+		}
+		for _, value := range values[name] {
+			if err := f.Value.Set(value); err != nil {
+				return fmt.Errorf("invalid value '%s' for flag --%s: %w", value, name, err)
+			}
+		}
+		f.Changed = true
+	}
+	return nil
+}
+
+// splitFlagValue splits the value of the list flags set from environment variables, which
+// use commas to separate the items
+func splitFlagValue(f *flag.Flag, value string) []string {
+	if typ := f.Value.Type(); typ == "stringArray" || typ == "stringSlice" {
+		return strings.Split(value, ",")
+	}
+	return []string{value}
+}
+
+func readFlagTOMLFile(flags *flag.FlagSet, recipe string, recipes []string, path string) (map[string][]string, error) {
+	var config map[string]interface{}
+	if _, err := toml.DecodeFile(path, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode config file: %w", err)
+	}
+
+	values := map[string][]string{}
+	var decode func(table map[string]interface{}, section string) error
+	decode = func(table map[string]interface{}, section string) error {
+		for key, val := range table {
+			if nested, ok := val.(map[string]interface{}); ok && section == "" {
+				if key == recipe {
+					if err := decode(nested, key); err != nil {
+						return err
+					}
+					continue
+				}
+				if slices.Contains(recipes, key) {
+					// flags of another recipe
+					continue
+				}
+			}
+
+			if flags.Lookup(key) == nil {
+				if section != "" {
+					return fmt.Errorf("unknown flag '%s' in section '%s' of the config file", key, section)
+				}
+				return fmt.Errorf("unknown flag '%s' in the config file", key)
+			}
+			switch v := val.(type) {
+			case []interface{}:
+				items := []string{}
+				for _, item := range v {
+					items = append(items, fmt.Sprint(item))
+				}
+				values[key] = items
+			case map[string]interface{}:
+				return fmt.Errorf("invalid value for flag '%s' in the config file", key)
+			default:
+				values[key] = []string{fmt.Sprint(v)}
+			}
+		}
+		return nil
+	}
+	if err := decode(config, ""); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func readFlagEnvFile(flags *flag.FlagSet, path string) (map[string][]string, error) {
+	env, err := readEnvFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := map[string][]string{}
+	flags.VisitAll(func(f *flag.Flag) {
+		// like the environment variables, the ones of other recipes are ignored
+		if value, ok := env[FlagEnvName(f.Name)]; ok {
+			values[f.Name] = splitFlagValue(f, value)
+		}
+	})
+	return values, nil
+}
@@ -0,0 +1,136 @@
+package playground
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// followColors are the ANSI colors of the service prefixes, like the ones of docker compose
+var followColors = []string{"6", "3", "2", "5", "4", "1", "14", "11", "10", "13", "12", "9"}
+
+var (
+	// followLevelKeyRe matches the level of the structured logs (level=info, lvl=warn or "level":"error")
+	followLevelKeyRe = regexp.MustCompile(`(?i)(?:\blevel=|\blvl=|"level":\s*")"?([a-z]+)`)
+
+	// followLevelWordRe matches the level of the text logs of the clients (i.e. INFO, WARN or ERRO)
+	followLevelWordRe = regexp.MustCompile(`\b(TRACE|TRCE|DEBUG|DBUG|DEBG|INFO|WARN|WARNING|ERROR|EROR|ERRO|CRIT|FATAL)\b`)
+)
+
+// logFollower multiplexes the logs of the services to a single output with the name of the
+// service in front of every line (see --follow-logs). The lines below the level are dropped.
+type logFollower struct {
+	lock sync.Mutex
+	out  io.Writer
+
+	level  LogLevel
+	width  int
+	colors map[string]lipgloss.Style
+}
+
+// FollowLogs writes the logs of the services to out, besides their log files, with the lines
+// prefixed by the name of the service. The lines with a level below the given one are skipped,
+// and the lines without a level (i.e. stack traces) take the level of the previous line.
+func (d *LocalRunner) FollowLogs(out io.Writer, level LogLevel) {
+	names := []string{}
+	for _, svc := range d.manifest.services {
+		names = append(names, svc.Name)
+	}
+	sort.Strings(names)
+
+	f := &logFollower{out: out, level: level, colors: map[string]lipgloss.Style{}}
+	for i, name := range names {
+		f.width = max(f.width, len(name))
+		f.colors[name] = lipgloss.NewStyle().Foreground(lipgloss.Color(followColors[i%len(followColors)]))
+	}
+	d.follower = f
+}
+
+// writer returns the writer of the logs of a service
+func (f *logFollower) writer(name string) io.Writer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	style, ok := f.colors[name]
+	if !ok {
+		// the services added after the start (i.e. by scale) get the next color
+		style = lipgloss.NewStyle().Foreground(lipgloss.Color(followColors[len(f.colors)%len(followColors)]))
+		f.colors[name] = style
+	}
+	prefix := style.Render(fmt.Sprintf("%-*s |", max(f.width, len(name)), name)) + " "
+	return &followWriter{follower: f, prefix: prefix}
+}
+
+func (f *logFollower) writeLine(prefix string, line []byte) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// the output is the console, there is nothing to do if it fails
+	io.WriteString(f.out, prefix+string(line)+"\n")
+}
+
+// followWriter splits the logs of a service in lines, which are written as a whole so
+// that the lines of the services do not interleave
+type followWriter struct {
+	follower *logFollower
+	prefix   string
+
+	buf   []byte
+	level LogLevel
+}
+
+func (w *followWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimSuffix(w.buf[:i], []byte("\r"))
+		w.buf = w.buf[i+1:]
+
+		if level, ok := parseLineLevel(string(line)); ok {
+			w.level = level
+		}
+		if w.level != "" && w.level.slogLevel() < w.follower.level.slogLevel() {
+			continue
+		}
+		w.follower.writeLine(w.prefix, line)
+	}
+	return len(p), nil
+}
+
+// parseLineLevel returns the level of a log line of a client, if it has one
+func parseLineLevel(line string) (LogLevel, bool) {
+	match := followLevelKeyRe.FindStringSubmatch(line)
+	if match == nil {
+		match = followLevelWordRe.FindStringSubmatch(line)
+	}
+	if match == nil {
+		return "", false
+	}
+	return parseLevelName(match[1])
+}
+
+// parseLevelName returns the level of the name of a level of the clients (i.e. INFO, DBUG or warning)
+func parseLevelName(name string) (LogLevel, bool) {
+	switch strings.ToLower(name) {
+	case "trace", "trce":
+		return LevelTrace, true
+	case "debug", "dbug", "debg":
+		return LevelDebug, true
+	case "info":
+		return LevelInfo, true
+	case "warn", "warning":
+		return LevelWarn, true
+	case "error", "eror", "erro", "crit", "fatal":
+		return LevelError, true
+	}
+	return "", false
+}
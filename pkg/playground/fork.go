@@ -0,0 +1,126 @@
+package playground
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var forkLog = Logger("fork")
+
+// forkConfig describes the live network to shadow fork the state from
+type forkConfig struct {
+	// rpcURL is the URL of an archive node of the network to fork
+	rpcURL string
+
+	// block is the block number to fork from. If zero, it uses the latest block
+	block uint64
+
+	// accounts is a list of extra accounts to copy besides the ones touched in the block
+	accounts []gethcommon.Address
+}
+
+type prestateAccount struct {
+	Balance *hexutil.Big                        `json:"balance"`
+	Nonce   uint64                              `json:"nonce"`
+	Code    hexutil.Bytes                       `json:"code"`
+	Storage map[gethcommon.Hash]gethcommon.Hash `json:"storage"`
+}
+
+type prestateTrace struct {
+	TxHash gethcommon.Hash                         `json:"txHash"`
+	Result map[gethcommon.Address]*prestateAccount `json:"result"`
+}
+
+// fetchForkState pre-seeds a state snapshot from a live network. It includes all the accounts
+// (with their storage) touched by the transactions of the fork block, using the prestate tracer,
+// plus the balance, nonce and code of any extra accounts. The storage of the extra accounts cannot
+// be enumerated with the standard RPC methods, so it is not copied.
+func fetchForkState(ctx context.Context, config *forkConfig) (types.GenesisAlloc, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	clt, err := rpc.DialContext(ctx, config.rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to fork rpc: %w", err)
+	}
+	defer clt.Close()
+
+	block := config.block
+	if block == 0 {
+		var num hexutil.Uint64
+		if err := clt.CallContext(ctx, &num, "eth_blockNumber"); err != nil {
+			return nil, fmt.Errorf("failed to get latest block: %w", err)
+		}
+		block = uint64(num)
+	}
+	blockNum := hexutil.EncodeUint64(block)
+	forkLog.Info("shadow forking state", "rpc", config.rpcURL, "block", block)
+
+	var traces []*prestateTrace
+	if err := clt.CallContext(ctx, &traces, "debug_traceBlockByNumber", blockNum, map[string]interface{}{
+		"tracer": "prestateTracer",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to trace fork block (the node must support the debug namespace): %w", err)
+	}
+
+	alloc := types.GenesisAlloc{}
+	for _, trace := range traces {
+		for addr, account := range trace.Result {
+			// the prestate of the first transaction that touches an account is the
+			// state of the account before the block, later ones are ignored. The storage
+			// slots are merged since each transaction might touch different ones.
+			existing, ok := alloc[addr]
+			if !ok {
+				balance := new(big.Int)
+				if account.Balance != nil {
+					balance = account.Balance.ToInt()
+				}
+				existing = types.Account{
+					Balance: balance,
+					Nonce:   account.Nonce,
+					Code:    account.Code,
+					Storage: map[gethcommon.Hash]gethcommon.Hash{},
+				}
+			}
+			for k, v := range account.Storage {
+				if _, ok := existing.Storage[k]; !ok {
+					existing.Storage[k] = v
+				}
+			}
+			alloc[addr] = existing
+		}
+	}
+
+	for _, addr := range config.accounts {
+		if _, ok := alloc[addr]; ok {
+			continue
+		}
+		var balance hexutil.Big
+		if err := clt.CallContext(ctx, &balance, "eth_getBalance", addr, blockNum); err != nil {
+			return nil, fmt.Errorf("failed to get balance of %s: %w", addr, err)
+		}
+		var nonce hexutil.Uint64
+		if err := clt.CallContext(ctx, &nonce, "eth_getTransactionCount", addr, blockNum); err != nil {
+			return nil, fmt.Errorf("failed to get nonce of %s: %w", addr, err)
+		}
+		var code hexutil.Bytes
+		if err := clt.CallContext(ctx, &code, "eth_getCode", addr, blockNum); err != nil {
+			return nil, fmt.Errorf("failed to get code of %s: %w", addr, err)
+		}
+		alloc[addr] = types.Account{
+			Balance: balance.ToInt(),
+			Nonce:   uint64(nonce),
+			Code:    code,
+		}
+	}
+
+	forkLog.Info("shadow fork state", "accounts", len(alloc))
+	return alloc, nil
+}
@@ -0,0 +1,256 @@
+package playground
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ServiceMetrics is a component that exposes Prometheus metrics on its metrics port, with the
+// panels of its Grafana dashboard (see AddGrafana)
+type ServiceMetrics interface {
+	// MetricsPath is the http path of the metrics on the metrics port
+	MetricsPath() string
+
+	// MetricsPanels are the panels of the dashboard of the service
+	MetricsPanels() []*MetricsPanel
+}
+
+// MetricsPanel is a panel of the Grafana dashboard of a service
+type MetricsPanel struct {
+	Title string
+
+	// Expr is the PromQL query of the panel, where %s is the label matcher of the service
+	// (i.e. reth_network_connected_peers{%s})
+	Expr string
+
+	// Unit is the Grafana unit of the values (i.e. s or bytes)
+	Unit string
+
+	// ChainHead is set for the panel with the head of the chain of the service, which is also
+	// part of the overview dashboard of the playground
+	ChainHead bool
+}
+
+var (
+	_ ServiceMetrics = &RethEL{}
+	_ ServiceMetrics = &OpReth{}
+	_ ServiceMetrics = &OpGeth{}
+	_ ServiceMetrics = &GethDev{}
+	_ ServiceMetrics = &OpNode{}
+	_ ServiceMetrics = &LighthouseBeaconNode{}
+)
+
+// grafanaDatasource is the uid of the provisioned Prometheus datasource of the dashboards
+const grafanaDatasource = "prometheus"
+
+// metricsService is a service of the manifest whose metrics are scraped
+type metricsService struct {
+	name    string
+	metrics ServiceMetrics
+}
+
+// AddGrafana adds Prometheus, which scrapes the services of the manifest that expose metrics,
+// and Grafana with a dashboard for each of them and an overview of the playground with their
+// chain heads (--with-grafana). The dashboards are generated for the services of the session,
+// so the queries use their names. It returns the outputs with the URLs of both.
+func AddGrafana(manifest *Manifest) (map[string]*RecipeOutput, error) {
+	services := []*metricsService{}
+	for _, svc := range manifest.Services() {
+		metrics, ok := svc.component.(ServiceMetrics)
+		if !ok {
+			continue
+		}
+		if _, ok := svc.GetPort("metrics"); !ok {
+			// the metrics of the service are not enabled (i.e. --el-metrics)
+			continue
+		}
+		services = append(services, &metricsService{name: svc.Name, metrics: metrics})
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("grafana requires services that expose metrics in the recipe")
+	}
+
+	targets := []*PrometheusTarget{}
+	dashboards := map[string]string{}
+	for _, svc := range services {
+		targets = append(targets, &PrometheusTarget{Service: svc.name, Path: svc.metrics.MetricsPath()})
+
+		data, err := serviceDashboard(svc)
+		if err != nil {
+			return nil, err
+		}
+		dashboards[svc.name] = data
+	}
+	data, err := overviewDashboard(services)
+	if err != nil {
+		return nil, err
+	}
+	dashboards["playground"] = data
+
+	manifest.AddService("prometheus", &Prometheus{
+		Targets: targets,
+	})
+	manifest.AddService("grafana", &Grafana{
+		Prometheus: "prometheus",
+		Dashboards: dashboards,
+	})
+	return map[string]*RecipeOutput{
+		"prometheus-http": OutputURL("http", "prometheus", "http"),
+		"grafana-http":    OutputURL("http", "grafana", "http"),
+	}, nil
+}
+
+type grafanaDashboard struct {
+	UID           string          `json:"uid"`
+	Title         string          `json:"title"`
+	Tags          []string        `json:"tags"`
+	SchemaVersion int             `json:"schemaVersion"`
+	Refresh       string          `json:"refresh"`
+	Time          grafanaTime     `json:"time"`
+	Panels        []*grafanaPanel `json:"panels"`
+}
+
+type grafanaTime struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type grafanaPanel struct {
+	ID          int                `json:"id"`
+	Type        string             `json:"type"`
+	Title       string             `json:"title"`
+	GridPos     grafanaGridPos     `json:"gridPos"`
+	Datasource  grafanaRef         `json:"datasource"`
+	FieldConfig grafanaFieldConfig `json:"fieldConfig"`
+	Targets     []*grafanaTarget   `json:"targets"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaRef struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaFieldConfig struct {
+	Defaults struct {
+		Unit string `json:"unit,omitempty"`
+	} `json:"defaults"`
+	Overrides []any `json:"overrides"`
+}
+
+type grafanaTarget struct {
+	RefID        string     `json:"refId"`
+	Datasource   grafanaRef `json:"datasource"`
+	Expr         string     `json:"expr"`
+	LegendFormat string     `json:"legendFormat"`
+}
+
+func newGrafanaDashboard(uid, title string) *grafanaDashboard {
+	return &grafanaDashboard{
+		UID:           uid,
+		Title:         title,
+		Tags:          []string{"playground"},
+		SchemaVersion: 39,
+		Refresh:       "5s",
+		Time:          grafanaTime{From: "now-15m", To: "now"},
+		Panels:        []*grafanaPanel{},
+	}
+}
+
+// panelQuery is the query of a panel for a service, whose name is its legend
+type panelQuery struct {
+	service string
+	expr    string
+}
+
+// addPanel adds a panel with the queries, two panels per row
+func (g *grafanaDashboard) addPanel(typ, title, unit string, queries []*panelQuery) {
+	i := len(g.Panels)
+	panel := &grafanaPanel{
+		ID:         i + 1,
+		Type:       typ,
+		Title:      title,
+		GridPos:    grafanaGridPos{H: 8, W: 12, X: (i % 2) * 12, Y: (i / 2) * 8},
+		Datasource: grafanaRef{Type: "prometheus", UID: grafanaDatasource},
+		Targets:    []*grafanaTarget{},
+	}
+	panel.FieldConfig.Defaults.Unit = unit
+	panel.FieldConfig.Overrides = []any{}
+	for _, query := range queries {
+		panel.Targets = append(panel.Targets, &grafanaTarget{
+			RefID:      string(rune('A' + len(panel.Targets))),
+			Datasource: panel.Datasource,
+			Expr:       query.expr,
+			// the legend is the name of the service, not a template of its labels
+			LegendFormat: query.service,
+		})
+	}
+	g.Panels = append(g.Panels, panel)
+}
+
+func (g *grafanaDashboard) encode() (string, error) {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode dashboard %s: %w", g.UID, err)
+	}
+	// the dashboards are rendered as templates like the other files of the services
+	if strings.Contains(string(data), "{{") {
+		return "", fmt.Errorf("dashboard %s cannot have template delimiters", g.UID)
+	}
+	return string(data), nil
+}
+
+// serviceQuery returns the query of the panel for the service
+func serviceQuery(panel *MetricsPanel, service string) *panelQuery {
+	return &panelQuery{
+		service: service,
+		expr:    fmt.Sprintf(panel.Expr, fmt.Sprintf(`service="%s"`, service)),
+	}
+}
+
+// serviceDashboard returns the dashboard with the panels of the service
+func serviceDashboard(svc *metricsService) (string, error) {
+	dashboard := newGrafanaDashboard("playground-"+svc.name, svc.name)
+	for _, panel := range svc.metrics.MetricsPanels() {
+		dashboard.addPanel("timeseries", panel.Title, panel.Unit, []*panelQuery{serviceQuery(panel, svc.name)})
+	}
+	return dashboard.encode()
+}
+
+// overviewDashboard returns the dashboard of the playground with the chain heads of the
+// services, one panel for all the services with the same chain head title, and whether
+// they are up
+func overviewDashboard(services []*metricsService) (string, error) {
+	dashboard := newGrafanaDashboard("playground", "Playground overview")
+
+	titles := []string{}
+	queries := map[string][]*panelQuery{}
+	for _, svc := range services {
+		for _, panel := range svc.metrics.MetricsPanels() {
+			if !panel.ChainHead {
+				continue
+			}
+			if _, ok := queries[panel.Title]; !ok {
+				titles = append(titles, panel.Title)
+			}
+			queries[panel.Title] = append(queries[panel.Title], serviceQuery(panel, svc.name))
+		}
+	}
+	for _, title := range titles {
+		dashboard.addPanel("timeseries", title, "", queries[title])
+	}
+
+	up := []*panelQuery{}
+	for _, svc := range services {
+		up = append(up, &panelQuery{service: svc.name, expr: fmt.Sprintf(`up{service="%s"}`, svc.name)})
+	}
+	dashboard.addPanel("stat", "Up", "", up)
+	return dashboard.encode()
+}
@@ -0,0 +1,197 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	watchdogStateRunning = "running"
+	watchdogStateFailed  = "failed"
+)
+
+// WatchdogStatus tracks the state of the watchdog of each service (see RunWatchdog)
+type WatchdogStatus struct {
+	lock   sync.Mutex
+	states map[string]*watchdogState
+}
+
+type watchdogState struct {
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+func NewWatchdogStatus() *WatchdogStatus {
+	return &WatchdogStatus{states: map[string]*watchdogState{}}
+}
+
+// update records that the watchdog of the service is running, or that it failed if err is set
+func (w *WatchdogStatus) update(name string, err error) {
+	if w == nil {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	state := &watchdogState{State: watchdogStateRunning}
+	if err != nil {
+		state.State, state.Error = watchdogStateFailed, err.Error()
+	}
+	w.states[name] = state
+}
+
+func (w *WatchdogStatus) snapshot() map[string]*watchdogState {
+	states := map[string]*watchdogState{}
+	if w == nil {
+		return states
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for name, state := range w.states {
+		states[name] = &watchdogState{State: state.State, Error: state.Error}
+	}
+	return states
+}
+
+// HealthServer reports the condition of the devnet to the external supervisors (i.e. systemd or
+// a CI job): /healthz returns 200 if every service is running and healthy and no watchdog failed,
+// and 503 with the problems otherwise. /status has the details in JSON.
+type HealthServer struct {
+	manifest  *Manifest
+	runner    *LocalRunner
+	watchdogs *WatchdogStatus
+	server    *http.Server
+}
+
+// NewHealthServer creates the health server. The watchdogs are nil if the watchdog is not enabled.
+func NewHealthServer(addr string, manifest *Manifest, runner *LocalRunner, watchdogs *WatchdogStatus) *HealthServer {
+	h := &HealthServer{
+		manifest:  manifest,
+		runner:    runner,
+		watchdogs: watchdogs,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", h.handleHealthz)
+	mux.HandleFunc("/status", h.handleStatus)
+
+	h.server = &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+	return h
+}
+
+// Run starts the HTTP server and blocks until it is closed
+func (h *HealthServer) Run() error {
+	if err := h.server.ListenAndServe(); err != http.ErrServerClosed {
+		return fmt.Errorf("health server error: %w", err)
+	}
+	return nil
+}
+
+func (h *HealthServer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return h.server.Shutdown(ctx)
+}
+
+type healthService struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Healthy *bool  `json:"healthy,omitempty"`
+}
+
+type healthStatus struct {
+	Healthy   bool                      `json:"healthy"`
+	Problems  []string                  `json:"problems,omitempty"`
+	Services  []*healthService          `json:"services"`
+	Chains    []*uiChain                `json:"chains"`
+	Watchdogs map[string]*watchdogState `json:"watchdogs"`
+}
+
+// status probes the services. The jobs that completed are healthy and the chain heads are
+// only queried if withChains is set.
+func (h *HealthServer) status(ctx context.Context, withChains bool) *healthStatus {
+	status := &healthStatus{
+		Services:  []*healthService{},
+		Chains:    []*uiChain{},
+		Watchdogs: h.watchdogs.snapshot(),
+	}
+
+	for _, svc := range h.manifest.Services() {
+		item := &healthService{
+			Name:   svc.Name,
+			Status: h.runner.TaskStatus(svc.Name),
+		}
+		if item.Status != taskStatusStarted && item.Status != taskStatusCompleted {
+			status.Problems = append(status.Problems, fmt.Sprintf("service %s is %s", svc.Name, item.Status))
+		}
+		if svc.readyCheck != nil && item.Status == taskStatusStarted {
+			err := svc.readyCheck.probe(svc)
+			healthy := err == nil
+			item.Healthy = &healthy
+			if err != nil {
+				status.Problems = append(status.Problems, fmt.Sprintf("service %s is not healthy: %v", svc.Name, err))
+			}
+		}
+		status.Services = append(status.Services, item)
+
+		chainName, ok := serviceChainName(svc)
+		if !withChains || !ok {
+			continue
+		}
+		chain := &uiChain{Name: chainName, Service: svc.Name}
+		if head, err := queryChainHead(ctx, "http://"+svc.hostAddr("http")); err != nil {
+			chain.Error = err.Error()
+		} else {
+			chain.Head = head
+		}
+		status.Chains = append(status.Chains, chain)
+	}
+
+	names := make([]string, 0, len(status.Watchdogs))
+	for name := range status.Watchdogs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if state := status.Watchdogs[name]; state.State == watchdogStateFailed {
+			status.Problems = append(status.Problems, fmt.Sprintf("watchdog of %s failed: %s", name, state.Error))
+		}
+	}
+
+	status.Healthy = len(status.Problems) == 0
+	return status
+}
+
+func (h *HealthServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	status := h.status(r.Context(), false)
+
+	w.Header().Set("Content-Type", "text/plain")
+	if !status.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, strings.Join(status.Problems, "\n"))
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (h *HealthServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := h.status(r.Context(), true)
+
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		watchdogLog.Warn("failed to encode health status", "error", err)
+	}
+}
@@ -0,0 +1,83 @@
+package playground
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+)
+
+// sleepService is a service that runs 'sleep' on the host
+type sleepService struct{}
+
+func (s *sleepService) Run(service *ServiceSpec, ctx *ExContext) {
+	service.WithArgs("30")
+}
+
+func (s *sleepService) Name() string {
+	return "sleep"
+}
+
+// TestHealthHostService checks that a service that runs on the host is started once its
+// process is running, there are no docker events for it
+func TestHealthHostService(t *testing.T) {
+	sleepPath, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	out := &output{dst: t.TempDir()}
+	manifest := NewManifest(&ExContext{}, out)
+	manifest.AddService("sleep", &sleepService{})
+
+	runner := &LocalRunner{
+		out:          out,
+		manifest:     manifest,
+		overrides:    map[string]string{"sleep": sleepPath},
+		tasks:        map[string]*task{},
+		taskUpdateCh: make(chan struct{}, 1),
+		exitErr:      make(chan error, 2),
+	}
+	svc := manifest.MustGetService("sleep")
+	if err := runner.trackService(svc); err != nil {
+		t.Fatalf("failed to track the service: %v", err)
+	}
+	if err := runner.runOnHost(svc); err != nil {
+		t.Fatalf("failed to run the service: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, handle := range runner.handles {
+			handle.Process.Kill()
+		}
+	})
+
+	if status := runner.TaskStatus("sleep"); status != taskStatusStarted {
+		t.Fatalf("expected the service to be %s, got %s", taskStatusStarted, status)
+	}
+	status := NewHealthServer("127.0.0.1:0", manifest, runner, nil).status(context.Background(), false)
+	if len(status.Problems) != 0 {
+		t.Fatalf("expected a healthy devnet, got %v", status.Problems)
+	}
+}
+
+func TestServiceChainName(t *testing.T) {
+	cases := []struct {
+		component Service
+		chain     string
+	}{
+		{&RethEL{}, "L1"},
+		{&GethDev{}, "L1"},
+		{&Anvil{}, "L1"},
+		{&OpGeth{}, "L2"},
+		{&OpReth{}, "L2"},
+	}
+	for _, c := range cases {
+		chain, ok := serviceChainName(&ServiceSpec{component: c.component})
+		if !ok || chain != c.chain {
+			t.Fatalf("%T: expected chain %s, got %s (%v)", c.component, c.chain, chain, ok)
+		}
+	}
+
+	if _, ok := serviceChainName(&ServiceSpec{component: &sleepService{}}); ok {
+		t.Fatal("expected no chain for a service that is not an EL")
+	}
+}
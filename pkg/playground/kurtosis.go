@@ -0,0 +1,284 @@
+package playground
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// kurtosisDir is the folder of the Kurtosis package inside the output folder
+const kurtosisDir = "kurtosis"
+
+// ExportKurtosis writes a Kurtosis package (kurtosis.yml and main.star) with the services of the
+// manifest to the kurtosis folder of the output folder. The artifacts (genesis, keystores, JWT
+// secrets and the config files of the services) are copied into the package and uploaded as a
+// files artifact mounted on /artifacts, like the output folder in the containers. The services
+// are added in the startup order of the runner and the jobs run to completion with run_sh. The
+// variables of the env files are not exported.
+func (s *Manifest) ExportKurtosis(recipe string) error {
+	order, err := s.startOrder()
+	if err != nil {
+		return err
+	}
+
+	// the JWT secrets and the config files are generated by the runner before the services start
+	secrets := map[string]bool{}
+	funcs := template.FuncMap{
+		"Service": func(name string, portLabel string) string {
+			return fmt.Sprintf("http://%s:%d", name, s.MustGetService(name).MustGetPort(portLabel).Port)
+		},
+		"Addr": func(name string, portLabel string) string {
+			return fmt.Sprintf("%s:%d", name, s.MustGetService(name).MustGetPort(portLabel).Port)
+		},
+		"HostPort": func(name string, portLabel string) int {
+			return s.MustGetService(name).MustGetPort(portLabel).Port
+		},
+		"HostName": func(name string) string {
+			return "localhost"
+		},
+		"JWTSecret": func(name string) (string, error) {
+			owner, err := s.jwtSecretOwner(name)
+			if err != nil {
+				return "", err
+			}
+			if !secrets[owner] {
+				secret, err := newJWTSecret()
+				if err != nil {
+					return "", err
+				}
+				if err := s.out.WriteFile(jwtSecretFile(owner), secret); err != nil {
+					return "", err
+				}
+				secrets[owner] = true
+			}
+			return "/artifacts/" + filepath.ToSlash(jwtSecretFile(owner)), nil
+		},
+		"Port": func(name string, defaultPort int) int {
+			return defaultPort
+		},
+		"Volume": func(name string) string {
+			return "/volumes/" + name
+		},
+	}
+	resolve := func(templates []string) ([]string, error) {
+		result := []string{}
+		for _, arg := range templates {
+			tpl, err := template.New("").Funcs(funcs).Parse(arg)
+			if err != nil {
+				return nil, err
+			}
+			var out strings.Builder
+			if err := tpl.Execute(&out, map[string]interface{}{"Dir": "/artifacts"}); err != nil {
+				return nil, err
+			}
+			result = append(result, out.String())
+		}
+		return result, nil
+	}
+
+	var star strings.Builder
+	star.WriteString("# Generated by builder-playground from the recipe " + quoteStar(recipe) + "\n\n")
+	star.WriteString("def run(plan):\n")
+	star.WriteString("    artifacts = plan.upload_files(src = \"./artifacts\", name = \"artifacts\")\n")
+
+	for _, svc := range order {
+		if len(svc.dataDirSeeds) != 0 {
+			return fmt.Errorf("service %s starts from an existing datadir, which the Kurtosis export does not support", svc.Name)
+		}
+		for name, content := range svc.files {
+			resolved, err := resolve([]string{content})
+			if err != nil {
+				return fmt.Errorf("failed to resolve file %s of service %s: %w", name, svc.Name, err)
+			}
+			if err := s.out.WriteFile(name, resolved[0]); err != nil {
+				return err
+			}
+		}
+
+		args, err := resolve(svc.args)
+		if err != nil {
+			return fmt.Errorf("failed to resolve the args of service %s: %w", svc.Name, err)
+		}
+
+		entrypoint := []string{}
+		if svc.entrypoint != "" {
+			entrypoint = []string{svc.entrypoint}
+		}
+		if len(svc.initArgs) > 0 {
+			// Kurtosis has no init containers, the init step runs in the same container before the service
+			initArgs, err := resolve(svc.initArgs)
+			if err != nil {
+				return fmt.Errorf("failed to resolve the init of service %s: %w", svc.Name, err)
+			}
+			script := quoteShell(append(entrypoint, initArgs...)) + " && exec " + quoteShell(append(entrypoint, args...))
+			entrypoint, args = []string{"/bin/sh", "-c"}, []string{script}
+		}
+
+		envVars := []string{}
+		if len(svc.env) > 0 {
+			keys := make([]string, 0, len(svc.env))
+			for k := range svc.env {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				value, err := resolve([]string{svc.env[k]})
+				if err != nil {
+					return fmt.Errorf("failed to resolve env %s of service %s: %w", k, svc.Name, err)
+				}
+				envVars = append(envVars, fmt.Sprintf("%s: %s", quoteStar(k), quoteStar(value[0])))
+			}
+		}
+		writeEnvVars := func(indent string) {
+			if len(envVars) == 0 {
+				return
+			}
+			fmt.Fprintf(&star, "%senv_vars = {\n", indent)
+			for _, envVar := range envVars {
+				fmt.Fprintf(&star, "%s    %s,\n", indent, envVar)
+			}
+			fmt.Fprintf(&star, "%s},\n", indent)
+		}
+
+		if svc.job {
+			// the jobs run to completion with run_sh before the services added after them
+			run := quoteShell(append(entrypoint, args...))
+			if len(svc.initArgs) > 0 {
+				run = args[0]
+			}
+			fmt.Fprintf(&star, "\n    plan.run_sh(\n")
+			fmt.Fprintf(&star, "        name = %s,\n", quoteStar(svc.Name))
+			fmt.Fprintf(&star, "        image = %s,\n", quoteStar(serviceImage(svc)))
+			fmt.Fprintf(&star, "        run = %s,\n", quoteStar(run))
+			writeEnvVars("        ")
+			fmt.Fprintf(&star, "        files = {\"/artifacts\": artifacts},\n")
+			fmt.Fprintf(&star, "        wait = None,\n")
+			fmt.Fprintf(&star, "    )\n")
+			continue
+		}
+
+		fmt.Fprintf(&star, "\n    plan.add_service(\n")
+		fmt.Fprintf(&star, "        name = %s,\n", quoteStar(svc.Name))
+		fmt.Fprintf(&star, "        config = ServiceConfig(\n")
+		fmt.Fprintf(&star, "            image = %s,\n", quoteStar(serviceImage(svc)))
+		if svc.user != "" {
+			uid, gid, ok := strings.Cut(svc.user, ":")
+			if ok {
+				fmt.Fprintf(&star, "            user = User(uid = %s, gid = %s),\n", uid, gid)
+			} else {
+				fmt.Fprintf(&star, "            user = User(uid = %s),\n", uid)
+			}
+		}
+		if len(entrypoint) > 0 {
+			quoted := []string{}
+			for _, arg := range entrypoint {
+				quoted = append(quoted, quoteStar(arg))
+			}
+			fmt.Fprintf(&star, "            entrypoint = [%s],\n", strings.Join(quoted, ", "))
+		}
+		if len(args) > 0 {
+			fmt.Fprintf(&star, "            cmd = [\n")
+			for _, arg := range args {
+				fmt.Fprintf(&star, "                %s,\n", quoteStar(arg))
+			}
+			fmt.Fprintf(&star, "            ],\n")
+		}
+		if len(svc.ports) > 0 {
+			fmt.Fprintf(&star, "            ports = {\n")
+			for _, port := range svc.ports {
+				protocol := ""
+				if port.Protocol == PortProtocolHTTP || port.Protocol == PortProtocolWS {
+					protocol = fmt.Sprintf(", application_protocol = %s", quoteStar(string(port.Protocol)))
+				}
+				fmt.Fprintf(&star, "                %s: PortSpec(number = %d, transport_protocol = \"TCP\"%s),\n", quoteStar(port.Name), port.Port, protocol)
+			}
+			fmt.Fprintf(&star, "            },\n")
+		}
+		writeEnvVars("            ")
+		if len(svc.volumes) == 0 {
+			fmt.Fprintf(&star, "            files = {\"/artifacts\": artifacts},\n")
+		} else {
+			// the shared volumes are persistent directories with the name of the volume
+			fmt.Fprintf(&star, "            files = {\n")
+			fmt.Fprintf(&star, "                \"/artifacts\": artifacts,\n")
+			for _, name := range svc.volumes {
+				fmt.Fprintf(&star, "                %s: Directory(persistent_key = %s),\n", quoteStar("/volumes/"+name), quoteStar(name))
+			}
+			fmt.Fprintf(&star, "            },\n")
+		}
+		if check := svc.readyCheck; check != nil && check.Path != "" {
+			// the ready checks without a path are the TCP checks that Kurtosis does for every port
+			fmt.Fprintf(&star, "            ready_conditions = ReadyCondition(\n")
+			fmt.Fprintf(&star, "                recipe = GetHttpRequestRecipe(port_id = %s, endpoint = %s),\n", quoteStar(check.PortLabel), quoteStar(check.Path))
+			fmt.Fprintf(&star, "                field = \"code\",\n")
+			fmt.Fprintf(&star, "                assertion = \"==\",\n")
+			fmt.Fprintf(&star, "                target_value = 200,\n")
+			fmt.Fprintf(&star, "            ),\n")
+		}
+		fmt.Fprintf(&star, "        ),\n")
+		fmt.Fprintf(&star, "    )\n")
+	}
+
+	kurtosisYml := fmt.Sprintf("name: github.com/builder-playground/%s\ndescription: Devnet exported by builder-playground\n", recipe)
+	if err := s.out.WriteFile(filepath.Join(kurtosisDir, "kurtosis.yml"), kurtosisYml); err != nil {
+		return err
+	}
+	if err := s.out.WriteFile(filepath.Join(kurtosisDir, "main.star"), star.String()); err != nil {
+		return err
+	}
+	return s.copyKurtosisArtifacts()
+}
+
+// copyKurtosisArtifacts copies the output folder, without the logs and the package itself, to the
+// artifacts folder of the package. The services have not run, so the data folders only have the
+// artifacts (i.e. the validator keystores).
+func (s *Manifest) copyKurtosisArtifacts() error {
+	root, err := s.out.AbsoluteDstPath()
+	if err != nil {
+		return err
+	}
+	dst := filepath.Join(kurtosisDir, "artifacts")
+	if err := s.out.Remove(dst); err != nil {
+		return err
+	}
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && (rel == kurtosisDir || rel == "logs") {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return s.out.CopyFile(path, filepath.Join(dst, rel))
+	})
+}
+
+// quoteStar quotes a string for Starlark, which accepts the JSON string escapes
+func quoteStar(s string) string {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// quoteShell joins the args in a shell command, with every arg in single quotes
+func quoteShell(args []string) string {
+	quoted := []string{}
+	for _, arg := range args {
+		quoted = append(quoted, "'"+strings.ReplaceAll(arg, "'", `'\''`)+"'")
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,1412 @@
+package playground
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v2"
+)
+
+var runnerLog = Logger("runner")
+
+const networkPrefix = "ethplayground"
+
+// LocalRunner is a component that runs the services from the manifest on the local host machine.
+// By default, it uses docker and docker compose to run all the services.
+// But, some services (if they are configured to do so) can be run on the host machine instead.
+// When running inside docker, each service will use the port numbers they define in the component description.
+// Besides, they will also bind to an available public port on the host machine.
+// If the service runs on the host, it will use the host port numbers instead directly.
+type LocalRunner struct {
+	out      *output
+	manifest *Manifest
+	client   *client.Client
+
+	// session namespaces the docker compose project, network and containers
+	// so that multiple devnets can run on the same host
+	session *Session
+
+	// reservedPorts is a map of port numbers reserved for each service to avoid conflicts
+	// since we reserve ports for all the services before they are used
+	reservedPorts map[int]bool
+
+	// overrides is a map of service name to the path of the executable to run
+	// on the host machine instead of a container.
+	overrides map[string]string
+
+	// handles stores the references to the processes that are running on host machine
+	// they are executed sequentially so we do not need to lock the handles
+	handles []*exec.Cmd
+
+	// exitError signals when one of the services fails
+	exitErr chan error
+
+	// signals whether we are running in interactive mode
+	interactive bool
+
+	// dockerDesktop signals whether the docker daemon is provided by Docker Desktop (Macos, Windows or WSL2).
+	// Docker Desktop already resolves host.docker.internal to the host machine.
+	dockerDesktop bool
+
+	// podman signals whether the Docker API is provided by podman (see engine.go)
+	podman bool
+
+	// rootless signals whether the engine runs without root, so the host user is root inside
+	// the containers and the other users of the containers are not users of the host
+	rootless bool
+
+	// offline signals whether the services run in a network without external egress.
+	// The services in egressServices are also attached to a network with egress.
+	offline        bool
+	egressServices map[string]bool
+
+	// hostNames signals whether the services running on the host reach the other services
+	// by their names, like the containers do, instead of localhost (see discovery.go)
+	hostNames bool
+
+	// jwtSecrets are the JWT secrets generated for each execution node (see secrets.go)
+	jwtSecretsMtx sync.Mutex
+	jwtSecrets    map[string]string
+
+	// tasks tracks the status of each service
+	tasksMtx     sync.Mutex
+	tasks        map[string]*task
+	taskUpdateCh chan struct{}
+
+	// stopping signals that the containers are being removed, so they are not restarted
+	stopping bool
+
+	// restartPolicy is the restart policy of the services without their own, which can be
+	// overridden for each service in serviceRestartPolicies (see restart.go)
+	restartPolicy          *RestartPolicy
+	serviceRestartPolicies map[string]*RestartPolicy
+
+	// bindAddr is the host interface the published ports bind to, which can be
+	// overridden for each service in serviceBindAddrs
+	bindAddr         string
+	serviceBindAddrs map[string]string
+
+	// user is the user the containers run as, which can be overridden for each service in
+	// serviceUsers (see permissions.go). If empty, they run as the user of the service or its image.
+	user         string
+	serviceUsers map[string]string
+
+	// lock pins the images to the digests of a previous run (see lockfile.go)
+	lock *Lockfile
+
+	// scaleMtx serializes the changes of the scalable groups of the manifest (see scale.go)
+	scaleMtx sync.Mutex
+
+	// follower writes the logs of the services to the console (see follow_logs.go)
+	follower *logFollower
+
+	// pprof are the last goroutine dumps of the services for their crash dumps (see crash.go)
+	pprof pprofDumps
+
+	// remote runs the containers on the docker daemon of a remote host (see remote.go)
+	remote *RemoteHost
+}
+
+type task struct {
+	status string
+	logs   *logFile
+
+	// logsDone is closed when the logs of the current container are fully written
+	logsDone chan struct{}
+
+	// restartCount is the number of restarts by the restart policy and restarts the time of
+	// the recent ones to detect crash loops
+	restartCount int
+	restarts     []time.Time
+
+	// expectRestart signals that the runner restarts the container, so its exit is not a failure
+	expectRestart bool
+}
+
+var (
+	taskStatusPending    = "pending"
+	taskStatusStarted    = "started"
+	taskStatusRestarting = "restarting"
+	taskStatusCompleted  = "completed"
+	taskStatusDie        = "die"
+
+	// taskStatusStopped is a service stopped on purpose (see ExportSlashingProtection)
+	taskStatusStopped = "stopped"
+)
+
+type taskUI struct {
+	tasks    map[string]string
+	spinners map[string]spinner.Model
+	style    lipgloss.Style
+}
+
+func NewLocalRunner(out *output, manifest *Manifest, overrides map[string]string, interactive bool, session *Session) (*LocalRunner, error) {
+	client, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	// Check that the docker daemon is reachable. On Windows this goes through the
+	// Docker Desktop named pipe, so it is the most common failure.
+	info, err := client.Info(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the docker daemon (is Docker Desktop or the podman socket running?): %w", err)
+	}
+
+	d, err := newLocalRunner(out, manifest, overrides, session)
+	if err != nil {
+		return nil, err
+	}
+	d.client = client
+	d.dockerDesktop = info.OperatingSystem == "Docker Desktop"
+	d.podman = isPodmanEngine(context.Background(), client)
+	d.rootless = slices.ContainsFunc(info.SecurityOptions, func(opt string) bool { return strings.Contains(opt, "name=rootless") })
+
+	if interactive {
+		go d.printStatus()
+
+		select {
+		case d.taskUpdateCh <- struct{}{}:
+		default:
+		}
+	}
+
+	return d, nil
+}
+
+// newLocalRunner creates the runner without the docker client
+func newLocalRunner(out *output, manifest *Manifest, overrides map[string]string, session *Session) (*LocalRunner, error) {
+	// merge the overrides with the manifest overrides
+	if overrides == nil {
+		overrides = make(map[string]string)
+	}
+	for k, v := range manifest.overrides {
+		overrides[k] = v
+	}
+
+	tasks := map[string]*task{}
+	for _, svc := range manifest.services {
+		tasks[svc.Name] = &task{
+			status: taskStatusPending,
+			logs:   nil,
+		}
+	}
+
+	// do not reserve the ports used by the other sessions running on the host
+	reservedPorts := map[int]bool{}
+	sessions, err := readSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions: %w", err)
+	}
+	for _, other := range sessions {
+		if other.Name == session.Name {
+			continue
+		}
+		for _, port := range other.Ports {
+			reservedPorts[port] = true
+		}
+	}
+
+	return &LocalRunner{
+		out:           out,
+		manifest:      manifest,
+		session:       session,
+		reservedPorts: reservedPorts,
+		overrides:     overrides,
+		handles:       []*exec.Cmd{},
+		tasks:         tasks,
+		taskUpdateCh:  make(chan struct{}),
+		exitErr:       make(chan error, 2),
+		jwtSecrets:    map[string]string{},
+		// the devnet RPC endpoints are not meant to be exposed outside of the host
+		bindAddr:               "127.0.0.1",
+		serviceBindAddrs:       map[string]string{},
+		serviceUsers:           map[string]string{},
+		restartPolicy:          &RestartPolicy{Mode: RestartNever},
+		serviceRestartPolicies: map[string]*RestartPolicy{},
+	}, nil
+}
+
+// EnableOffline runs the services in a network without access to the outside world so that the
+// devnet is hermetic. The allowEgress services are the exceptions that can still reach it.
+// Services running on the host are not affected.
+func (d *LocalRunner) EnableOffline(allowEgress []string) error {
+	if d.podman {
+		// podman networks do not support disabling the masquerading
+		return fmt.Errorf("offline mode is not supported with podman")
+	}
+	d.offline = true
+	d.egressServices = map[string]bool{}
+	for _, name := range allowEgress {
+		if _, ok := d.manifest.GetService(name); !ok {
+			return fmt.Errorf("egress exception for unknown service '%s'", name)
+		}
+		d.egressServices[name] = true
+	}
+	return nil
+}
+
+// SetBindAddress sets the IP of the host interface that the published ports of the service bind to,
+// or of all the services if the name is empty. By default, they bind to the loopback interface.
+// The services running on the host are not affected.
+func (d *LocalRunner) SetBindAddress(name string, addr string) error {
+	if net.ParseIP(addr) == nil {
+		return fmt.Errorf("invalid bind address '%s', expected an IP address", addr)
+	}
+	if name == "" {
+		d.bindAddr = addr
+		return nil
+	}
+	if _, ok := d.manifest.GetService(name); !ok {
+		return fmt.Errorf("bind address for unknown service '%s'", name)
+	}
+	d.serviceBindAddrs[name] = addr
+	return nil
+}
+
+// bindAddress returns the host IP the ports of the service bind to, in the format of the
+// docker compose ports
+func (d *LocalRunner) bindAddress(s *ServiceSpec) string {
+	addr, ok := d.serviceBindAddrs[s.Name]
+	if !ok {
+		addr = d.bindAddr
+	}
+	if net.ParseIP(addr).To4() == nil {
+		return "[" + addr + "]"
+	}
+	return addr
+}
+
+// hostIP returns the address the clients on the host dial to reach the published ports of the
+// service: the bind address, or empty for localhost if the ports bind to the loopback or to
+// all the interfaces. The services on the host and the ports forwarded from a remote host
+// are on localhost.
+func (d *LocalRunner) hostIP(s *ServiceSpec) string {
+	if d.isHostService(s.Name) || d.remote != nil {
+		return ""
+	}
+	addr, ok := d.serviceBindAddrs[s.Name]
+	if !ok {
+		addr = d.bindAddr
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.IsLoopback() || ip.IsUnspecified() {
+		return ""
+	}
+	return ip.String()
+}
+
+func (d *LocalRunner) printStatus() {
+	fmt.Print("\033[s")
+	lineOffset := 0
+
+	// Initialize UI state
+	ui := taskUI{
+		tasks:    make(map[string]string),
+		spinners: make(map[string]spinner.Model),
+		style:    lipgloss.NewStyle(),
+	}
+
+	for {
+		select {
+		case <-d.taskUpdateCh:
+			d.tasksMtx.Lock()
+
+			// Get ordered service names from manifest, the services change if the devnet is scaled
+			orderedServices := make([]string, 0, len(d.manifest.Services()))
+			for _, svc := range d.manifest.Services() {
+				if _, ok := d.tasks[svc.Name]; !ok {
+					continue
+				}
+				orderedServices = append(orderedServices, svc.Name)
+
+				// Initialize spinners for each service
+				if _, ok := ui.spinners[svc.Name]; !ok {
+					sp := spinner.New()
+					sp.Spinner = spinner.Dot
+					ui.spinners[svc.Name] = sp
+				}
+			}
+
+			// Clear the previous lines and move cursor up
+			if lineOffset > 0 {
+				fmt.Printf("\033[%dA", lineOffset)
+				fmt.Print("\033[J")
+			}
+
+			lineOffset = 0
+			// Use ordered services instead of ranging over map
+			for _, name := range orderedServices {
+				status := d.tasks[name].status
+				var statusLine string
+
+				switch status {
+				case taskStatusStarted:
+					sp := ui.spinners[name]
+					sp.Tick()
+					ui.spinners[name] = sp
+					statusLine = ui.style.Foreground(lipgloss.Color("2")).Render(fmt.Sprintf("%s [%s] Running", sp.View(), name))
+				case taskStatusRestarting:
+					statusLine = ui.style.Foreground(lipgloss.Color("3")).Render(fmt.Sprintf("↻ [%s] Restarting", name))
+				case taskStatusCompleted:
+					statusLine = ui.style.Foreground(lipgloss.Color("2")).Render(fmt.Sprintf("✓ [%s] Completed", name))
+				case taskStatusDie:
+					statusLine = ui.style.Foreground(lipgloss.Color("1")).Render(fmt.Sprintf("✗ [%s] Failed", name))
+				case taskStatusPending:
+					sp := ui.spinners[name]
+					sp.Tick()
+					ui.spinners[name] = sp
+					statusLine = ui.style.Foreground(lipgloss.Color("3")).Render(fmt.Sprintf("%s [%s] Pending", sp.View(), name))
+				}
+
+				fmt.Println(statusLine)
+				lineOffset++
+			}
+
+			d.tasksMtx.Unlock()
+		}
+	}
+}
+
+func (d *LocalRunner) updateTaskStatus(name string, status string) {
+	d.tasksMtx.Lock()
+	defer d.tasksMtx.Unlock()
+
+	task, ok := d.tasks[name]
+	if !ok {
+		// the service was removed when the devnet was scaled down
+		return
+	}
+	task.status = status
+
+	select {
+	case d.taskUpdateCh <- struct{}{}:
+	default:
+	}
+}
+
+// TaskStatus returns the status of the service (pending, started, restarting or die)
+func (d *LocalRunner) TaskStatus(name string) string {
+	d.tasksMtx.Lock()
+	defer d.tasksMtx.Unlock()
+
+	task, ok := d.tasks[name]
+	if !ok {
+		return ""
+	}
+	return task.status
+}
+
+func (d *LocalRunner) ExitErr() <-chan error {
+	return d.exitErr
+}
+
+// sessionFilters returns the filters to select the containers, the networks and the volumes
+// of this session
+func (d *LocalRunner) sessionFilters() filters.Args {
+	return filters.NewArgs(
+		filters.Arg("label", "playground=true"),
+		filters.Arg("label", sessionLabel+"="+d.session.Name),
+	)
+}
+
+func (d *LocalRunner) networkName() string {
+	return networkPrefix + "-" + d.session.Name
+}
+
+func (d *LocalRunner) egressNetworkName() string {
+	return d.networkName() + "-egress"
+}
+
+// volumeName is the name of the docker volume of a shared volume of the manifest
+func (d *LocalRunner) volumeName(name string) string {
+	return networkPrefix + "-" + d.session.Name + "-" + name
+}
+
+// isHostVolume returns true if a service that runs on the host mounts the volume. Those volumes
+// are a folder of the output folder bind mounted in the containers instead of docker volumes.
+func (d *LocalRunner) isHostVolume(name string) bool {
+	for _, svc := range d.manifest.services {
+		if d.isHostService(svc.Name) && slices.Contains(svc.volumes, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// volumePath returns the path of the shared volume from the point of view of the service
+func (d *LocalRunner) volumePath(s *ServiceSpec, name string) (string, error) {
+	if !slices.Contains(s.volumes, name) {
+		return "", fmt.Errorf("service %s uses volume %s, but it does not mount it", s.Name, name)
+	}
+	if d.isHostService(s.Name) {
+		return filepath.Join(d.out.dst, "volumes", name), nil
+	}
+	return "/volumes/" + name, nil
+}
+
+func (d *LocalRunner) Stop() error {
+	d.tasksMtx.Lock()
+	d.stopping = true
+	d.tasksMtx.Unlock()
+
+	// the errors do not stop the cleanup, the processes on the host are killed and the session
+	// is removed anyway. The resources left behind are removed by 'playground prune'.
+	var errs []error
+	resources, err := listSessionResources(context.Background(), d.client, d.sessionFilters())
+	if err != nil {
+		errs = append(errs, err)
+	}
+	containers, others := []*SessionResource{}, []*SessionResource{}
+	for _, res := range resources {
+		if res.Kind == "container" {
+			containers = append(containers, res)
+		} else {
+			others = append(others, res)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(containers))
+
+	errCh := make(chan error, len(containers))
+	for _, cont := range containers {
+		go func(contID string) {
+			defer wg.Done()
+			if err := d.client.ContainerRemove(context.Background(), contID, container.RemoveOptions{
+				RemoveVolumes: true,
+				RemoveLinks:   false,
+				Force:         true,
+			}); err != nil {
+				errCh <- fmt.Errorf("error removing container: %w", err)
+			}
+		}(cont.ID)
+	}
+
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+
+	// the networks and the shared volumes are removed once there are no containers that use them
+	if err := removeSessionResources(context.Background(), d.client, others); err != nil {
+		errs = append(errs, err)
+	}
+
+	// stop all the handles
+	for _, handle := range d.handles {
+		handle.Process.Kill()
+	}
+
+	if err := removeSession(d.session.Name); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove session: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// reservePort finds the first available port from the startPort and reserves it
+// Note that we have to keep track of the port in 'reservedPorts' because
+// the port allocation happens before the services uses it and binds to it.
+func (d *LocalRunner) reservePort(startPort int) int {
+	for i := startPort; i < startPort+1000; i++ {
+		if _, ok := d.reservedPorts[i]; ok {
+			continue
+		}
+		// make a net.Listen on the port to see if it is aavailable
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", i))
+		if err != nil {
+			continue
+		}
+		listener.Close()
+		d.reservedPorts[i] = true
+		return i
+	}
+	panic("BUG: could not reserve a port")
+}
+
+func (d *LocalRunner) getService(name string) *ServiceSpec {
+	for _, svc := range d.manifest.services {
+		if svc.Name == name {
+			return svc
+		}
+	}
+	return nil
+}
+
+// applyTemplate resolves the templates from the manifest (Dir, Port, Connect) into
+// the actual values for this specific docker execution.
+func (d *LocalRunner) applyTemplate(s *ServiceSpec) ([]string, error) {
+	return d.resolveTemplates(s, s.args)
+}
+
+// resolveTemplates resolves a list of templates from the point of view of the service
+func (d *LocalRunner) resolveTemplates(s *ServiceSpec, templates []string) ([]string, error) {
+	var input map[string]interface{}
+
+	// For {{.Dir}}:
+	// - Service runs on host: bind to the output folder
+	// - Service runs inside docker: bind to /artifacts
+	if d.isHostService(s.Name) {
+		input = map[string]interface{}{
+			"Dir": d.out.dst,
+		}
+	} else {
+		input = map[string]interface{}{
+			"Dir": "/artifacts",
+		}
+	}
+
+	// addr is the host:port where the service reaches the port of another service
+	addr := func(name string, portLabel string) string {
+		// For {{Service "name" "portLabel"}} and {{Addr "name" "portLabel"}}:
+		// - Service runs on host:
+		//   A: target is inside docker: access with localhost:hostPort
+		//   B: target is on the host: access with localhost:hostPort
+		// - Service runs inside docker:
+		//   C: target is inside docker: access it with DNS service:port
+		//   D: target is on the host: access it with host.docker.internal:hostPort
+
+		// find the service and the port that it resolves for that label
+		svc := d.manifest.MustGetService(name)
+		port := svc.MustGetPort(portLabel)
+
+		if d.isHostService(s.Name) {
+			// A and B
+			return net.JoinHostPort(d.addrFromHost(svc), strconv.Itoa(port.HostPort))
+		} else {
+			if d.isHostService(svc.Name) {
+				// D
+				return fmt.Sprintf("%s:%d", d.hostServiceAddrFromDocker(svc.Name), port.HostPort)
+			}
+			// C
+			return fmt.Sprintf("%s:%d", svc.Name, port.Port)
+		}
+	}
+
+	funcs := template.FuncMap{
+		"Service": func(name string, portLabel string) string {
+			return "http://" + addr(name, portLabel)
+		},
+		"Addr": func(name string, portLabel string) string {
+			// For {{Addr "name" "portLabel"}}: like Service without the http scheme
+			return addr(name, portLabel)
+		},
+		"Volume": func(name string) (string, error) {
+			// For {{Volume "name"}}: the path where the service mounts the shared volume
+			return d.volumePath(s, name)
+		},
+		"HostPort": func(name string, portLabel string) int {
+			// For {{HostPort "name" "portLabel"}}: the port of the service on the host, used for the
+			// addresses that are reached from outside of the playground (i.e. from the browser)
+			return d.manifest.MustGetService(name).MustGetPort(portLabel).HostPort
+		},
+		"HostName": func(name string) string {
+			// For {{HostName "name"}}: the host of the ports of the service on the host (see HostPort)
+			return d.manifest.MustGetService(name).hostName()
+		},
+		"JWTSecret": func(name string) (string, error) {
+			path, err := d.jwtSecret(name)
+			if err != nil {
+				return "", err
+			}
+			return input["Dir"].(string) + "/" + filepath.ToSlash(path), nil
+		},
+		"Port": func(name string, defaultPort int) int {
+			// For {{Port "name" "defaultPort"}}:
+			// - Service runs on host: return the host port
+			// - Service runs inside docker: return the docker port
+			if d.isHostService(s.Name) {
+				return s.MustGetPort(name).HostPort
+			}
+			return defaultPort
+		},
+	}
+
+	var argsResult []string
+	for _, arg := range templates {
+		tpl, err := template.New("").Funcs(funcs).Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		var out strings.Builder
+		if err := tpl.Execute(&out, input); err != nil {
+			return nil, err
+		}
+		argsResult = append(argsResult, out.String())
+	}
+
+	return argsResult, nil
+}
+
+func (d *LocalRunner) toDockerComposeService(s *ServiceSpec) (map[string]interface{}, error) {
+	// apply the template again on the arguments to figure out the connections
+	// at this point all of them are valid, we just have to resolve them again. We assume for now
+	// everyone is going to be on docker at the same network.
+	args, err := d.applyTemplate(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply template, err: %w", err)
+	}
+
+	// The containers have access to the full set of artifacts on the /artifacts folder
+	// so, we have to bind it as a volume on the container.
+	outputFolder, err := d.out.AbsoluteDstPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for output folder: %w", err)
+	}
+	if d.remote != nil {
+		// the copy of the output folder on the remote host
+		outputFolder = d.remote.Dir
+	}
+
+	volumes := d.serviceMounts(s, outputFolder)
+
+	service := map[string]interface{}{
+		"image":   d.imageRef(s),
+		"command": args,
+		// Add volume mounts for the output directory and the shared volumes
+		"volumes": volumes,
+		// Add the ethereum network
+		"networks": d.serviceNetworks(s),
+		// It is important to use the playground and session labels to identify the containers
+		// during the cleanup process
+		"labels": d.session.labels(),
+	}
+
+	if (runtime.GOOS == "linux" || d.remote != nil) && !d.dockerDesktop && !d.podman {
+		// We rely on host.docker.internal as the DNS address for the host inside
+		// the container. But, this is only available with Docker Desktop (Macos, Windows and WSL2).
+		// On Linux, you can use the IP address 172.17.0.1 to access the host.
+		// Thus, if we are running on Linux with a native docker engine, we need to add an extra host entry.
+		// Podman already resolves host.docker.internal to the host, also in rootless mode.
+		service["extra_hosts"] = map[string]string{
+			"host.docker.internal": "172.17.0.1",
+		}
+	}
+	if extraHosts := d.hostServiceEntries(); len(extraHosts) > 0 {
+		// resolve the names of the services running on the host to the host machine
+		hosts, _ := service["extra_hosts"].(map[string]string)
+		if hosts == nil {
+			hosts = map[string]string{}
+		}
+		for name, addr := range extraHosts {
+			hosts[name] = addr
+		}
+		service["extra_hosts"] = hosts
+	}
+
+	if s.entrypoint != "" {
+		service["entrypoint"] = s.entrypoint
+	}
+	if user := d.containerUser(s); user != "" {
+		service["user"] = user
+	}
+
+	env, envFiles, err := d.composeEnv(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(env) > 0 {
+		service["environment"] = env
+	}
+	if len(envFiles) > 0 {
+		service["env_file"] = envFiles
+	}
+
+	if platform := d.resolvePlatform(s); platform != "" {
+		service["platform"] = platform
+	}
+
+	if len(s.dependsOn) > 0 {
+		// The runner starts the services one by one in dependency order, but we still
+		// include the dependencies in the compose file in case it gets used directly.
+		dependsOn := map[string]interface{}{}
+		for _, dep := range s.dependsOn {
+			if d.isHostService(dep.Service) {
+				continue
+			}
+			condition := "service_started"
+			if dep.Condition == DependsOnConditionCompleted {
+				condition = "service_completed_successfully"
+			}
+			dependsOn[dep.Service] = map[string]string{"condition": condition}
+		}
+		if len(dependsOn) > 0 {
+			service["depends_on"] = dependsOn
+		}
+	}
+
+	if len(s.ports) > 0 {
+		service["ports"] = d.servicePorts(s)
+	}
+
+	return service, nil
+}
+
+// serviceMounts returns the bind mounts of the container of the service in the format of
+// docker compose, with the output folder at /artifacts and the shared volumes at /volumes
+func (d *LocalRunner) serviceMounts(s *ServiceSpec, outputFolder string) []string {
+	volumes := []string{
+		fmt.Sprintf("%s:/artifacts", toDockerMountPath(outputFolder)),
+	}
+	for _, name := range s.volumes {
+		source := name
+		if d.isHostVolume(name) {
+			source = toDockerMountPath(filepath.Join(outputFolder, "volumes", name))
+		}
+		volumes = append(volumes, fmt.Sprintf("%s:/volumes/%s", source, name))
+	}
+	for dataDir, seed := range s.dataDirSeeds {
+		if seed.Mode == DataDirModeBind {
+			volumes = append(volumes, fmt.Sprintf("%s:/artifacts/%s", toDockerMountPath(seed.Path), filepath.ToSlash(dataDir)))
+		}
+	}
+	return volumes
+}
+
+// servicePorts returns the published ports of the service in the format of docker compose
+func (d *LocalRunner) servicePorts(s *ServiceSpec) []string {
+	ports := []string{}
+	for _, p := range s.ports {
+		ports = append(ports, fmt.Sprintf("%s:%d:%d", d.bindAddress(s), p.HostPort, p.Port))
+	}
+	return ports
+}
+
+// resolvePlatform returns the platform of the image to run for the service. If the service does not
+// request a specific platform, it uses the variant of the image that matches the host architecture
+// and, if there is none, it falls back to an emulated platform with a warning.
+func (d *LocalRunner) resolvePlatform(s *ServiceSpec) string {
+	if s.platform != "" {
+		return s.platform
+	}
+	if d.client == nil {
+		// the dry runs plan the services without docker
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	image := d.imageRef(s)
+	inspect, err := d.client.DistributionInspect(ctx, image, "")
+	if err != nil {
+		// the image might be local only, let docker decide
+		runnerLog.Debug("failed to inspect image platforms", "image", image, "err", err)
+		return ""
+	}
+
+	hostPlatform := "linux/" + runtime.GOARCH
+	var fallback string
+	for _, p := range inspect.Platforms {
+		if p.OS != "linux" {
+			continue
+		}
+		if p.Architecture == runtime.GOARCH {
+			return hostPlatform
+		}
+		if fallback == "" {
+			fallback = p.OS + "/" + p.Architecture
+		}
+	}
+	if fallback == "" {
+		return ""
+	}
+
+	runnerLog.Warn("image not available for the host platform, running under emulation", "service", s.Name, "image", image, "host", hostPlatform, "platform", fallback)
+	return fallback
+}
+
+// toDockerMountPath converts a host path into the format expected by docker compose
+// bind mounts. On Windows, paths like C:\Users\foo are converted to C:/Users/foo
+// which Docker Desktop translates to the WSL2 VM.
+func toDockerMountPath(path string) string {
+	if runtime.GOOS != "windows" {
+		return path
+	}
+	return filepath.ToSlash(path)
+}
+
+func (d *LocalRunner) isHostService(name string) bool {
+	_, ok := d.overrides[name]
+	return ok
+}
+
+func (d *LocalRunner) serviceNetworks(s *ServiceSpec) []string {
+	networks := []string{d.networkName()}
+	if d.offline && d.egressServices[s.Name] {
+		networks = append(networks, d.egressNetworkName())
+	}
+	return networks
+}
+
+func (d *LocalRunner) generateDockerCompose() ([]byte, error) {
+	// We create a new network to be used by all the services so that
+	// we can do DNS discovery between them.
+	network := map[string]interface{}{
+		"name":   d.networkName(),
+		"labels": d.session.labels(),
+	}
+	networks := map[string]interface{}{
+		d.networkName(): network,
+	}
+	if d.offline {
+		// An 'internal' network would also disable the published ports that we use to reach
+		// the services from the host. Instead, we disable the masquerading of the bridge network
+		// so that the traffic from the containers cannot be routed outside of the host.
+		network["driver_opts"] = map[string]string{
+			"com.docker.network.bridge.enable_ip_masquerade": "false",
+		}
+		if len(d.egressServices) > 0 {
+			networks[d.egressNetworkName()] = map[string]interface{}{
+				"name":   d.egressNetworkName(),
+				"labels": d.session.labels(),
+			}
+		}
+	}
+	compose := map[string]interface{}{
+		"networks": networks,
+	}
+
+	services := map[string]interface{}{}
+
+	// for each service, reserve a port on the host machine. We use this ports
+	// both to have access to the services from localhost but also to do communication
+	// between services running inside docker and the ones running on the host machine.
+	// The ports reserved before (if the devnet is scaled) do not change.
+	for _, svc := range d.manifest.services {
+		for _, port := range svc.ports {
+			if port.HostPort == 0 {
+				port.HostPort = d.reservePort(port.Port)
+			}
+		}
+		svc.hostIP = d.hostIP(svc)
+	}
+
+	for _, svc := range d.manifest.services {
+		if d.isHostService(svc.Name) {
+			// skip services that are going to be launched on host
+			continue
+		}
+		var err error
+		if services[svc.Name], err = d.toDockerComposeService(svc); err != nil {
+			return nil, fmt.Errorf("failed to convert service %s to docker compose service: %w", svc.Name, err)
+		}
+	}
+
+	compose["services"] = services
+
+	// the shared volumes not used from the host are docker volumes with the labels of the session
+	volumes := map[string]interface{}{}
+	for _, svc := range d.manifest.services {
+		for _, name := range svc.volumes {
+			if d.isHostVolume(name) {
+				continue
+			}
+			volumes[name] = map[string]interface{}{
+				"name":   d.volumeName(name),
+				"labels": d.session.labels(),
+			}
+		}
+	}
+	if len(volumes) > 0 {
+		compose["volumes"] = volumes
+	}
+	yamlData, err := yaml.Marshal(compose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal docker compose: %w", err)
+	}
+
+	return yamlData, nil
+}
+
+// runOnHost runs the service on the host machine
+func (d *LocalRunner) runOnHost(ss *ServiceSpec) error {
+	args, err := d.applyTemplate(ss)
+	if err != nil {
+		return fmt.Errorf("failed to apply template, err: %w", err)
+	}
+
+	execPath := d.overrides[ss.Name]
+	cmd := exec.Command(execPath, args...)
+	if len(ss.env) > 0 || len(ss.envFiles) > 0 {
+		env, err := d.resolveEnv(ss)
+		if err != nil {
+			return err
+		}
+		fileValues, err := ss.envFileValues()
+		if err != nil {
+			return err
+		}
+		for k, v := range fileValues {
+			env[k] = v
+		}
+		cmd.Env = os.Environ()
+		for k, v := range env {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+
+	var logOutput io.Writer = os.Stdout
+	if file, err := d.out.LogOutput(ss.Name); err == nil {
+		if d.follower != nil {
+			file.tee = d.follower.writer(ss.Name)
+		}
+		logOutput = file
+	}
+
+	// Output the command itself to the log output for debugging purposes
+	fmt.Fprint(logOutput, strings.Join(args, " ")+"\n\n")
+
+	cmd.Stdout = logOutput
+	cmd.Stderr = logOutput
+
+	if err := cmd.Start(); err != nil {
+		d.updateTaskStatus(ss.Name, taskStatusDie)
+		return fmt.Errorf("error running host service %s: %w", ss.Name, err)
+	}
+	// the status of the containers is set by the docker events (see trackContainerStatusAndLogs)
+	d.updateTaskStatus(ss.Name, taskStatusStarted)
+
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			d.updateTaskStatus(ss.Name, taskStatusDie)
+			d.exitErr <- fmt.Errorf("error running host service %s: %w", ss.Name, err)
+		} else if ss.job {
+			d.updateTaskStatus(ss.Name, taskStatusCompleted)
+		}
+	}()
+
+	// we do not need to lock this array because we run the host services sequentially
+	d.handles = append(d.handles, cmd)
+	return nil
+}
+
+// trackLogs tracks the logs of a container and writes them to the log output
+func (d *LocalRunner) trackLogs(serviceName string, containerID string) error {
+	d.tasksMtx.Lock()
+	log_output := d.tasks[serviceName].logs
+	d.tasksMtx.Unlock()
+
+	if log_output == nil {
+		panic("BUG: log output not found for service " + serviceName)
+	}
+
+	logs, err := d.client.ContainerLogs(context.Background(), containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting container logs: %w", err)
+	}
+
+	if _, err := stdcopy.StdCopy(log_output, log_output, logs); err != nil {
+		return fmt.Errorf("error copying logs: %w", err)
+	}
+
+	return nil
+}
+
+func (d *LocalRunner) trackContainerStatusAndLogs() {
+	eventCh, errCh := d.client.Events(context.Background(), events.ListOptions{
+		Filters: d.sessionFilters(),
+	})
+
+	for {
+		select {
+		case event := <-eventCh:
+			name := event.Actor.Attributes["com.docker.compose.service"]
+			if event.Actor.Attributes["com.docker.compose.oneoff"] == "True" {
+				// the one-off containers of the init steps are tracked by runInit
+				continue
+			}
+
+			switch event.Action {
+			case events.ActionStart:
+				logsDone := make(chan struct{})
+				d.tasksMtx.Lock()
+				if task, ok := d.tasks[name]; ok {
+					task.expectRestart = false
+					task.logsDone = logsDone
+				}
+				d.tasksMtx.Unlock()
+				d.updateTaskStatus(name, taskStatusStarted)
+
+				// the container has started, we can track the logs now
+				go func() {
+					defer close(logsDone)
+					if err := d.trackLogs(name, event.Actor.ID); err != nil {
+						runnerLog.Warn("error tracking logs", "err", err)
+					}
+				}()
+			case events.ActionDie:
+				runnerLog.Info("container died", "service", name)
+				exitCode, _ := strconv.Atoi(event.Actor.Attributes["exitCode"])
+				d.handleContainerExit(name, event.Actor.ID, exitCode)
+			}
+
+		case err := <-errCh:
+			runnerLog.Warn("error tracking events", "err", err)
+		}
+	}
+}
+
+func (d *LocalRunner) Run(ctx context.Context) (err error) {
+	ctx, span := StartSpan(ctx, "start services", attribute.Int("services", len(d.manifest.services)))
+	defer func() {
+		EndSpan(span, err)
+	}()
+
+	if err := d.removeStaleResources(ctx); err != nil {
+		return err
+	}
+
+	go d.trackContainerStatusAndLogs()
+
+	yamlData, err := d.generateDockerCompose()
+	if err != nil {
+		return fmt.Errorf("failed to generate docker-compose.yaml: %w", err)
+	}
+
+	if err := d.out.WriteFile("docker-compose.yaml", yamlData); err != nil {
+		return fmt.Errorf("failed to write docker-compose.yaml: %w", err)
+	}
+
+	if err := d.writeHostsFile(); err != nil {
+		return err
+	}
+
+	if d.session.Output, err = d.out.AbsoluteDstPath(); err != nil {
+		return err
+	}
+	d.session.StartedAt = time.Now()
+	if err := d.updateSession(); err != nil {
+		return err
+	}
+
+	for _, svc := range d.manifest.services {
+		if err := d.trackService(svc); err != nil {
+			return err
+		}
+	}
+
+	order, err := d.manifest.startOrder()
+	if err != nil {
+		return err
+	}
+
+	// Start the services one by one in dependency order. Before starting a service, wait for
+	// all the dependencies that require to be healthy to pass their ready checks.
+	healthy := map[string]bool{}
+	for _, svc := range order {
+		if err := d.startService(ctx, svc, healthy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateSession registers the session with the reserved ports so that other sessions do not use them
+func (d *LocalRunner) updateSession() error {
+	ports := map[int]bool{}
+	for _, port := range d.session.Ports {
+		// keep the ports of the services removed when the devnet is scaled down
+		ports[port] = true
+	}
+	d.session.Services = map[string]map[string]int{}
+	d.session.Hosts = map[string]string{}
+	d.session.Components = map[string]string{}
+	for _, svc := range d.manifest.services {
+		d.session.Services[svc.Name] = map[string]int{}
+		if svc.hostIP != "" {
+			d.session.Hosts[svc.Name] = svc.hostIP
+		}
+		if svc.component != nil {
+			d.session.Components[svc.Name] = svc.component.Name()
+		}
+		for _, port := range svc.ports {
+			ports[port.HostPort] = true
+			d.session.Services[svc.Name][port.Name] = port.HostPort
+		}
+	}
+	d.session.Ports = make([]int, 0, len(ports))
+	for port := range ports {
+		d.session.Ports = append(d.session.Ports, port)
+	}
+	sort.Ints(d.session.Ports)
+	if err := writeSession(d.session); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
+
+// trackService generates the output log file of the service so that it is available after Run is done
+func (d *LocalRunner) trackService(svc *ServiceSpec) error {
+	log_output, err := d.out.LogOutput(svc.Name)
+	if err != nil {
+		return fmt.Errorf("error getting log output: %w", err)
+	}
+	svc.logs = &serviceLogs{
+		path: log_output.Name(),
+	}
+	if d.follower != nil {
+		log_output.tee = d.follower.writer(svc.Name)
+	}
+
+	d.tasksMtx.Lock()
+	defer d.tasksMtx.Unlock()
+	if _, ok := d.tasks[svc.Name]; !ok {
+		d.tasks[svc.Name] = &task{status: taskStatusPending}
+	}
+	d.tasks[svc.Name].logs = log_output
+	return nil
+}
+
+// startService waits for the dependencies of the service that have to be healthy and starts it
+func (d *LocalRunner) startService(ctx context.Context, svc *ServiceSpec, healthy map[string]bool) (err error) {
+	ctx, span := StartSpan(ctx, "start "+svc.Name, attribute.String("service", svc.Name), attribute.Bool("host", d.isHostService(svc.Name)))
+	defer func() {
+		EndSpan(span, err)
+	}()
+
+	for _, dep := range svc.dependsOn {
+		if dep.Condition == DependsOnConditionCompleted {
+			_, waitSpan := StartSpan(ctx, "wait completed "+dep.Service, attribute.String("service", dep.Service))
+			err := d.waitForCompleted(ctx, dep.Service)
+			EndSpan(waitSpan, err)
+			if err != nil {
+				return fmt.Errorf("service %s dependency not completed: %w", svc.Name, err)
+			}
+			continue
+		}
+		if dep.Condition != DependsOnConditionHealthy || healthy[dep.Service] {
+			continue
+		}
+		_, waitSpan := StartSpan(ctx, "wait healthy "+dep.Service, attribute.String("service", dep.Service))
+		err := d.waitForHealthy(ctx, d.manifest.MustGetService(dep.Service))
+		EndSpan(waitSpan, err)
+		if err != nil {
+			return fmt.Errorf("service %s dependency not healthy: %w", svc.Name, err)
+		}
+		healthy[dep.Service] = true
+	}
+
+	for _, name := range svc.volumes {
+		if d.isHostVolume(name) {
+			if err := os.MkdirAll(filepath.Join(d.out.dst, "volumes", name), 0755); err != nil {
+				return fmt.Errorf("failed to create volume %s: %w", name, err)
+			}
+		}
+	}
+
+	if err := d.seedDataDirs(svc); err != nil {
+		return err
+	}
+	if err := d.writeServiceFiles(svc); err != nil {
+		return err
+	}
+	if err := d.fixPermissions(svc); err != nil {
+		return err
+	}
+	if err := d.syncRemote(ctx, svc); err != nil {
+		return err
+	}
+
+	if len(svc.initArgs) > 0 {
+		_, initSpan := StartSpan(ctx, "init "+svc.Name, attribute.String("service", svc.Name))
+		err := d.runInit(ctx, svc)
+		EndSpan(initSpan, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.isHostService(svc.Name) {
+		runnerLog.Debug("starting service on the host", "service", svc.Name)
+		return d.runOnHost(svc)
+	}
+	runnerLog.Debug("starting service", "service", svc.Name, "image", d.imageRef(svc))
+	return d.runDockerComposeService(ctx, svc)
+}
+
+// resolveEnv resolves the templates of the environment variables of the service
+func (d *LocalRunner) resolveEnv(s *ServiceSpec) (map[string]string, error) {
+	env := map[string]string{}
+	for k, v := range s.env {
+		resolved, err := d.resolveTemplates(s, []string{v})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve env %s: %w", k, err)
+		}
+		env[k] = resolved[0]
+	}
+	return env, nil
+}
+
+// writeServiceFiles renders the config files of the service into the output folder
+func (d *LocalRunner) writeServiceFiles(svc *ServiceSpec) error {
+	for name, content := range svc.files {
+		resolved, err := d.resolveTemplates(svc, []string{content})
+		if err != nil {
+			return fmt.Errorf("failed to resolve file %s of service %s: %w", name, svc.Name, err)
+		}
+		if err := d.out.WriteFile(name, resolved[0]); err != nil {
+			return fmt.Errorf("failed to write file %s of service %s: %w", name, svc.Name, err)
+		}
+	}
+	return nil
+}
+
+// runDockerComposeService starts a single service from the docker-compose.yaml file
+// without starting its dependencies, those are handled by the runner itself.
+func (d *LocalRunner) runDockerComposeService(ctx context.Context, svc *ServiceSpec) error {
+	cmd := d.composeCommand(ctx, "-p", d.session.Name, "-f", filepath.Join(d.out.dst, "docker-compose.yaml"), "up", "-d", "--no-deps", svc.Name)
+
+	var errOut bytes.Buffer
+	cmd.Stderr = &errOut
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run docker-compose for service %s: %w, err: %s", svc.Name, err, errOut.String())
+	}
+	return nil
+}
+
+// runInit runs the init step of the service to completion (see runOneOff)
+func (d *LocalRunner) runInit(ctx context.Context, svc *ServiceSpec) error {
+	args, err := d.resolveTemplates(svc, svc.initArgs)
+	if err != nil {
+		return fmt.Errorf("failed to apply template on the init of service %s: %w", svc.Name, err)
+	}
+	runnerLog.Debug("running init", "service", svc.Name, "args", strings.Join(args, " "))
+
+	if err := d.runOneOff(ctx, svc, args); err != nil {
+		return fmt.Errorf("failed to run the init of service %s: %w", svc.Name, err)
+	}
+	return nil
+}
+
+// runOneOff runs the entrypoint of the service with the args to completion, on the host or in a
+// one-off container of the service. The output goes to the logs of the service.
+func (d *LocalRunner) runOneOff(ctx context.Context, svc *ServiceSpec, args []string) error {
+	var cmd *exec.Cmd
+	if d.isHostService(svc.Name) {
+		cmd = exec.CommandContext(ctx, d.overrides[svc.Name], args...)
+	} else {
+		// the one-off container has the image, entrypoint and volumes of the service, but not its ports
+		cmd = d.composeCommand(ctx, append([]string{"-p", d.session.Name, "-f", filepath.Join(d.out.dst, "docker-compose.yaml"), "run", "--rm", "--no-deps", "-T", svc.Name}, args...)...)
+	}
+
+	d.tasksMtx.Lock()
+	logOutput := d.tasks[svc.Name].logs
+	d.tasksMtx.Unlock()
+
+	cmd.Stdout = logOutput
+	cmd.Stderr = logOutput
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w, logs:\n%s", err, strings.Join(tailLines(logOutput.Name(), serviceFailedLogLines), "\n"))
+	}
+	return nil
+}
+
+// restartService restarts the container of the service
+func (d *LocalRunner) restartService(name string) error {
+	runnerLog.Info("restarting service", "service", name)
+	d.tasksMtx.Lock()
+	if task, ok := d.tasks[name]; ok {
+		task.expectRestart = true
+	}
+	d.tasksMtx.Unlock()
+
+	return d.composeService("restart", name)
+}
+
+// waitForHealthy probes the ready check of the service from the host machine until it passes
+// or the context is done. While it waits, the last error of the probes is logged every few seconds.
+func (d *LocalRunner) waitForHealthy(ctx context.Context, svc *ServiceSpec) error {
+	check := svc.readyCheck
+
+	timeout := check.Timeout
+	if timeout == 0 {
+		timeout = defaultReadyCheckTimeout
+	}
+	timeout = readyTimeoutOf(svc, timeout)
+
+	start := time.Now()
+	lastReport := start
+	timeoutCh := time.After(timeout)
+	for {
+		err := check.probe(svc)
+		if err == nil {
+			runnerLog.Debug("service healthy", "service", svc.Name)
+			return nil
+		}
+		runnerLog.Log(ctx, slogLevelTrace, "ready check failed", "service", svc.Name, "err", err)
+		if time.Since(lastReport) >= readyReportInterval {
+			lastReport = time.Now()
+			runnerLog.Info("waiting for service to become healthy", "service", svc.Name, "elapsed", time.Since(start).Round(time.Second), "err", err)
+		}
+
+		d.tasksMtx.Lock()
+		status := d.tasks[svc.Name].status
+		d.tasksMtx.Unlock()
+		if status == taskStatusDie {
+			return fmt.Errorf("service %s died before becoming healthy", svc.Name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeoutCh:
+			return fmt.Errorf("timeout after %s waiting for service %s to become healthy: %w", timeout, svc.Name, err)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// waitForCompleted waits for the job to exit, it fails if the job does not exit successfully
+func (d *LocalRunner) waitForCompleted(ctx context.Context, name string) error {
+	for {
+		d.tasksMtx.Lock()
+		status := d.tasks[name].status
+		d.tasksMtx.Unlock()
+
+		switch status {
+		case taskStatusCompleted:
+			runnerLog.Debug("job completed", "service", name)
+			return nil
+		case taskStatusDie:
+			return fmt.Errorf("job %s failed", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
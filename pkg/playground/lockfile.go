@@ -0,0 +1,193 @@
+package playground
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// LockfileName is the name of the lockfile written to the output folder on every run
+const LockfileName = "playground.lock"
+
+// Lockfile pins the exact images and release artifacts used by a run. Image tags like 'latest'
+// move upstream, so a run with the lockfile of a previous one (--locked) uses the same images.
+type Lockfile struct {
+	// Images are the digests of the images by reference (image:tag). The digest is empty
+	// for the images built locally, which are not pinned.
+	Images map[string]string `json:"images"`
+
+	// Releases are the SHA256 checksums of the release binaries that run on the host by
+	// name and version (name-version)
+	Releases map[string]string `json:"releases,omitempty"`
+}
+
+// ReadLockfile reads a lockfile written by a previous run
+func ReadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to decode lockfile %s: %w", path, err)
+	}
+	return &lock, nil
+}
+
+func serviceImage(s *ServiceSpec) string {
+	return fmt.Sprintf("%s:%s", s.image, s.tag)
+}
+
+// imageRef returns the reference used to pull and run the image of the service, which is
+// pinned to the digest of the lockfile if there is one
+func (d *LocalRunner) imageRef(s *ServiceSpec) string {
+	img := serviceImage(s)
+	if d.lock != nil {
+		if digest := d.lock.Images[img]; digest != "" {
+			return img + "@" + digest
+		}
+	}
+	return img
+}
+
+// UseLockfile pins the images of the services to the digests of the lockfile and checks that the
+// release binaries that run on the host have the same checksums. Every image must be in the lockfile.
+func (d *LocalRunner) UseLockfile(lock *Lockfile) error {
+	for _, svc := range d.manifest.services {
+		if d.isHostService(svc.Name) {
+			artifact, ok := d.releaseArtifact(svc)
+			if !ok {
+				// a binary provided by the user, it is not part of the lockfile
+				continue
+			}
+			name := artifact.Name + "-" + artifact.Version
+
+			expected, ok := lock.Releases[name]
+			if !ok {
+				return fmt.Errorf("release %s of service %s is not in the lockfile", name, svc.Name)
+			}
+			checksum, err := fileChecksum(d.overrides[svc.Name])
+			if err != nil {
+				return err
+			}
+			if checksum != expected {
+				return fmt.Errorf("release %s of service %s does not match the lockfile, checksum %s but expected %s", name, svc.Name, checksum, expected)
+			}
+			continue
+		}
+		if _, ok := lock.Images[serviceImage(svc)]; !ok {
+			return fmt.Errorf("image %s of service %s is not in the lockfile", serviceImage(svc), svc.Name)
+		}
+	}
+	d.lock = lock
+	return nil
+}
+
+// WriteLockfile writes the lockfile of the run to the output folder. The images must be pulled before.
+func (d *LocalRunner) WriteLockfile(ctx context.Context) error {
+	lock := &Lockfile{
+		Images:   map[string]string{},
+		Releases: map[string]string{},
+	}
+	for _, svc := range d.manifest.services {
+		if d.isHostService(svc.Name) {
+			artifact, ok := d.releaseArtifact(svc)
+			if !ok {
+				continue
+			}
+			checksum, err := fileChecksum(d.overrides[svc.Name])
+			if err != nil {
+				return err
+			}
+			lock.Releases[artifact.Name+"-"+artifact.Version] = checksum
+			continue
+		}
+
+		img := serviceImage(svc)
+		if _, ok := lock.Images[img]; ok {
+			continue
+		}
+		if d.lock != nil {
+			lock.Images[img] = d.lock.Images[img]
+			continue
+		}
+		digest, err := d.imageDigest(ctx, img)
+		if err != nil {
+			return err
+		}
+		lock.Images[img] = digest
+	}
+
+	data, err := json.MarshalIndent(lock, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := d.out.WriteFile(LockfileName, data); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+// releaseArtifact returns the release of the service if it runs a release binary on the host
+func (d *LocalRunner) releaseArtifact(svc *ServiceSpec) (*release, bool) {
+	if svc.labels[useHostExecutionLabel] != "true" {
+		return nil, false
+	}
+	releaseService, ok := svc.component.(ReleaseService)
+	if !ok {
+		return nil, false
+	}
+	return releaseService.ReleaseArtifact(), true
+}
+
+// imageDigest returns the digest of the image in its registry
+func (d *LocalRunner) imageDigest(ctx context.Context, img string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return "", fmt.Errorf("invalid image %s: %w", img, err)
+	}
+	inspect, err := d.client.ImageInspect(ctx, img)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image %s: %w", img, err)
+	}
+	for _, repoDigest := range inspect.RepoDigests {
+		repo, digest, ok := strings.Cut(repoDigest, "@")
+		if !ok {
+			continue
+		}
+		repoNamed, err := reference.ParseNormalizedNamed(repo)
+		if err != nil {
+			continue
+		}
+		if repoNamed.Name() == named.Name() {
+			return digest, nil
+		}
+	}
+	return "", nil
+}
+
+func fileChecksum(path string) (string, error) {
+	// the release binary might be the one in the PATH if the platform has no release
+	if resolved, err := exec.LookPath(path); err == nil {
+		path = resolved
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,413 @@
+package playground
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ansiColorRe matches the color codes of the logs of the clients that write them to files
+	ansiColorRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+	// gethLineRe matches the terminal format of geth (and op-geth), i.e.:
+	// INFO [10-14|12:00:00.123] Imported new potential chain segment number=10
+	gethLineRe = regexp.MustCompile(`^(TRACE|DEBUG|INFO|WARN|ERROR|CRIT)\s*\[(\d{2}-\d{2}\|\d{2}:\d{2}:\d{2}(?:\.\d+)?)\]\s*(.*)$`)
+
+	// lighthouseLineRe matches the format of lighthouse, i.e.:
+	// Oct 14 12:00:00.123 INFO Synced, slot: 10, epoch: 0, service: slot_notifier
+	lighthouseLineRe = regexp.MustCompile(`^([A-Z][a-z]{2}\s+\d{1,2} \d{2}:\d{2}:\d{2}(?:\.\d+)?)\s+(CRIT|ERRO|WARN|INFO|DEBG|TRCE)\s+(.*)$`)
+
+	// lighthouseFieldsRe matches the start of the fields of a lighthouse line (', key: ' or two spaces)
+	lighthouseFieldsRe = regexp.MustCompile(`(?:,\s+|\s{2,})[\w\-]+: `)
+
+	// gethFieldsRe matches the start of the key=value fields after the message of a line
+	gethFieldsRe = regexp.MustCompile(`\s+[\w.\-]+=`)
+
+	// tracingLineRe matches the format of the tracing crate of reth and rbuilder, i.e.:
+	// 2024-10-14T12:00:00.123456Z  INFO reth::cli: Status connected_peers=1
+	tracingLineRe = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2}))\s+(TRACE|DEBUG|INFO|WARN|ERROR)\s+(.*)$`)
+
+	// logfmtPairRe matches a key=value pair of logfmt, with a quoted or a plain value
+	logfmtPairRe = regexp.MustCompile(`([\w.\-]+)=("(?:[^"\\]|\\.)*"|\S*)`)
+)
+
+// LogEntry is a log line of a service, normalized from the format of its client
+type LogEntry struct {
+	Service string
+	Time    time.Time
+	Level   LogLevel
+	Message string
+
+	// Fields are the key/values of the line after the message, as written by the client
+	Fields string
+
+	// Extra are the lines without a format that follow the line (i.e. stack traces)
+	Extra []string
+}
+
+// logParser parses a line of a log format, if the line is in the format
+type logParser struct {
+	name  string
+	parse func(line string, now time.Time) (*LogEntry, bool)
+}
+
+// logParsers are the formats of the clients of the playground. The json and logfmt formats are
+// the ones of op-node, op-geth and the go services with the structured logs.
+var logParsers = []*logParser{
+	{name: "geth", parse: parseGethLine},
+	{name: "lighthouse", parse: parseLighthouseLine},
+	{name: "tracing", parse: parseTracingLine},
+	{name: "json", parse: parseJSONLine},
+	{name: "logfmt", parse: parseLogfmtLine},
+}
+
+// LogQuery selects the log entries of the services
+type LogQuery struct {
+	// Since skips the entries older than this, if set
+	Since time.Duration
+
+	// Grep keeps the entries that match the expression, if set
+	Grep *regexp.Regexp
+
+	// Level skips the entries below the level. The entries without a level are kept.
+	Level LogLevel
+}
+
+func (q *LogQuery) match(entry *LogEntry, cutoff time.Time) bool {
+	if q.Since != 0 && (entry.Time.IsZero() || entry.Time.Before(cutoff)) {
+		return false
+	}
+	if q.Level != "" && entry.Level != "" && entry.Level.slogLevel() < q.Level.slogLevel() {
+		return false
+	}
+	if q.Grep != nil && !q.Grep.MatchString(entry.Message+" "+entry.Fields+"\n"+strings.Join(entry.Extra, "\n")) {
+		return false
+	}
+	return true
+}
+
+// QueryLogs parses the log files of the services in the logs folder of the output folder,
+// with the rotated ones, and returns the entries that match the query sorted by time
+func QueryLogs(outputDir string, services []string, query *LogQuery) ([]*LogEntry, error) {
+	now := time.Now()
+	cutoff := now.Add(-query.Since)
+
+	entries := []*LogEntry{}
+	for _, name := range services {
+		files, err := serviceLogFiles(outputDir, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			fileEntries, err := parseLogFile(file, name, now)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse the logs of service %s: %w", name, err)
+			}
+			for _, entry := range fileEntries {
+				if query.match(entry, cutoff) {
+					entries = append(entries, entry)
+				}
+			}
+		}
+	}
+
+	// the entries of a service are already in order, this interleaves the services
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// serviceLogFiles returns the log file of the service and its rotated files, the oldest first
+func serviceLogFiles(outputDir string, name string) ([]string, error) {
+	path := filepath.Join(outputDir, "logs", name+".log")
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("service %s has no logs in %s", name, filepath.Join(outputDir, "logs"))
+		}
+		return nil, err
+	}
+
+	rotated, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	indexes := []int{}
+	for _, file := range rotated {
+		if i, err := strconv.Atoi(strings.TrimPrefix(file, path+".")); err == nil {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(indexes)))
+
+	files := []string{}
+	for _, i := range indexes {
+		files = append(files, fmt.Sprintf("%s.%d", path, i))
+	}
+	return append(files, path), nil
+}
+
+// parseLogFile parses the lines of a log file. The format that matched the last line is tried
+// first, and the lines that do not match any format belong to the previous entry.
+func parseLogFile(path string, service string, now time.Time) ([]*LogEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []*LogEntry{}
+	var last *logParser
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(ansiColorRe.ReplaceAllString(scanner.Text(), ""), "\r ")
+		if line == "" {
+			continue
+		}
+
+		var entry *LogEntry
+		if last != nil {
+			entry, _ = last.parse(line, now)
+		}
+		if entry == nil {
+			for _, parser := range logParsers {
+				var ok bool
+				if entry, ok = parser.parse(line, now); ok {
+					last = parser
+					break
+				}
+			}
+		}
+		if entry == nil {
+			if len(entries) != 0 {
+				prev := entries[len(entries)-1]
+				prev.Extra = append(prev.Extra, line)
+			} else {
+				entries = append(entries, &LogEntry{Service: service, Message: line})
+			}
+			continue
+		}
+		entry.Service = service
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// timeWithoutYear sets the year of a timestamp of a format without one, the current one
+// unless the timestamp would be in the future (i.e. the logs of December read in January)
+func timeWithoutYear(t time.Time, now time.Time) time.Time {
+	t = t.AddDate(now.Year()-t.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+func parseGethLine(line string, now time.Time) (*LogEntry, bool) {
+	match := gethLineRe.FindStringSubmatch(line)
+	if match == nil {
+		return nil, false
+	}
+	// the containers run in UTC
+	t, err := time.Parse("01-02|15:04:05.000", match[2])
+	if err != nil {
+		if t, err = time.Parse("01-02|15:04:05", match[2]); err != nil {
+			return nil, false
+		}
+	}
+	level, _ := parseLevelName(match[1])
+	entry := &LogEntry{Time: timeWithoutYear(t, now), Level: level, Message: match[3]}
+
+	// the message is padded before the fields
+	if i := strings.Index(entry.Message, "  "); i >= 0 {
+		entry.Message, entry.Fields = entry.Message[:i], strings.TrimSpace(entry.Message[i:])
+	} else if loc := gethFieldsRe.FindStringIndex(entry.Message); loc != nil {
+		entry.Message, entry.Fields = entry.Message[:loc[0]], strings.TrimSpace(entry.Message[loc[0]:])
+	}
+	return entry, true
+}
+
+func parseLighthouseLine(line string, now time.Time) (*LogEntry, bool) {
+	match := lighthouseLineRe.FindStringSubmatch(line)
+	if match == nil {
+		return nil, false
+	}
+	t, err := time.Parse("Jan _2 15:04:05.000", match[1])
+	if err != nil {
+		if t, err = time.Parse("Jan _2 15:04:05", match[1]); err != nil {
+			return nil, false
+		}
+	}
+	level, _ := parseLevelName(match[2])
+	entry := &LogEntry{Time: timeWithoutYear(t, now), Level: level, Message: match[3]}
+	if loc := lighthouseFieldsRe.FindStringIndex(entry.Message); loc != nil {
+		entry.Message, entry.Fields = entry.Message[:loc[0]], strings.TrimLeft(entry.Message[loc[0]:], ", ")
+	}
+	return entry, true
+}
+
+func parseTracingLine(line string, now time.Time) (*LogEntry, bool) {
+	match := tracingLineRe.FindStringSubmatch(line)
+	if match == nil {
+		return nil, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, match[1])
+	if err != nil {
+		return nil, false
+	}
+	level, _ := parseLevelName(match[2])
+	entry := &LogEntry{Time: t, Level: level, Message: match[3]}
+	if loc := gethFieldsRe.FindStringIndex(entry.Message); loc != nil {
+		entry.Message, entry.Fields = entry.Message[:loc[0]], strings.TrimSpace(entry.Message[loc[0]:])
+	}
+	return entry, true
+}
+
+func parseJSONLine(line string, now time.Time) (*LogEntry, bool) {
+	if !strings.HasPrefix(line, "{") {
+		return nil, false
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &obj); err != nil {
+		return nil, false
+	}
+	values := map[string]string{}
+	for k, v := range obj {
+		if str, ok := v.(string); ok {
+			values[k] = str
+		} else {
+			data, _ := json.Marshal(v)
+			values[k] = string(data)
+		}
+	}
+	return structuredEntry(values)
+}
+
+func parseLogfmtLine(line string, now time.Time) (*LogEntry, bool) {
+	pairs := logfmtPairRe.FindAllStringSubmatch(line, -1)
+	if len(pairs) == 0 || !strings.HasPrefix(line, pairs[0][0]) {
+		return nil, false
+	}
+	values := map[string]string{}
+	for _, pair := range pairs {
+		value := pair[2]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		values[pair[1]] = value
+	}
+	entry, ok := structuredEntry(values)
+	if !ok {
+		return nil, false
+	}
+
+	// keep the order of the fields of the line
+	fields := []string{}
+	for _, pair := range pairs {
+		if !structuredKeys[pair[1]] {
+			fields = append(fields, pair[0])
+		}
+	}
+	entry.Fields = strings.Join(fields, " ")
+	return entry, true
+}
+
+// structuredKeys are the keys of the time, the level and the message of the structured formats
+var structuredKeys = map[string]bool{
+	"t": true, "ts": true, "time": true, "timestamp": true,
+	"lvl": true, "level": true, "severity": true,
+	"msg": true, "message": true,
+}
+
+// structuredEntry returns the entry of the key/values of a structured line, which needs at
+// least a level and a message
+func structuredEntry(values map[string]string) (*LogEntry, bool) {
+	entry := &LogEntry{}
+	for _, key := range []string{"lvl", "level", "severity"} {
+		if level, ok := parseLevelName(values[key]); ok {
+			entry.Level = level
+			break
+		}
+	}
+	for _, key := range []string{"msg", "message"} {
+		if msg, ok := values[key]; ok {
+			entry.Message = msg
+			break
+		}
+	}
+	if entry.Level == "" || entry.Message == "" {
+		return nil, false
+	}
+	for _, key := range []string{"t", "ts", "time", "timestamp"} {
+		if t, ok := parseStructuredTime(values[key]); ok {
+			entry.Time = t
+			break
+		}
+	}
+
+	fields := []string{}
+	for k, v := range values {
+		if !structuredKeys[k] {
+			fields = append(fields, k+"="+v)
+		}
+	}
+	sort.Strings(fields)
+	entry.Fields = strings.Join(fields, " ")
+	return entry, true
+}
+
+// parseStructuredTime parses the timestamps of the structured formats, which are RFC3339 (with
+// or without a colon in the offset) or unix timestamps in seconds
+func parseStructuredTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999-0700"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Unix(0, int64(secs*float64(time.Second))), true
+	}
+	return time.Time{}, false
+}
+
+// Format returns the entry as a line with the time in UTC, the level, the message and the
+// fields, prefixed by the name of the service if withService is set
+func (e *LogEntry) Format(withService bool) string {
+	var b strings.Builder
+	if withService {
+		b.WriteString(e.Service + " | ")
+	}
+	if e.Time.IsZero() {
+		b.WriteString(strings.Repeat(" ", len("2006-01-02T15:04:05.000Z")))
+	} else {
+		b.WriteString(e.Time.UTC().Format("2006-01-02T15:04:05.000Z"))
+	}
+	level := "-"
+	if e.Level != "" {
+		level = strings.ToUpper(string(e.Level))
+	}
+	fmt.Fprintf(&b, " %-5s %s", level, e.Message)
+	if e.Fields != "" {
+		b.WriteString(" " + e.Fields)
+	}
+	for _, line := range e.Extra {
+		b.WriteString("\n" + line)
+	}
+	return b.String()
+}
@@ -0,0 +1,177 @@
+package playground
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// slogLevelTrace is the slog level of LevelTrace, which slog does not define
+const slogLevelTrace = slog.LevelDebug - 4
+
+func (l LogLevel) slogLevel() slog.Level {
+	switch l {
+	case LevelTrace:
+		return slogLevelTrace
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// logModules are the modules of the playground with their own log level. The 'services' module
+// is the log level of the clients deployed by the recipes (i.e. the verbosity of the EL).
+var logModules = []string{"artifacts", "events", "fork", "playground", "plugins", "ready", "releases", "runner", "services", "ui", "watchdog"}
+
+// LogConfig is the log level of each module, parsed from --log-level
+type LogConfig struct {
+	// Default is the level of the modules without their own
+	Default LogLevel
+
+	// Modules are the levels by module
+	Modules map[string]LogLevel
+}
+
+// ParseLogConfig parses a comma separated list of levels. An entry without a module sets the
+// default level, i.e. 'info', 'runner=debug,artifacts=warn' or 'warn,services=debug'.
+func ParseLogConfig(s string) (*LogConfig, error) {
+	config := &LogConfig{Default: LevelInfo, Modules: map[string]LogLevel{}}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		module, levelStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			module, levelStr = "", entry
+		}
+
+		var level LogLevel
+		if err := level.Unmarshal(levelStr); err != nil {
+			return nil, err
+		}
+		if module == "" {
+			config.Default = level
+			continue
+		}
+		if !contains(logModules, module) {
+			return nil, fmt.Errorf("unknown log module '%s', expected one of %s", module, strings.Join(logModules, ", "))
+		}
+		config.Modules[module] = level
+	}
+	return config, nil
+}
+
+// Level returns the log level of the module
+func (c *LogConfig) Level(module string) LogLevel {
+	if level, ok := c.Modules[module]; ok {
+		return level
+	}
+	return c.Default
+}
+
+func (c *LogConfig) String() string {
+	entries := []string{string(c.Default)}
+	for module, level := range c.Modules {
+		entries = append(entries, module+"="+string(level))
+	}
+	sort.Strings(entries[1:])
+	return strings.Join(entries, ",")
+}
+
+type logState struct {
+	config  *LogConfig
+	handler slog.Handler
+}
+
+var currentLogState atomic.Pointer[logState]
+
+func init() {
+	handler, _ := newLogHandler("text")
+	currentLogState.Store(&logState{
+		config:  &LogConfig{Default: LevelInfo, Modules: map[string]LogLevel{}},
+		handler: handler,
+	})
+}
+
+// SetupLogging sets the log levels of the modules and the format (text or json) of the logs
+// written to stderr. The logs of the standard library logger go to the 'playground' module.
+func SetupLogging(config *LogConfig, format string) error {
+	handler, err := newLogHandler(format)
+	if err != nil {
+		return err
+	}
+	currentLogState.Store(&logState{config: config, handler: handler})
+	slog.SetDefault(Logger("playground"))
+	return nil
+}
+
+func newLogHandler(format string) (slog.Handler, error) {
+	// the levels are filtered by module, the handler writes all of them
+	opts := &slog.HandlerOptions{
+		Level: slogLevelTrace,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.LevelKey && len(groups) == 0 {
+				if level, ok := a.Value.Any().(slog.Level); ok && level == slogLevelTrace {
+					a.Value = slog.StringValue("TRACE")
+				}
+			}
+			return a
+		},
+	}
+
+	switch format {
+	case "text":
+		return slog.NewTextHandler(os.Stderr, opts), nil
+	case "json":
+		return slog.NewJSONHandler(os.Stderr, opts), nil
+	}
+	return nil, fmt.Errorf("invalid log format '%s', expected text or json", format)
+}
+
+// Logger returns the logger of a module. The loggers can be created before SetupLogging,
+// the levels and the format are resolved on every record.
+func Logger(module string) *slog.Logger {
+	return slog.New(&moduleHandler{module: module})
+}
+
+// moduleHandler filters the records with the level of its module and writes them with
+// the handler of the current log setup
+type moduleHandler struct {
+	module string
+
+	// wrap applies the attributes and groups added with the With methods of the logger
+	wrap []func(slog.Handler) slog.Handler
+}
+
+func (m *moduleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= currentLogState.Load().config.Level(m.module).slogLevel()
+}
+
+func (m *moduleHandler) Handle(ctx context.Context, record slog.Record) error {
+	handler := currentLogState.Load().handler.WithAttrs([]slog.Attr{slog.String("module", m.module)})
+	for _, wrap := range m.wrap {
+		handler = wrap(handler)
+	}
+	return handler.Handle(ctx, record)
+}
+
+func (m *moduleHandler) with(wrap func(slog.Handler) slog.Handler) *moduleHandler {
+	return &moduleHandler{module: m.module, wrap: append(append([]func(slog.Handler) slog.Handler{}, m.wrap...), wrap)}
+}
+
+func (m *moduleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return m.with(func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs) })
+}
+
+func (m *moduleHandler) WithGroup(name string) slog.Handler {
+	return m.with(func(h slog.Handler) slog.Handler { return h.WithGroup(name) })
+}
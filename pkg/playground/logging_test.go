@@ -0,0 +1,45 @@
+package playground
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// loggerCall matches the loggers of a module, i.e. Logger("runner")
+var loggerCall = regexp.MustCompile(`\bLogger\("([^"]*)"\)`)
+
+// TestLogModules checks that the module of every logger of the playground and of the cli can
+// be configured with --log-level
+func TestLogModules(t *testing.T) {
+	found := 0
+	for _, dir := range []string{".", filepath.Join("..", "cli")} {
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			if strings.HasSuffix(file, "_test.go") {
+				continue
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, match := range loggerCall.FindAllStringSubmatch(string(data), -1) {
+				found++
+				if !contains(logModules, match[1]) {
+					t.Errorf("%s: the module '%s' of the logger is not in logModules", file, match[1])
+				}
+				if _, err := ParseLogConfig(match[1] + "=debug"); err != nil {
+					t.Errorf("%s: %v", file, err)
+				}
+			}
+		}
+	}
+	if found == 0 {
+		t.Fatal("no loggers found")
+	}
+}
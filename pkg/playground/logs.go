@@ -0,0 +1,91 @@
+package playground
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// logFile is a log file of the output folder. If maxSize is set, the file is rotated
+// once it reaches the size and the last 'retention' rotated files are kept
+// (<name>.log.1 being the most recent one).
+type logFile struct {
+	lock sync.Mutex
+
+	path string
+	file *os.File
+	size uint64
+
+	maxSize   uint64
+	retention int
+
+	// tee also receives the logs written to the file (see FollowLogs)
+	tee io.Writer
+}
+
+func newLogFile(path string, maxSize uint64, retention int) (*logFile, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &logFile{path: path, file: file, maxSize: maxSize, retention: retention}, nil
+}
+
+func (l *logFile) Name() string {
+	return l.path
+}
+
+func (l *logFile) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.maxSize != 0 && l.size+uint64(len(p)) > l.maxSize && l.size != 0 {
+		if err := l.rotate(); err != nil {
+			return 0, fmt.Errorf("failed to rotate log file %s: %w", l.path, err)
+		}
+	}
+
+	n, err := l.file.Write(p)
+	l.size += uint64(n)
+	if l.tee != nil {
+		l.tee.Write(p[:n])
+	}
+	return n, err
+}
+
+func (l *logFile) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	// shift the rotated files and drop the ones beyond the retention
+	if err := os.Remove(fmt.Sprintf("%s.%d", l.path, l.retention)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := l.retention - 1; i >= 1; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if l.retention > 0 {
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Create(l.path)
+	if err != nil {
+		return err
+	}
+	l.file = file
+	l.size = 0
+	return nil
+}
+
+func (l *logFile) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.file.Close()
+}
@@ -0,0 +1,1124 @@
+// Package playground describes devnets as manifests of services built by
+// recipes and runs them with the local or kurtosis runners. Downstream
+// modules can implement Recipe or Service and register them with
+// RegisterComponent to extend the playground without forking it.
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const useHostExecutionLabel = "use-host-execution"
+
+// Recipe is a named devnet that builds its artifacts and the manifest of services.
+type Recipe interface {
+	Name() string
+	Description() string
+	Flags() *flag.FlagSet
+	Artifacts() *ArtifactsBuilder
+	Apply(ctx *ExContext, artifacts *Artifacts) *Manifest
+	Output(manifest *Manifest) map[string]*RecipeOutput
+}
+
+// RecipeValidator is a recipe that checks the values of its flags before its artifacts are
+// built, so that the invalid values are reported as errors instead of panics in Apply
+type RecipeValidator interface {
+	Validate() error
+}
+
+// ValidateRecipe validates the flags of the recipe if it is a RecipeValidator. The errors
+// that are not classified are usage errors.
+func ValidateRecipe(recipe Recipe) error {
+	validator, ok := recipe.(RecipeValidator)
+	if !ok {
+		return nil
+	}
+	err := validator.Validate()
+	if err == nil {
+		return nil
+	}
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		return err
+	}
+	return NewClassifiedError(ErrorClassUsage, err)
+}
+
+// Manifest describes a list of services and their dependencies
+type Manifest struct {
+	ctx *ExContext
+
+	// list of services
+	services []*ServiceSpec
+
+	// overrides is a map of service name to the path of the executable to run
+	// on the host machine instead of a container.
+	overrides map[string]string
+
+	// deployments is the list of contracts to deploy once the services are ready
+	deployments []*Deployment
+
+	// scalable are the groups of services that can be scaled at runtime (see scale.go)
+	scalable map[string]*scalableGroup
+
+	// templates are the config files rendered to the output folder (see templates.go)
+	templates map[string]string
+
+	// l2ChainID is the chain id of the OP chain of the recipe, if it has one
+	l2ChainID uint64
+
+	// opDeployment are the L1 contracts of the OP chain of the recipe, if it has one
+	opDeployment *opChainDeployment
+
+	// readyTimeout is the time to wait for the services without their own timeout to
+	// be ready (see ready.go). If zero, the defaults of the checks are used.
+	readyTimeout time.Duration
+
+	out *output
+}
+
+func NewManifest(ctx *ExContext, out *output) *Manifest {
+	return &Manifest{ctx: ctx, out: out, overrides: make(map[string]string), scalable: map[string]*scalableGroup{}, l2ChainID: defaultL2ChainID}
+}
+
+type LogLevel string
+
+var (
+	LevelDebug LogLevel = "debug"
+	LevelInfo  LogLevel = "info"
+	LevelWarn  LogLevel = "warn"
+	LevelError LogLevel = "error"
+	LevelTrace LogLevel = "trace"
+)
+
+func (l *LogLevel) Unmarshal(s string) error {
+	switch s {
+	case "debug":
+		*l = LevelDebug
+	case "info":
+		*l = LevelInfo
+	case "trace":
+		*l = LevelTrace
+	case "warn":
+		*l = LevelWarn
+	case "error":
+		*l = LevelError
+	default:
+		return fmt.Errorf("invalid log level: %s", s)
+	}
+	return nil
+}
+
+// Execution context
+type ExContext struct {
+	LogLevel LogLevel
+
+	// SlotTime is the number of seconds per slot in the L1 chain
+	SlotTime uint64
+}
+
+func (e *ExContext) slotDuration() time.Duration {
+	if e.SlotTime == 0 {
+		return time.Duration(DefaultSlotTime) * time.Second
+	}
+	return time.Duration(e.SlotTime) * time.Second
+}
+
+// Service is a component that adds its ServiceSpec to the manifest.
+type Service interface {
+	Run(service *ServiceSpec, ctx *ExContext)
+	Name() string
+}
+
+type ServiceReady interface {
+	Ready(out io.Writer, service *ServiceSpec, ctx context.Context) error
+}
+
+// WaitForReady waits for the Ready of the components of the manifest. The services that are
+// not ready yet are logged every few seconds with the last error of their probes.
+func WaitForReady(ctx context.Context, manifest *Manifest) (err error) {
+	ctx, span := StartSpan(ctx, "wait for ready")
+	defer func() {
+		EndSpan(span, err)
+	}()
+
+	var wg sync.WaitGroup
+	readyErr := make(chan error, len(manifest.Services()))
+
+	output, err := manifest.out.LogOutput("ready")
+	if err != nil {
+		return fmt.Errorf("failed to create log output: %w", err)
+	}
+
+	progress := newReadyProgress()
+	reportCtx, stopReport := context.WithCancel(ctx)
+	defer stopReport()
+	go progress.report(reportCtx)
+
+	for _, s := range manifest.Services() {
+		if readyFn, ok := s.component.(ServiceReady); ok {
+			wg.Add(1)
+			progress.waiting(s.Name)
+
+			go func() {
+				defer wg.Done()
+				defer progress.done(s.Name)
+
+				ctx, span := StartSpan(withReadyProbe(ctx, progress, s.Name), "ready "+s.Name, attribute.String("service", s.Name))
+				err := readyFn.Ready(output, s, ctx)
+				EndSpan(span, err)
+				if err != nil {
+					readyErr <- fmt.Errorf("service %s failed to start: %w", s.Name, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	close(readyErr)
+	for err := range readyErr {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type ServiceWatchdog interface {
+	Watchdog(out io.Writer, service *ServiceSpec, ctx context.Context) error
+}
+
+// RunWatchdog runs the watchdogs of the services until one of them fails. The state of each
+// watchdog is reported to status, which can be nil.
+func RunWatchdog(manifest *Manifest, status *WatchdogStatus) error {
+	var wg sync.WaitGroup
+	watchdogErr := make(chan error, len(manifest.Services()))
+
+	output, err := manifest.out.LogOutput("watchdog")
+	if err != nil {
+		return fmt.Errorf("failed to create log output: %w", err)
+	}
+
+	for _, s := range manifest.Services() {
+		if watchdogFn, ok := s.component.(ServiceWatchdog); ok {
+			wg.Add(1)
+
+			status.update(s.Name, nil)
+			go func() {
+				defer wg.Done()
+				if err := watchdogFn.Watchdog(output, s, context.Background()); err != nil {
+					status.update(s.Name, err)
+					watchdogErr <- fmt.Errorf("service %s watchdog failed: %w", s.Name, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	close(watchdogErr)
+	for err := range watchdogErr {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Manifest) Services() []*ServiceSpec {
+	return s.services
+}
+
+// ReleaseService is a service that can also be runned as an artifact in the host machine
+type ReleaseService interface {
+	ReleaseArtifact() *release
+}
+
+func (s *Manifest) AddService(name string, srv Service) {
+	service := s.NewService(name)
+	service.component = srv
+	srv.Run(service, s.ctx)
+
+	s.services = append(s.services, service)
+}
+
+func (s *Manifest) MustGetService(name string) *ServiceSpec {
+	service, ok := s.GetService(name)
+	if !ok {
+		panic(fmt.Sprintf("service %s not found", name))
+	}
+	return service
+}
+
+func (s *Manifest) GetService(name string) (*ServiceSpec, bool) {
+	for _, ss := range s.services {
+		if ss.Name == name {
+			return ss, true
+		}
+	}
+	return nil, false
+}
+
+// Validate validates the manifest
+// - checks if all the port dependencies are met from the service description
+// - downloads any local release artifacts for the services that require host execution
+func (s *Manifest) Validate() error {
+	for _, ss := range s.services {
+		for _, nodeRef := range ss.nodeRefs {
+			targetService, ok := s.GetService(nodeRef.Service)
+			if !ok {
+				return fmt.Errorf("service %s depends on service %s, but it is not defined", ss.Name, nodeRef.Service)
+			}
+
+			if _, ok := targetService.GetPort(nodeRef.PortLabel); !ok {
+				return fmt.Errorf("service %s depends on service %s, but it does not expose port %s", ss.Name, nodeRef.Service, nodeRef.PortLabel)
+			}
+		}
+
+		for _, dep := range ss.dependsOn {
+			targetService, ok := s.GetService(dep.Service)
+			if !ok {
+				return fmt.Errorf("service %s depends on service %s, but it is not defined", ss.Name, dep.Service)
+			}
+			if dep.Condition == DependsOnConditionHealthy && targetService.readyCheck == nil {
+				return fmt.Errorf("service %s depends on service %s being healthy, but it does not define a ready check", ss.Name, dep.Service)
+			}
+			if dep.Condition == DependsOnConditionCompleted && !targetService.job {
+				return fmt.Errorf("service %s depends on service %s being completed, but it is not a job", ss.Name, dep.Service)
+			}
+		}
+
+		if ss.readyCheck != nil {
+			if _, ok := ss.GetPort(ss.readyCheck.PortLabel); !ok {
+				return fmt.Errorf("service %s ready check uses port %s, but it is not exposed", ss.Name, ss.readyCheck.PortLabel)
+			}
+		}
+	}
+
+	// make sure there are no cycles in the startup dependencies
+	if _, err := s.startOrder(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DownloadReleases downloads the release artifacts of the services that run on the host,
+// all of them concurrently
+func (s *Manifest) DownloadReleases(ctx context.Context, opts *ReleaseDownloadOptions) error {
+	downloads := map[string]*releaseDownload{}
+	byPath := map[string]*releaseDownload{}
+	unique := []*releaseDownload{}
+	for _, ss := range s.services {
+		if ss.labels[useHostExecutionLabel] == "true" {
+			// If the service wants to run on the host, it must implement the ReleaseService interface
+			// which provides functions to download the release artifact.
+			releaseService, ok := ss.component.(ReleaseService)
+			if !ok {
+				return fmt.Errorf("service '%s' must implement the ReleaseService interface", ss.Name)
+			}
+			download, err := newReleaseDownload(releaseService.ReleaseArtifact(), opts.Platform, s.out.homeDir, opts)
+			if err != nil {
+				return fmt.Errorf("failed to download release artifact for service '%s': %w", ss.Name, err)
+			}
+			if prev, ok := byPath[download.outPath]; ok {
+				// the services of the same release share the binary
+				download = prev
+			} else {
+				byPath[download.outPath] = download
+				unique = append(unique, download)
+			}
+			downloads[ss.Name] = download
+		}
+	}
+	if err := downloadReleases(ctx, unique, opts); err != nil {
+		return fmt.Errorf("failed to download the release artifacts: %w", err)
+	}
+	for name, download := range downloads {
+		s.overrides[name] = download.outPath
+	}
+	return nil
+}
+
+// startOrder returns the services sorted so that every service comes after
+// the services it depends on. Services without dependencies between them keep
+// the order in which they were added to the manifest.
+func (s *Manifest) startOrder() ([]*ServiceSpec, error) {
+	started := map[string]bool{}
+	order := make([]*ServiceSpec, 0, len(s.services))
+
+	for len(order) < len(s.services) {
+		progress := false
+		for _, ss := range s.services {
+			if started[ss.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range ss.dependsOn {
+				if !started[dep.Service] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				started[ss.Name] = true
+				order = append(order, ss)
+				progress = true
+			}
+		}
+		if !progress {
+			pending := []string{}
+			for _, ss := range s.services {
+				if !started[ss.Name] {
+					pending = append(pending, ss.Name)
+				}
+			}
+			return nil, fmt.Errorf("cyclic dependency between services: %s", strings.Join(pending, ", "))
+		}
+	}
+	return order, nil
+}
+
+// PortProtocol is the application protocol served on a port
+type PortProtocol string
+
+var (
+	PortProtocolTCP  PortProtocol = "tcp"
+	PortProtocolHTTP PortProtocol = "http"
+	PortProtocolWS   PortProtocol = "ws"
+)
+
+// portProtocol returns the protocol of a port from its label. The components use
+// the same labels for the same kind of endpoints (i.e. http for the JSON-RPC API
+// and ws for the WebSocket API).
+func portProtocol(name string) PortProtocol {
+	switch name {
+	case "http", "authrpc", "metrics":
+		return PortProtocolHTTP
+	case "ws":
+		return PortProtocolWS
+	default:
+		return PortProtocolTCP
+	}
+}
+
+// Port describes a port that a service exposes
+type Port struct {
+	// Name is the name of the port
+	Name string
+
+	// Port is the port number
+	Port int
+
+	// Protocol is the protocol served on the port
+	Protocol PortProtocol
+
+	// HostPort is the port number assigned on the host machine for this
+	// container port. It is populated by the local runner
+	// TODO: We might want to move this to the runner itself.
+	HostPort int
+}
+
+// NodeRef describes a reference from one service to another
+type NodeRef struct {
+	Service   string
+	PortLabel string
+}
+
+// DependsOnCondition is the condition a dependency has to meet before
+// the dependent service is started
+type DependsOnCondition string
+
+var (
+	DependsOnConditionStarted DependsOnCondition = "started"
+	DependsOnConditionHealthy DependsOnCondition = "healthy"
+
+	// DependsOnConditionCompleted waits for a job to exit successfully
+	DependsOnConditionCompleted DependsOnCondition = "completed"
+)
+
+// DependsOn describes a startup dependency of one service on another
+type DependsOn struct {
+	Service   string
+	Condition DependsOnCondition
+}
+
+// defaultReadyCheckTimeout is the time the runner waits for a service to
+// become healthy if the ReadyCheck does not specify one
+var defaultReadyCheckTimeout = 60 * time.Second
+
+// ReadyCheck describes how to probe from the host machine that a service is healthy
+type ReadyCheck struct {
+	// PortLabel is the label of the port to probe
+	PortLabel string
+
+	// Path is an optional HTTP path to query. If empty, the service is considered
+	// healthy as soon as the port accepts TCP connections. Otherwise, the endpoint
+	// must return a 2xx status code.
+	Path string
+
+	// Timeout is the maximum time to wait for the service to become healthy
+	Timeout time.Duration
+}
+
+// probe checks once whether the service is healthy
+func (r *ReadyCheck) probe(svc *ServiceSpec) error {
+	addr := svc.hostAddr(r.PortLabel)
+
+	if r.Path == "" {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	client := &http.Client{Timeout: time.Second}
+	resp, err := client.Get("http://" + addr + r.Path)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// serviceLogs is a service to access the logs of the running service
+type serviceLogs struct {
+	path string
+}
+
+func (s *serviceLogs) readLogs() (string, error) {
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs: %w", err)
+	}
+	return string(content), nil
+}
+
+func (s *serviceLogs) WaitForLog(pattern string, timeout time.Duration) error {
+	timer := time.After(timeout)
+	for {
+		select {
+		case <-timer:
+			return fmt.Errorf("timeout waiting for log pattern %s", pattern)
+		case <-time.After(500 * time.Millisecond):
+			logs, err := s.readLogs()
+			if err != nil {
+				return fmt.Errorf("failed to read logs: %w", err)
+			}
+			if strings.Contains(logs, pattern) {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *serviceLogs) FindLog(pattern string) (string, error) {
+	logs, err := s.readLogs()
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs: %w", err)
+	}
+
+	lines := strings.Split(logs, "\n")
+	for _, line := range lines {
+		if strings.Contains(line, pattern) {
+			return line, nil
+		}
+	}
+	return "", fmt.Errorf("log pattern %s not found", pattern)
+}
+
+// ServiceSpec is the runnable description of a service in the manifest.
+type ServiceSpec struct {
+	Name string
+	args []string
+
+	labels map[string]string
+
+	ports    []*Port
+	nodeRefs []*NodeRef
+
+	dependsOn  []*DependsOn
+	readyCheck *ReadyCheck
+
+	// readyTimeout is the time to wait for the service to be ready (see ready.go)
+	readyTimeout time.Duration
+
+	// restartPolicy is what the runner does when the container exits, the default of the
+	// runner if nil
+	restartPolicy *RestartPolicy
+
+	tag        string
+	image      string
+	entrypoint string
+	env        map[string]string
+
+	// job marks a service that runs to completion (i.e. a contract deployment) instead of a
+	// long running one. The runner does not restart it and its successful exit does not end
+	// the session.
+	job bool
+
+	// volumes are the names of the shared volumes mounted by the service (see WithVolume)
+	volumes []string
+
+	// dataDirSeeds are the existing data folders that the service starts from, by the
+	// folder of the output folder they are copied or mounted to (see WithDataDirSeed)
+	dataDirSeeds map[string]*DataDirSeed
+
+	// initArgs are the args of the init step that the runner runs to completion with the
+	// image and the entrypoint of the service before starting it (i.e. reth init)
+	initArgs []string
+
+	// envFiles are the .env files with more environment variables, read by the runner
+	envFiles []string
+
+	// files are the config files of the service by path relative to the output folder.
+	// They use the same templates as the args.
+	files map[string]string
+
+	// platform is the os/arch of the image to run (i.e. linux/amd64). If empty,
+	// the runner picks the variant of the image that matches the host.
+	platform string
+
+	// user is the <uid>[:<gid>] the container runs as. If empty, the runner uses the
+	// user of the image.
+	user string
+
+	// hostIP is the address of the published ports of the service from the host, set by the
+	// runner when they bind to another interface than the loopback one (see --bind)
+	hostIP string
+
+	logs      *serviceLogs
+	component Service
+
+	// manifest is the manifest the service belongs to
+	manifest *Manifest
+}
+
+func (s *ServiceSpec) Ports() []*Port {
+	return s.ports
+}
+
+func (s *ServiceSpec) MustGetPort(name string) *Port {
+	port, ok := s.GetPort(name)
+	if !ok {
+		panic(fmt.Sprintf("port %s not found", name))
+	}
+	return port
+}
+
+// hostName returns the host of the service from the host machine, the interface the published
+// ports bind to
+func (s *ServiceSpec) hostName() string {
+	if s.hostIP == "" {
+		return "localhost"
+	}
+	return s.hostIP
+}
+
+// hostAddr returns the address of a port of the service from the host machine, the host port
+// on the interface the published ports bind to
+func (s *ServiceSpec) hostAddr(portLabel string) string {
+	return net.JoinHostPort(s.hostName(), strconv.Itoa(s.MustGetPort(portLabel).HostPort))
+}
+
+func (s *ServiceSpec) GetPort(name string) (*Port, bool) {
+	for _, p := range s.ports {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+func (s *ServiceSpec) UseHostExecution() *ServiceSpec {
+	s.WithLabel(useHostExecutionLabel, "true")
+	return s
+}
+
+// DependsOnStarted makes the runner start the service only after the given service has been started
+func (s *ServiceSpec) DependsOnStarted(name string) *ServiceSpec {
+	return s.dependOn(name, DependsOnConditionStarted)
+}
+
+// DependsOnHealthy makes the runner start the service only after the given service
+// passes its ready check
+func (s *ServiceSpec) DependsOnHealthy(name string) *ServiceSpec {
+	return s.dependOn(name, DependsOnConditionHealthy)
+}
+
+// DependsOnCompleted makes the runner start the service only after the given job
+// exits successfully
+func (s *ServiceSpec) DependsOnCompleted(name string) *ServiceSpec {
+	return s.dependOn(name, DependsOnConditionCompleted)
+}
+
+// AsJob marks the service as a job that runs to completion. The services that need its
+// result wait for it with DependsOnCompleted, and a job that fails ends the session.
+func (s *ServiceSpec) AsJob() *ServiceSpec {
+	s.job = true
+	return s
+}
+
+func (s *ServiceSpec) dependOn(name string, condition DependsOnCondition) *ServiceSpec {
+	for _, d := range s.dependsOn {
+		if d.Service == name {
+			// the healthy and completed conditions are stricter and include the started one
+			if condition != DependsOnConditionStarted {
+				d.Condition = condition
+			}
+			return s
+		}
+	}
+	s.dependsOn = append(s.dependsOn, &DependsOn{Service: name, Condition: condition})
+	return s
+}
+
+func (s *ServiceSpec) WithReadyCheck(check *ReadyCheck) *ServiceSpec {
+	s.readyCheck = check
+	return s
+}
+
+func (s *ServiceSpec) WithRestartPolicy(policy *RestartPolicy) *ServiceSpec {
+	s.restartPolicy = policy
+	return s
+}
+
+func (s *ServiceSpec) WithLabel(key, value string) *ServiceSpec {
+	if s.labels == nil {
+		s.labels = make(map[string]string)
+	}
+	s.labels[key] = value
+	return s
+}
+
+func (s *Manifest) NewService(name string) *ServiceSpec {
+	return &ServiceSpec{Name: name, args: []string{}, ports: []*Port{}, nodeRefs: []*NodeRef{}, manifest: s}
+}
+
+func (s *ServiceSpec) WithImage(image string) *ServiceSpec {
+	s.image = image
+	return s
+}
+
+func (s *ServiceSpec) WithEntrypoint(entrypoint string) *ServiceSpec {
+	s.entrypoint = entrypoint
+	return s
+}
+
+// WithEnv sets an environment variable of the service. The value accepts the same templates as the args.
+func (s *ServiceSpec) WithEnv(key, value string) *ServiceSpec {
+	value, ports, nodeRefs := applyTemplate(value)
+	for _, p := range ports {
+		s.WithPort(p.Name, p.Port)
+	}
+	for _, n := range nodeRefs {
+		s.nodeRefs = append(s.nodeRefs, &n)
+	}
+	if s.env == nil {
+		s.env = make(map[string]string)
+	}
+	s.env[key] = value
+	return s
+}
+
+func (s *ServiceSpec) WithPlatform(platform string) *ServiceSpec {
+	s.platform = platform
+	return s
+}
+
+// WithUser sets the <uid>[:<gid>] the container of the service runs as (see ParseContainerUser)
+func (s *ServiceSpec) WithUser(user string) *ServiceSpec {
+	s.user = user
+	return s
+}
+
+// WithFile adds a config file to the service. The content accepts the same templates as
+// the args and it is rendered in the output folder right before the service starts.
+func (s *ServiceSpec) WithFile(name string, content string) *ServiceSpec {
+	content, ports, nodeRefs := applyTemplate(content)
+	for _, p := range ports {
+		s.WithPort(p.Name, p.Port)
+	}
+	for _, n := range nodeRefs {
+		s.nodeRefs = append(s.nodeRefs, &n)
+	}
+	if s.files == nil {
+		s.files = map[string]string{}
+	}
+	s.files[name] = content
+	return s
+}
+
+func (s *ServiceSpec) WithTag(tag string) *ServiceSpec {
+	s.tag = tag
+	return s
+}
+
+func (s *ServiceSpec) WithPort(name string, portNumber int) *ServiceSpec {
+	// add the port if not already present with the same name.
+	// if preset with the same name, they must have same port number
+	for _, p := range s.ports {
+		if p.Name == name {
+			if p.Port != portNumber {
+				panic(fmt.Sprintf("port %s already defined with different port number", name))
+			}
+			return s
+		}
+	}
+	s.ports = append(s.ports, &Port{Name: name, Port: portNumber, Protocol: portProtocol(name)})
+	return s
+}
+
+func (s *ServiceSpec) WithArgs(args ...string) *ServiceSpec {
+	for i, arg := range args {
+		var port []Port
+		var nodeRef []NodeRef
+		args[i], port, nodeRef = applyTemplate(arg)
+		for _, p := range port {
+			s.WithPort(p.Name, p.Port)
+		}
+		for _, n := range nodeRef {
+			s.nodeRefs = append(s.nodeRefs, &n)
+		}
+	}
+	s.args = append(s.args, args...)
+	return s
+}
+
+// WithVolume mounts the shared volume with the given name, created by the runner for the session.
+// The services that mount the same volume share its files (i.e. the database of a node read by
+// another service), and the {{Volume "name"}} template is the path of the volume in the service.
+func (s *ServiceSpec) WithVolume(name string) *ServiceSpec {
+	if !slices.Contains(s.volumes, name) {
+		s.volumes = append(s.volumes, name)
+	}
+	return s
+}
+
+// WithInit sets the args of the init step of the service, a one-shot run of its entrypoint
+// that has to succeed before the service starts (i.e. to write the genesis to the database).
+// The args accept the same templates as the service args.
+func (s *ServiceSpec) WithInit(args ...string) *ServiceSpec {
+	s.initArgs = []string{}
+	for _, arg := range args {
+		arg, _, nodeRefs := applyTemplate(arg)
+		for _, n := range nodeRefs {
+			s.nodeRefs = append(s.nodeRefs, &n)
+		}
+		s.initArgs = append(s.initArgs, arg)
+	}
+	return s
+}
+
+func applyTemplate(templateStr string) (string, []Port, []NodeRef) {
+	// use template substitution to load constants
+	// pass-through the Dir template because it has to be resolved at the runtime
+	input := map[string]interface{}{
+		"Dir": "{{.Dir}}",
+	}
+
+	var portRef []Port
+	var nodeRef []NodeRef
+	// ther can be multiple port and nodere because in the case of op-geth we pass a whole string as nested command args
+
+	funcs := template.FuncMap{
+		"Service": func(name string, portLabel string) string {
+			if name == "" {
+				panic("BUG: service name cannot be empty")
+			}
+			if portLabel == "" {
+				panic("BUG: port label cannot be empty")
+			}
+
+			// for the first pass of service we do not do anything, keep it as it is for the followup pass
+			// here we only keep the references to the services to be checked if they are valid and an be resolved
+			// later on for the runtime we will do the resolve stage.
+			// TODO: this will get easier when we move away from templates and use interface and structs.
+			nodeRef = append(nodeRef, NodeRef{Service: name, PortLabel: portLabel})
+			return fmt.Sprintf(`{{Service "%s" "%s"}}`, name, portLabel)
+		},
+		"Addr": func(name string, portLabel string) string {
+			// like Service, for the connection strings without the http scheme (i.e. postgres)
+			if name == "" || portLabel == "" {
+				panic("BUG: service name and port label cannot be empty")
+			}
+			nodeRef = append(nodeRef, NodeRef{Service: name, PortLabel: portLabel})
+			return fmt.Sprintf(`{{Addr "%s" "%s"}}`, name, portLabel)
+		},
+		"JWTSecret": func(name string) string {
+			// resolved at runtime, once all the services (and proxies) are known
+			return fmt.Sprintf(`{{JWTSecret "%s"}}`, name)
+		},
+		"Volume": func(name string) string {
+			// resolved at runtime, the path depends on where the service runs
+			return fmt.Sprintf(`{{Volume "%s"}}`, name)
+		},
+		"HostPort": func(name string, portLabel string) string {
+			// resolved at runtime, once the ports on the host are reserved
+			return fmt.Sprintf(`{{HostPort "%s" "%s"}}`, name, portLabel)
+		},
+		"HostName": func(name string) string {
+			// resolved at runtime, once the bind address of the ports is known
+			return fmt.Sprintf(`{{HostName "%s"}}`, name)
+		},
+		"Port": func(name string, defaultPort int) string {
+			portRef = append(portRef, Port{Name: name, Port: defaultPort})
+			return fmt.Sprintf(`{{Port "%s" %d}}`, name, defaultPort)
+		},
+	}
+
+	tpl, err := template.New("").Funcs(funcs).Parse(templateStr)
+	if err != nil {
+		panic(fmt.Sprintf("BUG: failed to parse template, err: %s", err))
+	}
+
+	var out strings.Builder
+	if err := tpl.Execute(&out, input); err != nil {
+		panic(fmt.Sprintf("BUG: failed to execute template, err: %s", err))
+	}
+	res := out.String()
+
+	// escape quotes
+	res = strings.ReplaceAll(res, `&#34;`, `"`)
+
+	return res, portRef, nodeRef
+}
+
+func (s *Manifest) GenerateDotGraph() string {
+	var b strings.Builder
+	b.WriteString("digraph G {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=record];\n\n")
+
+	// Create a map of services for easy lookup
+	servicesMap := make(map[string]*ServiceSpec)
+	for _, ss := range s.services {
+		servicesMap[ss.Name] = ss
+	}
+
+	// Add nodes (services) with their ports as labels
+	for _, ss := range s.services {
+		var ports []string
+		for _, p := range ss.ports {
+			ports = append(ports, fmt.Sprintf("%s:%d", p.Name, p.Port))
+		}
+		portLabel := ""
+		if len(ports) > 0 {
+			portLabel = "|{" + strings.Join(ports, "|") + "}"
+		}
+		// Replace hyphens with underscores for DOT compatibility
+		nodeName := strings.ReplaceAll(ss.Name, "-", "_")
+		b.WriteString(fmt.Sprintf("  %s [label=\"%s%s\"];\n", nodeName, ss.Name, portLabel))
+	}
+
+	b.WriteString("\n")
+
+	// Add edges (connections between services)
+	for _, ss := range s.services {
+		sourceNode := strings.ReplaceAll(ss.Name, "-", "_")
+		for _, ref := range ss.nodeRefs {
+			targetNode := strings.ReplaceAll(ref.Service, "-", "_")
+			b.WriteString(fmt.Sprintf("  %s -> %s [label=\"%s\"];\n",
+				sourceNode,
+				targetNode,
+				s.edgeLabel(ref),
+			))
+		}
+	}
+
+	// Add the startup dependencies as dashed edges
+	for _, ss := range s.services {
+		sourceNode := strings.ReplaceAll(ss.Name, "-", "_")
+		for _, dep := range ss.dependsOn {
+			targetNode := strings.ReplaceAll(dep.Service, "-", "_")
+			b.WriteString(fmt.Sprintf("  %s -> %s [style=dashed, label=\"%s\"];\n",
+				sourceNode,
+				targetNode,
+				dep.Condition,
+			))
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// edgeLabel returns the label of a connection between services with the port name and number
+func (s *Manifest) edgeLabel(ref *NodeRef) string {
+	if target, ok := s.GetService(ref.Service); ok {
+		if port, ok := target.GetPort(ref.PortLabel); ok {
+			return fmt.Sprintf("%s:%d", port.Name, port.Port)
+		}
+	}
+	return ref.PortLabel
+}
+
+// GenerateMermaidGraph generates a Mermaid flowchart of the services and their connections
+func (s *Manifest) GenerateMermaidGraph() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	for _, ss := range s.services {
+		var ports []string
+		for _, p := range ss.ports {
+			ports = append(ports, fmt.Sprintf("%s:%d", p.Name, p.Port))
+		}
+		label := ss.Name
+		if len(ports) > 0 {
+			label += "<br/>" + strings.Join(ports, "<br/>")
+		}
+		b.WriteString(fmt.Sprintf("  %s[\"%s\"]\n", mermaidID(ss.Name), label))
+	}
+
+	for _, ss := range s.services {
+		for _, ref := range ss.nodeRefs {
+			b.WriteString(fmt.Sprintf("  %s -->|%s| %s\n", mermaidID(ss.Name), s.edgeLabel(ref), mermaidID(ref.Service)))
+		}
+	}
+	for _, ss := range s.services {
+		for _, dep := range ss.dependsOn {
+			b.WriteString(fmt.Sprintf("  %s -.->|%s| %s\n", mermaidID(ss.Name), dep.Condition, mermaidID(dep.Service)))
+		}
+	}
+	return b.String()
+}
+
+func mermaidID(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+type topologyPort struct {
+	Name     string       `json:"name"`
+	Port     int          `json:"port"`
+	Protocol PortProtocol `json:"protocol"`
+}
+
+type topologyReadyCheck struct {
+	Port string `json:"port"`
+	Path string `json:"path,omitempty"`
+}
+
+type topologyService struct {
+	Name       string              `json:"name"`
+	Image      string              `json:"image"`
+	Tag        string              `json:"tag"`
+	Ports      []*topologyPort     `json:"ports"`
+	ReadyCheck *topologyReadyCheck `json:"readyCheck,omitempty"`
+	Restart    string              `json:"restart,omitempty"`
+	Job        bool                `json:"job,omitempty"`
+}
+
+type topologyConnection struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Port string `json:"port"`
+
+	// PortNumber is the port number inside the target service
+	PortNumber int `json:"portNumber"`
+}
+
+type topologyDependency struct {
+	From      string             `json:"from"`
+	To        string             `json:"to"`
+	Condition DependsOnCondition `json:"condition"`
+}
+
+type topology struct {
+	Services     []*topologyService    `json:"services"`
+	Connections  []*topologyConnection `json:"connections"`
+	Dependencies []*topologyDependency `json:"dependencies"`
+}
+
+// GenerateTopologyJSON generates a JSON description of the services, their
+// connections and their startup dependencies
+func (s *Manifest) GenerateTopologyJSON() ([]byte, error) {
+	t := &topology{
+		Services:     []*topologyService{},
+		Connections:  []*topologyConnection{},
+		Dependencies: []*topologyDependency{},
+	}
+
+	for _, ss := range s.services {
+		svc := &topologyService{
+			Name:  ss.Name,
+			Image: ss.image,
+			Tag:   ss.tag,
+			Ports: []*topologyPort{},
+		}
+		for _, p := range ss.ports {
+			svc.Ports = append(svc.Ports, &topologyPort{Name: p.Name, Port: p.Port, Protocol: p.Protocol})
+		}
+		if ss.readyCheck != nil {
+			svc.ReadyCheck = &topologyReadyCheck{Port: ss.readyCheck.PortLabel, Path: ss.readyCheck.Path}
+		}
+		if ss.restartPolicy != nil {
+			svc.Restart = ss.restartPolicy.String()
+		}
+		svc.Job = ss.job
+		t.Services = append(t.Services, svc)
+
+		for _, ref := range ss.nodeRefs {
+			conn := &topologyConnection{From: ss.Name, To: ref.Service, Port: ref.PortLabel}
+			if target, ok := s.GetService(ref.Service); ok {
+				if port, ok := target.GetPort(ref.PortLabel); ok {
+					conn.PortNumber = port.Port
+				}
+			}
+			t.Connections = append(t.Connections, conn)
+		}
+		for _, dep := range ss.dependsOn {
+			t.Dependencies = append(t.Dependencies, &topologyDependency{From: ss.Name, To: dep.Service, Condition: dep.Condition})
+		}
+	}
+	return json.MarshalIndent(t, "", "\t")
+}
+
+// WriteGraphs writes the topology of the manifest in the given formats (dot, mermaid or json)
+func (s *Manifest) WriteGraphs(formats []string) error {
+	for _, format := range formats {
+		switch format {
+		case "dot":
+			if err := s.out.WriteFile("graph.dot", s.GenerateDotGraph()); err != nil {
+				return err
+			}
+		case "mermaid":
+			if err := s.out.WriteFile("graph.mmd", s.GenerateMermaidGraph()); err != nil {
+				return err
+			}
+		case "json":
+			data, err := s.GenerateTopologyJSON()
+			if err != nil {
+				return err
+			}
+			if err := s.out.WriteFile("topology.json", data); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown graph format '%s', expected dot, mermaid or json", format)
+		}
+	}
+	return nil
+}
+
+func saveDotGraph(svcManager *Manifest, out *output) error {
+	dotGraph := svcManager.GenerateDotGraph()
+	return out.WriteFile("services.dot", dotGraph)
+}
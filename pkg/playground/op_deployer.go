@@ -0,0 +1,179 @@
+package playground
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+//go:embed utils/intent.toml
+var opDeployerIntent []byte
+
+const (
+	opDeployerImage      = "us-docker.pkg.dev/oplabs-tools-artifacts/images/op-deployer"
+	defaultOpDeployerTag = "v0.0.13"
+
+	// defaultOpContractsLocator are the contract artifacts of the embedded op-deployer state
+	defaultOpContractsLocator = "https://storage.googleapis.com/oplabs-contract-artifacts/artifacts-v1-c193a1863182092bc6cb723e523e8313a0f4b6e9c9636513927f1db74c047c15.tar.gz"
+
+	// opDeployerDir is the working folder of op-deployer inside the output folder
+	opDeployerDir = "op-deployer"
+)
+
+var (
+	opIntentChainIDRe = regexp.MustCompile(`(?m)^id = ".*"$`)
+	opIntentL1Re      = regexp.MustCompile(`(?m)^l1ContractsLocator = ".*"$`)
+	opIntentL2Re      = regexp.MustCompile(`(?m)^l2ContractsLocator = ".*"$`)
+)
+
+// OpDeployerConfig runs op-deployer in a container to deploy the OP chain in the L1 genesis and
+// generate its L2 genesis and rollup config, instead of using the embedded state.
+type OpDeployerConfig struct {
+	// Tag is the tag of the op-deployer image
+	Tag string
+
+	// ContractsLocator is the locator of the L1 and L2 contract artifacts (i.e. a tag:// or
+	// https:// locator), which selects the version of the OP contracts
+	ContractsLocator string
+
+	// Intent is the path of an intent.toml that replaces the generated one. It must have
+	// the L1 chain id of the playground and a single chain with the L2 chain id.
+	Intent string
+}
+
+// opDeployerState is the state.json written by op-deployer
+type opDeployerState struct {
+	L1StateDump          string `json:"l1StateDump"`
+	SuperchainDeployment struct {
+		ProtocolVersions string `json:"protocolVersionsProxyAddress"`
+	} `json:"superchainDeployment"`
+	OpChainDeployments []*opChainDeployment `json:"opChainDeployments"`
+}
+
+func parseOpDeployerState(data []byte) (*opDeployerState, error) {
+	var state opDeployerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the op-deployer state: %w", err)
+	}
+	return &state, nil
+}
+
+// chainDeployment returns the deployment of the only chain of the state
+func (s *opDeployerState) chainDeployment() (*opChainDeployment, error) {
+	if len(s.OpChainDeployments) != 1 {
+		return nil, fmt.Errorf("expected one OP chain deployment, found %d", len(s.OpChainDeployments))
+	}
+	return s.OpChainDeployments[0], nil
+}
+
+// opDeployerOutput are the files generated by op-deployer for a chain
+type opDeployerOutput struct {
+	state   []byte
+	genesis []byte
+	rollup  []byte
+}
+
+// runOpDeployer applies the intent for the chain id with the deployment target 'genesis', which
+// generates the L1 state dump without an L1 node, and then inspects the genesis and the rollup
+// config of the chain. The working folder is kept in the output folder.
+func runOpDeployer(ctx context.Context, out *output, config *OpDeployerConfig, chainID uint64) (*opDeployerOutput, error) {
+	intent, err := opDeployerIntentFor(config, chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := out.Remove(opDeployerDir); err != nil {
+		return nil, err
+	}
+	err = out.WriteBatch(map[string]interface{}{
+		filepath.Join(opDeployerDir, "intent.toml"): intent,
+		filepath.Join(opDeployerDir, "state.json"):  `{"version": 1}`,
+	})
+	if err != nil {
+		return nil, err
+	}
+	dir, err := out.AbsoluteDstPath()
+	if err != nil {
+		return nil, err
+	}
+	workdir := filepath.Join(dir, opDeployerDir)
+
+	tag := config.Tag
+	if tag == "" {
+		tag = defaultOpDeployerTag
+	}
+	chainIDStr := strconv.FormatUint(chainID, 10)
+	steps := [][]string{
+		{"apply", "--workdir", "/work", "--deployment-target", "genesis"},
+		{"inspect", "genesis", "--workdir", "/work", "--outfile", "/work/genesis.json", chainIDStr},
+		{"inspect", "rollup", "--workdir", "/work", "--outfile", "/work/rollup.json", chainIDStr},
+	}
+	for _, step := range steps {
+		args := []string{"run", "--rm", "-v", workdir + ":/work", "--entrypoint", "op-deployer"}
+		if runtime.GOOS != "windows" {
+			// the files of the working folder belong to the user, which has no home
+			// folder in the image for the cache of the contract artifacts
+			args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()), "-e", "HOME=/tmp")
+		}
+		args = append(args, opDeployerImage+":"+tag)
+		args = append(args, step...)
+
+		artifactsLog.Info("running op-deployer", "command", step[0])
+		var logs bytes.Buffer
+		cmd := containerCommand(ctx, args...)
+		cmd.Stdout = &logs
+		cmd.Stderr = &logs
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("op-deployer %s failed: %w, logs:\n%s", step[0], err, logs.String())
+		}
+	}
+
+	result := &opDeployerOutput{}
+	for name, dst := range map[string]*[]byte{"state.json": &result.state, "genesis.json": &result.genesis, "rollup.json": &result.rollup} {
+		if *dst, err = os.ReadFile(filepath.Join(workdir, name)); err != nil {
+			return nil, fmt.Errorf("failed to read the %s of op-deployer: %w", name, err)
+		}
+	}
+	return result, nil
+}
+
+// opDeployerIntentFor returns the intent of the user, or the one of the embedded state for the
+// chain id and the contracts locator
+func opDeployerIntentFor(config *OpDeployerConfig, chainID uint64) ([]byte, error) {
+	if config.Intent != "" {
+		data, err := os.ReadFile(config.Intent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the op-deployer intent: %w", err)
+		}
+		return data, nil
+	}
+
+	locator := config.ContractsLocator
+	if locator == "" {
+		locator = defaultOpContractsLocator
+	}
+	intent := opIntentChainIDRe.ReplaceAll(opDeployerIntent, []byte(fmt.Sprintf("id = %q", gethcommon.BigToHash(new(big.Int).SetUint64(chainID)).Hex())))
+	intent = opIntentL1Re.ReplaceAll(intent, []byte(fmt.Sprintf("l1ContractsLocator = %q", locator)))
+	intent = opIntentL2Re.ReplaceAll(intent, []byte(fmt.Sprintf("l2ContractsLocator = %q", locator)))
+	return intent, nil
+}
+
+// containerCommand returns the command of the container cli, podman if the docker cli is not available
+func containerCommand(ctx context.Context, args ...string) *exec.Cmd {
+	if _, err := exec.LookPath("docker"); err != nil {
+		if _, err := exec.LookPath("podman"); err == nil {
+			return exec.CommandContext(ctx, "podman", args...)
+		}
+	}
+	return exec.CommandContext(ctx, "docker", args...)
+}
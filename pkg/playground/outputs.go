@@ -0,0 +1,157 @@
+package playground
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+type OutputKind string
+
+var (
+	OutputKindValue   OutputKind = "value"
+	OutputKindURL     OutputKind = "url"
+	OutputKindIPCPath OutputKind = "ipc-path"
+	OutputKindJWTPath OutputKind = "jwt-path"
+	OutputKindEnode   OutputKind = "enode"
+	OutputKindChainID OutputKind = "chain-id"
+	OutputKindAddress OutputKind = "address"
+)
+
+// RecipeOutput is a typed value exported by a recipe once the services are running.
+// The value is a template resolved against the deployed manifest:
+// {{HostPort "service" "port"}} is the port of the service exposed on the host,
+// {{JWTSecret "service"}} is the path of the JWT secret of the service and
+// {{.Dir}} is the absolute path of the output folder.
+type RecipeOutput struct {
+	Kind  OutputKind
+	Value string
+}
+
+// OutputURL is the URL of a service port reachable from the host
+func OutputURL(scheme string, service string, portLabel string) *RecipeOutput {
+	return &RecipeOutput{
+		Kind:  OutputKindURL,
+		Value: fmt.Sprintf(`%s://{{HostAddr "%s" "%s"}}`, scheme, service, portLabel),
+	}
+}
+
+// OutputJWTPath is the path of the JWT secret of the Engine API of the execution node
+func OutputJWTPath(service string) *RecipeOutput {
+	return &RecipeOutput{Kind: OutputKindJWTPath, Value: JWTSecret(service)}
+}
+
+// OutputIPCPath is the path of an IPC socket in the output folder. The socket is only
+// reachable from the host if the service runs on the host or the output folder is
+// shared with the containers (Linux)
+func OutputIPCPath(name string) *RecipeOutput {
+	return &RecipeOutput{Kind: OutputKindIPCPath, Value: "{{.Dir}}/" + name}
+}
+
+func OutputEnode(enode string) *RecipeOutput {
+	return &RecipeOutput{Kind: OutputKindEnode, Value: enode}
+}
+
+func OutputChainID(chainID uint64) *RecipeOutput {
+	return &RecipeOutput{Kind: OutputKindChainID, Value: fmt.Sprintf("%d", chainID)}
+}
+
+func OutputAddress(addr string) *RecipeOutput {
+	return &RecipeOutput{Kind: OutputKindAddress, Value: addr}
+}
+
+func OutputValue(value string) *RecipeOutput {
+	return &RecipeOutput{Kind: OutputKindValue, Value: value}
+}
+
+// ResolveOutputs resolves the templates of the recipe outputs against the host ports
+// assigned to the services. It must be called after the services have been deployed.
+func (s *Manifest) ResolveOutputs(outputs map[string]*RecipeOutput) (map[string]string, error) {
+	dir, err := s.out.AbsoluteDstPath()
+	if err != nil {
+		return nil, err
+	}
+
+	funcs := template.FuncMap{
+		"HostPort": func(name string, portLabel string) (int, error) {
+			svc, ok := s.GetService(name)
+			if !ok {
+				return 0, fmt.Errorf("service %s not found", name)
+			}
+			port, ok := svc.GetPort(portLabel)
+			if !ok {
+				return 0, fmt.Errorf("service %s does not have port %s", name, portLabel)
+			}
+			return port.HostPort, nil
+		},
+		"HostAddr": func(name string, portLabel string) (string, error) {
+			// the host port on the interface the ports of the service bind to
+			svc, ok := s.GetService(name)
+			if !ok {
+				return "", fmt.Errorf("service %s not found", name)
+			}
+			if _, ok := svc.GetPort(portLabel); !ok {
+				return "", fmt.Errorf("service %s does not have port %s", name, portLabel)
+			}
+			return svc.hostAddr(portLabel), nil
+		},
+		"JWTSecret": func(name string) (string, error) {
+			owner, err := s.jwtSecretOwner(name)
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(dir, jwtSecretFile(owner)), nil
+		},
+	}
+	input := map[string]interface{}{
+		"Dir": dir,
+	}
+
+	res := map[string]string{}
+	for name, output := range outputs {
+		tpl, err := template.New(name).Funcs(funcs).Parse(output.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse output %s: %w", name, err)
+		}
+		var out strings.Builder
+		if err := tpl.Execute(&out, input); err != nil {
+			return nil, fmt.Errorf("failed to resolve output %s: %w", name, err)
+		}
+		res[name] = out.String()
+	}
+	return res, nil
+}
+
+// WriteOutputEnv writes the resolved outputs to output.env in dotenv format so that
+// they can be loaded with 'source'. Output names are converted into upper case
+// variable names (i.e. el-http becomes EL_HTTP).
+func (s *Manifest) WriteOutputEnv(values map[string]string) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		// single quotes avoid any shell expansion of the value
+		value := strings.ReplaceAll(values[name], "'", `'\''`)
+		b.WriteString(fmt.Sprintf("%s='%s'\n", outputEnvName(name), value))
+	}
+	return s.out.WriteFile("output.env", b.String())
+}
+
+func outputEnvName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,164 @@
+package playground
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OverrideField is the field of a service changed by an override
+type OverrideField string
+
+var (
+	OverrideFieldImage OverrideField = "image"
+	OverrideFieldTag   OverrideField = "tag"
+	OverrideFieldArgs  OverrideField = "args"
+	OverrideFieldEnv   OverrideField = "env"
+)
+
+// Override changes a field of a service of the manifest before it is deployed. The syntax is:
+// - <service>.image=<image>
+// - <service>.tag=<tag>
+// - <service>.args+=<arg> to append an argument
+// - <service>.env.<name>=<value>
+type Override struct {
+	Service string
+	Field   OverrideField
+
+	// Key is the name of the environment variable for the env overrides
+	Key   string
+	Value string
+}
+
+// ParseOverride parses an override with the <service>.<field>=<value> syntax
+func ParseOverride(str string) (*Override, error) {
+	key, value, ok := strings.Cut(str, "=")
+	if !ok {
+		return nil, fmt.Errorf("invalid override '%s', expected <service>.<field>=<value>", str)
+	}
+	appendArg := strings.HasSuffix(key, "+")
+	key = strings.TrimSuffix(key, "+")
+
+	name, field, ok := strings.Cut(key, ".")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid override '%s', expected <service>.<field>=<value>", str)
+	}
+
+	override := &Override{Service: name, Value: value}
+	switch {
+	case field == string(OverrideFieldImage) || field == string(OverrideFieldTag):
+		override.Field = OverrideField(field)
+		if value == "" {
+			return nil, fmt.Errorf("invalid override '%s', the %s cannot be empty", str, field)
+		}
+	case field == string(OverrideFieldArgs):
+		if !appendArg {
+			return nil, fmt.Errorf("invalid override '%s', the args can only be appended with %s.args+=<arg>", str, name)
+		}
+		override.Field = OverrideFieldArgs
+	case strings.HasPrefix(field, string(OverrideFieldEnv)+"."):
+		override.Field = OverrideFieldEnv
+		override.Key = strings.TrimPrefix(field, string(OverrideFieldEnv)+".")
+		if override.Key == "" {
+			return nil, fmt.Errorf("invalid override '%s', the env variable name cannot be empty", str)
+		}
+	default:
+		return nil, fmt.Errorf("invalid override '%s', unknown field '%s' (image, tag, args or env.<name>)", str, field)
+	}
+	if appendArg && override.Field != OverrideFieldArgs {
+		return nil, fmt.Errorf("invalid override '%s', only the args can be appended", str)
+	}
+	return override, nil
+}
+
+// ApplyOverride applies the override to the service of the manifest
+func (s *Manifest) ApplyOverride(override *Override) error {
+	svc, ok := s.GetService(override.Service)
+	if !ok {
+		return fmt.Errorf("override for unknown service '%s'", override.Service)
+	}
+
+	switch override.Field {
+	case OverrideFieldImage:
+		svc.WithImage(override.Value)
+	case OverrideFieldTag:
+		svc.WithTag(override.Value)
+	case OverrideFieldEnv:
+		svc.WithEnv(override.Key, override.Value)
+	case OverrideFieldArgs:
+		if svc.isShellCommand() {
+			// the services that run through a shell have the whole command as the last arg
+			arg, ports, nodeRefs := applyTemplate(override.Value)
+			for _, p := range ports {
+				svc.WithPort(p.Name, p.Port)
+			}
+			for _, n := range nodeRefs {
+				svc.nodeRefs = append(svc.nodeRefs, &n)
+			}
+			svc.args[len(svc.args)-1] += " " + arg
+		} else {
+			svc.WithArgs(override.Value)
+		}
+	default:
+		return fmt.Errorf("BUG: unknown override field '%s'", override.Field)
+	}
+	return nil
+}
+
+// isShellCommand returns whether the service runs its command with 'sh -c'
+func (s *ServiceSpec) isShellCommand() bool {
+	return strings.HasSuffix(s.entrypoint, "sh") && len(s.args) == 2 && s.args[0] == "-c"
+}
+
+// OverrideKeys returns the keys of the overrides of the services of the manifest, with the
+// trailing '=' (i.e. el.image=), for the shell completion of --override
+func (s *Manifest) OverrideKeys() []string {
+	keys := []string{}
+	for _, svc := range s.services {
+		keys = append(keys,
+			fmt.Sprintf("%s.%s=", svc.Name, OverrideFieldImage),
+			fmt.Sprintf("%s.%s=", svc.Name, OverrideFieldTag),
+			fmt.Sprintf("%s.%s+=", svc.Name, OverrideFieldArgs),
+		)
+		env := make([]string, 0, len(svc.env))
+		for k := range svc.env {
+			env = append(env, k)
+		}
+		sort.Strings(env)
+		for _, k := range env {
+			keys = append(keys, fmt.Sprintf("%s.%s.%s=", svc.Name, OverrideFieldEnv, k))
+		}
+	}
+	return keys
+}
+
+// Describe returns a human readable description of the services of the manifest and the
+// values that can be overridden
+func (s *Manifest) Describe() string {
+	var b strings.Builder
+	for _, svc := range s.services {
+		b.WriteString(fmt.Sprintf("%s\n", svc.Name))
+		b.WriteString(fmt.Sprintf("  %s.image=%s\n", svc.Name, svc.image))
+		b.WriteString(fmt.Sprintf("  %s.tag=%s\n", svc.Name, svc.tag))
+		if svc.entrypoint != "" {
+			b.WriteString(fmt.Sprintf("  entrypoint: %s\n", svc.entrypoint))
+		}
+		if len(svc.args) != 0 {
+			b.WriteString("  args:\n")
+			for _, arg := range svc.args {
+				b.WriteString(fmt.Sprintf("    %s\n", arg))
+			}
+		}
+
+		keys := make([]string, 0, len(svc.env))
+		for k := range svc.env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString(fmt.Sprintf("  %s.env.%s=%s\n", svc.Name, k, svc.env[k]))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package playground
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+
+	ecrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// nodeKey returns the deterministic p2p key of a service, so that the enode of every
+// node of the devnet is known before it starts
+func nodeKey(service string) *ecdsa.PrivateKey {
+	key, err := ecrypto.ToECDSA(ecrypto.Keccak256([]byte("builder-playground/p2p/" + service)))
+	if err != nil {
+		panic(fmt.Sprintf("BUG: invalid p2p key for %s: %s", service, err))
+	}
+	return key
+}
+
+func nodeKeyHex(service string) string {
+	return hex.EncodeToString(ecrypto.FromECDSA(nodeKey(service)))
+}
+
+// nodeEnode returns the enode of the service inside the docker network. The clients
+// resolve the name of the service when they connect.
+func nodeEnode(service string, port int) string {
+	return fmt.Sprintf("enode://%s@%s:%d", nodePubkeyHex(service), service, port)
+}
+
+// nodePubkeyHex returns the public key of the deterministic p2p key of a service in the
+// format of the enodes
+func nodePubkeyHex(service string) string {
+	pub := ecrypto.FromECDSAPub(&nodeKey(service).PublicKey)
+	return hex.EncodeToString(pub[1:])
+}
+
+// Topology is how the nodes of a multi-node devnet peer with each other
+type Topology string
+
+var (
+	// TopologyStar connects every node to the first one
+	TopologyStar Topology = "star"
+
+	// TopologyRing connects every node to the previous one and the last one to the first one
+	TopologyRing Topology = "ring"
+
+	// TopologyFull connects every node to all the others
+	TopologyFull Topology = "full"
+)
+
+func (t Topology) Validate() error {
+	switch t {
+	case TopologyStar, TopologyRing, TopologyFull:
+		return nil
+	}
+	return fmt.Errorf("invalid topology '%s', expected star, ring or full", t)
+}
+
+// Peers returns the indexes of the nodes that the node with the given index connects to, out of
+// the first total nodes. Only the nodes started before (with a lower index) are returned, the
+// connections are bidirectional so every link is configured once. The nodes beyond total (i.e.
+// added with 'playground scale') extend the ring from the last node.
+func (t Topology) Peers(index int, total int) []int {
+	if index == 0 {
+		return nil
+	}
+	switch t {
+	case TopologyRing:
+		if index == total-1 && total > 2 {
+			// close the ring
+			return []int{index - 1, 0}
+		}
+		return []int{index - 1}
+	case TopologyFull:
+		peers := []int{}
+		for i := 0; i < index; i++ {
+			peers = append(peers, i)
+		}
+		return peers
+	}
+	return []int{0}
+}
+
+// TargetPeers returns the number of peers of a node that is not the first one
+func (t Topology) TargetPeers(total int) uint64 {
+	switch t {
+	case TopologyRing:
+		return 3
+	case TopologyFull:
+		return uint64(total - 1)
+	}
+	return 1
+}
@@ -0,0 +1,130 @@
+package playground
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ContainerUserHost maps the containers to the user and group of the host user, so that the
+// files they write in the output folder belong to the host user
+const ContainerUserHost = "host"
+
+// ParseContainerUser parses the user of a container, either a numeric <uid>[:<gid>] or
+// "host" for the user and group of the host user. It returns the user in the docker format.
+func ParseContainerUser(user string) (string, error) {
+	if user == ContainerUserHost {
+		if runtime.GOOS == "windows" {
+			return "", fmt.Errorf("the host user mapping is not supported on windows")
+		}
+		return fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()), nil
+	}
+
+	uid, gid, hasGid := strings.Cut(user, ":")
+	if _, err := strconv.ParseUint(uid, 10, 32); err != nil {
+		return "", fmt.Errorf("invalid user '%s', expected <uid>[:<gid>] or host", user)
+	}
+	if hasGid {
+		if _, err := strconv.ParseUint(gid, 10, 32); err != nil {
+			return "", fmt.Errorf("invalid user '%s', expected <uid>[:<gid>] or host", user)
+		}
+	}
+	return user, nil
+}
+
+// SetUser sets the user the containers of the service run as, or of all the services if the
+// name is empty, overriding the user of the service in the manifest (see WithUser).
+// The services running on the host are not affected.
+func (d *LocalRunner) SetUser(name string, user string) error {
+	user, err := ParseContainerUser(user)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		d.user = user
+		return nil
+	}
+	if _, ok := d.manifest.GetService(name); !ok {
+		return fmt.Errorf("user for unknown service '%s'", name)
+	}
+	d.serviceUsers[name] = user
+	return nil
+}
+
+// containerUser returns the user the container of the service runs as, or empty for
+// the user of the image
+func (d *LocalRunner) containerUser(s *ServiceSpec) string {
+	if user, ok := d.serviceUsers[s.Name]; ok {
+		return user
+	}
+	if s.user != "" {
+		return s.user
+	}
+	return d.user
+}
+
+// fixPermissions makes the output folder readable and writable by the user of the container
+// of the service, if it is not root. The artifacts are written by the host user with modes like 0644 or 0600,
+// which a container running as another user (or any user of a rootless engine, where the host
+// user is root inside the container) cannot write or even read. The files that the host user
+// does not own were written by the containers themselves and are left as they are.
+func (d *LocalRunner) fixPermissions(svc *ServiceSpec) error {
+	if d.isHostService(svc.Name) {
+		return nil
+	}
+	user := d.containerUser(svc)
+	if user == "" {
+		// the images of some clients already run as a user other than root
+		inspect, err := d.client.ImageInspect(context.Background(), d.imageRef(svc))
+		if err != nil || inspect.Config == nil {
+			return nil
+		}
+		user = inspect.Config.User
+	}
+	if user == "" || user == "root" || user == "0" || strings.HasPrefix(user, "0:") {
+		return nil
+	}
+	if d.remote == nil && !d.rootless && user == fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()) {
+		// the container already owns the files of the host user
+		return nil
+	}
+
+	return filepath.WalkDir(d.out.dst, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		mode := info.Mode().Perm() | 0666
+		if entry.IsDir() {
+			mode |= 0111
+		}
+		if mode == info.Mode().Perm() {
+			return nil
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			if os.IsPermission(err) {
+				// owned by the user of a container
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			return fmt.Errorf("failed to fix the permissions of %s: %w", path, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,237 @@
+package playground
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// planFolder is the folder of the output folder where the dry runs write the plan
+const planFolder = "plan"
+
+// planArtifacts are the patterns of the chain artifacts whose hashes are part of the plan
+var planArtifacts = []string{
+	"genesis.json",
+	"genesis-*.json",
+	"l2-genesis*.json",
+	"rollup*.json",
+	"testnet/config.yaml",
+	"testnet/genesis.ssz",
+}
+
+// Plan is the summary of a dry run written to plan/plan.json
+type Plan struct {
+	// OutputFolder is the absolute path of the output folder, which is part of the mounts
+	// and the args of the services on the host
+	OutputFolder string `json:"outputFolder"`
+
+	// GenesisTime is the timestamp of the L1 genesis, which changes the hashes of the
+	// genesis artifacts of every run
+	GenesisTime uint64 `json:"genesisTime,omitempty"`
+
+	// Artifacts are the sha256 hashes of the chain artifacts by their path
+	Artifacts map[string]string `json:"artifacts"`
+
+	Services []string `json:"services"`
+}
+
+// ServicePlan is what the runner would run for a service, with the templates resolved
+// against the planned host ports
+type ServicePlan struct {
+	Name string `json:"name"`
+
+	// Host is the executable that runs the service on the host, empty for a container
+	Host string `json:"host,omitempty"`
+
+	Image      string            `json:"image,omitempty"`
+	Platform   string            `json:"platform,omitempty"`
+	User       string            `json:"user,omitempty"`
+	Entrypoint string            `json:"entrypoint,omitempty"`
+	Command    []string          `json:"command"`
+	Init       []string          `json:"init,omitempty"`
+	Job        bool              `json:"job,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+
+	// EnvFiles are the .env files read when the service starts, their values are not
+	// part of the plan since they usually are secrets
+	EnvFiles  []string          `json:"envFiles,omitempty"`
+	Mounts    []string          `json:"mounts,omitempty"`
+	Ports     []string          `json:"ports,omitempty"`
+	Restart   string            `json:"restart,omitempty"`
+	DependsOn map[string]string `json:"dependsOn,omitempty"`
+
+	// Files are the rendered config files of the service in the plan folder, by their
+	// path relative to the output folder
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// NewPlanRunner creates a runner that plans the services of the manifest without connecting to
+// docker, for the dry runs (see WritePlan). The settings of the runner apply to the plan.
+func NewPlanRunner(out *output, manifest *Manifest, overrides map[string]string, session *Session) (*LocalRunner, error) {
+	return newLocalRunner(out, manifest, overrides, session)
+}
+
+// WritePlan writes what the runner would run to the plan folder of the output folder: the
+// docker-compose.yaml file, a <service>.json file with the command, the env, the mounts and
+// the ports of each service, their rendered config files in <service>/ and the plan.json
+// summary with the hashes of the chain artifacts (see DiffPlans). The host ports
+// are reserved like the runner does, so they are the ones of a run started right after.
+func (d *LocalRunner) WritePlan() error {
+	yamlData, err := d.generateDockerCompose()
+	if err != nil {
+		return fmt.Errorf("failed to generate docker-compose.yaml: %w", err)
+	}
+	if err := d.out.WriteFile(filepath.Join(planFolder, "docker-compose.yaml"), yamlData); err != nil {
+		return fmt.Errorf("failed to write docker-compose.yaml: %w", err)
+	}
+
+	outputFolder, err := d.out.AbsoluteDstPath()
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for output folder: %w", err)
+	}
+	summary := &Plan{
+		OutputFolder: outputFolder,
+		Artifacts:    map[string]string{},
+		Services:     []string{},
+	}
+	for _, svc := range d.manifest.services {
+		plan, err := d.servicePlan(svc, outputFolder)
+		if err != nil {
+			return fmt.Errorf("failed to plan service %s: %w", svc.Name, err)
+		}
+		if err := d.out.WriteFile(filepath.Join(planFolder, svc.Name+".json"), plan); err != nil {
+			return fmt.Errorf("failed to write the plan of service %s: %w", svc.Name, err)
+		}
+		summary.Services = append(summary.Services, svc.Name)
+	}
+	sort.Strings(summary.Services)
+
+	if summary.Artifacts, err = hashArtifacts(d.out.dst); err != nil {
+		return err
+	}
+	summary.GenesisTime = readGenesisTime(filepath.Join(d.out.dst, "genesis.json"))
+	if err := d.out.WriteFile(filepath.Join(planFolder, "plan.json"), summary); err != nil {
+		return fmt.Errorf("failed to write the plan: %w", err)
+	}
+	return nil
+}
+
+// hashArtifacts returns the sha256 hashes of the chain artifacts of the output folder
+func hashArtifacts(dir string) (map[string]string, error) {
+	hashes := map[string]string{}
+	for _, pattern := range planArtifacts {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read artifact %s: %w", path, err)
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return nil, err
+			}
+			hash := sha256.Sum256(data)
+			hashes[filepath.ToSlash(rel)] = hex.EncodeToString(hash[:])
+		}
+	}
+	return hashes, nil
+}
+
+// readGenesisTime returns the timestamp of the genesis of the L1, or zero if there is none
+func readGenesisTime(path string) uint64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	var genesis struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return 0
+	}
+	timestamp, err := strconv.ParseUint(genesis.Timestamp, 0, 64)
+	if err != nil {
+		return 0
+	}
+	return timestamp
+}
+
+func (d *LocalRunner) servicePlan(svc *ServiceSpec, outputFolder string) (*ServicePlan, error) {
+	args, err := d.applyTemplate(svc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply template: %w", err)
+	}
+	env, err := d.resolveEnv(svc)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ServicePlan{
+		Name:      svc.Name,
+		Command:   args,
+		Job:       svc.job,
+		DependsOn: map[string]string{},
+		Files:     map[string]string{},
+	}
+	if len(env) > 0 {
+		plan.Env = env
+	}
+	for _, path := range svc.envFiles {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		plan.EnvFiles = append(plan.EnvFiles, abs)
+	}
+	if len(svc.initArgs) > 0 {
+		if plan.Init, err = d.resolveTemplates(svc, svc.initArgs); err != nil {
+			return nil, fmt.Errorf("failed to apply template on the init: %w", err)
+		}
+	}
+	for _, dep := range svc.dependsOn {
+		plan.DependsOn[dep.Service] = string(dep.Condition)
+	}
+
+	if d.isHostService(svc.Name) {
+		plan.Host = d.overrides[svc.Name]
+		for _, p := range svc.ports {
+			plan.Ports = append(plan.Ports, fmt.Sprintf("%d", p.HostPort))
+		}
+	} else {
+		plan.Image = d.imageRef(svc)
+		plan.Platform = svc.platform
+		plan.User = d.containerUser(svc)
+		plan.Entrypoint = svc.entrypoint
+		plan.Mounts = d.serviceMounts(svc, outputFolder)
+		plan.Ports = d.servicePorts(svc)
+		if !svc.job {
+			plan.Restart = d.restartPolicyOf(svc.Name).String()
+		}
+	}
+
+	names := make([]string, 0, len(svc.files))
+	for name := range svc.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		resolved, err := d.resolveTemplates(svc, []string{svc.files[name]})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve file %s: %w", name, err)
+		}
+		path := filepath.Join(planFolder, svc.Name, name)
+		if err := d.out.WriteFile(path, resolved[0]); err != nil {
+			return nil, fmt.Errorf("failed to write file %s: %w", name, err)
+		}
+		plan.Files[name] = filepath.ToSlash(path)
+	}
+	return plan, nil
+}
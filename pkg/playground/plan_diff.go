@@ -0,0 +1,334 @@
+package playground
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// outputFolderPlaceholder replaces the output folder in the plans, so that the plans of two
+// output folders can be compared
+const outputFolderPlaceholder = "<output>"
+
+// maxDiffCells bounds the size of the line diffs, the bigger files are shown as replaced
+const maxDiffCells = 4_000_000
+
+// PlanDiff is the difference between two plans of dry runs
+type PlanDiff struct {
+	Added    []string       `json:"added,omitempty"`
+	Removed  []string       `json:"removed,omitempty"`
+	Services []*ServiceDiff `json:"services,omitempty"`
+
+	// Artifacts are the chain artifacts with another hash, the ones only in one of the plans
+	// have an empty hash on the other side
+	Artifacts []*FieldDiff `json:"artifacts,omitempty"`
+
+	// GenesisTime is set if the genesis time changed, which also changes the hashes of the
+	// genesis artifacts
+	GenesisTime *FieldDiff `json:"genesisTime,omitempty"`
+}
+
+// ServiceDiff are the changes of a service present in both plans
+type ServiceDiff struct {
+	Name    string       `json:"name"`
+	Changes []*FieldDiff `json:"changes"`
+}
+
+// FieldDiff is the change of a field, either a value or a list of lines prefixed with
+// '-' for the old ones and '+' for the new ones
+type FieldDiff struct {
+	Field string   `json:"field"`
+	Old   string   `json:"old,omitempty"`
+	New   string   `json:"new,omitempty"`
+	Lines []string `json:"lines,omitempty"`
+}
+
+// Empty returns whether the plans are the same
+func (p *PlanDiff) Empty() bool {
+	return len(p.Added) == 0 && len(p.Removed) == 0 && len(p.Services) == 0 && len(p.Artifacts) == 0 && p.GenesisTime == nil
+}
+
+// Print writes the diff in a readable format
+func (p *PlanDiff) Print(w io.Writer) {
+	if p.Empty() {
+		fmt.Fprintln(w, "The plans are the same")
+		return
+	}
+	for _, name := range p.Added {
+		fmt.Fprintf(w, "+ service %s\n", name)
+	}
+	for _, name := range p.Removed {
+		fmt.Fprintf(w, "- service %s\n", name)
+	}
+	for _, svc := range p.Services {
+		fmt.Fprintf(w, "~ service %s\n", svc.Name)
+		for _, change := range svc.Changes {
+			printFieldDiff(w, change)
+		}
+	}
+	if len(p.Artifacts) > 0 {
+		fmt.Fprintf(w, "~ artifacts\n")
+		for _, change := range p.Artifacts {
+			printFieldDiff(w, &FieldDiff{Field: change.Field, Old: shortHash(change.Old), New: shortHash(change.New)})
+		}
+	}
+	if p.GenesisTime != nil {
+		fmt.Fprintf(w, "The genesis time changed (%s -> %s), the hashes of the genesis artifacts change with it\n", p.GenesisTime.Old, p.GenesisTime.New)
+	}
+}
+
+func printFieldDiff(w io.Writer, change *FieldDiff) {
+	if change.Lines == nil {
+		fmt.Fprintf(w, "    %s: %s -> %s\n", change.Field, orNone(change.Old), orNone(change.New))
+		return
+	}
+	fmt.Fprintf(w, "    %s:\n", change.Field)
+	for _, line := range change.Lines {
+		fmt.Fprintf(w, "      %s\n", line)
+	}
+}
+
+// shortHash returns the prefix of a hash that is enough to tell two artifacts apart
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// DiffPlans compares the plans written by two dry runs (see WritePlan). The folders are either
+// the output folders of the dry runs or their plan folders.
+func DiffPlans(oldDir, newDir string) (*PlanDiff, error) {
+	oldPlan, oldDir, err := readPlan(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newPlan, newDir, err := readPlan(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &PlanDiff{}
+	for _, name := range newPlan.Services {
+		if !slices.Contains(oldPlan.Services, name) {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+	for _, name := range oldPlan.Services {
+		if !slices.Contains(newPlan.Services, name) {
+			diff.Removed = append(diff.Removed, name)
+			continue
+		}
+		oldSvc, err := readServicePlan(oldDir, oldPlan, name)
+		if err != nil {
+			return nil, err
+		}
+		newSvc, err := readServicePlan(newDir, newPlan, name)
+		if err != nil {
+			return nil, err
+		}
+		changes, err := diffServicePlans(oldDir, oldPlan, oldSvc, newDir, newPlan, newSvc)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) > 0 {
+			diff.Services = append(diff.Services, &ServiceDiff{Name: name, Changes: changes})
+		}
+	}
+
+	for _, path := range sortedKeys(oldPlan.Artifacts, newPlan.Artifacts) {
+		if oldPlan.Artifacts[path] != newPlan.Artifacts[path] {
+			diff.Artifacts = append(diff.Artifacts, &FieldDiff{Field: path, Old: oldPlan.Artifacts[path], New: newPlan.Artifacts[path]})
+		}
+	}
+	if oldPlan.GenesisTime != newPlan.GenesisTime {
+		diff.GenesisTime = &FieldDiff{Field: "genesisTime", Old: fmt.Sprint(oldPlan.GenesisTime), New: fmt.Sprint(newPlan.GenesisTime)}
+	}
+	return diff, nil
+}
+
+// readPlan reads the plan.json summary of a plan, from the output folder or the plan folder
+func readPlan(dir string) (*Plan, string, error) {
+	if _, err := os.Stat(filepath.Join(dir, planFolder, "plan.json")); err == nil {
+		dir = filepath.Join(dir, planFolder)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "plan.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", fmt.Errorf("no plan in %s, write one with 'cook <recipe> --dry-run --output %s'", dir, dir)
+		}
+		return nil, "", fmt.Errorf("failed to read the plan: %w", err)
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, "", fmt.Errorf("failed to decode the plan %s: %w", dir, err)
+	}
+	return &plan, dir, nil
+}
+
+// readServicePlan reads the plan of a service with the output folder replaced by a placeholder
+func readServicePlan(dir string, plan *Plan, name string) (*ServicePlan, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the plan of service %s: %w", name, err)
+	}
+	var svc ServicePlan
+	if err := json.Unmarshal(data, &svc); err != nil {
+		return nil, fmt.Errorf("failed to decode the plan of service %s: %w", name, err)
+	}
+
+	normalize := func(values []string) []string {
+		for i, value := range values {
+			values[i] = normalizeOutputFolder(plan, value)
+		}
+		return values
+	}
+	svc.Host = normalizeOutputFolder(plan, svc.Host)
+	svc.Command = normalize(svc.Command)
+	svc.Init = normalize(svc.Init)
+	svc.Mounts = normalize(svc.Mounts)
+	for k, v := range svc.Env {
+		svc.Env[k] = normalizeOutputFolder(plan, v)
+	}
+	return &svc, nil
+}
+
+func normalizeOutputFolder(plan *Plan, value string) string {
+	if plan.OutputFolder == "" {
+		return value
+	}
+	return strings.ReplaceAll(value, plan.OutputFolder, outputFolderPlaceholder)
+}
+
+func diffServicePlans(oldDir string, oldPlan *Plan, oldSvc *ServicePlan, newDir string, newPlan *Plan, newSvc *ServicePlan) ([]*FieldDiff, error) {
+	changes := []*FieldDiff{}
+	value := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, &FieldDiff{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	lines := func(field string, oldLines, newLines []string) {
+		if !slices.Equal(oldLines, newLines) {
+			changes = append(changes, &FieldDiff{Field: field, Lines: diffLines(oldLines, newLines)})
+		}
+	}
+
+	value("host", oldSvc.Host, newSvc.Host)
+	value("image", oldSvc.Image, newSvc.Image)
+	value("platform", oldSvc.Platform, newSvc.Platform)
+	value("user", oldSvc.User, newSvc.User)
+	value("entrypoint", oldSvc.Entrypoint, newSvc.Entrypoint)
+	lines("command", oldSvc.Command, newSvc.Command)
+	lines("init", oldSvc.Init, newSvc.Init)
+	value("job", fmt.Sprint(oldSvc.Job), fmt.Sprint(newSvc.Job))
+	for _, k := range sortedKeys(oldSvc.Env, newSvc.Env) {
+		value("env."+k, oldSvc.Env[k], newSvc.Env[k])
+	}
+	lines("envFiles", oldSvc.EnvFiles, newSvc.EnvFiles)
+	lines("mounts", oldSvc.Mounts, newSvc.Mounts)
+	lines("ports", oldSvc.Ports, newSvc.Ports)
+	value("restart", oldSvc.Restart, newSvc.Restart)
+	for _, k := range sortedKeys(oldSvc.DependsOn, newSvc.DependsOn) {
+		value("dependsOn."+k, oldSvc.DependsOn[k], newSvc.DependsOn[k])
+	}
+
+	for _, name := range sortedKeys(oldSvc.Files, newSvc.Files) {
+		oldContent, err := readPlanFile(oldDir, oldPlan, oldSvc.Files[name])
+		if err != nil {
+			return nil, err
+		}
+		newContent, err := readPlanFile(newDir, newPlan, newSvc.Files[name])
+		if err != nil {
+			return nil, err
+		}
+		lines("file "+name, oldContent, newContent)
+	}
+	return changes, nil
+}
+
+// readPlanFile reads the lines of a rendered config file of the plan, by its path relative to
+// the output folder. It returns no lines if the service has no such file.
+func readPlanFile(dir string, plan *Plan, path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	// the paths start with the plan folder, which is the dir
+	rel := strings.TrimPrefix(path, planFolder+"/")
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the plan file %s: %w", path, err)
+	}
+	return strings.Split(normalizeOutputFolder(plan, strings.TrimSuffix(string(data), "\n")), "\n"), nil
+}
+
+// diffLines returns the lines removed from the old ones prefixed with '-' and the lines added
+// in the new ones prefixed with '+', in the order of a longest common subsequence
+func diffLines(oldLines, newLines []string) []string {
+	result := []string{}
+	if len(oldLines)*len(newLines) > maxDiffCells {
+		for _, line := range oldLines {
+			result = append(result, "- "+line)
+		}
+		for _, line := range newLines {
+			result = append(result, "+ "+line)
+		}
+		return result
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:]
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			i, j = i+1, j+1
+		case i < len(oldLines) && (j == len(newLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			result = append(result, "- "+oldLines[i])
+			i++
+		default:
+			result = append(result, "+ "+newLines[j])
+			j++
+		}
+	}
+	return result
+}
+
+// sortedKeys returns the keys of both maps, sorted
+func sortedKeys(a, b map[string]string) []string {
+	keys := []string{}
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,194 @@
+package playground
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/ferranbt/builder-playground/plugin"
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+var _ Recipe = &PluginRecipe{}
+
+var pluginsLog = Logger("plugins")
+
+// PluginRecipe is a recipe served by an external binary in ~/.playground/plugins (see the
+// plugin package). The services returned by the plugin are applied like a YAML recipe.
+type PluginRecipe struct {
+	path  string
+	info  *plugin.Info
+	flags *flag.FlagSet
+
+	// recipe is the YAML recipe of the services returned by the plugin for the values
+	// of the flags, resolved on the first use
+	recipe *YamlRecipe
+}
+
+// LoadPluginRecipes starts the binaries in the plugins folder of the playground home
+// (~/.playground/plugins) to get the recipes they serve. The plugins that fail to load
+// are skipped with a warning.
+func LoadPluginRecipes() ([]*PluginRecipe, error) {
+	homeDir, err := GetHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(homeDir, "plugins")
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins folder: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	recipes := []*PluginRecipe{}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			// not an executable
+			continue
+		}
+		recipe, err := loadPluginRecipe(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			pluginsLog.Warn("failed to load plugin", "plugin", entry.Name(), "err", err)
+			continue
+		}
+		recipes = append(recipes, recipe)
+	}
+	return recipes, nil
+}
+
+func loadPluginRecipe(path string) (*PluginRecipe, error) {
+	var info *plugin.Info
+	err := withPlugin(path, func(recipe plugin.Recipe) (err error) {
+		info, err = recipe.Info()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if info.Name == "" {
+		return nil, fmt.Errorf("recipe name is required")
+	}
+
+	flags := flag.NewFlagSet(info.Name, flag.ContinueOnError)
+	for _, f := range info.Flags {
+		flags.String(f.Name, f.Default, f.Usage)
+	}
+	return &PluginRecipe{path: path, info: info, flags: flags}, nil
+}
+
+// withPlugin starts the plugin binary, calls fn with its recipe and stops it
+func withPlugin(path string, fn func(recipe plugin.Recipe) error) error {
+	client := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig: plugin.Handshake,
+		Plugins: map[string]goplugin.Plugin{
+			plugin.PluginName: &plugin.RecipePlugin{},
+		},
+		Cmd: exec.Command(path),
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Name:   "plugin",
+			Output: os.Stderr,
+			Level:  hclog.Warn,
+		}),
+	})
+	defer client.Kill()
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		return err
+	}
+	raw, err := rpcClient.Dispense(plugin.PluginName)
+	if err != nil {
+		return err
+	}
+	return fn(raw.(plugin.Recipe))
+}
+
+func (p *PluginRecipe) Name() string {
+	return p.info.Name
+}
+
+func (p *PluginRecipe) Description() string {
+	return p.info.Description
+}
+
+func (p *PluginRecipe) Flags() *flag.FlagSet {
+	return p.flags
+}
+
+// resolve gets the services of the plugin for the values of the flags
+func (p *PluginRecipe) resolve() (*YamlRecipe, error) {
+	if p.recipe != nil {
+		return p.recipe, nil
+	}
+
+	req := &plugin.ApplyRequest{Flags: map[string]string{}}
+	p.flags.VisitAll(func(f *flag.Flag) {
+		req.Flags[f.Name] = f.Value.String()
+	})
+
+	var spec *plugin.Spec
+	err := withPlugin(p.path, func(recipe plugin.Recipe) (err error) {
+		spec, err = recipe.Apply(req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply plugin recipe %s: %w", p.info.Name, err)
+	}
+
+	// the spec has the schema of the YAML recipes
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the services of plugin recipe %s: %w", p.info.Name, err)
+	}
+	config := &YamlRecipeConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("failed to decode the services of plugin recipe %s: %w", p.info.Name, err)
+	}
+	config.Name, config.Description = p.info.Name, p.info.Description
+
+	recipe, err := newYamlRecipe(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid services of plugin recipe %s: %w", p.info.Name, err)
+	}
+	p.recipe = recipe
+	return recipe, nil
+}
+
+// Validate resolves the services of the plugin, it has to be called (see ValidateRecipe)
+// before the artifacts are built. The errors of the plugin are artifact failures.
+func (p *PluginRecipe) Validate() error {
+	_, err := p.resolve()
+	return NewClassifiedError(ErrorClassArtifactsFailed, err)
+}
+
+// resolved returns the services of the plugin, resolved by Validate
+func (p *PluginRecipe) resolved() *YamlRecipe {
+	if p.recipe == nil {
+		panic(fmt.Sprintf("plugin recipe %s used before it was validated", p.info.Name))
+	}
+	return p.recipe
+}
+
+func (p *PluginRecipe) Artifacts() *ArtifactsBuilder {
+	return p.resolved().Artifacts()
+}
+
+func (p *PluginRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
+	return p.resolved().Apply(ctx, artifacts)
+}
+
+func (p *PluginRecipe) Output(manifest *Manifest) map[string]*RecipeOutput {
+	return p.resolved().Output(manifest)
+}
@@ -0,0 +1,190 @@
+package playground
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+// SessionResource is a docker container, network or volume created for a session, with the
+// labels of the session (see Session.labels)
+type SessionResource struct {
+	// Kind is container, network or volume
+	Kind string
+	ID   string
+	Name string
+
+	Session   string
+	SessionID string
+}
+
+// listSessionResources returns the containers (stopped too), the networks and the volumes
+// that match the label filters
+func listSessionResources(ctx context.Context, clt *client.Client, args filters.Args) ([]*SessionResource, error) {
+	resources := []*SessionResource{}
+	add := func(kind, id, name string, labels map[string]string) {
+		resources = append(resources, &SessionResource{
+			Kind:      kind,
+			ID:        id,
+			Name:      name,
+			Session:   labels[sessionLabel],
+			SessionID: labels[sessionIDLabel],
+		})
+	}
+
+	containers, err := clt.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("error getting container list: %w", err)
+	}
+	for _, cont := range containers {
+		name := cont.ID
+		if len(cont.Names) > 0 {
+			name = strings.TrimPrefix(cont.Names[0], "/")
+		}
+		add("container", cont.ID, name, cont.Labels)
+	}
+
+	networks, err := clt.NetworkList(ctx, network.ListOptions{Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("error getting network list: %w", err)
+	}
+	for _, net := range networks {
+		add("network", net.ID, net.Name, net.Labels)
+	}
+
+	volumes, err := clt.VolumeList(ctx, volume.ListOptions{Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("error getting volume list: %w", err)
+	}
+	for _, vol := range volumes.Volumes {
+		add("volume", vol.Name, vol.Name, vol.Labels)
+	}
+	return resources, nil
+}
+
+// removeSessionResources removes the containers, then the networks and the volumes that
+// they used. The resources already removed are skipped.
+func removeSessionResources(ctx context.Context, clt *client.Client, resources []*SessionResource) error {
+	for _, kind := range []string{"container", "network", "volume"} {
+		for _, res := range resources {
+			if res.Kind != kind {
+				continue
+			}
+			var err error
+			switch kind {
+			case "container":
+				err = clt.ContainerRemove(ctx, res.ID, container.RemoveOptions{RemoveVolumes: true, Force: true})
+			case "network":
+				err = clt.NetworkRemove(ctx, res.ID)
+			case "volume":
+				err = clt.VolumeRemove(ctx, res.ID, true)
+			}
+			if err != nil && !client.IsErrNotFound(err) {
+				return fmt.Errorf("error removing %s %s: %w", kind, res.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// removeStaleResources removes the resources left by a previous session with the same name
+// whose playground process did not stop them (i.e. it crashed), so that the services of this
+// session do not join its network or reuse its volumes
+func (d *LocalRunner) removeStaleResources(ctx context.Context) error {
+	resources, err := listSessionResources(ctx, d.client, d.sessionFilters())
+	if err != nil {
+		return err
+	}
+	stale := []*SessionResource{}
+	for _, res := range resources {
+		if res.SessionID != d.session.ID {
+			stale = append(stale, res)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	runnerLog.Warn("removing the resources of a previous session that did not stop", "session", d.session.Name, "resources", len(stale))
+	if err := removeSessionResources(ctx, d.client, stale); err != nil {
+		return fmt.Errorf("failed to remove the resources of the previous session: %w", err)
+	}
+	return nil
+}
+
+// LeakedResources returns the resources of the local docker daemon labeled by the playground
+// whose session is not alive, the ones left by the sessions that crashed. The resources without
+// id, of the sessions started by an older version, are leaked if there is no session with their
+// name.
+func LeakedResources(ctx context.Context) ([]*SessionResource, error) {
+	sessions, err := readSessions()
+	if err != nil {
+		return nil, err
+	}
+	aliveIDs, aliveNames := map[string]bool{}, map[string]bool{}
+	for _, session := range sessions {
+		if session.DockerHost != "" {
+			// the resources of the sessions of a remote host are not on the local daemon
+			continue
+		}
+		if !session.alive(ctx) {
+			continue
+		}
+		if session.ID != "" {
+			aliveIDs[session.ID] = true
+		} else {
+			aliveNames[session.Name] = true
+		}
+	}
+
+	clt, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer clt.Close()
+
+	resources, err := listSessionResources(ctx, clt, filters.NewArgs(filters.Arg("label", "playground=true")))
+	if err != nil {
+		return nil, err
+	}
+	leaked := []*SessionResource{}
+	for _, res := range resources {
+		if aliveIDs[res.SessionID] || (res.SessionID == "" && aliveNames[res.Session]) {
+			continue
+		}
+		leaked = append(leaked, res)
+	}
+	return leaked, nil
+}
+
+// PruneResources removes the leaked resources (see LeakedResources) and the files of the
+// sessions that crashed
+func PruneResources(ctx context.Context, resources []*SessionResource) error {
+	clt, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer clt.Close()
+
+	if err := removeSessionResources(ctx, clt, resources); err != nil {
+		return err
+	}
+
+	sessions, err := readSessions()
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.DockerHost == "" && !session.alive(ctx) {
+			if err := removeSession(session.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/mattn/go-isatty"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PullPolicy decides when the images of the services are pulled before they start
+type PullPolicy string
+
+var (
+	PullPolicyAlways  PullPolicy = "always"
+	PullPolicyMissing PullPolicy = "missing"
+	PullPolicyNever   PullPolicy = "never"
+)
+
+func (p PullPolicy) Validate() error {
+	switch p {
+	case PullPolicyAlways, PullPolicyMissing, PullPolicyNever:
+		return nil
+	default:
+		return fmt.Errorf("invalid pull policy '%s', expected always, missing or never", p)
+	}
+}
+
+// imagePull tracks the progress of the pull of an image
+type imagePull struct {
+	image    string
+	platform string
+
+	// layers is the downloaded and total size of each layer
+	layers map[string]*jsonmessage.JSONProgress
+	done   bool
+	err    error
+}
+
+func (p *imagePull) size() (current int64, total int64) {
+	for _, layer := range p.layers {
+		current += layer.Current
+		total += layer.Total
+	}
+	return
+}
+
+// PullImages pulls the images of the services running in containers before they are started
+// (all of them concurrently) and reports the progress of each image
+func (d *LocalRunner) PullImages(ctx context.Context, policy PullPolicy) (err error) {
+	ctx, span := StartSpan(ctx, "pull images", attribute.String("policy", string(policy)))
+	defer func() {
+		EndSpan(span, err)
+	}()
+
+	pulls := []*imagePull{}
+	seen := map[string]bool{}
+	for _, svc := range d.manifest.services {
+		if d.isHostService(svc.Name) {
+			continue
+		}
+		img := d.imageRef(svc)
+		platform := d.resolvePlatform(svc)
+		if seen[img+platform] {
+			continue
+		}
+		seen[img+platform] = true
+
+		if policy != PullPolicyAlways {
+			if _, err := d.client.ImageInspect(ctx, img); err == nil {
+				continue
+			}
+			if policy == PullPolicyNever {
+				return fmt.Errorf("image %s of service %s is not available locally and the pull policy is never", img, svc.Name)
+			}
+		}
+		pulls = append(pulls, &imagePull{image: img, platform: platform, layers: map[string]*jsonmessage.JSONProgress{}})
+	}
+	if len(pulls) == 0 {
+		return nil
+	}
+	sort.Slice(pulls, func(i, j int) bool {
+		return pulls[i].image < pulls[j].image
+	})
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for _, pull := range pulls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := d.pullImage(ctx, pull, &lock)
+
+			lock.Lock()
+			pull.done, pull.err = true, err
+			lock.Unlock()
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	report := newPullReport(os.Stdout, pulls, &lock)
+	for {
+		select {
+		case <-done:
+			report.render()
+			for _, pull := range pulls {
+				if pull.err != nil {
+					return fmt.Errorf("failed to pull image %s: %w", pull.image, pull.err)
+				}
+			}
+			return nil
+		case <-time.After(500 * time.Millisecond):
+			report.render()
+		}
+	}
+}
+
+func (d *LocalRunner) pullImage(ctx context.Context, pull *imagePull, lock *sync.Mutex) (err error) {
+	ctx, span := StartSpan(ctx, "pull "+pull.image, attribute.String("image", pull.image), attribute.String("platform", pull.platform))
+	defer func() {
+		lock.Lock()
+		_, total := pull.size()
+		lock.Unlock()
+		span.SetAttributes(attribute.Int64("size", total))
+		EndSpan(span, err)
+	}()
+
+	resp, err := d.client.ImagePull(ctx, pull.image, image.PullOptions{Platform: pull.platform})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	decoder := json.NewDecoder(resp)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if msg.ID == "" {
+			continue
+		}
+
+		lock.Lock()
+		layer, ok := pull.layers[msg.ID]
+		if !ok {
+			layer = &jsonmessage.JSONProgress{}
+			pull.layers[msg.ID] = layer
+		}
+		switch msg.Status {
+		case "Downloading":
+			if msg.Progress != nil {
+				layer.Current, layer.Total = msg.Progress.Current, msg.Progress.Total
+			}
+		case "Download complete", "Pull complete", "Already exists":
+			// the extraction progress is not part of the download size
+			layer.Current = layer.Total
+		}
+		lock.Unlock()
+	}
+}
+
+// pullReport prints the progress of the pulls. On a terminal, it redraws a progress bar for
+// each image, otherwise it prints a line when each pull starts and finishes.
+type pullReport struct {
+	out      io.Writer
+	pulls    []*imagePull
+	lock     *sync.Mutex
+	terminal bool
+
+	lines    int
+	reported map[string]bool
+}
+
+func newPullReport(out *os.File, pulls []*imagePull, lock *sync.Mutex) *pullReport {
+	r := &pullReport{
+		out:      out,
+		pulls:    pulls,
+		lock:     lock,
+		terminal: isatty.IsTerminal(out.Fd()),
+		reported: map[string]bool{},
+	}
+	fmt.Fprintf(out, "Pulling %d images\n", len(pulls))
+	if !r.terminal {
+		for _, pull := range pulls {
+			fmt.Fprintf(out, "- %s\n", pull.image)
+		}
+	}
+	return r
+}
+
+func (r *pullReport) render() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.terminal {
+		for _, pull := range r.pulls {
+			if pull.done && !r.reported[pull.image] {
+				r.reported[pull.image] = true
+				if pull.err != nil {
+					fmt.Fprintf(r.out, "Failed to pull %s: %v\n", pull.image, pull.err)
+				} else {
+					_, total := pull.size()
+					fmt.Fprintf(r.out, "Pulled %s (%s)\n", pull.image, formatBytes(total))
+				}
+			}
+		}
+		return
+	}
+
+	if r.lines > 0 {
+		fmt.Fprintf(r.out, "\033[%dA\033[J", r.lines)
+	}
+	r.lines = 0
+
+	var current, total int64
+	for _, pull := range r.pulls {
+		c, t := pull.size()
+		current, total = current+c, total+t
+
+		bar, status := progressBar(c, t, 30), fmt.Sprintf("%s/%s", formatBytes(c), formatBytes(t))
+		if pull.err != nil {
+			status = "failed"
+		} else if pull.done {
+			bar, status = progressBar(1, 1, 30), formatBytes(t)
+		}
+		fmt.Fprintf(r.out, "%s %s %s\n", bar, pull.image, status)
+		r.lines++
+	}
+	// the total is an estimate until the manifests of all the layers are known
+	fmt.Fprintf(r.out, "Total: %s/%s\n", formatBytes(current), formatBytes(total))
+	r.lines++
+}
+
+func progressBar(current, total int64, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = int(float64(width) * float64(current) / float64(total))
+	}
+	filled = min(filled, width)
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
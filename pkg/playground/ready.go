@@ -0,0 +1,129 @@
+package playground
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var readyLog = Logger("ready")
+
+// readyReportInterval is how often the services that are not ready yet are reported
+var readyReportInterval = 5 * time.Second
+
+// SetReadyTimeout sets the time to wait for the service to be ready, or for all the services
+// without their own timeout if the name is empty. It applies to the ready checks of the
+// 'healthy' dependencies and to the Ready of the components, instead of their defaults.
+func (s *Manifest) SetReadyTimeout(name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		return fmt.Errorf("invalid ready timeout %s, it must be positive", timeout)
+	}
+	if name == "" {
+		s.readyTimeout = timeout
+		return nil
+	}
+	svc, ok := s.GetService(name)
+	if !ok {
+		return fmt.Errorf("ready timeout for unknown service '%s'", name)
+	}
+	svc.WithReadyTimeout(timeout)
+	return nil
+}
+
+// WithReadyTimeout sets the time to wait for the service to be ready (see SetReadyTimeout)
+func (s *ServiceSpec) WithReadyTimeout(timeout time.Duration) *ServiceSpec {
+	s.readyTimeout = timeout
+	return s
+}
+
+// readyTimeoutOf returns the time to wait for the service to be ready: its own timeout, the
+// one of the manifest or the default of the check
+func readyTimeoutOf(service *ServiceSpec, defaultTimeout time.Duration) time.Duration {
+	if service.readyTimeout != 0 {
+		return service.readyTimeout
+	}
+	if service.manifest != nil && service.manifest.readyTimeout != 0 {
+		return service.manifest.readyTimeout
+	}
+	return defaultTimeout
+}
+
+// readyProgress tracks the services that are not ready yet with the last error of their probes,
+// to report them while the playground waits
+type readyProgress struct {
+	lock     sync.Mutex
+	start    time.Time
+	services map[string]error
+}
+
+func newReadyProgress() *readyProgress {
+	return &readyProgress{start: time.Now(), services: map[string]error{}}
+}
+
+func (r *readyProgress) waiting(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.services[name] = nil
+}
+
+func (r *readyProgress) probe(name string, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.services[name]; ok {
+		r.services[name] = err
+	}
+}
+
+func (r *readyProgress) done(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.services, name)
+}
+
+// report logs the services that are not ready yet every readyReportInterval until the
+// context is done
+func (r *readyProgress) report(ctx context.Context) {
+	ticker := time.NewTicker(readyReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		r.lock.Lock()
+		names := make([]string, 0, len(r.services))
+		for name := range r.services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			args := []any{"service", name, "elapsed", time.Since(r.start).Round(time.Second)}
+			if err := r.services[name]; err != nil {
+				args = append(args, "err", err)
+			}
+			readyLog.Info("waiting for service to be ready", args...)
+		}
+		r.lock.Unlock()
+	}
+}
+
+type readyProbeKey struct{}
+
+// withReadyProbe returns a context that reports the probes of the Ready of the service
+func withReadyProbe(ctx context.Context, progress *readyProgress, name string) context.Context {
+	return context.WithValue(ctx, readyProbeKey{}, func(err error) {
+		progress.probe(name, err)
+	})
+}
+
+// reportReadyProbe reports the result of a probe of the Ready of a component, so that the
+// playground reports why the service is not ready yet
+func reportReadyProbe(ctx context.Context, err error) {
+	if report, ok := ctx.Value(readyProbeKey{}).(func(error)); ok {
+		report(err)
+	}
+}
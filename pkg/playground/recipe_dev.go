@@ -0,0 +1,98 @@
+package playground
+
+import (
+	"fmt"
+
+	flag "github.com/spf13/pflag"
+)
+
+var _ Recipe = &DevRecipe{}
+
+// DevRecipe is a lightweight execution-only chain: a geth node in developer mode (or anvil) that
+// builds the blocks by itself, without a beacon node or validators, with the genesis and the
+// prefunded accounts of the L1 recipe and a faucet.
+type DevRecipe struct {
+	// el is the execution client, geth in developer mode or anvil
+	el string
+
+	// anvilForkURL and anvilForkBlock fork another chain with anvil instead of starting from
+	// the genesis of the playground
+	anvilForkURL   string
+	anvilForkBlock uint64
+
+	// blockTime is the number of seconds between the blocks, zero mines a block for each
+	// transaction
+	blockTime uint64
+
+	latestFork bool
+}
+
+func (d *DevRecipe) Name() string {
+	return "dev"
+}
+
+func (d *DevRecipe) Description() string {
+	return "Deploy an execution-only chain with geth in developer mode (or anvil) and a faucet"
+}
+
+func (d *DevRecipe) Flags() *flag.FlagSet {
+	flags := flag.NewFlagSet("dev", flag.ContinueOnError)
+	flags.StringVar(&d.el, "el", "geth", "execution client (geth, anvil)")
+	flags.StringVar(&d.anvilForkURL, "anvil-fork-url", "", "RPC endpoint of a chain to fork with anvil instead of starting from the genesis of the playground")
+	flags.Uint64Var(&d.anvilForkBlock, "anvil-fork-block", 0, "block of the fork of --anvil-fork-url (defaults to the latest block)")
+	flags.Uint64Var(&d.blockTime, "block-time", 0, "seconds between the blocks, 0 mines a block as soon as there is a pending transaction")
+	flags.BoolVar(&d.latestFork, "latest-fork", false, "use the latest fork")
+	return flags
+}
+
+func (d *DevRecipe) Artifacts() *ArtifactsBuilder {
+	builder := NewArtifactsBuilder()
+	builder.ApplyLatestL1Fork(d.latestFork)
+	builder.ExecutionOnly(true)
+	return builder
+}
+
+// Validate checks the values of the flags before the artifacts are built (see RecipeValidator)
+func (d *DevRecipe) Validate() error {
+	switch d.el {
+	case "geth", "anvil":
+	default:
+		return fmt.Errorf("invalid --el '%s', expected geth or anvil", d.el)
+	}
+	if d.el != "anvil" && (d.anvilForkURL != "" || d.anvilForkBlock != 0) {
+		return fmt.Errorf("--anvil-fork-url and --anvil-fork-block require --el anvil")
+	}
+	return nil
+}
+
+func (d *DevRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
+	svcManager := NewManifest(ctx, artifacts.Out)
+
+	switch d.el {
+	case "geth":
+		svcManager.AddService("el", &GethDev{
+			BlockTime: d.blockTime,
+		})
+	case "anvil":
+		svcManager.AddService("el", &Anvil{
+			ForkURL:   d.anvilForkURL,
+			ForkBlock: d.anvilForkBlock,
+			BlockTime: d.blockTime,
+		})
+	default:
+		panic(fmt.Sprintf("BUG: invalid --el '%s', it is checked by Validate", d.el))
+	}
+	svcManager.AddService("faucet", &Faucet{
+		ExecutionNode: "el",
+	})
+	return svcManager
+}
+
+func (d *DevRecipe) Output(manifest *Manifest) map[string]*RecipeOutput {
+	return map[string]*RecipeOutput{
+		"el-http":     OutputURL("http", "el", "http"),
+		"el-ws":       OutputURL("ws", "el", "ws"),
+		"faucet-http": OutputURL("http", "faucet", "http"),
+		"l1-chain-id": OutputChainID(l1ChainID),
+	}
+}
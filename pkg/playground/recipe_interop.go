@@ -0,0 +1,170 @@
+package playground
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+var _ Recipe = &OpInteropRecipe{}
+
+// OpInteropRecipe is a recipe that deploys two (or more) OP chains with interop messaging
+// between them, validated by op-supervisor. The chains are deployed in the L1 genesis from
+// the op-deployer output folder.
+type OpInteropRecipe struct {
+	// interopDir is the op-deployer output folder with the chains (see InteropChainIDs)
+	interopDir string
+}
+
+func (o *OpInteropRecipe) Name() string {
+	return "opinterop"
+}
+
+func (o *OpInteropRecipe) Description() string {
+	return "Deploy two OP chains with interop messaging and op-supervisor"
+}
+
+func (o *OpInteropRecipe) Flags() *flag.FlagSet {
+	flags := flag.NewFlagSet("opinterop", flag.ContinueOnError)
+	flags.StringVar(&o.interopDir, "interop-dir", "", "op-deployer output folder with the state.json and the genesis and rollup config of each chain")
+	return flags
+}
+
+func (o *OpInteropRecipe) Artifacts() *ArtifactsBuilder {
+	builder := NewArtifactsBuilder()
+	builder.OpInterop(o.interopDir)
+	return builder
+}
+
+func (o *OpInteropRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
+	// the chains are validated when the artifacts are built
+	chainIDs, err := InteropChainIDs(o.interopDir)
+	if err != nil {
+		panic(fmt.Sprintf("BUG: failed to read the interop chains: %v", err))
+	}
+
+	svcManager := NewManifest(ctx, artifacts.Out)
+	svcManager.AddService("el", &RethEL{})
+	svcManager.AddService("beacon", &LighthouseBeaconNode{
+		ExecutionNode: "el",
+	})
+	svcManager.AddService("validator", &LighthouseValidator{
+		BeaconNode: "beacon",
+	})
+
+	nodes := []string{}
+	for _, chainID := range chainIDs {
+		opGeth := fmt.Sprintf("op-geth-%d", chainID)
+		opNode := fmt.Sprintf("op-node-%d", chainID)
+
+		svcManager.AddService(opNode, &OpNode{
+			L1Node:       "el",
+			L1Beacon:     "beacon",
+			L2Node:       opGeth,
+			RollupConfig: fmt.Sprintf("rollup-%d.json", chainID),
+			DataDir:      fmt.Sprintf("db_%d", chainID),
+			Interop:      true,
+		})
+		svcManager.AddService(opGeth, &OpGeth{
+			Genesis:    fmt.Sprintf("l2-genesis-%d.json", chainID),
+			DataDir:    fmt.Sprintf("data_opgeth_%d", chainID),
+			Supervisor: "op-supervisor",
+		})
+		svcManager.AddService(fmt.Sprintf("op-batcher-%d", chainID), &OpBatcher{
+			L1Node:     "el",
+			L2Node:     opGeth,
+			RollupNode: opNode,
+		})
+		nodes = append(nodes, opNode)
+	}
+
+	svcManager.AddService("op-supervisor", &OpSupervisor{
+		L1Node: "el",
+		Nodes:  nodes,
+	})
+	return svcManager
+}
+
+func (o *OpInteropRecipe) Output(manifest *Manifest) map[string]*RecipeOutput {
+	outputs := map[string]*RecipeOutput{
+		"el-http":         OutputURL("http", "el", "http"),
+		"el-ws":           OutputURL("ws", "el", "ws"),
+		"el-ipc":          OutputIPCPath("reth.ipc"),
+		"beacon-http":     OutputURL("http", "beacon", "http"),
+		"supervisor-http": OutputURL("http", "op-supervisor", "http"),
+		"jwt-path":        OutputJWTPath("el"),
+		"l1-chain-id":     OutputChainID(l1ChainID),
+	}
+
+	chainIDs, err := InteropChainIDs(o.interopDir)
+	if err != nil {
+		panic(fmt.Sprintf("BUG: failed to read the interop chains: %v", err))
+	}
+	for _, chainID := range chainIDs {
+		outputs[fmt.Sprintf("l2-el-http-%d", chainID)] = OutputURL("http", fmt.Sprintf("op-geth-%d", chainID), "http")
+		outputs[fmt.Sprintf("l2-el-ws-%d", chainID)] = OutputURL("ws", fmt.Sprintf("op-geth-%d", chainID), "ws")
+		outputs[fmt.Sprintf("l2-el-ipc-%d", chainID)] = OutputIPCPath(fmt.Sprintf("data_opgeth_%d/geth.ipc", chainID))
+		outputs[fmt.Sprintf("op-node-http-%d", chainID)] = OutputURL("http", fmt.Sprintf("op-node-%d", chainID), "http")
+	}
+	return outputs
+}
+
+// InteropChainIDs returns the ids of the chains in the op-deployer output folder. Each chain has
+// the genesis-<chain id>.json and rollup-<chain id>.json files generated with
+// 'op-deployer inspect genesis' and 'op-deployer inspect rollup'. There must be at least two chains.
+func InteropChainIDs(dir string) ([]uint64, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("the op-deployer output folder is required")
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the op-deployer output folder: %w", err)
+	}
+
+	chainIDs := []uint64{}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasPrefix(name, "rollup-") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		chainID, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, "rollup-"), ".json"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rollup config file name '%s', expected rollup-<chain id>.json", name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("genesis-%d.json", chainID))); err != nil {
+			return nil, fmt.Errorf("genesis of chain %d not found: %w", chainID, err)
+		}
+		chainIDs = append(chainIDs, chainID)
+	}
+	if len(chainIDs) < 2 {
+		return nil, fmt.Errorf("at least two chains are required for interop, found %d", len(chainIDs))
+	}
+
+	sort.Slice(chainIDs, func(i, j int) bool {
+		return chainIDs[i] < chainIDs[j]
+	})
+	return chainIDs, nil
+}
+
+type interopDependency struct {
+	ChainIndex     uint64 `json:"chainIndex"`
+	ActivationTime uint64 `json:"activationTime"`
+	HistoryMinTime uint64 `json:"historyMinTime"`
+}
+
+// newInteropDependencySet returns the dependency set of op-supervisor with all the chains
+// active from genesis
+func newInteropDependencySet(chainIDs []uint64) map[string]interface{} {
+	dependencies := map[string]*interopDependency{}
+	for _, chainID := range chainIDs {
+		dependencies[strconv.FormatUint(chainID, 10)] = &interopDependency{ChainIndex: chainID}
+	}
+	return map[string]interface{}{
+		"dependencies": dependencies,
+	}
+}
@@ -0,0 +1,731 @@
+package playground
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+var _ Recipe = &L1Recipe{}
+
+type L1Recipe struct {
+	// latestFork enables the use of the latest fork at startup
+	latestFork bool
+
+	// useRethForValidation signals mev-boost to use the Reth EL node for block validation
+	useRethForValidation bool
+
+	// validationNode deploys a dedicated node (with its own beacon node) to validate the
+	// block submissions of the relay
+	validationNode bool
+
+	// optimisticRelay makes the relay accept the bids of the builder before they are validated
+	optimisticRelay bool
+
+	// secondaryELPort enables the use of a secondary EL connected to the validator beacon node
+	// It is enabled through the use of the cl-proxy service
+	secondaryELPort uint64
+
+	// if useNativeReth is set to true, the Reth EL execution client for the validator beacon node
+	// will run on the host machine. This is useful if you want to bind to the Reth database and you
+	// are running a host machine (i.e Mac) that is differerent from the docker one (Linux)
+	useNativeReth bool
+
+	// elPruning, elStaticFiles and elMetrics configure the reth node of the validator beacon node
+	// (see RethEL)
+	elPruning     string
+	elStaticFiles string
+	elMetrics     bool
+
+	// expectBids makes the watchdog assert that the relay receives and delivers
+	// builder bids every slot
+	expectBids bool
+
+	// expectPeers makes the watchdog assert that the nodes are connected to the peers of
+	// their topology
+	expectPeers bool
+
+	// extraNodes is the number of EL/CL node pairs without validators added to the devnet
+	extraNodes uint64
+
+	// builder is the block builder to deploy (geth-builder or rbuilder).
+	// It submits the blocks to the relay.
+	builder string
+
+	// recordEngineAPI and recordBeaconAPI deploy a proxy in front of the Engine API of the EL
+	// and the Beacon API of the beacon node that records all the requests
+	recordEngineAPI bool
+	recordBeaconAPI bool
+
+	// fault injection on the Engine API proxy, it enables the proxy
+	engineProxyLatency  time.Duration
+	engineProxyJitter   time.Duration
+	engineProxyFailRate float64
+	engineProxyMethods  []string
+
+	// engineMux drives these ELs besides the main one from the beacon node (see EngineMux),
+	// selecting the payload of the proposals with engineMuxPolicy
+	engineMux       []string
+	engineMuxPolicy string
+
+	// checkpointSync makes the extra beacon nodes checkpoint sync from the first beacon node
+	checkpointSync bool
+
+	// minorityNode deploys an EL/CL node pair with its own validator client and a third of the
+	// validators, which 'chaos reorg' partitions from the rest of the network to create reorgs
+	minorityNode bool
+
+	// topology is how the main node and the extra nodes peer with each other. If it is empty,
+	// the beacon nodes connect to the main beacon node and the execution nodes do not peer.
+	topology string
+
+	// bootnode deploys a discovery bootnode and enables the discovery of the nodes
+	bootnode bool
+
+	// rpcGateway deploys a JSON-RPC gateway in front of the EL that routes the bundle methods
+	// to the builder, plus the extra rpcGatewayRoutes (method=service)
+	rpcGateway       bool
+	rpcGatewayRoutes []string
+
+	// withAnvil deploys anvil forking the EL, to simulate the transactions and the bundles
+	// against the state of the devnet
+	withAnvil bool
+
+	// sentryNodes is the number of EL/CL node pairs that forward the transactions sent to them
+	// only to the mempool node (see mempoolNode), and privateMempool makes the mempool node
+	// connect only to the sentry nodes, so that its transactions are not shared with the network
+	sentryNodes    uint64
+	privateMempool bool
+
+	// feeRecipient and gasLimit are the preferences of the validators, which registerValidators
+	// registers with the relay once it is ready
+	feeRecipient       string
+	gasLimit           uint64
+	registerValidators bool
+
+	// genesisGasLimit is the gas limit of the genesis block, the blocks move from it towards
+	// gasLimit. extraData is the extra data of the genesis and of the blocks built by the ELs
+	// and the builders.
+	genesisGasLimit uint64
+	extraData       string
+
+	// flashbotsRelay deploys the Flashbots mev-boost-relay instead of the relay of playground-utils
+	// (see RelayRecipe)
+	flashbotsRelay bool
+
+	// withMevBoost deploys a mev-boost sidecar for the beacon nodes of the validators connected to
+	// these relays: "local" for the relay of the recipe or the URL of an external relay
+	withMevBoost []string
+
+	// elDataDir and clDataDir are existing data folders of the chain that the EL and the beacon
+	// node start from, copied or mounted depending on dataDirMode (see SeedDataDirs)
+	elDataDir   string
+	clDataDir   string
+	dataDirMode string
+}
+
+func (l *L1Recipe) Name() string {
+	return "l1"
+}
+
+func (l *L1Recipe) Description() string {
+	return "Deploy a full L1 stack with mev-boost"
+}
+
+func (l *L1Recipe) Flags() *flag.FlagSet {
+	flags := flag.NewFlagSet("l1", flag.ContinueOnError)
+	flags.BoolVar(&l.latestFork, "latest-fork", false, "use the latest fork")
+	flags.BoolVar(&l.useRethForValidation, "use-reth-for-validation", false, "use reth for validation")
+	flags.BoolVar(&l.validationNode, "validation-node", false, "deploy a dedicated reth node to validate the block submissions of the relay")
+	flags.BoolVar(&l.optimisticRelay, "optimistic-relay", false, "accept the builder bids before they are validated (optimistic relaying)")
+	flags.Uint64Var(&l.secondaryELPort, "secondary-el", 0, "port to use for the secondary builder")
+	flags.BoolVar(&l.useNativeReth, "use-native-reth", false, "use the native reth binary")
+	flags.StringVar(&l.elPruning, "el-pruning", "archive", "pruning mode of the EL (archive, full)")
+	flags.StringVar(&l.elStaticFiles, "el-static-files", "", "folder of the static files of the EL inside the output folder, defaults to the data folder")
+	flags.BoolVar(&l.elMetrics, "el-metrics", false, "expose the Prometheus metrics of the EL on the metrics port")
+	flags.Uint64Var(&l.extraNodes, "extra-nodes", 0, "number of extra EL/CL node pairs without validators")
+	flags.StringSliceVar(&l.withMevBoost, "with-mev-boost", []string{}, "deploy a mev-boost sidecar for the beacon nodes of the validators connected to the relays: local (the relay of the recipe) or the URL of an external relay with its public key, --with-mev-boost alone uses the local relay")
+	flags.Lookup("with-mev-boost").NoOptDefVal = mevBoostLocalRelay
+	flags.BoolVar(&l.minorityNode, "minority-node", false, "deploy an EL/CL node pair with a third of the validators that 'chaos reorg' can partition from the network")
+	flags.BoolVar(&l.checkpointSync, "checkpoint-sync", false, "checkpoint sync the extra beacon nodes from the first beacon node instead of syncing from genesis")
+	flags.StringVar(&l.builder, "builder", "", "block builder that submits blocks to the relay (geth-builder, rbuilder)")
+	flags.BoolVar(&l.recordEngineAPI, "record-engine-api", false, "record the Engine API requests between the beacon node and the EL")
+	flags.DurationVar(&l.engineProxyLatency, "engine-proxy-latency", 0, "latency added to the Engine API requests (enables the Engine API proxy)")
+	flags.DurationVar(&l.engineProxyJitter, "engine-proxy-jitter", 0, "random jitter added to the Engine API latency")
+	flags.Float64Var(&l.engineProxyFailRate, "engine-proxy-fail-rate", 0, "rate (0-1) of Engine API requests that fail (enables the Engine API proxy)")
+	flags.StringSliceVar(&l.engineProxyMethods, "engine-proxy-methods", []string{}, "Engine API methods (or prefixes) affected by the latency and failures, defaults to all")
+	flags.StringSliceVar(&l.engineMux, "engine-mux", []string{}, "ELs driven by the beacon node besides the main one to compare their payloads: reth (a new reth node), a service of the recipe or the host port of an external EL")
+	flags.StringVar(&l.engineMuxPolicy, "engine-mux-policy", "primary", "payload returned to the beacon node with --engine-mux (primary, highest-value, round-robin)")
+	flags.BoolVar(&l.recordBeaconAPI, "record-beacon-api", false, "record the Beacon API requests to the beacon node")
+	flags.StringVar(&l.topology, "topology", "", "how the main node and the extra nodes peer over p2p (star, ring, full)")
+	flags.BoolVar(&l.bootnode, "bootnode", false, "deploy a discovery bootnode for the execution and beacon nodes")
+	flags.BoolVar(&l.rpcGateway, "rpc-gateway", false, "deploy a JSON-RPC gateway that routes the bundles to the builder and the rest of the methods to the EL")
+	flags.StringArrayVar(&l.rpcGatewayRoutes, "rpc-gateway-route", []string{}, "extra route of the JSON-RPC gateway from a method (or prefix ending with *) to a service (i.e. eth_call=el-1)")
+	flags.BoolVar(&l.withAnvil, "with-anvil", false, "deploy anvil forking the EL to simulate transactions and bundles against the state of the devnet")
+	flags.Uint64Var(&l.sentryNodes, "sentry-nodes", 0, "number of sentry EL/CL node pairs that forward their transactions only to the builder EL")
+	flags.BoolVar(&l.privateMempool, "private-mempool", false, "connect the builder EL only to its trusted peers (the sentry nodes) so that its mempool is private")
+	flags.StringVar(&l.feeRecipient, "fee-recipient", defaultFeeRecipient, "fee recipient of the blocks proposed by the validators")
+	flags.Uint64Var(&l.gasLimit, "gas-limit", defaultGasLimit, "target gas limit of the blocks, registered by the validators with the relay and used by the ELs and the builders")
+	flags.Uint64Var(&l.genesisGasLimit, "genesis-gas-limit", 0, "gas limit of the genesis block, the blocks move from it towards --gas-limit (defaults to the one of the genesis state of prysm)")
+	flags.StringVar(&l.extraData, "extra-data", "", "extra data (up to 32 bytes) of the genesis block and of the blocks built by the ELs and the builders")
+	flags.BoolVar(&l.registerValidators, "register-validators", true, "register the validators with the relay once it is ready")
+	flags.BoolVar(&l.expectBids, "expect-bids", false, "assert in the watchdog that the relay receives and delivers builder bids every slot")
+	flags.BoolVar(&l.expectPeers, "expect-peers", false, "assert in the watchdog that the ELs and the beacon nodes are connected to the peers of their topology")
+	flags.StringVar(&l.elDataDir, "el-datadir", "", "existing data folder of the EL, from a previous output folder, to start the chain from")
+	flags.StringVar(&l.clDataDir, "cl-datadir", "", "existing data folder of the beacon node, from a previous output folder, to start the chain from")
+	flags.StringVar(&l.dataDirMode, "datadir-mode", string(DataDirModeCopy), "how the existing data folders are used (copy, bind)")
+	return flags
+}
+
+func (l *L1Recipe) Artifacts() *ArtifactsBuilder {
+	builder := NewArtifactsBuilder()
+	builder.ApplyLatestL1Fork(l.latestFork)
+	builder.MinorityValidators(l.minorityNode)
+	builder.SeedDataDirs(l.elDataDir, l.clDataDir)
+	builder.GenesisBlock(l.genesisGasLimit, l.extraData)
+
+	return builder
+}
+
+// Validate checks the values of the flags before the artifacts are built (see RecipeValidator)
+func (l *L1Recipe) Validate() error {
+	switch l.builder {
+	case "", "geth-builder", "rbuilder":
+	default:
+		return fmt.Errorf("unknown builder '%s', expected geth-builder or rbuilder", l.builder)
+	}
+	if l.topology != "" {
+		if err := Topology(l.topology).Validate(); err != nil {
+			return err
+		}
+		if l.useNativeReth {
+			return fmt.Errorf("--topology is not supported with --use-native-reth, the native EL cannot peer with the ones in docker")
+		}
+	}
+	if l.sentryNodes != 0 || l.privateMempool {
+		switch mempoolNode := l.mempoolNode(); mempoolNode {
+		case "":
+			return fmt.Errorf("--sentry-nodes and --private-mempool are not supported with --builder %s, it does not peer with other nodes", l.builder)
+		case "el":
+			if l.useNativeReth && l.sentryNodes != 0 {
+				return fmt.Errorf("--sentry-nodes is not supported with --use-native-reth, the native EL cannot peer with the ones in docker")
+			}
+		}
+	}
+	for _, route := range l.rpcGatewayRoutes {
+		if method, target, ok := strings.Cut(route, "="); !ok || method == "" || target == "" {
+			return fmt.Errorf("invalid --rpc-gateway-route '%s', expected method=service", route)
+		}
+	}
+	registration := &ValidatorRegistration{
+		FeeRecipient: l.feeRecipient,
+		GasLimit:     l.gasLimit,
+	}
+	if err := registration.Validate(); err != nil {
+		return err
+	}
+	if len(l.extraData) > maxExtraDataSize {
+		return fmt.Errorf("--extra-data is %d bytes, the maximum is %d", len(l.extraData), maxExtraDataSize)
+	}
+	if len(l.engineMux) != 0 {
+		if l.secondaryELPort != 0 {
+			return fmt.Errorf("--engine-mux cannot be used with --secondary-el")
+		}
+		switch l.engineMuxPolicy {
+		case "primary", "highest-value", "round-robin":
+		default:
+			return fmt.Errorf("unknown engine mux policy '%s', expected primary, highest-value or round-robin", l.engineMuxPolicy)
+		}
+	}
+	for _, relay := range l.withMevBoost {
+		if err := validateMevBoostRelay(relay); err != nil {
+			return err
+		}
+	}
+	if l.optimisticRelay {
+		if l.flashbotsRelay {
+			return fmt.Errorf("--optimistic-relay is not supported by the Flashbots relay")
+		}
+		if !l.validationNode && !l.useRethForValidation && l.builder != "geth-builder" {
+			return fmt.Errorf("--optimistic-relay requires a validation server (--validation-node, --use-reth-for-validation or --builder geth-builder)")
+		}
+	}
+	if err := RethPruning(l.elPruning).Validate(); err != nil {
+		return fmt.Errorf("invalid --el-pruning: %w", err)
+	}
+	if l.elDataDir != "" && l.elStaticFiles != "" {
+		return fmt.Errorf("--el-static-files cannot be used with --el-datadir, the static files are in the datadir")
+	}
+	if err := DataDirMode(l.dataDirMode).Validate(); err != nil {
+		return fmt.Errorf("invalid --datadir-mode: %w", err)
+	}
+	for _, datadir := range []struct{ flag, path string }{{"--el-datadir", l.elDataDir}, {"--cl-datadir", l.clDataDir}} {
+		if datadir.path == "" {
+			continue
+		}
+		if _, err := NewDataDirSeed(datadir.path, DataDirMode(l.dataDirMode)); err != nil {
+			return fmt.Errorf("invalid %s: %w", datadir.flag, err)
+		}
+	}
+	return nil
+}
+
+func (l *L1Recipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
+	svcManager := NewManifest(ctx, artifacts.Out)
+
+	registration := &ValidatorRegistration{
+		FeeRecipient: l.feeRecipient,
+		GasLimit:     l.gasLimit,
+	}
+
+	mempoolNode := l.mempoolNode()
+	sentries := []string{}
+	for i := 1; i <= int(l.sentryNodes); i++ {
+		sentries = append(sentries, fmt.Sprintf("sentry-el-%d", i))
+	}
+	// the mempool node connects to the sentry nodes as trusted peers
+	mempoolPeers := func(name string) []string {
+		if name == mempoolNode {
+			return sentries
+		}
+		return nil
+	}
+
+	var bootnode string
+	if l.bootnode {
+		bootnode = "bootnode"
+		svcManager.AddService(bootnode, &Bootnode{})
+	}
+
+	elSeed, beaconSeed := l.dataDirSeed(l.elDataDir), l.dataDirSeed(l.clDataDir)
+
+	svcManager.AddService("el", &RethEL{
+		UseRethForValidation: l.useRethForValidation,
+		UseNativeReth:        l.useNativeReth,
+		Bootnode:             bootnode,
+		Pruning:              RethPruning(l.elPruning),
+		StaticFilesDir:       l.elStaticFiles,
+		Metrics:              l.elMetrics,
+		Peers:                mempoolPeers("el"),
+		TrustedOnly:          l.privateMempool && mempoolNode == "el",
+		Seed:                 elSeed,
+		GasLimit:             registration.GasLimit,
+		ExtraData:            l.extraData,
+		VerifyBlocks:         true,
+		ExpectPeers:          l.expectPeers,
+	})
+
+	var elService string
+	if len(l.engineMux) != 0 {
+		secondaries := []string{}
+		for i, secondary := range l.engineMux {
+			if secondary == "reth" {
+				// the node follows the chain only through the Engine API of the mux
+				secondary = fmt.Sprintf("mux-reth-%d", i+1)
+				svcManager.AddService(secondary, &RethEL{
+					DataDir:   "data_reth_" + secondary,
+					Bootnode:  bootnode,
+					GasLimit:  registration.GasLimit,
+					ExtraData: l.extraData,
+				})
+			}
+			secondaries = append(secondaries, secondary)
+		}
+		elService = "engine-mux"
+		svcManager.AddService("engine-mux", &EngineMux{
+			Primary:     "el",
+			Secondaries: secondaries,
+			Policy:      l.engineMuxPolicy,
+		})
+	} else if l.secondaryELPort != 0 {
+		// we are going to use the cl-proxy service to connect the beacon node to two builders
+		// one the 'el' builder and another one the remote one
+		elService = "cl-proxy"
+		svcManager.AddService("cl-proxy", &ClProxy{
+			PrimaryBuilder:   "el",
+			SecondaryBuilder: fmt.Sprintf("http://localhost:%d", l.secondaryELPort),
+		})
+	} else {
+		elService = "el"
+	}
+
+	if l.recordEngineAPI || l.engineProxyLatency != 0 || l.engineProxyJitter != 0 || l.engineProxyFailRate != 0 {
+		svcManager.AddService("el-proxy", &ApiProxy{
+			Service:   elService,
+			PortLabel: "authrpc",
+			Latency:   l.engineProxyLatency,
+			Jitter:    l.engineProxyJitter,
+			FailRate:  l.engineProxyFailRate,
+			Methods:   l.engineProxyMethods,
+		})
+		elService = "el-proxy"
+	}
+
+	mevBoostNode := "mev-boost"
+	if len(l.withMevBoost) != 0 {
+		svcManager.AddService("mev-boost-sidecar", &MevBoost{Relays: l.mevBoostRelays()})
+		mevBoostNode = "mev-boost-sidecar"
+	}
+
+	svcManager.AddService("beacon", &LighthouseBeaconNode{
+		ExecutionNode: elService,
+		MevBoostNode:  mevBoostNode,
+		TargetPeers:   l.targetPeers(),
+		Bootnode:      bootnode,
+		Seed:          beaconSeed,
+		ExpectPeers:   l.expectPeers,
+	})
+	// the extra nodes can be scaled at runtime with 'playground scale cl-node=N'
+	svcManager.AddScalable("cl-node", int(l.maxNodes()), func(manifest *Manifest, i int) {
+		elName, beaconName := nodeNames(i)
+		el := &RethEL{
+			DataDir:     "data_reth_" + elName,
+			Bootnode:    bootnode,
+			ExpectPeers: l.expectPeers,
+		}
+		beacon := &LighthouseBeaconNode{
+			ExecutionNode: elName,
+			DataDir:       "data_beacon_node_" + beaconName,
+			TargetPeers:   1,
+			PeerNodes:     []string{"beacon"},
+			Bootnode:      bootnode,
+			ExpectPeers:   l.expectPeers,
+		}
+		if l.topology != "" {
+			topology := Topology(l.topology)
+			beacon.PeerNodes = []string{}
+			for _, peer := range topology.Peers(i, 1+int(l.extraNodes)) {
+				peerEL, peerBeacon := nodeNames(peer)
+				el.Peers = append(el.Peers, peerEL)
+				beacon.PeerNodes = append(beacon.PeerNodes, peerBeacon)
+			}
+			beacon.TargetPeers = topology.TargetPeers(1 + int(l.maxNodes()))
+		}
+		manifest.AddService(elName, el)
+		if l.checkpointSync {
+			beacon.CheckpointSyncNode = "beacon"
+		}
+		manifest.AddService(beaconName, beacon)
+	})
+	if _, _, err := svcManager.Scale("cl-node", int(l.extraNodes)); err != nil {
+		panic(fmt.Sprintf("BUG: failed to add the extra nodes: %s", err))
+	}
+
+	beaconService := "beacon"
+	if l.recordBeaconAPI {
+		svcManager.AddService("beacon-proxy", &ApiProxy{
+			Service:   "beacon",
+			PortLabel: "http",
+		})
+		beaconService = "beacon-proxy"
+	}
+
+	svcManager.AddService("validator", &LighthouseValidator{
+		BeaconNode:   beaconService,
+		FeeRecipient: registration.FeeRecipient,
+		GasLimit:     registration.GasLimit,
+	})
+
+	if l.minorityNode {
+		svcManager.AddService("el-minority", &RethEL{
+			DataDir:     "data_reth_minority",
+			Bootnode:    bootnode,
+			GasLimit:    registration.GasLimit,
+			ExtraData:   l.extraData,
+			ExpectPeers: l.expectPeers,
+		})
+		minorityBeacon := &LighthouseBeaconNode{
+			ExecutionNode: "el-minority",
+			DataDir:       "data_beacon_node_minority",
+			TargetPeers:   1,
+			PeerNodes:     []string{"beacon"},
+			Bootnode:      bootnode,
+			ExpectPeers:   l.expectPeers,
+		}
+		if len(l.withMevBoost) != 0 {
+			svcManager.AddService("mev-boost-sidecar-minority", &MevBoost{Relays: l.mevBoostRelays()})
+			minorityBeacon.MevBoostNode = "mev-boost-sidecar-minority"
+		}
+		svcManager.AddService("beacon-minority", minorityBeacon)
+		svcManager.AddService("validator-minority", &LighthouseValidator{
+			BeaconNode:   "beacon-minority",
+			DataDir:      "data_validator_minority",
+			FeeRecipient: registration.FeeRecipient,
+			GasLimit:     registration.GasLimit,
+		})
+	}
+
+	mevBoostValidationServer := ""
+	if l.validationNode {
+		svcManager.AddService("validation", &ValidationNode{Bootnode: bootnode})
+		svcManager.AddService("beacon-validation", &LighthouseBeaconNode{
+			ExecutionNode: "validation",
+			DataDir:       "data_beacon_node_validation",
+			TargetPeers:   1,
+			PeerNodes:     []string{"beacon"},
+			Bootnode:      bootnode,
+			ExpectPeers:   l.expectPeers,
+		})
+		mevBoostValidationServer = "validation"
+	} else if l.useRethForValidation {
+		mevBoostValidationServer = "el"
+	}
+
+	// the builder follows the chain with its own beacon node, peered with the main one
+	switch l.builder {
+	case "":
+	case "geth-builder":
+		svcManager.AddService("builder", &FlashbotsBuilder{
+			BeaconNode: "beacon-builder",
+			Relay:      "mev-boost",
+			GasLimit:   registration.GasLimit,
+			ExtraData:  l.extraData,
+		})
+		svcManager.AddService("beacon-builder", &LighthouseBeaconNode{
+			ExecutionNode: "builder",
+			DataDir:       "data_beacon_node_builder",
+			TargetPeers:   1,
+			PeerNodes:     []string{"beacon"},
+			Bootnode:      bootnode,
+			ExpectPeers:   l.expectPeers,
+		})
+		if mevBoostValidationServer == "" {
+			// the builder exposes the flashbots block validation API
+			mevBoostValidationServer = "builder"
+		}
+	case "rbuilder":
+		// rbuilder reads the state from the database of its own reth node, in a shared volume
+		svcManager.AddService("builder-el", &RethEL{
+			DataDir:     "data_reth_builder",
+			DataVolume:  "reth-builder",
+			Bootnode:    bootnode,
+			Peers:       mempoolPeers("builder-el"),
+			TrustedOnly: l.privateMempool,
+			ExpectPeers: l.expectPeers,
+		})
+		svcManager.AddService("beacon-builder", &LighthouseBeaconNode{
+			ExecutionNode: "builder-el",
+			DataDir:       "data_beacon_node_builder",
+			TargetPeers:   1,
+			PeerNodes:     []string{"beacon"},
+			Bootnode:      bootnode,
+			ExpectPeers:   l.expectPeers,
+		})
+		svcManager.AddService("builder", &Rbuilder{
+			ExecutionNode:  "builder-el",
+			BeaconNode:     "beacon-builder",
+			Relay:          "mev-boost",
+			RethDataDir:    "data_reth_builder",
+			RethDataVolume: "reth-builder",
+			ExtraData:      l.extraData,
+		})
+	default:
+		panic(fmt.Sprintf("BUG: unknown builder '%s', it is checked by Validate", l.builder))
+	}
+	// the sentry nodes follow the chain with their own beacon nodes and their only peer over
+	// p2p is the mempool node, so the transactions sent to them only reach its mempool
+	for i, sentry := range sentries {
+		svcManager.AddService(sentry, &RethEL{
+			DataDir:     fmt.Sprintf("data_reth_sentry_%d", i+1),
+			ExpectPeers: l.expectPeers,
+		})
+		svcManager.AddService(fmt.Sprintf("sentry-beacon-%d", i+1), &LighthouseBeaconNode{
+			ExecutionNode: sentry,
+			DataDir:       fmt.Sprintf("data_beacon_node_sentry_%d", i+1),
+			TargetPeers:   1,
+			PeerNodes:     []string{"beacon"},
+			Bootnode:      bootnode,
+			ExpectPeers:   l.expectPeers,
+		})
+	}
+
+	var optimisticBuilders []string
+	if l.optimisticRelay {
+		builderPubkey, err := builderPublicKey()
+		if err != nil {
+			panic(fmt.Sprintf("BUG: failed to get the builder public key: %s", err))
+		}
+		optimisticBuilders = []string{builderPubkey}
+	}
+	if !l.registerValidators {
+		registration = nil
+	}
+	if l.flashbotsRelay {
+		// the relay API keeps the name of the relay so that the beacon node and the builders connect to it
+		if mevBoostValidationServer == "" {
+			panic("BUG: the Flashbots relay requires a validation server")
+		}
+		svcManager.AddService("relay-db", relayDatabase)
+		svcManager.AddService("relay-redis", relayRedis)
+		svcManager.AddService("relay-housekeeper", &FlashbotsRelayHousekeeper{
+			BeaconClient: beaconService,
+			Database:     "relay-db",
+			Redis:        "relay-redis",
+		})
+		svcManager.AddService("mev-boost", &FlashbotsRelayAPI{
+			BeaconClient:     beaconService,
+			ValidationServer: mevBoostValidationServer,
+			Housekeeper:      "relay-housekeeper",
+			Database:         "relay-db",
+			Redis:            "relay-redis",
+			ExpectBids:       l.expectBids,
+			Registration:     registration,
+		})
+		svcManager.AddService("relay-website", &FlashbotsRelayWebsite{
+			Database: "relay-db",
+			Redis:    "relay-redis",
+		})
+	} else {
+		svcManager.AddService("mev-boost", &MevBoostRelay{
+			BeaconClient:       beaconService,
+			ValidationServer:   mevBoostValidationServer,
+			OptimisticBuilders: optimisticBuilders,
+			ExpectBids:         l.expectBids,
+			Registration:       registration,
+		})
+	}
+
+	if l.rpcGateway || len(l.rpcGatewayRoutes) != 0 {
+		routes := map[string]string{}
+		if l.builder != "" {
+			for _, method := range bundleMethods {
+				routes[method] = "builder"
+			}
+		}
+		// the routes to unknown services fail the validation of the manifest
+		for _, route := range l.rpcGatewayRoutes {
+			method, target, _ := strings.Cut(route, "=")
+			routes[method] = target
+		}
+		svcManager.AddService("rpc-gateway", &RpcGateway{
+			Default: "el",
+			Routes:  routes,
+		})
+	}
+	if l.withAnvil {
+		svcManager.AddService("anvil", &Anvil{ForkNode: "el"})
+	}
+	return svcManager
+}
+
+// bundleMethods are the JSON-RPC methods of the bundles that the gateway routes to the builder
+var bundleMethods = []string{"eth_sendBundle", "eth_callBundle", "eth_cancelBundle", "mev_*"}
+
+// mempoolNode returns the EL that receives the transactions of the sentry nodes: the EL of the
+// builder or, without a builder, the EL of the validators. It is empty if the builder does not
+// peer with other nodes.
+func (l *L1Recipe) mempoolNode() string {
+	switch l.builder {
+	case "":
+		return "el"
+	case "rbuilder":
+		return "builder-el"
+	}
+	return ""
+}
+
+// maxScaledNodes is the number of extra nodes that can be added at runtime if --extra-nodes is lower
+const maxScaledNodes = 8
+
+// mevBoostLocalRelay is the relay of --with-mev-boost for the relay of the recipe
+const mevBoostLocalRelay = "local"
+
+// validateMevBoostRelay checks a relay of --with-mev-boost, local or the URL of an external
+// relay with its public key
+func validateMevBoostRelay(relay string) error {
+	if relay == mevBoostLocalRelay {
+		return nil
+	}
+	u, err := url.Parse(relay)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid --with-mev-boost relay '%s', expected local or the URL of a relay", relay)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return fmt.Errorf("invalid --with-mev-boost relay '%s', the URL must include the public key of the relay (i.e. https://0xabc@relay.example)", relay)
+	}
+	return nil
+}
+
+// mevBoostRelays returns the relays of the mev-boost sidecars, the services of the relays of the
+// recipe or the URLs of the external relays
+func (l *L1Recipe) mevBoostRelays() []string {
+	relays := []string{}
+	for _, relay := range l.withMevBoost {
+		if relay == mevBoostLocalRelay {
+			relay = "mev-boost"
+		}
+		relays = append(relays, relay)
+	}
+	return relays
+}
+
+// maxNodes returns the maximum number of extra nodes of the devnet
+func (l *L1Recipe) maxNodes() uint64 {
+	return max(l.extraNodes, maxScaledNodes)
+}
+
+// nodeNames returns the names of the EL and the beacon node of the main node (index 0) or an extra node
+func nodeNames(index int) (string, string) {
+	if index == 0 {
+		return "el", "beacon"
+	}
+	return fmt.Sprintf("el-%d", index), fmt.Sprintf("beacon-%d", index)
+}
+
+// dataDirSeed returns the seed of an existing data folder (see --el-datadir), nil if the path is empty
+func (l *L1Recipe) dataDirSeed(path string) *DataDirSeed {
+	if path == "" {
+		return nil
+	}
+	seed, err := NewDataDirSeed(path, DataDirMode(l.dataDirMode))
+	if err != nil {
+		panic(fmt.Sprintf("BUG: invalid datadir, it is checked by Validate: %s", err))
+	}
+	return seed
+}
+
+// targetPeers returns the number of beacon nodes that peer with the main beacon node, including
+// the extra nodes that can be added at runtime
+func (l *L1Recipe) targetPeers() uint64 {
+	peers := l.maxNodes()
+	if l.builder != "" {
+		peers++
+	}
+	if l.validationNode {
+		peers++
+	}
+	if l.minorityNode {
+		peers++
+	}
+	return peers + l.sentryNodes
+}
+
+func (l *L1Recipe) Output(manifest *Manifest) map[string]*RecipeOutput {
+	outputs := map[string]*RecipeOutput{
+		"el-http":         OutputURL("http", "el", "http"),
+		"el-ws":           OutputURL("ws", "el", "ws"),
+		"el-ipc":          OutputIPCPath("reth.ipc"),
+		"el-authrpc":      OutputURL("http", "el", "authrpc"),
+		"beacon-http":     OutputURL("http", "beacon", "http"),
+		"mev-boost-relay": OutputURL("http", "mev-boost", "http"),
+		"jwt-path":        OutputJWTPath("el"),
+		"l1-chain-id":     OutputChainID(l1ChainID),
+	}
+	if l.withAnvil {
+		outputs["anvil-http"] = OutputURL("http", "anvil", "http")
+	}
+	if len(l.withMevBoost) != 0 {
+		outputs["mev-boost-http"] = OutputURL("http", "mev-boost-sidecar", "http")
+	}
+	if l.builder != "" {
+		outputs["builder-http"] = OutputURL("http", "builder", "http")
+	}
+	for i := 1; i <= int(l.sentryNodes); i++ {
+		sentry := fmt.Sprintf("sentry-el-%d", i)
+		outputs[sentry+"-http"] = OutputURL("http", sentry, "http")
+	}
+	return outputs
+}
@@ -0,0 +1,210 @@
+package playground
+
+import (
+	"fmt"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+var _ Recipe = &OpRecipe{}
+
+// OpRecipe is a recipe that deploys an OP stack
+type OpRecipe struct {
+	// externalBuilder is the URL of the external builder to use. If enabled, the recipe deploys
+	// rollup-boost on the sequencer and uses this URL as the external builder.
+	externalBuilder string
+
+	// l2EL is the execution client of the L2 sequencer (op-geth or op-reth)
+	l2EL string
+
+	// batcherMaxChannelDuration, batcherSubmissionInterval and batcherDAType configure
+	// how the batches are posted to the L1
+	batcherMaxChannelDuration uint64
+	batcherSubmissionInterval time.Duration
+	batcherDAType             string
+
+	// proposerInterval is the interval between output proposals. If zero, the recipe
+	// does not deploy op-proposer unless the fault proofs are enabled
+	proposerInterval time.Duration
+
+	// withFaultProofs deploys op-proposer and op-challenger to create and play the
+	// dispute games of the contracts in the L1 genesis
+	withFaultProofs bool
+
+	// l2ChainID is the chain id of the L2, the genesis and the rollup config are
+	// regenerated for it
+	l2ChainID uint64
+
+	// l2GenesisGasLimit is the gas limit of the L2 genesis block and of the system config
+	l2GenesisGasLimit uint64
+
+	// opDeployer deploys the chain with op-deployer instead of using the embedded state,
+	// with the contracts of opContractsLocator or the custom opDeployerIntent
+	opDeployer         bool
+	opDeployerTag      string
+	opContractsLocator string
+	opDeployerIntent   string
+}
+
+func (o *OpRecipe) Name() string {
+	return "opstack"
+}
+
+func (o *OpRecipe) Description() string {
+	return "Deploy an OP stack"
+}
+
+func (o *OpRecipe) Flags() *flag.FlagSet {
+	flags := flag.NewFlagSet("opstack", flag.ContinueOnError)
+	flags.StringVar(&o.externalBuilder, "external-builder", "", "External builder URL")
+	flags.StringVar(&o.l2EL, "l2-el", "op-geth", "execution client of the L2 sequencer (op-geth, op-reth)")
+	flags.Uint64Var(&o.batcherMaxChannelDuration, "batcher-max-channel-duration", 2, "maximum number of L1 blocks a batcher channel stays open")
+	flags.DurationVar(&o.batcherSubmissionInterval, "batcher-submission-interval", time.Second, "interval at which the batcher loads the new L2 blocks and submits them")
+	flags.StringVar(&o.batcherDAType, "batcher-da-type", "calldata", "where the batcher posts the batches (calldata, blobs, auto)")
+	flags.DurationVar(&o.proposerInterval, "proposer-interval", 0, "deploy op-proposer and submit output proposals at this interval")
+	flags.BoolVar(&o.withFaultProofs, "with-fault-proofs", false, "deploy op-proposer and op-challenger to test the fault proofs")
+	flags.Uint64Var(&o.l2ChainID, "l2-chain-id", defaultL2ChainID, "chain id of the L2, the L2 genesis and the rollup config are regenerated for it")
+	flags.Uint64Var(&o.l2GenesisGasLimit, "l2-genesis-gas-limit", 0, "gas limit of the L2 genesis block and of the L2 blocks (defaults to the one of the L2 genesis)")
+	flags.BoolVar(&o.opDeployer, "op-deployer", false, "deploy the OP chain with op-deployer instead of using the embedded state")
+	flags.StringVar(&o.opDeployerTag, "op-deployer-tag", defaultOpDeployerTag, "tag of the op-deployer image")
+	flags.StringVar(&o.opContractsLocator, "op-contracts-locator", defaultOpContractsLocator, "locator of the OP contract artifacts deployed by op-deployer")
+	flags.StringVar(&o.opDeployerIntent, "op-deployer-intent", "", "intent.toml of op-deployer that replaces the generated one (implies --op-deployer)")
+	return flags
+}
+
+func (o *OpRecipe) Artifacts() *ArtifactsBuilder {
+	builder := NewArtifactsBuilder()
+	builder.L2ChainID(o.l2ChainID)
+	builder.L2GenesisGasLimit(o.l2GenesisGasLimit)
+	if o.opDeployer || o.opDeployerIntent != "" {
+		builder.OpDeployer(&OpDeployerConfig{
+			Tag:              o.opDeployerTag,
+			ContractsLocator: o.opContractsLocator,
+			Intent:           o.opDeployerIntent,
+		})
+	}
+	return builder
+}
+
+// Validate checks the values of the flags before the artifacts are built (see RecipeValidator)
+func (o *OpRecipe) Validate() error {
+	if o.withFaultProofs && o.l2ChainID != defaultL2ChainID && !o.opDeployer && o.opDeployerIntent == "" {
+		// the dispute games of the embedded op-deployer state are deployed for the default chain id
+		return fmt.Errorf("--with-fault-proofs requires the default L2 chain id %d or --op-deployer", defaultL2ChainID)
+	}
+	switch o.l2EL {
+	case "op-geth", "op-reth":
+	default:
+		return fmt.Errorf("unknown L2 execution client '%s', expected op-geth or op-reth", o.l2EL)
+	}
+	switch o.batcherDAType {
+	case "calldata", "blobs", "auto":
+	default:
+		return fmt.Errorf("unknown batcher data availability type '%s', expected calldata, blobs or auto", o.batcherDAType)
+	}
+	return nil
+}
+
+func (o *OpRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
+	svcManager := NewManifest(ctx, artifacts.Out)
+	svcManager.l2ChainID = artifacts.L2ChainID
+	svcManager.opDeployment = artifacts.OpDeployment
+	svcManager.AddService("el", &RethEL{})
+	svcManager.AddService("beacon", &LighthouseBeaconNode{
+		ExecutionNode: "el",
+	})
+	svcManager.AddService("validator", &LighthouseValidator{
+		BeaconNode: "beacon",
+	})
+
+	// the name of the L2 execution service matches the client
+	var l2EL Service
+	switch o.l2EL {
+	case "op-geth":
+		l2EL = &OpGeth{
+			UseDeterministicP2PKey: o.externalBuilder != "",
+		}
+	case "op-reth":
+		l2EL = &OpReth{}
+	default:
+		panic(fmt.Sprintf("BUG: unknown L2 execution client '%s', it is checked by Validate", o.l2EL))
+	}
+
+	elNode := o.l2EL
+	if o.externalBuilder != "" {
+		elNode = "rollup-boost"
+
+		svcManager.AddService("rollup-boost", &RollupBoost{
+			ELNode:  o.l2EL,
+			Builder: o.externalBuilder,
+		})
+	}
+	svcManager.AddService("op-node", &OpNode{
+		L1Node:   "el",
+		L1Beacon: "beacon",
+		L2Node:   elNode,
+	})
+	svcManager.AddService(o.l2EL, l2EL)
+	svcManager.AddService("op-batcher", &OpBatcher{
+		L1Node:               "el",
+		L2Node:               o.l2EL,
+		RollupNode:           "op-node",
+		MaxChannelDuration:   o.batcherMaxChannelDuration,
+		PollInterval:         o.batcherSubmissionInterval,
+		DataAvailabilityType: o.batcherDAType,
+	})
+	if o.proposerInterval != 0 || o.withFaultProofs {
+		svcManager.AddService("op-proposer", &OpProposer{
+			L1Node:           "el",
+			RollupNode:       "op-node",
+			ProposalInterval: o.proposerInterval,
+			GameFactory:      artifacts.OpDeployment.DisputeGameFactory,
+		})
+	}
+	if o.withFaultProofs {
+		svcManager.AddService("op-challenger", &OpChallenger{
+			L1Node:      "el",
+			L1Beacon:    "beacon",
+			L2Node:      o.l2EL,
+			RollupNode:  "op-node",
+			GameFactory: artifacts.OpDeployment.DisputeGameFactory,
+		})
+	}
+	return svcManager
+}
+
+func (o *OpRecipe) Output(manifest *Manifest) map[string]*RecipeOutput {
+	outputs := map[string]*RecipeOutput{
+		"el-http":      OutputURL("http", "el", "http"),
+		"el-ws":        OutputURL("ws", "el", "ws"),
+		"el-ipc":       OutputIPCPath("reth.ipc"),
+		"beacon-http":  OutputURL("http", "beacon", "http"),
+		"l2-el-http":   OutputURL("http", o.l2EL, "http"),
+		"l2-el-ws":     OutputURL("ws", o.l2EL, "ws"),
+		"op-node-http": OutputURL("http", "op-node", "http"),
+		"jwt-path":     OutputJWTPath("el"),
+		"l2-jwt-path":  OutputJWTPath(o.l2EL),
+		"l1-chain-id":  OutputChainID(l1ChainID),
+		"l2-chain-id":  OutputChainID(o.l2ChainID),
+	}
+	if o.l2EL == "op-reth" {
+		outputs["l2-el-ipc"] = OutputIPCPath("op_reth.ipc")
+	} else {
+		// op-geth creates the socket inside its data folder
+		outputs["l2-el-ipc"] = OutputIPCPath("data_opgeth/geth.ipc")
+	}
+
+	if o.withFaultProofs {
+		deployment := manifest.opDeployment
+		outputs["dispute-game-factory"] = OutputAddress(deployment.DisputeGameFactory)
+		outputs["anchor-state-registry"] = OutputAddress(deployment.AnchorStateRegistry)
+		outputs["permissioned-dispute-game"] = OutputAddress(deployment.PermissionedDisputeGame)
+	}
+
+	if opGeth, ok := manifest.MustGetService(o.l2EL).component.(*OpGeth); ok && opGeth.Enode != "" {
+		// Only output if enode was set
+		outputs["op-geth-enode"] = OutputEnode(opGeth.Enode)
+	}
+	return outputs
+}
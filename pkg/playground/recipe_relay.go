@@ -0,0 +1,57 @@
+package playground
+
+import (
+	flag "github.com/spf13/pflag"
+)
+
+var _ Recipe = &RelayRecipe{}
+
+// RelayRecipe is the L1 recipe with the full Flashbots mev-boost-relay (API, housekeeper and
+// website, with their Postgres database and Redis server) instead of the relay of playground-utils.
+// The relay validates the block submissions with a dedicated validation node, unless
+// --use-reth-for-validation is set, and the validators of the devnet are registered with the
+// relay once it is ready.
+type RelayRecipe struct {
+	L1Recipe
+}
+
+func (r *RelayRecipe) Name() string {
+	return "relay"
+}
+
+func (r *RelayRecipe) Description() string {
+	return "Deploy a full L1 stack with the Flashbots mev-boost-relay, Postgres and Redis"
+}
+
+func (r *RelayRecipe) Flags() *flag.FlagSet {
+	return r.L1Recipe.Flags()
+}
+
+func (r *RelayRecipe) Artifacts() *ArtifactsBuilder {
+	return r.L1Recipe.Artifacts()
+}
+
+// useFlashbotsRelay sets the Flashbots relay in the L1 recipe, with the validation node unless
+// --use-reth-for-validation is set
+func (r *RelayRecipe) useFlashbotsRelay() {
+	r.flashbotsRelay = true
+	if !r.useRethForValidation {
+		r.validationNode = true
+	}
+}
+
+func (r *RelayRecipe) Validate() error {
+	r.useFlashbotsRelay()
+	return r.L1Recipe.Validate()
+}
+
+func (r *RelayRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
+	r.useFlashbotsRelay()
+	return r.L1Recipe.Apply(ctx, artifacts)
+}
+
+func (r *RelayRecipe) Output(manifest *Manifest) map[string]*RecipeOutput {
+	outputs := r.L1Recipe.Output(manifest)
+	outputs["relay-website"] = OutputURL("http", "relay-website", "http")
+	return outputs
+}
@@ -0,0 +1,351 @@
+package playground
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+var _ Recipe = &YamlRecipe{}
+
+// YamlRecipe is a recipe defined in a declarative YAML file
+type YamlRecipe struct {
+	config *YamlRecipeConfig
+}
+
+// YamlRecipeConfig is the schema of a YAML recipe file
+type YamlRecipeConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+
+	Artifacts *YamlArtifactsConfig `yaml:"artifacts"`
+
+	// Services is the list of services to deploy, by name. They are added
+	// to the manifest in alphabetical order.
+	Services map[string]*YamlServiceConfig `yaml:"services"`
+
+	// Templates are config files rendered to the output folder with the endpoints of the services,
+	// by path relative to the output folder (see RenderTemplates)
+	Templates map[string]string `yaml:"templates"`
+}
+
+type YamlArtifactsConfig struct {
+	// LatestFork enables the latest L1 fork at genesis
+	LatestFork bool `yaml:"latest_fork"`
+
+	// Files are extra files to write in the output folder, by path relative to the output folder
+	Files map[string]string `yaml:"files"`
+}
+
+type YamlServiceConfig struct {
+	// Component is the name of a built-in component (i.e. reth or lighthouse-beacon-node).
+	// If set, Config is decoded into the component fields.
+	Component string                 `yaml:"component"`
+	Config    map[string]interface{} `yaml:"config"`
+
+	// Low level service description used if there is no component. Args and
+	// entrypoint accept the same templates as the built-in components
+	// (i.e. {{Port "http" 8545}}, {{Service "el" "http"}} and {{.Dir}}).
+	Image      string            `yaml:"image"`
+	Tag        string            `yaml:"tag"`
+	Entrypoint string            `yaml:"entrypoint"`
+	Args       []string          `yaml:"args"`
+	Env        map[string]string `yaml:"env"`
+
+	// EnvFiles are .env files with more environment variables (i.e. secrets), relative to
+	// the current directory
+	EnvFiles []string `yaml:"env_file"`
+
+	// DependsOn maps a service name to the condition (started, healthy or completed for a job)
+	DependsOn map[string]string `yaml:"depends_on"`
+
+	ReadyCheck *YamlReadyCheckConfig `yaml:"ready_check"`
+
+	// Restart is the restart policy (never, always, on-failure or on-failure:<retries>)
+	Restart string `yaml:"restart"`
+
+	// Volumes are the shared volumes mounted by the service, at the path of the {{Volume "name"}}
+	// template. The services that mount the same volume share its files.
+	Volumes []string `yaml:"volumes"`
+
+	// Job marks a service that runs to completion (i.e. a contract deployment), the services
+	// that depend on it as completed start once it exits successfully
+	Job bool `yaml:"job"`
+
+	// User is the <uid>[:<gid>] the container runs as, or host for the host user
+	User string `yaml:"user"`
+
+	// Ports are the ports of the service that are not set with a {{Port}} template in its args
+	// or env (i.e. an image that always listens on 8080), by label
+	Ports map[string]int `yaml:"ports"`
+}
+
+type YamlReadyCheckConfig struct {
+	Port string `yaml:"port"`
+	Path string `yaml:"path"`
+
+	// Timeout is the time to wait for the service to be ready (i.e. 2m)
+	Timeout string `yaml:"timeout"`
+}
+
+func NewYamlRecipe(path string) (*YamlRecipe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipe file: %w", err)
+	}
+
+	var config YamlRecipeConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode recipe file: %w", err)
+	}
+	return newYamlRecipe(&config)
+}
+
+// newYamlRecipe validates the config of a YAML recipe
+func newYamlRecipe(config *YamlRecipeConfig) (*YamlRecipe, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("recipe name is required")
+	}
+	if len(config.Services) == 0 {
+		return nil, fmt.Errorf("recipe %s does not define any services", config.Name)
+	}
+
+	if config.Artifacts != nil {
+		for path := range config.Artifacts.Files {
+			if !filepath.IsLocal(path) {
+				return nil, fmt.Errorf("artifact file %s must be a path inside the output folder", path)
+			}
+		}
+	}
+	if err := validateYamlServices(config.Services); err != nil {
+		return nil, err
+	}
+	return &YamlRecipe{config: config}, nil
+}
+
+func (y *YamlRecipe) Name() string {
+	return y.config.Name
+}
+
+func (y *YamlRecipe) Description() string {
+	return y.config.Description
+}
+
+func (y *YamlRecipe) Flags() *flag.FlagSet {
+	return flag.NewFlagSet(y.config.Name, flag.ContinueOnError)
+}
+
+func (y *YamlRecipe) Artifacts() *ArtifactsBuilder {
+	builder := NewArtifactsBuilder()
+	if y.config.Artifacts != nil {
+		builder.ApplyLatestL1Fork(y.config.Artifacts.LatestFork)
+		builder.ExtraFiles(y.config.Artifacts.Files)
+	}
+	return builder
+}
+
+func (y *YamlRecipe) Apply(ctx *ExContext, artifacts *Artifacts) *Manifest {
+	svcManager := NewManifest(ctx, artifacts.Out)
+
+	applyYamlServices(svcManager, y.config.Services)
+	for name, content := range y.config.Templates {
+		svcManager.AddTemplate(name, content)
+	}
+	return svcManager
+}
+
+// validateYamlServices validates the services of a YAML recipe or of an attached file
+func validateYamlServices(services map[string]*YamlServiceConfig) error {
+	for name, svc := range services {
+		if svc.Component != "" {
+			if _, err := newComponent(svc.Component, svc.Config); err != nil {
+				return fmt.Errorf("service %s: %w", name, err)
+			}
+			if svc.Image != "" || len(svc.Args) != 0 {
+				return fmt.Errorf("service %s cannot set both a component and an image or args", name)
+			}
+		} else if svc.Image == "" {
+			return fmt.Errorf("service %s must define either a component or an image", name)
+		}
+
+		for dep, condition := range svc.DependsOn {
+			switch DependsOnCondition(condition) {
+			case DependsOnConditionStarted, DependsOnConditionHealthy, DependsOnConditionCompleted:
+			default:
+				return fmt.Errorf("service %s has invalid condition '%s' for dependency %s", name, condition, dep)
+			}
+		}
+		if svc.Restart != "" {
+			if svc.Job {
+				return fmt.Errorf("service %s is a job, it cannot set a restart policy", name)
+			}
+			if _, err := ParseRestartPolicy(svc.Restart); err != nil {
+				return fmt.Errorf("service %s: %w", name, err)
+			}
+		}
+		if svc.ReadyCheck != nil && svc.ReadyCheck.Timeout != "" {
+			if _, err := time.ParseDuration(svc.ReadyCheck.Timeout); err != nil {
+				return fmt.Errorf("service %s has invalid ready check timeout '%s': %w", name, svc.ReadyCheck.Timeout, err)
+			}
+		}
+		if svc.User != "" {
+			if _, err := ParseContainerUser(svc.User); err != nil {
+				return fmt.Errorf("service %s: %w", name, err)
+			}
+		}
+		for label, port := range svc.Ports {
+			if svc.Component != "" {
+				return fmt.Errorf("service %s cannot set both a component and ports", name)
+			}
+			if port <= 0 || port > 65535 {
+				return fmt.Errorf("service %s has invalid port %d for %s", name, port, label)
+			}
+		}
+	}
+	return nil
+}
+
+// applyYamlServices adds the services of a YAML recipe or of an attached file to the manifest
+// in alphabetical order. The services are validated on load.
+func applyYamlServices(svcManager *Manifest, services map[string]*YamlServiceConfig) {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		svc := services[name]
+		if svc.Component != "" {
+			component, err := newComponent(svc.Component, svc.Config)
+			if err != nil {
+				// the component and its config are validated on load
+				panic(fmt.Sprintf("failed to create component for service %s: %v", name, err))
+			}
+			svcManager.AddService(name, component)
+		} else {
+			svcManager.AddService(name, &yamlService{config: svc})
+		}
+
+		service := svcManager.MustGetService(name)
+		for dep, condition := range svc.DependsOn {
+			service.dependOn(dep, DependsOnCondition(condition))
+		}
+		if svc.ReadyCheck != nil {
+			// the timeout is validated on load
+			timeout, _ := time.ParseDuration(svc.ReadyCheck.Timeout)
+			service.WithReadyCheck(&ReadyCheck{PortLabel: svc.ReadyCheck.Port, Path: svc.ReadyCheck.Path, Timeout: timeout})
+		}
+		if svc.Restart != "" {
+			// the policy is validated on load
+			policy, _ := ParseRestartPolicy(svc.Restart)
+			service.WithRestartPolicy(policy)
+		}
+		if svc.Job {
+			service.AsJob()
+		}
+		if svc.User != "" {
+			// the user is validated on load
+			user, _ := ParseContainerUser(svc.User)
+			service.WithUser(user)
+		}
+	}
+}
+
+func (y *YamlRecipe) Output(manifest *Manifest) map[string]*RecipeOutput {
+	return map[string]*RecipeOutput{}
+}
+
+// newComponent creates a new instance of a built-in component and decodes the config into its
+// public fields. Field names are matched case insensitively (i.e. executionNode for ExecutionNode).
+func newComponent(name string, config map[string]interface{}) (Service, error) {
+	template := FindComponent(name)
+	if template == nil {
+		return nil, fmt.Errorf("component %s not found", name)
+	}
+	component := reflect.New(reflect.TypeOf(template).Elem()).Interface().(Service)
+
+	if len(config) != 0 {
+		data, err := json.Marshal(toStringKeys(config))
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, component); err != nil {
+			return nil, fmt.Errorf("failed to decode config for component %s: %w", name, err)
+		}
+	}
+	return component, nil
+}
+
+// toStringKeys converts the map[interface{}]interface{} values produced by the yaml decoder
+// into map[string]interface{} so that they can be encoded as JSON.
+func toStringKeys(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[interface{}]interface{}:
+		res := map[string]interface{}{}
+		for k, vv := range v {
+			res[fmt.Sprint(k)] = toStringKeys(vv)
+		}
+		return res
+	case map[string]interface{}:
+		res := map[string]interface{}{}
+		for k, vv := range v {
+			res[k] = toStringKeys(vv)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, vv := range v {
+			res[i] = toStringKeys(vv)
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+// yamlService is a service described with the low level fields of the YAML recipe
+type yamlService struct {
+	config *YamlServiceConfig
+}
+
+func (y *yamlService) Run(service *ServiceSpec, ctx *ExContext) {
+	tag := y.config.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	service.
+		WithImage(y.config.Image).
+		WithTag(tag).
+		WithEntrypoint(y.config.Entrypoint).
+		WithArgs(append([]string{}, y.config.Args...)...)
+
+	for k, v := range y.config.Env {
+		service.WithEnv(k, v)
+	}
+	for _, path := range y.config.EnvFiles {
+		service.WithEnvFromFile(path)
+	}
+	for _, name := range y.config.Volumes {
+		service.WithVolume(name)
+	}
+	labels := make([]string, 0, len(y.config.Ports))
+	for label := range y.config.Ports {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		service.WithPort(label, y.config.Ports[label])
+	}
+}
+
+func (y *yamlService) Name() string {
+	return "yaml-service"
+}
@@ -0,0 +1,31 @@
+package playground
+
+import (
+	"testing"
+)
+
+func TestYamlRecipeArtifactFiles(t *testing.T) {
+	cases := []struct {
+		path  string
+		valid bool
+	}{
+		{"config.toml", true},
+		{"configs/config.toml", true},
+		{"/etc/passwd", false},
+		{"../config.toml", false},
+		{"configs/../../config.toml", false},
+	}
+	for _, c := range cases {
+		_, err := newYamlRecipe(&YamlRecipeConfig{
+			Name:      "test",
+			Artifacts: &YamlArtifactsConfig{Files: map[string]string{c.path: "content"}},
+			Services:  map[string]*YamlServiceConfig{"svc": {Image: "alpine"}},
+		})
+		if c.valid && err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.path, err)
+		}
+		if !c.valid && err == nil {
+			t.Fatalf("%s: expected an error", c.path)
+		}
+	}
+}
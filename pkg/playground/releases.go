@@ -0,0 +1,403 @@
+package playground
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+var releasesLog = Logger("releases")
+
+type release struct {
+	Name    string
+	Org     string
+	Version string
+	Arch    func(string, string) string
+
+	// Signature is how the archives of the release are signed, nil if they are not
+	Signature *releaseSignature
+}
+
+// ReleaseDownloadOptions are the options of the downloads of the release binaries
+type ReleaseDownloadOptions struct {
+	// RequireSignature fails the download if the signature of the archive cannot be verified,
+	// and downloads again the binaries that were downloaded before without a verified signature
+	RequireSignature bool
+
+	// Platform is the <os>/<arch> of the binary (i.e. linux/arm64), the one of the host if empty
+	Platform string
+
+	// Proxy is the URL of the proxy of the downloads, the proxy of the environment
+	// (HTTPS_PROXY...) if empty
+	Proxy string
+
+	// Connections is the number of concurrent range requests of each download
+	Connections int
+}
+
+// ParseReleasePlatform parses a <os>/<arch> platform of the release binaries
+func ParseReleasePlatform(platform string) (goos string, goarch string, err error) {
+	goos, goarch, ok := strings.Cut(platform, "/")
+	if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+		return "", "", fmt.Errorf("invalid platform '%s', expected <os>/<arch> (i.e. linux/amd64)", platform)
+	}
+	return goos, goarch, nil
+}
+
+// releaseDownload is the download of the binary of a release for a platform
+type releaseDownload struct {
+	artifact *release
+	goos     string
+	goarch   string
+	url      string
+	outPath  string
+
+	progress *downloadProgress
+	done     bool
+	err      error
+}
+
+func (r *releaseDownload) platform() string {
+	return r.goos + "/" + r.goarch
+}
+
+// DownloadRelease downloads the binary of the release for the platform of the options (the
+// host by default) into the output folder, unless it was already downloaded, and returns its path.
+// The signature of the archive is verified if the release is signed and the tool to verify it is
+// installed (see ReleaseDownloadOptions.RequireSignature).
+func DownloadRelease(ctx context.Context, outputFolder string, artifact *release, opts *ReleaseDownloadOptions) (string, error) {
+	paths, err := DownloadReleasePlatforms(ctx, outputFolder, artifact, []string{opts.Platform}, opts)
+	if err != nil {
+		return "", err
+	}
+	return paths[0], nil
+}
+
+// DownloadReleasePlatforms downloads the binaries of the release for the platforms concurrently
+// (see DownloadRelease) and returns their paths. An empty platform is the one of the host.
+func DownloadReleasePlatforms(ctx context.Context, outputFolder string, artifact *release, platforms []string, opts *ReleaseDownloadOptions) ([]string, error) {
+	downloads := []*releaseDownload{}
+	for _, platform := range platforms {
+		download, err := newReleaseDownload(artifact, platform, outputFolder, opts)
+		if err != nil {
+			return nil, err
+		}
+		downloads = append(downloads, download)
+	}
+	if err := downloadReleases(ctx, downloads, opts); err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, download := range downloads {
+		paths = append(paths, download.outPath)
+	}
+	return paths, nil
+}
+
+// newReleaseDownload resolves the binary of the release for the platform. The download
+// has no url if the binary does not have to be downloaded.
+func newReleaseDownload(artifact *release, platform string, outputFolder string, opts *ReleaseDownloadOptions) (*releaseDownload, error) {
+	goos, goarch := runtime.GOOS, runtime.GOARCH
+	host := true
+	if platform != "" {
+		var err error
+		if goos, goarch, err = ParseReleasePlatform(platform); err != nil {
+			return nil, err
+		}
+		host = goos == runtime.GOOS && goarch == runtime.GOARCH
+	}
+	download := &releaseDownload{artifact: artifact, goos: goos, goarch: goarch}
+
+	// Windows binaries require the .exe extension to be executed
+	var exeSuffix string
+	if goos == "windows" {
+		exeSuffix = ".exe"
+	}
+
+	name := artifact.Name + "-" + artifact.Version
+	if !host {
+		// the binaries of other platforms do not replace the one of the host
+		name += "-" + goos + "-" + goarch
+	}
+	download.outPath = filepath.Join(outputFolder, name+exeSuffix)
+	_, err := os.Stat(download.outPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error checking file existence: %v", err)
+	}
+	if err == nil {
+		if !opts.RequireSignature {
+			return download, nil
+		}
+		if _, err := os.Stat(verifiedMarker(download.outPath)); err == nil {
+			return download, nil
+		}
+		releasesLog.Warn("release downloaded without a verified signature, downloading it again", "path", download.outPath)
+	}
+
+	// create the output folder if it doesn't exist yet
+	if err := os.MkdirAll(outputFolder, 0755); err != nil {
+		return nil, fmt.Errorf("error creating output folder: %v", err)
+	}
+
+	archVersion := artifact.Arch(goos, goarch)
+	if archVersion == "" {
+		// Case 2. The architecture is not supported.
+		if !host {
+			return nil, fmt.Errorf("release %s has no binary for %s", artifact.Name, download.platform())
+		}
+		releasesLog.Warn("unsupported OS/Arch", "os", goos, "arch", goarch)
+		if opts.RequireSignature {
+			return nil, fmt.Errorf("no release for %s with a signature to verify, the binary in PATH cannot be used with a required signature", download.platform())
+		}
+		if _, err := exec.LookPath(artifact.Name); err != nil {
+			return nil, fmt.Errorf("error looking up binary in PATH: %v", err)
+		}
+		download.outPath = artifact.Name
+		releasesLog.Info("using release from PATH", "name", artifact.Name)
+		return download, nil
+	}
+
+	// Case 3. Download the binary from the release page
+	download.url = fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s-%s-%s.tar.gz", artifact.Org, artifact.Name, artifact.Version, artifact.Name, artifact.Version, archVersion)
+	return download, nil
+}
+
+// downloadReleases downloads the releases that have an url concurrently and reports their progress
+func downloadReleases(ctx context.Context, downloads []*releaseDownload, opts *ReleaseDownloadOptions) error {
+	pending := []*releaseDownload{}
+	for _, download := range downloads {
+		if download.url != "" {
+			download.progress = &downloadProgress{}
+			pending = append(pending, download)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	dl, err := newDownloader(opts.Proxy, opts.Connections)
+	if err != nil {
+		return err
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for _, download := range pending {
+		releasesLog.Info("downloading release", "path", download.outPath, "url", download.url)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := downloadArtifact(ctx, dl, download, opts.RequireSignature)
+
+			lock.Lock()
+			download.done, download.err = true, err
+			lock.Unlock()
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	report := newDownloadReport(os.Stdout, pending, &lock)
+	for {
+		select {
+		case <-done:
+			report.render()
+			for _, download := range pending {
+				if download.err != nil {
+					return fmt.Errorf("error downloading artifact: %v", download.err)
+				}
+			}
+			return nil
+		case <-time.After(500 * time.Millisecond):
+			report.render()
+		}
+	}
+}
+
+func downloadArtifact(ctx context.Context, dl *downloader, download *releaseDownload, requireSignature bool) error {
+	// the archive is kept until the signature is verified, the partial download of the archive
+	// is kept to resume it
+	url, outPath := download.url, download.outPath
+	archivePath := outPath + ".tar.gz"
+	if err := dl.download(ctx, url, archivePath, download.progress); err != nil {
+		return err
+	}
+	defer os.Remove(archivePath)
+
+	verified := true
+	if err := verifyArchive(ctx, dl, download.artifact.Signature, url, archivePath); err != nil {
+		var unavailable *errSignatureUnavailable
+		if !errors.As(err, &unavailable) {
+			return fmt.Errorf("error verifying the signature of %s: %w", url, err)
+		}
+		if requireSignature {
+			return fmt.Errorf("the signature of %s is required but cannot be verified: %w", url, err)
+		}
+		releasesLog.Warn("the signature of the release is not verified", "url", url, "reason", err)
+		verified = false
+	} else {
+		releasesLog.Info("verified the signature of the release", "url", url)
+	}
+
+	expectedFile := download.artifact.Name
+	if download.goos == "windows" {
+		expectedFile += ".exe"
+	}
+	if err := extractArtifact(archivePath, expectedFile, outPath); err != nil {
+		return err
+	}
+	if verified {
+		if err := os.WriteFile(verifiedMarker(outPath), []byte(url+"\n"), 0644); err != nil {
+			return fmt.Errorf("error writing the verified marker: %v", err)
+		}
+	} else {
+		os.Remove(verifiedMarker(outPath))
+	}
+	return nil
+}
+
+// downloadReport reports the progress of the downloads, with a progress bar per download on a
+// terminal and a line per finished download otherwise (see pullReport)
+type downloadReport struct {
+	out       io.Writer
+	downloads []*releaseDownload
+	lock      *sync.Mutex
+	terminal  bool
+
+	lines    int
+	reported map[*releaseDownload]bool
+}
+
+func newDownloadReport(out *os.File, downloads []*releaseDownload, lock *sync.Mutex) *downloadReport {
+	return &downloadReport{
+		out:       out,
+		downloads: downloads,
+		lock:      lock,
+		terminal:  isatty.IsTerminal(out.Fd()),
+		reported:  map[*releaseDownload]bool{},
+	}
+}
+
+func (r *downloadReport) render() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.terminal {
+		for _, download := range r.downloads {
+			if download.done && !r.reported[download] {
+				r.reported[download] = true
+				if download.err != nil {
+					fmt.Fprintf(r.out, "Failed to download %s (%s): %v\n", download.artifact.Name, download.platform(), download.err)
+				} else {
+					_, total := download.progress.size()
+					fmt.Fprintf(r.out, "Downloaded %s (%s, %s)\n", download.artifact.Name, download.platform(), formatBytes(total))
+				}
+			}
+		}
+		return
+	}
+
+	if r.lines > 0 {
+		fmt.Fprintf(r.out, "\033[%dA\033[J", r.lines)
+	}
+	r.lines = 0
+	for _, download := range r.downloads {
+		c, t := download.progress.size()
+		bar, status := progressBar(c, t, 30), fmt.Sprintf("%s/%s", formatBytes(c), formatBytes(t))
+		if download.err != nil {
+			status = "failed"
+		} else if download.done {
+			bar, status = progressBar(1, 1, 30), formatBytes(t)
+		}
+		fmt.Fprintf(r.out, "%s %s %s (%s) %s\n", bar, download.artifact.Name, download.artifact.Version, download.platform(), status)
+		r.lines++
+	}
+}
+
+// extractArtifact extracts the binary of the archive to outPath
+func extractArtifact(archivePath string, expectedFile string, outPath string) error {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	// Create a gzip reader
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return fmt.Errorf("error creating gzip reader: %v", err)
+	}
+	defer gzipReader.Close()
+
+	// Create a tar reader
+	tarReader := tar.NewReader(gzipReader)
+
+	// Extract the file
+	var found bool
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading tar: %v", err)
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			if header.Name != expectedFile {
+				return fmt.Errorf("unexpected file in archive: %s", header.Name)
+			}
+			// write to a temporary file first so that an interrupted download
+			// does not leave a partial binary that would be reused
+			tmpPath := outPath + ".tmp"
+			if err := writeArtifact(tmpPath, tarReader); err != nil {
+				os.Remove(tmpPath)
+				return err
+			}
+			if err := os.Rename(tmpPath, outPath); err != nil {
+				return fmt.Errorf("error moving output file: %v", err)
+			}
+			found = true
+			break // Assuming there's only one file per repo
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("file not found in archive: %s", expectedFile)
+	}
+	return nil
+}
+
+func writeArtifact(path string, r io.Reader) error {
+	outFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating output file: %v", err)
+	}
+	defer outFile.Close()
+
+	if _, err := io.Copy(outFile, r); err != nil {
+		return fmt.Errorf("error writing output file: %v", err)
+	}
+
+	// change permissions
+	if err := os.Chmod(path, 0755); err != nil {
+		return fmt.Errorf("error changing permissions: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package playground
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// remoteDockerSocket is the socket of the docker daemon on the remote host. The user of the
+// ssh connection must have access to it (i.e. be in the docker group).
+const remoteDockerSocket = "/var/run/docker.sock"
+
+// RemoteHost runs the containers of a session on the docker daemon of a remote machine over ssh,
+// for the devnets that do not fit on a laptop. The docker socket of the remote host is forwarded
+// to a local socket so that the runner and docker compose use the remote daemon, the artifacts
+// are synced to a folder of the remote host before the services start and the published ports
+// of the services are forwarded to the same local ports, so the endpoints do not change.
+type RemoteHost struct {
+	// Target is the ssh destination, i.e. user@host or the name of a host of the ssh config
+	Target string
+
+	// Dir is the output folder on the remote host
+	Dir string
+
+	// localDir has the control socket of the ssh connection and the docker socket
+	localDir    string
+	controlPath string
+	socketPath  string
+}
+
+// ConnectRemoteHost opens the ssh connection to the remote host, which is reused by all the
+// commands, and forwards its docker socket. It sets DOCKER_HOST to the forwarded socket so
+// that the docker clients created afterwards (and docker compose) use the remote daemon.
+func ConnectRemoteHost(ctx context.Context, target string, session string) (*RemoteHost, error) {
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return nil, fmt.Errorf("the ssh client is required to run the session on a remote host: %w", err)
+	}
+	// the path of the unix sockets is limited to ~100 characters, so they are not in the
+	// temporary folder of macos
+	localDir, err := os.MkdirTemp("/tmp", "playground-remote-")
+	if err != nil {
+		return nil, err
+	}
+	r := &RemoteHost{
+		Target:      target,
+		localDir:    localDir,
+		controlPath: filepath.Join(localDir, "ssh.sock"),
+		socketPath:  filepath.Join(localDir, "docker.sock"),
+	}
+
+	runnerLog.Info("connecting to the remote host", "target", target)
+	master := exec.CommandContext(ctx, "ssh", "-M", "-S", r.controlPath, "-o", "ControlPersist=yes", "-fnNT", target)
+	if out, err := master.CombinedOutput(); err != nil {
+		os.RemoveAll(localDir)
+		return nil, fmt.Errorf("failed to connect to the remote host %s: %w, output: %s", target, err, strings.TrimSpace(string(out)))
+	}
+
+	if err := r.setup(ctx, session); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RemoteHost) setup(ctx context.Context, session string) error {
+	if _, err := r.run(ctx, nil, quoteShell([]string{"docker", "version", "--format", "{{.Server.Version}}"})); err != nil {
+		return fmt.Errorf("docker is not available on the remote host %s (it must be installed and the user must have access to %s): %w", r.Target, remoteDockerSocket, err)
+	}
+	home, err := r.run(ctx, nil, `printf %s "$HOME"`)
+	if err != nil {
+		return err
+	}
+	r.Dir = path.Join(strings.TrimSpace(home), ".playground", "remote", session)
+
+	if err := r.control(ctx, "forward", "-L", r.socketPath+":"+remoteDockerSocket); err != nil {
+		return fmt.Errorf("failed to forward the docker socket of the remote host: %w", err)
+	}
+	return os.Setenv("DOCKER_HOST", r.DockerHost())
+}
+
+// DockerHost is the address of the forwarded docker socket
+func (r *RemoteHost) DockerHost() string {
+	return "unix://" + r.socketPath
+}
+
+// run runs a command line with the shell of the remote host over the ssh connection and returns
+// its output. The arguments must be quoted, ssh joins them in a single command line.
+func (r *RemoteHost) run(ctx context.Context, stdin *bytes.Buffer, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, "ssh", "-S", r.controlPath, r.Target, "--", script)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w, output: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// control sends a command to the master ssh connection (i.e. to add a port forward)
+func (r *RemoteHost) control(ctx context.Context, command string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "ssh", append([]string{"-S", r.controlPath, "-O", command}, append(args, r.Target)...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w, output: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ForwardPort forwards a local port to the same port of the loopback interface of the remote host
+func (r *RemoteHost) ForwardPort(ctx context.Context, port int) error {
+	forward := fmt.Sprintf("127.0.0.1:%d:127.0.0.1:%d", port, port)
+	if err := r.control(ctx, "forward", "-L", forward); err != nil {
+		return fmt.Errorf("failed to forward port %d of the remote host: %w", port, err)
+	}
+	return nil
+}
+
+// Sync copies the output folder to the one of the remote host, without the logs (which are
+// written locally). The existing files of the remote folder, like the databases of the running
+// services, are kept.
+func (r *RemoteHost) Sync(ctx context.Context, localDir string) error {
+	tar := exec.CommandContext(ctx, "tar", "-C", localDir, "--exclude", "./logs", "-cf", "-", ".")
+	var archive, stderr bytes.Buffer
+	tar.Stdout = &archive
+	tar.Stderr = &stderr
+	if err := tar.Run(); err != nil {
+		return fmt.Errorf("failed to archive the output folder: %w, output: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	script := quoteShell([]string{"mkdir", "-p", r.Dir}) + " && " + quoteShell([]string{"tar", "-C", r.Dir, "-xf", "-"})
+	if _, err := r.run(ctx, &archive, script); err != nil {
+		return fmt.Errorf("failed to sync the output folder to the remote host: %w", err)
+	}
+	return nil
+}
+
+// Close closes the ssh connection, with the forwards. The output folder of the remote host is kept.
+func (r *RemoteHost) Close() error {
+	err := r.control(context.Background(), "exit")
+	os.RemoveAll(r.localDir)
+	return err
+}
+
+// SetRemoteHost runs the containers on the remote host, which must be connected before the
+// runner is created so that its docker client uses the remote daemon. The services on the host
+// and the data folders mounted from the host are not supported, they are not on the remote host.
+func (d *LocalRunner) SetRemoteHost(remote *RemoteHost) error {
+	for _, svc := range d.manifest.services {
+		if d.isHostService(svc.Name) {
+			return fmt.Errorf("service %s runs on the host, which is not supported on a remote host", svc.Name)
+		}
+		for _, seed := range svc.dataDirSeeds {
+			if seed.Mode == DataDirModeBind {
+				return fmt.Errorf("the datadir of service %s is mounted, which is not supported on a remote host (use the copy mode)", svc.Name)
+			}
+		}
+	}
+	d.remote = remote
+	d.session.DockerHost = remote.DockerHost()
+	return nil
+}
+
+// syncRemote syncs the output folder to the remote host before the service starts, with the
+// files written for it, and forwards its published ports
+func (d *LocalRunner) syncRemote(ctx context.Context, svc *ServiceSpec) error {
+	if d.remote == nil {
+		return nil
+	}
+	if err := d.remote.Sync(ctx, d.out.dst); err != nil {
+		return err
+	}
+	for _, port := range svc.ports {
+		if err := d.remote.ForwardPort(ctx, port.HostPort); err != nil {
+			return err
+		}
+	}
+	return nil
+}
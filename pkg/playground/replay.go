@@ -0,0 +1,205 @@
+package playground
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+	apiproxy "github.com/ferranbt/builder-playground/api-proxy"
+)
+
+// LoadEngineSession reads the Engine API requests recorded by the api proxy (--record-engine-api),
+// either as JSON lines (logs/<proxy>-requests.jsonl) or as a JSON array of entries. The requests
+// that failed or were failed by the fault injection are skipped since they did not reach the EL.
+func LoadEngineSession(path string) ([]*apiproxy.Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+
+	var entries []*apiproxy.Entry
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, fmt.Errorf("failed to decode session file: %w", err)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		// the payloads with blobs do not fit in the default buffer
+		scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			var entry apiproxy.Entry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				return nil, fmt.Errorf("failed to decode line %d of the session file: %w", line, err)
+			}
+			entries = append(entries, &entry)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read session file: %w", err)
+		}
+	}
+
+	res := []*apiproxy.Entry{}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Method, "engine_") || entry.InjectedFailure || entry.Error != "" {
+			continue
+		}
+		res = append(res, entry)
+	}
+	return res, nil
+}
+
+// ReadJWTSecret reads a hex encoded JWT secret file
+func ReadJWTSecret(path string) ([32]byte, error) {
+	var secret [32]byte
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return secret, fmt.Errorf("failed to read jwt secret: %w", err)
+	}
+	buf, err := hexutil.Decode("0x" + strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil || len(buf) != 32 {
+		return secret, fmt.Errorf("invalid jwt secret in %s, expected 32 hex encoded bytes", path)
+	}
+	copy(secret[:], buf)
+	return secret, nil
+}
+
+// EngineReplayDiff is a replayed request whose response differs from the recorded one
+type EngineReplayDiff struct {
+	Index    int
+	Method   string
+	Expected string
+	Actual   string
+}
+
+type EngineReplayReport struct {
+	Requests int
+	Diffs    []*EngineReplayDiff
+}
+
+type engineRequest struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type engineResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *engineError    `json:"error"`
+}
+
+type engineError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ReplayEngineSession sends the recorded Engine API requests in order to the target EL and
+// compares the responses with the recorded ones. The payload ids returned by the target are
+// different, so the ones of the recorded engine_getPayload requests are replaced with them.
+func ReplayEngineSession(ctx context.Context, entries []*apiproxy.Entry, target string, jwtSecret [32]byte) (*EngineReplayReport, error) {
+	clt, err := rpc.DialOptions(ctx, target, rpc.WithHTTPAuth(node.NewJWTAuth(jwtSecret)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", target, err)
+	}
+	defer clt.Close()
+
+	// payloadIDs maps the recorded payload ids to the ones of the target
+	payloadIDs := map[string]string{}
+
+	report := &EngineReplayReport{}
+	for i, entry := range entries {
+		var req engineRequest
+		if err := json.Unmarshal(entry.Request, &req); err != nil {
+			return nil, fmt.Errorf("failed to decode request %d (%s): %w", i, entry.Method, err)
+		}
+		var expected engineResponse
+		if err := json.Unmarshal(entry.Response, &expected); err != nil {
+			return nil, fmt.Errorf("failed to decode response %d (%s): %w", i, entry.Method, err)
+		}
+
+		if strings.HasPrefix(req.Method, "engine_getPayload") && len(req.Params) > 0 {
+			var id string
+			if json.Unmarshal(req.Params[0], &id) == nil {
+				if newID, ok := payloadIDs[id]; ok {
+					req.Params[0], _ = json.Marshal(newID)
+				}
+			}
+		}
+
+		params := make([]interface{}, len(req.Params))
+		for j, param := range req.Params {
+			params[j] = param
+		}
+		actual := engineResponse{}
+		if err := clt.CallContext(ctx, &actual.Result, req.Method, params...); err != nil {
+			var rpcErr rpc.Error
+			if !errors.As(err, &rpcErr) {
+				return nil, fmt.Errorf("failed to replay request %d (%s): %w", i, req.Method, err)
+			}
+			actual.Error = &engineError{Code: rpcErr.ErrorCode(), Message: rpcErr.Error()}
+		}
+		report.Requests++
+
+		if strings.HasPrefix(req.Method, "engine_forkchoiceUpdated") {
+			expectedID, actualID := payloadID(expected.Result), payloadID(actual.Result)
+			if expectedID != "" && actualID != "" {
+				payloadIDs[expectedID] = actualID
+			}
+		}
+
+		expectedStr, actualStr := normalizeEngineResponse(req.Method, &expected), normalizeEngineResponse(req.Method, &actual)
+		if expectedStr != actualStr {
+			report.Diffs = append(report.Diffs, &EngineReplayDiff{
+				Index:    i,
+				Method:   req.Method,
+				Expected: expectedStr,
+				Actual:   actualStr,
+			})
+		}
+	}
+	return report, nil
+}
+
+func payloadID(result json.RawMessage) string {
+	var res struct {
+		PayloadID *string `json:"payloadId"`
+	}
+	if json.Unmarshal(result, &res) != nil || res.PayloadID == nil {
+		return ""
+	}
+	return *res.PayloadID
+}
+
+// normalizeEngineResponse returns the response as canonical JSON. The errors are compared by
+// code since the messages depend on the client, and the payload ids of the fork choice updates
+// are compared by presence.
+func normalizeEngineResponse(method string, resp *engineResponse) string {
+	if resp.Error != nil {
+		return fmt.Sprintf(`{"error":{"code":%d}}`, resp.Error.Code)
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return string(resp.Result)
+	}
+	if obj, ok := result.(map[string]interface{}); ok && strings.HasPrefix(method, "engine_forkchoiceUpdated") {
+		if obj["payloadId"] != nil {
+			obj["payloadId"] = "<payload id>"
+		}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return string(resp.Result)
+	}
+	return string(data)
+}
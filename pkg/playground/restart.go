@@ -0,0 +1,256 @@
+package playground
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+type RestartMode string
+
+var (
+	// RestartNever ends the session when the container exits, the default
+	RestartNever RestartMode = "never"
+
+	// RestartOnFailure restarts the container when it exits with a non-zero code
+	RestartOnFailure RestartMode = "on-failure"
+
+	// RestartAlways restarts the container whenever it exits
+	RestartAlways RestartMode = "always"
+)
+
+// RestartPolicy is what the runner does when the container of a service exits
+type RestartPolicy struct {
+	Mode RestartMode
+
+	// MaxRetries is the number of restarts of on-failure before the session ends, 0 is unlimited
+	MaxRetries int
+}
+
+// ParseRestartPolicy parses a restart policy: never, always, on-failure or on-failure:<retries>
+func ParseRestartPolicy(s string) (*RestartPolicy, error) {
+	mode, retriesStr, hasRetries := strings.Cut(s, ":")
+	policy := &RestartPolicy{Mode: RestartMode(mode)}
+	switch policy.Mode {
+	case RestartNever, RestartAlways:
+		if hasRetries {
+			return nil, fmt.Errorf("restart policy '%s' does not accept a number of retries", mode)
+		}
+	case RestartOnFailure:
+		if hasRetries {
+			retries, err := strconv.Atoi(retriesStr)
+			if err != nil || retries <= 0 {
+				return nil, fmt.Errorf("invalid number of retries '%s' in restart policy '%s'", retriesStr, s)
+			}
+			policy.MaxRetries = retries
+		}
+	default:
+		return nil, fmt.Errorf("invalid restart policy '%s', expected never, always, on-failure or on-failure:<retries>", s)
+	}
+	return policy, nil
+}
+
+func (r *RestartPolicy) String() string {
+	if r.Mode == RestartOnFailure && r.MaxRetries != 0 {
+		return fmt.Sprintf("%s:%d", r.Mode, r.MaxRetries)
+	}
+	return string(r.Mode)
+}
+
+const (
+	// the services restarted crashLoopRestarts times within crashLoopWindow are in a crash loop
+	// and end the session, whatever their restart policy
+	crashLoopRestarts = 5
+	crashLoopWindow   = 2 * time.Minute
+
+	// the restarts of a service wait an exponential backoff from restartBackoff to maxRestartBackoff
+	restartBackoff    = time.Second
+	maxRestartBackoff = 30 * time.Second
+
+	// serviceFailedLogLines is the number of log lines of the failed service in the error
+	serviceFailedLogLines = 20
+)
+
+// ServiceFailedError is the error of a service that exited and was not restarted
+type ServiceFailedError struct {
+	Service  string
+	ExitCode int
+
+	// Reason is why the service was not restarted
+	Reason string
+
+	// Logs are the last lines of the logs of the service
+	Logs []string
+}
+
+func (s *ServiceFailedError) Error() string {
+	return fmt.Sprintf("container %s failed with exit code %d: %s", s.Service, s.ExitCode, s.Reason)
+}
+
+// SetRestartPolicy sets the restart policy of the service, or of all the services without
+// their own policy if the name is empty. The services running on the host are not restarted.
+func (d *LocalRunner) SetRestartPolicy(name string, policy *RestartPolicy) error {
+	if name == "" {
+		d.restartPolicy = policy
+		return nil
+	}
+	if _, ok := d.manifest.GetService(name); !ok {
+		return fmt.Errorf("restart policy for unknown service '%s'", name)
+	}
+	d.serviceRestartPolicies[name] = policy
+	return nil
+}
+
+// restartPolicyOf returns the restart policy of the service: the one set in the runner for the
+// service, the one of the manifest or the default of the runner
+func (d *LocalRunner) restartPolicyOf(name string) *RestartPolicy {
+	if policy, ok := d.serviceRestartPolicies[name]; ok {
+		return policy
+	}
+	if svc, ok := d.manifest.GetService(name); ok && svc.restartPolicy != nil {
+		return svc.restartPolicy
+	}
+	return d.restartPolicy
+}
+
+// handleContainerExit applies the restart policy of the service when its container exits. The
+// session ends if the service is not restarted, with the last lines of its logs in the error.
+func (d *LocalRunner) handleContainerExit(name string, containerID string, exitCode int) {
+	d.tasksMtx.Lock()
+	task, ok := d.tasks[name]
+	if !ok || d.stopping {
+		// the service was removed when the devnet was scaled down, or the session is stopping
+		d.tasksMtx.Unlock()
+		return
+	}
+	if task.expectRestart {
+		// restarted by the runner (i.e. to rotate the JWT secrets)
+		d.tasksMtx.Unlock()
+		return
+	}
+
+	if svc, ok := d.manifest.GetService(name); ok && svc.job {
+		// the jobs are not restarted, they either complete or fail
+		logs, logsDone := task.logs, task.logsDone
+		d.tasksMtx.Unlock()
+
+		if exitCode != 0 {
+			d.failService(name, containerID, exitCode, "the job did not complete successfully", logs, logsDone)
+			return
+		}
+		waitLogs(logsDone)
+		runnerLog.Info("job completed", "service", name)
+		d.updateTaskStatus(name, taskStatusCompleted)
+		return
+	}
+
+	policy := d.restartPolicyOf(name)
+	reason := ""
+	switch {
+	case policy.Mode == RestartNever:
+		reason = "the restart policy is never"
+	case policy.Mode == RestartOnFailure && exitCode == 0:
+		reason = "the container exited successfully and the restart policy is on-failure"
+	case policy.Mode == RestartOnFailure && policy.MaxRetries != 0 && task.restartCount >= policy.MaxRetries:
+		reason = fmt.Sprintf("the container was restarted %d times, the limit of its restart policy", task.restartCount)
+	}
+
+	if reason == "" {
+		now := time.Now()
+		recent := []time.Time{}
+		for _, t := range task.restarts {
+			if now.Sub(t) < crashLoopWindow {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) >= crashLoopRestarts {
+			reason = fmt.Sprintf("crash loop, the container was restarted %d times in the last %s", len(recent), crashLoopWindow)
+		}
+		task.restarts = append(recent, now)
+	}
+
+	if reason != "" {
+		logs, logsDone := task.logs, task.logsDone
+		d.tasksMtx.Unlock()
+		d.failService(name, containerID, exitCode, reason, logs, logsDone)
+		return
+	}
+
+	task.restartCount++
+	backoff := restartBackoff << min(task.restartCount-1, 5)
+	if backoff > maxRestartBackoff {
+		backoff = maxRestartBackoff
+	}
+	attempt := task.restartCount
+	logs, logsDone := task.logs, task.logsDone
+	d.tasksMtx.Unlock()
+
+	runnerLog.Warn("container exited, restarting", "service", name, "exitCode", exitCode, "policy", policy.String(), "attempt", attempt, "backoff", backoff)
+	d.updateTaskStatus(name, taskStatusRestarting)
+
+	go func() {
+		d.captureCrashDump(name, containerID, exitCode, true, logs, logsDone)
+		time.Sleep(backoff)
+
+		d.tasksMtx.Lock()
+		stopping := d.stopping
+		d.tasksMtx.Unlock()
+		if stopping {
+			return
+		}
+		if err := d.client.ContainerStart(context.Background(), containerID, container.StartOptions{}); err != nil {
+			runnerLog.Error("failed to restart container", "service", name, "err", err)
+			d.updateTaskStatus(name, taskStatusDie)
+			select {
+			case d.exitErr <- fmt.Errorf("failed to restart container %s: %w", name, err):
+			default:
+			}
+		}
+	}()
+}
+
+// failService marks the service as failed and ends the session with the last lines of its logs,
+// after the crash dump of its container is captured
+func (d *LocalRunner) failService(name string, containerID string, exitCode int, reason string, logs *logFile, logsDone chan struct{}) {
+	d.captureCrashDump(name, containerID, exitCode, false, logs, logsDone)
+	d.updateTaskStatus(name, taskStatusDie)
+
+	err := &ServiceFailedError{Service: name, ExitCode: exitCode, Reason: reason}
+	if logs != nil {
+		err.Logs = tailLines(logs.Name(), serviceFailedLogLines)
+	}
+	select {
+	case d.exitErr <- err:
+	default:
+		// the session is already ending
+	}
+}
+
+// waitLogs waits for the last logs of the container to be written
+func waitLogs(logsDone chan struct{}) {
+	if logsDone == nil {
+		return
+	}
+	select {
+	case <-logsDone:
+	case <-time.After(2 * time.Second):
+	}
+}
+
+// tailLines returns the last n lines of a file
+func tailLines(path string, n int) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
@@ -0,0 +1,378 @@
+package playground
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scalableGroup is a set of services of the manifest that is deployed a variable number of
+// times, i.e. a beacon node with its own execution node. The instances can be added and
+// removed while the devnet is running with 'playground scale'.
+type scalableGroup struct {
+	// max is the maximum number of instances
+	max int
+
+	// add adds the services of the instance with the given index (starting from 1)
+	add func(manifest *Manifest, index int)
+
+	// instances are the names of the services of each instance
+	instances [][]string
+}
+
+// AddScalable declares a group of services that can be scaled up to max instances. The add function
+// adds to the manifest the services of the instance with the given index (starting from 1), which
+// must have unique names and data folders. The group starts with zero instances, use Scale to
+// deploy the initial ones.
+func (s *Manifest) AddScalable(name string, max int, add func(manifest *Manifest, index int)) {
+	if _, ok := s.scalable[name]; ok {
+		panic(fmt.Sprintf("BUG: scalable group %s already exists", name))
+	}
+	s.scalable[name] = &scalableGroup{max: max, add: add}
+}
+
+// ScalableGroups returns the number of instances of each scalable group
+func (s *Manifest) ScalableGroups() map[string]int {
+	groups := map[string]int{}
+	for name, group := range s.scalable {
+		groups[name] = len(group.instances)
+	}
+	return groups
+}
+
+// Scale adds or removes instances of the scalable group until it has the given number of instances.
+// The instances are removed in the reverse order in which they were added. It returns the services
+// added and removed from the manifest.
+func (s *Manifest) Scale(name string, instances int) ([]*ServiceSpec, []*ServiceSpec, error) {
+	group, ok := s.scalable[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("scalable group '%s' not found", name)
+	}
+	if instances < 0 || instances > group.max {
+		return nil, nil, fmt.Errorf("the group '%s' can have between 0 and %d instances", name, group.max)
+	}
+
+	var added, removed []*ServiceSpec
+	for len(group.instances) < instances {
+		first := len(s.services)
+		group.add(s, len(group.instances)+1)
+
+		names := []string{}
+		for _, svc := range s.services[first:] {
+			names = append(names, svc.Name)
+			added = append(added, svc)
+		}
+		group.instances = append(group.instances, names)
+	}
+	if len(group.instances) > instances {
+		remove := map[string]bool{}
+		for _, names := range group.instances[instances:] {
+			for _, name := range names {
+				remove[name] = true
+			}
+		}
+
+		// build a new list instead of filtering in place since the list is
+		// shared with the goroutines that are iterating over the services
+		services := make([]*ServiceSpec, 0, len(s.services))
+		for _, svc := range s.services {
+			if remove[svc.Name] {
+				removed = append(removed, svc)
+				continue
+			}
+			for _, dep := range svc.dependsOn {
+				if remove[dep.Service] {
+					return nil, nil, fmt.Errorf("service %s depends on %s of the group '%s'", svc.Name, dep.Service, name)
+				}
+			}
+			services = append(services, svc)
+		}
+		s.services = services
+		group.instances = group.instances[:instances]
+	}
+	return added, removed, nil
+}
+
+// scaleUndo returns a function that restores the services of the manifest and the instances
+// of the scalable group to their current state, to undo a call to Scale
+func (s *Manifest) scaleUndo(name string) func() {
+	group, ok := s.scalable[name]
+	if !ok {
+		return func() {}
+	}
+	services, instances := s.services, group.instances
+	return func() {
+		s.services = services
+		group.instances = instances
+	}
+}
+
+// Scale changes the number of instances of a scalable group of the running devnet. The services
+// of the new instances get their own ports and config files and join the network of the session
+// without restarting the other services. It returns once the new services are healthy.
+func (d *LocalRunner) Scale(ctx context.Context, name string, instances int) error {
+	d.scaleMtx.Lock()
+	defer d.scaleMtx.Unlock()
+
+	// the manifest must keep matching the running devnet if the services cannot be
+	// removed or added
+	undo := d.manifest.scaleUndo(name)
+
+	added, removed, err := d.manifest.Scale(name, instances)
+	if err != nil {
+		return err
+	}
+	if len(removed) != 0 {
+		names := []string{}
+		for _, svc := range removed {
+			if d.isHostService(svc.Name) {
+				undo()
+				return fmt.Errorf("service %s runs on the host and cannot be removed", svc.Name)
+			}
+			names = append(names, svc.Name)
+		}
+
+		// the containers exit when they are removed, which is not a failure of the devnet
+		tasks := map[string]*task{}
+		d.tasksMtx.Lock()
+		for _, name := range names {
+			if task, ok := d.tasks[name]; ok {
+				tasks[name] = task
+				delete(d.tasks, name)
+			}
+		}
+		d.tasksMtx.Unlock()
+
+		// the services are still defined in the current docker-compose.yaml
+		if err := d.removeDockerComposeServices(ctx, names); err != nil {
+			d.tasksMtx.Lock()
+			for name, task := range tasks {
+				d.tasks[name] = task
+			}
+			d.tasksMtx.Unlock()
+			undo()
+			return err
+		}
+	}
+	if len(added) != 0 {
+		if err := d.manifest.Validate(); err != nil {
+			undo()
+			return fmt.Errorf("failed to validate manifest: %w", err)
+		}
+	}
+
+	// the ports of the existing services are already reserved, so they do not change
+	yamlData, err := d.generateDockerCompose()
+	if err != nil {
+		return fmt.Errorf("failed to generate docker-compose.yaml: %w", err)
+	}
+	if err := d.out.WriteFile("docker-compose.yaml", yamlData); err != nil {
+		return fmt.Errorf("failed to write docker-compose.yaml: %w", err)
+	}
+	if err := d.writeHostsFile(); err != nil {
+		return err
+	}
+	if err := d.updateSession(); err != nil {
+		return err
+	}
+
+	for _, svc := range added {
+		if err := d.trackService(svc); err != nil {
+			return err
+		}
+	}
+
+	// the dependencies outside of the group are already running and healthy
+	healthy := map[string]bool{}
+	for _, svc := range d.manifest.Services() {
+		healthy[svc.Name] = true
+	}
+	for _, svc := range added {
+		healthy[svc.Name] = false
+	}
+	for _, svc := range added {
+		if err := d.startService(ctx, svc, healthy); err != nil {
+			return err
+		}
+	}
+	for _, svc := range added {
+		if svc.readyCheck == nil || healthy[svc.Name] {
+			continue
+		}
+		if err := d.waitForHealthy(ctx, svc); err != nil {
+			return err
+		}
+		healthy[svc.Name] = true
+	}
+	return nil
+}
+
+// removeDockerComposeServices stops and removes the containers of the services
+func (d *LocalRunner) removeDockerComposeServices(ctx context.Context, names []string) error {
+	args := []string{"-p", d.session.Name, "-f", filepath.Join(d.out.dst, "docker-compose.yaml"), "rm", "--stop", "--force"}
+	cmd := d.composeCommand(ctx, append(args, names...)...)
+
+	var errOut bytes.Buffer
+	cmd.Stderr = &errOut
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to remove services %s: %w, err: %s", strings.Join(names, ", "), err, errOut.String())
+	}
+	return nil
+}
+
+// ControlServer is the HTTP API on localhost used by the commands that change a running
+// session, like 'playground scale'. The address is stored in the session file.
+type ControlServer struct {
+	runner   *LocalRunner
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewControlServer listens on a random port of localhost, so that the address can be
+// stored in the session before the services start
+func NewControlServer(runner *LocalRunner) (*ControlServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the control server: %w", err)
+	}
+	c := &ControlServer{
+		runner:   runner,
+		listener: listener,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scale", c.handleScale)
+	mux.HandleFunc("/slashing-protection", c.handleSlashingProtection)
+	mux.HandleFunc("/session", c.handleSession)
+	c.server = &http.Server{Handler: mux}
+
+	runner.session.ControlURL = "http://" + listener.Addr().String()
+	return c, nil
+}
+
+// Run serves the requests and blocks until the server is closed
+func (c *ControlServer) Run() error {
+	if err := c.server.Serve(c.listener); err != http.ErrServerClosed {
+		return fmt.Errorf("control server error: %w", err)
+	}
+	return nil
+}
+
+func (c *ControlServer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return c.server.Shutdown(ctx)
+}
+
+type scaleRequest struct {
+	Group     string `json:"group"`
+	Instances int    `json:"instances"`
+}
+
+type scaleResponse struct {
+	Groups map[string]int `json:"groups"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// handleScale returns the number of instances of the scalable groups (GET) or scales
+// one of them (POST)
+func (c *ControlServer) handleScale(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	resp := &scaleResponse{}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req scaleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			status, resp.Error = http.StatusBadRequest, fmt.Sprintf("invalid request: %s", err)
+		} else if err := c.runner.Scale(r.Context(), req.Group, req.Instances); err != nil {
+			status, resp.Error = http.StatusInternalServerError, err.Error()
+		}
+	default:
+		status, resp.Error = http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method)
+	}
+
+	c.runner.scaleMtx.Lock()
+	resp.Groups = c.runner.manifest.ScalableGroups()
+	c.runner.scaleMtx.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+type sessionInfo struct {
+	ID string `json:"id"`
+}
+
+// handleSession returns the id of the session, which tells the other commands that the
+// process of the session is alive (see Session.alive)
+func (c *ControlServer) handleSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&sessionInfo{ID: c.runner.session.ID})
+}
+
+// ScaleSession scales the scalable groups of a running session to the given number of instances
+// and returns the number of instances of all the groups
+func ScaleSession(ctx context.Context, session *Session, scale map[string]int) (map[string]int, error) {
+	if session.ControlURL == "" {
+		return nil, fmt.Errorf("session '%s' does not have scalable services", session.Name)
+	}
+
+	// scale the groups in a stable order
+	names := make([]string, 0, len(scale))
+	for name := range scale {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var groups map[string]int
+	var err error
+	for _, name := range names {
+		data, _ := json.Marshal(&scaleRequest{Group: name, Instances: scale[name]})
+		if groups, err = controlRequest(ctx, http.MethodPost, session.ControlURL+"/scale", data); err != nil {
+			return nil, fmt.Errorf("failed to scale '%s': %w", name, err)
+		}
+	}
+	if len(names) == 0 {
+		if groups, err = controlRequest(ctx, http.MethodGet, session.ControlURL+"/scale", nil); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+func controlRequest(ctx context.Context, method string, url string, body []byte) (map[string]int, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var scaleResp scaleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&scaleResp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if scaleResp.Error != "" {
+		return nil, fmt.Errorf("%s", scaleResp.Error)
+	}
+	return scaleResp.Groups, nil
+}
@@ -0,0 +1,35 @@
+package playground
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// TestScaleHostServiceRollback checks that the manifest does not change when an instance
+// cannot be removed because one of its services runs on the host
+func TestScaleHostServiceRollback(t *testing.T) {
+	manifest := NewManifest(&ExContext{}, &output{dst: t.TempDir()})
+	manifest.AddScalable("sleep", 2, func(manifest *Manifest, index int) {
+		manifest.AddService(fmt.Sprintf("sleep-%d", index), &sleepService{})
+	})
+	if _, _, err := manifest.Scale("sleep", 2); err != nil {
+		t.Fatalf("failed to scale the group: %v", err)
+	}
+
+	runner := &LocalRunner{
+		manifest:  manifest,
+		overrides: map[string]string{"sleep-2": "sleep"},
+		tasks:     map[string]*task{},
+	}
+	if err := runner.Scale(context.Background(), "sleep", 1); err == nil {
+		t.Fatal("expected an error when removing a host service")
+	}
+
+	if groups := manifest.ScalableGroups(); groups["sleep"] != 2 {
+		t.Fatalf("expected 2 instances, got %d", groups["sleep"])
+	}
+	if _, ok := manifest.GetService("sleep-2"); !ok {
+		t.Fatal("expected the service sleep-2 to be in the manifest")
+	}
+}
@@ -0,0 +1,517 @@
+package playground
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ecrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultScenarioTimeout is the maximum time of the steps that wait for the chain
+const defaultScenarioTimeout = 5 * time.Minute
+
+// Scenario is a test scenario of 'playground run-scenario': a list of steps run in order
+// against a running session, which stops at the first step that fails
+type Scenario struct {
+	Name string `yaml:"name"`
+
+	// Timeout is the default timeout of the steps, 5 minutes if not set
+	Timeout time.Duration `yaml:"timeout"`
+
+	Steps []*ScenarioStep `yaml:"steps"`
+}
+
+// ScenarioStep is a step of a scenario. Exactly one of the actions must be set.
+type ScenarioStep struct {
+	// Name describes the step in the report, it defaults to the action
+	Name string `yaml:"name"`
+
+	// Timeout overrides the timeout of the scenario for this step
+	Timeout time.Duration `yaml:"timeout"`
+
+	// WaitSlot waits until the head of the L1 beacon node (beacon) reaches the slot
+	WaitSlot *uint64 `yaml:"wait-slot"`
+
+	// WaitBlock waits until the head of the L1 EL (el) reaches the block number
+	WaitBlock *uint64 `yaml:"wait-block"`
+
+	Sleep *time.Duration `yaml:"sleep"`
+
+	SendTx *ScenarioSendTx `yaml:"send-tx"`
+
+	Kill *ScenarioKill `yaml:"kill"`
+
+	AssertMetric *ScenarioAssertMetric `yaml:"assert-metric"`
+}
+
+// ScenarioSendTx sends transactions from a prefunded account
+type ScenarioSendTx struct {
+	// Service is the EL the transactions are sent to, defaults to el
+	Service string `yaml:"service"`
+
+	// Account is the index of the prefunded account that signs the transactions
+	Account int `yaml:"account"`
+
+	// To is the recipient, defaults to the account itself
+	To string `yaml:"to"`
+
+	// Value is the value of each transaction in wei
+	Value string `yaml:"value"`
+
+	// Data is the hex encoded input of the transactions
+	Data string `yaml:"data"`
+
+	// Count is the number of transactions, defaults to 1
+	Count uint64 `yaml:"count"`
+
+	// Wait waits until the transactions are included and checks that they succeeded
+	Wait bool `yaml:"wait"`
+}
+
+// ScenarioKill sends a signal to the container of a service
+type ScenarioKill struct {
+	Service string `yaml:"service"`
+
+	// Signal defaults to SIGKILL
+	Signal string `yaml:"signal"`
+}
+
+// ScenarioAssertMetric checks the value of a Prometheus metric of a service
+type ScenarioAssertMetric struct {
+	Service string `yaml:"service"`
+
+	// Port is the port label of the metrics endpoint, defaults to metrics
+	Port string `yaml:"port"`
+
+	// Path defaults to /metrics
+	Path string `yaml:"path"`
+
+	// Metric is the name of the metric, the values of all its series are added up. A series
+	// is selected with its labels as in the exposition format (i.e. name{label="value"}).
+	Metric string `yaml:"metric"`
+
+	// Op is the comparison with the value: >, >=, <, <=, == or !=
+	Op    string  `yaml:"op"`
+	Value float64 `yaml:"value"`
+
+	// Within retries the assertion until it holds for this time, the assertion is checked
+	// once if not set
+	Within time.Duration `yaml:"within"`
+}
+
+var scenarioMetricOps = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+	var scenario Scenario
+	if err := yaml.UnmarshalStrict(data, &scenario); err != nil {
+		return nil, fmt.Errorf("failed to decode scenario file: %w", err)
+	}
+	if err := scenario.validate(); err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+func (s *Scenario) validate() error {
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("the scenario does not define any steps")
+	}
+	for i, step := range s.Steps {
+		actions := 0
+		for _, set := range []bool{step.WaitSlot != nil, step.WaitBlock != nil, step.Sleep != nil, step.SendTx != nil, step.Kill != nil, step.AssertMetric != nil} {
+			if set {
+				actions++
+			}
+		}
+		if actions != 1 {
+			return fmt.Errorf("step %d must have exactly one action (wait-slot, wait-block, sleep, send-tx, kill or assert-metric)", i+1)
+		}
+
+		switch {
+		case step.SendTx != nil:
+			tx := step.SendTx
+			if tx.Account < 0 || tx.Account >= len(prefundedAccounts) {
+				return fmt.Errorf("step %d: there are %d prefunded accounts", i+1, len(prefundedAccounts))
+			}
+			if tx.To != "" && !gethcommon.IsHexAddress(tx.To) {
+				return fmt.Errorf("step %d: invalid recipient '%s'", i+1, tx.To)
+			}
+			if _, ok := new(big.Int).SetString(cmp.Or(tx.Value, "0"), 10); !ok {
+				return fmt.Errorf("step %d: invalid value '%s', expected an amount in wei", i+1, tx.Value)
+			}
+			if _, err := hexutil.Decode(cmp.Or(tx.Data, "0x")); err != nil {
+				return fmt.Errorf("step %d: invalid data: %w", i+1, err)
+			}
+		case step.Kill != nil:
+			if step.Kill.Service == "" {
+				return fmt.Errorf("step %d: the service to kill is required", i+1)
+			}
+		case step.AssertMetric != nil:
+			assert := step.AssertMetric
+			if assert.Service == "" || assert.Metric == "" {
+				return fmt.Errorf("step %d: the service and the metric are required", i+1)
+			}
+			if _, ok := scenarioMetricOps[assert.Op]; !ok {
+				return fmt.Errorf("step %d: invalid op '%s', expected >, >=, <, <=, == or !=", i+1, assert.Op)
+			}
+		}
+	}
+	return nil
+}
+
+// describe returns the action of the step for the report
+func (s *ScenarioStep) describe() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	switch {
+	case s.WaitSlot != nil:
+		return fmt.Sprintf("wait for slot %d", *s.WaitSlot)
+	case s.WaitBlock != nil:
+		return fmt.Sprintf("wait for block %d", *s.WaitBlock)
+	case s.Sleep != nil:
+		return fmt.Sprintf("sleep %s", *s.Sleep)
+	case s.SendTx != nil:
+		return fmt.Sprintf("send %d transactions to %s", max(s.SendTx.Count, 1), cmp.Or(s.SendTx.Service, "el"))
+	case s.Kill != nil:
+		return fmt.Sprintf("kill %s", s.Kill.Service)
+	case s.AssertMetric != nil:
+		return fmt.Sprintf("assert %s %s %s %v", s.AssertMetric.Service, s.AssertMetric.Metric, s.AssertMetric.Op, s.AssertMetric.Value)
+	}
+	return ""
+}
+
+// ScenarioStepResult is the result of a step of the scenario
+type ScenarioStepResult struct {
+	Step     string       `json:"step"`
+	Status   VerifyStatus `json:"status"`
+	Duration string       `json:"duration"`
+	Message  string       `json:"message,omitempty"`
+}
+
+// ScenarioReport is the machine-readable report of 'playground run-scenario'. The steps
+// after the one that failed are skipped.
+type ScenarioReport struct {
+	Session  string                `json:"session"`
+	Scenario string                `json:"scenario"`
+	Passed   bool                  `json:"passed"`
+	Steps    []*ScenarioStepResult `json:"steps"`
+}
+
+// RunScenario runs the steps of the scenario in order against the session and writes the
+// progress to out
+func RunScenario(ctx context.Context, out io.Writer, session *Session, scenario *Scenario) *ScenarioReport {
+	report := &ScenarioReport{Session: session.Name, Scenario: scenario.Name, Passed: true, Steps: []*ScenarioStepResult{}}
+
+	for i, step := range scenario.Steps {
+		result := &ScenarioStepResult{Step: step.describe(), Status: VerifyStatusPass}
+		report.Steps = append(report.Steps, result)
+		if !report.Passed {
+			result.Status = VerifyStatusSkip
+			continue
+		}
+
+		timeout := cmp.Or(step.Timeout, scenario.Timeout, defaultScenarioTimeout)
+		stepCtx, cancel := context.WithTimeout(ctx, timeout)
+
+		fmt.Fprintf(out, "[%d/%d] %s\n", i+1, len(scenario.Steps), result.Step)
+		start := time.Now()
+		err := runScenarioStep(stepCtx, out, session, step)
+		result.Duration = time.Since(start).Round(time.Millisecond).String()
+		cancel()
+
+		if err != nil {
+			if stepCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+				err = fmt.Errorf("timeout after %s: %w", timeout, err)
+			}
+			result.Status, result.Message = VerifyStatusFail, err.Error()
+			report.Passed = false
+			fmt.Fprintf(out, "      failed: %v\n", err)
+		}
+	}
+	return report
+}
+
+func runScenarioStep(ctx context.Context, out io.Writer, session *Session, step *ScenarioStep) error {
+	switch {
+	case step.WaitSlot != nil:
+		beaconURL, err := sessionBeaconURL(session)
+		if err != nil {
+			return err
+		}
+		return waitScenario(ctx, func() (bool, error) {
+			head, err := getBeaconHead(ctx, beaconURL)
+			if err != nil {
+				return false, err
+			}
+			return head.Slot >= *step.WaitSlot, nil
+		})
+
+	case step.WaitBlock != nil:
+		clt, err := dialSessionEL(ctx, session, "el")
+		if err != nil {
+			return err
+		}
+		defer clt.Close()
+		return waitScenario(ctx, func() (bool, error) {
+			number, err := clt.BlockNumber(ctx)
+			if err != nil {
+				return false, err
+			}
+			return number >= *step.WaitBlock, nil
+		})
+
+	case step.Sleep != nil:
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(*step.Sleep):
+			return nil
+		}
+
+	case step.SendTx != nil:
+		return sendScenarioTxs(ctx, out, session, step.SendTx)
+
+	case step.Kill != nil:
+		if _, ok := session.Services[step.Kill.Service]; !ok {
+			return fmt.Errorf("session '%s' has no service %s", session.Name, step.Kill.Service)
+		}
+		clt, err := session.dockerClient()
+		if err != nil {
+			return err
+		}
+		defer clt.Close()
+
+		id, err := serviceContainer(ctx, clt, session, step.Kill.Service)
+		if err != nil {
+			return err
+		}
+		if err := clt.ContainerKill(ctx, id, cmp.Or(step.Kill.Signal, "SIGKILL")); err != nil {
+			return fmt.Errorf("failed to kill %s: %w", step.Kill.Service, err)
+		}
+		return nil
+
+	case step.AssertMetric != nil:
+		assert := step.AssertMetric
+		check := func() error {
+			value, err := scrapeMetric(ctx, sessionServiceURL(session, assert.Service, cmp.Or(assert.Port, "metrics"))+cmp.Or(assert.Path, "/metrics"), assert.Metric)
+			if err != nil {
+				return err
+			}
+			if !scenarioMetricOps[assert.Op](value, assert.Value) {
+				return fmt.Errorf("%s is %v, expected %s %v", assert.Metric, value, assert.Op, assert.Value)
+			}
+			return nil
+		}
+		if _, ok := session.Services[assert.Service][cmp.Or(assert.Port, "metrics")]; !ok {
+			return fmt.Errorf("service %s has no port %s", assert.Service, cmp.Or(assert.Port, "metrics"))
+		}
+
+		deadline := time.Now().Add(assert.Within)
+		for {
+			err := check()
+			if err == nil || !time.Now().Before(deadline) {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(time.Second):
+			}
+		}
+	}
+	return nil
+}
+
+// waitScenario polls the condition every second until it holds. The errors are retried since
+// the services may be restarting, the last one is returned if the context ends.
+func waitScenario(ctx context.Context, cond func() (bool, error)) error {
+	var lastErr error
+	for {
+		ok, err := cond()
+		if ok {
+			return nil
+		}
+		lastErr = err
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return lastErr
+			}
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func dialSessionEL(ctx context.Context, session *Session, name string) (*ethclient.Client, error) {
+	if _, ok := session.Services[name]["http"]; !ok {
+		return nil, fmt.Errorf("session '%s' has no EL %s", session.Name, name)
+	}
+	clt, err := ethclient.DialContext(ctx, sessionServiceURL(session, name, "http"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", name, err)
+	}
+	return clt, nil
+}
+
+func sendScenarioTxs(ctx context.Context, out io.Writer, session *Session, config *ScenarioSendTx) error {
+	clt, err := dialSessionEL(ctx, session, cmp.Or(config.Service, "el"))
+	if err != nil {
+		return err
+	}
+	defer clt.Close()
+
+	key, err := ecrypto.HexToECDSA(strings.TrimPrefix(prefundedAccounts[config.Account], "0x"))
+	if err != nil {
+		return err
+	}
+	from := ecrypto.PubkeyToAddress(key.PublicKey)
+	to := from
+	if config.To != "" {
+		to = gethcommon.HexToAddress(config.To)
+	}
+	value, _ := new(big.Int).SetString(cmp.Or(config.Value, "0"), 10)
+	data, _ := hexutil.Decode(cmp.Or(config.Data, "0x"))
+
+	chainID, err := clt.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain id: %w", err)
+	}
+	nonce, err := clt.PendingNonceAt(ctx, from)
+	if err != nil {
+		return fmt.Errorf("failed to get nonce: %w", err)
+	}
+	tip, err := clt.SuggestGasTipCap(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get gas tip: %w", err)
+	}
+	head, err := clt.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get latest block: %w", err)
+	}
+	gas, err := clt.EstimateGas(ctx, ethereum.CallMsg{From: from, To: &to, Value: value, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to estimate gas: %w", err)
+	}
+
+	txs := []*types.Transaction{}
+	for i := uint64(0); i < max(config.Count, 1); i++ {
+		tx, err := types.SignNewTx(key, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce + i,
+			GasTipCap: tip,
+			GasFeeCap: new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2))),
+			Gas:       gas,
+			To:        &to,
+			Value:     value,
+			Data:      data,
+		})
+		if err != nil {
+			return err
+		}
+		if err := clt.SendTransaction(ctx, tx); err != nil {
+			return fmt.Errorf("failed to send transaction: %w", err)
+		}
+		txs = append(txs, tx)
+	}
+	fmt.Fprintf(out, "      sent %d transactions from %s\n", len(txs), from)
+
+	if !config.Wait {
+		return nil
+	}
+	for _, tx := range txs {
+		receipt, err := waitForReceipt(ctx, clt, tx.Hash())
+		if err != nil {
+			return err
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			return fmt.Errorf("transaction %s failed", tx.Hash())
+		}
+	}
+	return nil
+}
+
+// scrapeMetric returns the value of a metric in the Prometheus text format of the endpoint
+func scrapeMetric(ctx context.Context, url string, metric string) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scrape the metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	found := false
+	sum := 0.0
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// the label values can have spaces, the value is after the closing brace
+		series, rest := line, ""
+		if i := strings.LastIndex(line, "}"); i >= 0 {
+			series, rest = line[:i+1], line[i+1:]
+		} else if i := strings.IndexByte(line, ' '); i >= 0 {
+			series, rest = line[:i], line[i:]
+		}
+		name, _, _ := strings.Cut(series, "{")
+		if series != metric && name != metric {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value of %s: %w", series, err)
+		}
+		found = true
+		sum += value
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("metric %s not found", metric)
+	}
+	return sum, nil
+}
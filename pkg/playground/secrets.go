@@ -0,0 +1,146 @@
+package playground
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// JWTSecret returns the template of the path of the JWT secret shared between an execution
+// node and the clients of its Engine API. There is one secret per execution node, generated
+// by the runner in the secrets folder of the output folder. The clients that connect through a
+// transparent proxy of the Engine API use the secret of the execution node behind the proxy.
+func JWTSecret(service string) string {
+	return fmt.Sprintf(`{{JWTSecret "%s"}}`, service)
+}
+
+// jwtProxy is implemented by the components that forward the Engine API requests
+// with the authentication of the client (i.e. cl-proxy)
+type jwtProxy interface {
+	jwtTarget() string
+}
+
+func (c *ClProxy) jwtTarget() string {
+	return c.PrimaryBuilder
+}
+
+func (a *ApiProxy) jwtTarget() string {
+	return a.Service
+}
+
+func (e *EngineMux) jwtTarget() string {
+	return e.Primary
+}
+
+func (r *RollupBoost) jwtTarget() string {
+	return r.ELNode
+}
+
+// jwtSecretOwner returns the execution node that owns the JWT secret used to connect to the service
+func (s *Manifest) jwtSecretOwner(name string) (string, error) {
+	visited := map[string]bool{}
+	for {
+		svc, ok := s.GetService(name)
+		if !ok {
+			return "", fmt.Errorf("jwt secret of service %s not found", name)
+		}
+		proxy, ok := svc.component.(jwtProxy)
+		if !ok {
+			return name, nil
+		}
+		if visited[name] {
+			return "", fmt.Errorf("cyclic jwt proxy for service %s", name)
+		}
+		visited[name] = true
+		name = proxy.jwtTarget()
+	}
+}
+
+func jwtSecretFile(owner string) string {
+	return filepath.Join("secrets", "jwt-"+owner+".hex")
+}
+
+// jwtSecret returns the path of the JWT secret of the service relative to the output folder
+// and generates the secret the first time it is used
+func (d *LocalRunner) jwtSecret(name string) (string, error) {
+	owner, err := d.manifest.jwtSecretOwner(name)
+	if err != nil {
+		return "", err
+	}
+
+	d.jwtSecretsMtx.Lock()
+	defer d.jwtSecretsMtx.Unlock()
+
+	if _, ok := d.jwtSecrets[owner]; !ok {
+		secret, err := newJWTSecret()
+		if err != nil {
+			return "", err
+		}
+		if err := d.writeJWTSecret(owner, secret); err != nil {
+			return "", err
+		}
+		d.jwtSecrets[owner] = secret
+	}
+	return jwtSecretFile(owner), nil
+}
+
+func (d *LocalRunner) writeJWTSecret(owner string, secret string) error {
+	path := filepath.Join(d.out.dst, jwtSecretFile(owner))
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create secrets folder: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(secret), 0600); err != nil {
+		return fmt.Errorf("failed to write jwt secret of %s: %w", owner, err)
+	}
+	return nil
+}
+
+// RotateJWTSecrets replaces the JWT secrets of all the execution nodes and restarts them so that
+// they load the new secret. The clients of the Engine API keep using the previous secret until
+// they are restarted, so it tests how the clients behave when the authentication fails.
+// The services running on the host are not restarted.
+func (d *LocalRunner) RotateJWTSecrets() error {
+	d.jwtSecretsMtx.Lock()
+	owners := make([]string, 0, len(d.jwtSecrets))
+	for owner := range d.jwtSecrets {
+		owners = append(owners, owner)
+	}
+	d.jwtSecretsMtx.Unlock()
+	sort.Strings(owners)
+
+	for _, owner := range owners {
+		secret, err := newJWTSecret()
+		if err != nil {
+			return err
+		}
+
+		d.jwtSecretsMtx.Lock()
+		err = d.writeJWTSecret(owner, secret)
+		if err == nil {
+			d.jwtSecrets[owner] = secret
+		}
+		d.jwtSecretsMtx.Unlock()
+		if err != nil {
+			return err
+		}
+
+		if d.isHostService(owner) {
+			continue
+		}
+		if err := d.restartService(owner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newJWTSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate jwt secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
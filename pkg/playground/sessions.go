@@ -0,0 +1,323 @@
+package playground
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// DefaultSessionName is the name of the session if none is provided
+var DefaultSessionName = "devnet"
+
+const sessionLabel = "playground.session"
+
+// sessionIDLabel is the label with the id of the session on the docker resources, which tells
+// apart the resources of a session from the ones leaked by a previous session with the same name
+const sessionIDLabel = "playground.session.id"
+
+// Session describes a devnet deployed on the host. Sessions are namespaced by name so
+// that multiple devnets can run concurrently on the same host.
+type Session struct {
+	// ID is unique to each run of the session (see NewSession)
+	ID        string    `json:"id,omitempty"`
+	Name      string    `json:"name"`
+	Recipe    string    `json:"recipe"`
+	Output    string    `json:"output"`
+	StartedAt time.Time `json:"startedAt"`
+
+	// Ports are the host ports reserved by the session
+	Ports []int `json:"ports"`
+
+	// Services are the host ports of each service by port label, used by the commands
+	// that interact with a running session
+	Services map[string]map[string]int `json:"services,omitempty"`
+
+	// Hosts are the addresses of the services whose ports bind to another interface than the
+	// loopback one (see --bind), the other services are reached on localhost
+	Hosts map[string]string `json:"hosts,omitempty"`
+
+	// Components are the names of the components of each service (i.e. lighthouse-beacon-node)
+	Components map[string]string `json:"components,omitempty"`
+
+	// ControlURL is the address of the control server of the session (see scale.go)
+	ControlURL string `json:"controlURL,omitempty"`
+
+	// DockerHost is the forwarded docker socket of the remote host of the session, if it
+	// runs on one (see remote.go)
+	DockerHost string `json:"dockerHost,omitempty"`
+}
+
+// sessionServiceURL returns the http endpoint of a port of a service of the session from the host
+func sessionServiceURL(session *Session, name string, port string) string {
+	host, ok := session.Hosts[name]
+	if !ok {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(session.Services[name][port]))
+}
+
+// NewSession returns a session of the recipe with a new id
+func NewSession(name string, recipe string) *Session {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return &Session{ID: hex.EncodeToString(id), Name: name, Recipe: recipe}
+}
+
+// labels returns the labels of the docker resources created for the session, used to find
+// them during the cleanup
+func (s *Session) labels() map[string]string {
+	labels := map[string]string{"playground": "true", sessionLabel: s.Name}
+	if s.ID != "" {
+		labels[sessionIDLabel] = s.ID
+	}
+	return labels
+}
+
+// alive returns whether the playground process of the session is still running, which is the
+// case if its control server answers with the id of the session. The containers of a session
+// whose process crashed keep running until they are pruned. The sessions without id, started
+// by an older version, are considered alive.
+func (s *Session) alive(ctx context.Context) bool {
+	if s.ID == "" {
+		return true
+	}
+	if s.ControlURL == "" {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.ControlURL+"/session", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	var info sessionInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return false
+	}
+	return info.ID == s.ID
+}
+
+// dockerClient returns a client of the docker daemon that runs the containers of the session
+func (s *Session) dockerClient() (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if s.DockerHost != "" {
+		opts = append(opts, client.WithHost(s.DockerHost))
+	}
+	clt, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return clt, nil
+}
+
+// ValidateSessionName checks that the name can be used as part of the docker
+// compose project, network and container names
+func ValidateSessionName(name string) error {
+	if name == "" {
+		return fmt.Errorf("session name cannot be empty")
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && c != '-' && c != '_' {
+			return fmt.Errorf("invalid session name '%s': only lowercase letters, digits, '-' and '_' are allowed", name)
+		}
+	}
+	return nil
+}
+
+func sessionsDir() (string, error) {
+	homeDir, err := GetHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, "sessions"), nil
+}
+
+func writeSession(session *Session) error {
+	dir, err := sessionsDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(session, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, session.Name+".json"), data, 0644)
+}
+
+func removeSession(name string) error {
+	dir, err := sessionsDir()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, name+".json")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func readSessions() ([]*Session, error) {
+	dir, err := sessionsDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Session{}, nil
+		}
+		return nil, err
+	}
+
+	sessions := []*Session{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var session Session
+		if err := json.Unmarshal(data, &session); err != nil {
+			return nil, fmt.Errorf("failed to decode session %s: %w", file.Name(), err)
+		}
+		sessions = append(sessions, &session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Name < sessions[j].Name
+	})
+	return sessions, nil
+}
+
+// SessionStatus is a session with the number of containers running for it
+type SessionStatus struct {
+	*Session
+	Containers int
+
+	// Crashed is set if the containers of the session are running but its playground process
+	// is not (see Session.alive). Its resources are removed by 'playground prune' or when
+	// a session with the same name starts.
+	Crashed bool
+}
+
+// ListSessions returns the sessions that are still running. The sessions that
+// do not have any running containers are considered stale and removed.
+func ListSessions() ([]*SessionStatus, error) {
+	sessions, err := readSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*SessionStatus{}
+	for _, session := range sessions {
+		containers, err := session.containers()
+		if err != nil {
+			return nil, err
+		}
+		if len(containers) == 0 {
+			if err := removeSession(session.Name); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		res = append(res, &SessionStatus{
+			Session:    session,
+			Containers: len(containers),
+			Crashed:    !session.alive(context.Background()),
+		})
+	}
+	return res, nil
+}
+
+// containers returns the containers of the session. The session of a remote host has no
+// containers once its forwarded docker socket is gone, when the session ended.
+func (s *Session) containers() ([]container.Summary, error) {
+	if socket, ok := strings.CutPrefix(s.DockerHost, "unix://"); ok {
+		if _, err := os.Stat(socket); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	clt, err := s.dockerClient()
+	if err != nil {
+		return nil, err
+	}
+	defer clt.Close()
+
+	containers, err := clt.ContainerList(context.Background(), container.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", sessionLabel+"="+s.Name)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting container list: %w", err)
+	}
+	return containers, nil
+}
+
+// FindSession returns the running session with the given name, if any
+func FindSession(name string) (*SessionStatus, error) {
+	sessions, err := ListSessions()
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if session.Name == name {
+			return session, nil
+		}
+	}
+	return nil, nil
+}
+
+// SessionNames returns the names of the sessions started on the host, without checking that
+// their containers are still running (i.e. for the shell completion)
+func SessionNames() ([]string, error) {
+	sessions, err := readSessions()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, session := range sessions {
+		names = append(names, session.Name)
+	}
+	return names, nil
+}
+
+// serviceContainer returns the id of the container of a service of the session
+func serviceContainer(ctx context.Context, clt *client.Client, session *Session, name string) (string, error) {
+	containers, err := clt.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", "com.docker.compose.project="+session.Name),
+			filters.Arg("label", "com.docker.compose.service="+name),
+		),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting container list: %w", err)
+	}
+	if len(containers) != 1 {
+		return "", fmt.Errorf("container of service %s not found", name)
+	}
+	return containers[0].ID, nil
+}
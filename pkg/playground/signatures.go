@@ -0,0 +1,128 @@
+package playground
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// releaseSignature is how the archives of a release are signed. The signatures are verified
+// with the gpg or cosign binaries of the host, if they are installed.
+type releaseSignature struct {
+	// GPGKey is the fingerprint of the key that signs the archives, with a detached
+	// signature in <archive>.asc. The key is fetched from gpgKeyserver.
+	GPGKey string
+
+	// CosignIdentity and CosignIssuer are the identity and the OIDC issuer of the keyless
+	// cosign signatures of the archives, in <archive>.sig with the certificate in <archive>.pem
+	CosignIdentity string
+	CosignIssuer   string
+}
+
+// gpgKeyserver is the keyserver of the keys of the releases signed with gpg, which keeps the
+// user ids of the keys that gpg requires to import them
+var gpgKeyserver = "hkps://keyserver.ubuntu.com"
+
+// errSignatureUnavailable is returned when the signature of an archive cannot be verified
+// because the release is not signed or the tool to verify it is not installed
+type errSignatureUnavailable struct {
+	reason string
+}
+
+func (e *errSignatureUnavailable) Error() string {
+	return e.reason
+}
+
+// verifiedMarker is the file next to a downloaded binary that records that the signature of
+// its archive was verified, so that --require-signature does not reuse binaries that were not
+func verifiedMarker(binPath string) string {
+	return binPath + ".verified"
+}
+
+// verifyArchive verifies the signature of the archive downloaded from url, downloading the
+// signature files next to it
+func verifyArchive(ctx context.Context, dl *downloader, sig *releaseSignature, url string, archivePath string) error {
+	if sig == nil {
+		return &errSignatureUnavailable{reason: "the release is not signed"}
+	}
+	if sig.GPGKey != "" {
+		return verifyGPG(ctx, dl, sig.GPGKey, url, archivePath)
+	}
+	return verifyCosign(ctx, dl, sig, url, archivePath)
+}
+
+func verifyGPG(ctx context.Context, dl *downloader, key string, url string, archivePath string) error {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		return &errSignatureUnavailable{reason: "gpg is not installed"}
+	}
+	sigPath := archivePath + ".asc"
+	defer os.Remove(sigPath)
+	if err := dl.fetch(ctx, url+".asc", sigPath); err != nil {
+		return fmt.Errorf("failed to download the signature: %w", err)
+	}
+
+	// a keyring of its own with only the key of the release, the keyring of the user may
+	// trust other keys
+	home, err := os.MkdirTemp("", "playground-gpg-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(home)
+
+	gpg := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "gpg", append([]string{"--homedir", home, "--batch", "--no-tty"}, args...)...)
+		var out, errOut bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &errOut
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(errOut.String()))
+		}
+		return out.String(), nil
+	}
+	if _, err := gpg("--keyserver", gpgKeyserver, "--recv-keys", key); err != nil {
+		return fmt.Errorf("failed to fetch the signing key %s: %w", key, err)
+	}
+	status, err := gpg("--status-fd", "1", "--verify", sigPath, archivePath)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	// the fingerprint of VALIDSIG is the one of the (sub)key that signed, the last one is the
+	// fingerprint of its primary key
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 2 && fields[1] == "VALIDSIG" && strings.EqualFold(fields[len(fields)-1], key) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the archive is not signed by the key %s", key)
+}
+
+func verifyCosign(ctx context.Context, dl *downloader, sig *releaseSignature, url string, archivePath string) error {
+	if _, err := exec.LookPath("cosign"); err != nil {
+		return &errSignatureUnavailable{reason: "cosign is not installed"}
+	}
+	sigPath, certPath := archivePath+".sig", archivePath+".pem"
+	defer os.Remove(sigPath)
+	defer os.Remove(certPath)
+	if err := dl.fetch(ctx, url+".sig", sigPath); err != nil {
+		return fmt.Errorf("failed to download the signature: %w", err)
+	}
+	if err := dl.fetch(ctx, url+".pem", certPath); err != nil {
+		return fmt.Errorf("failed to download the certificate: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "cosign", "verify-blob",
+		"--signature", sigPath,
+		"--certificate", certPath,
+		"--certificate-identity", sig.CosignIdentity,
+		"--certificate-oidc-issuer", sig.CosignIssuer,
+		archivePath,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("invalid signature: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
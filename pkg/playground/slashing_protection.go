@@ -0,0 +1,219 @@
+package playground
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/prysmaticlabs/prysm/v5/crypto/bls/common"
+)
+
+// slashingProtectionFile is the interchange file in the data folder of the validator clients,
+// with the slashing protection history that they import before they start
+const slashingProtectionFile = "slashing_protection.json"
+
+// slashingProtectionVersion is the version of the interchange format of EIP-3076
+const slashingProtectionVersion = "5"
+
+// SlashingProtectionInterchange is the slashing protection history of a set of validators in the
+// interchange format of EIP-3076, which all the validator clients import and export. A validator
+// client that starts from it does not sign the blocks and the attestations that would be
+// slashable with the ones signed by the previous validator client of the keys.
+type SlashingProtectionInterchange struct {
+	Metadata struct {
+		InterchangeFormatVersion string `json:"interchange_format_version"`
+		GenesisValidatorsRoot    string `json:"genesis_validators_root"`
+	} `json:"metadata"`
+	Data []*SlashingProtectionValidator `json:"data"`
+}
+
+// SlashingProtectionValidator is the history of the signatures of a validator
+type SlashingProtectionValidator struct {
+	Pubkey             string                           `json:"pubkey"`
+	SignedBlocks       []*SlashingProtectionBlock       `json:"signed_blocks"`
+	SignedAttestations []*SlashingProtectionAttestation `json:"signed_attestations"`
+}
+
+type SlashingProtectionBlock struct {
+	Slot        string `json:"slot"`
+	SigningRoot string `json:"signing_root,omitempty"`
+}
+
+type SlashingProtectionAttestation struct {
+	SourceEpoch string `json:"source_epoch"`
+	TargetEpoch string `json:"target_epoch"`
+	SigningRoot string `json:"signing_root,omitempty"`
+}
+
+// newSlashingProtectionInterchange returns the interchange of the validators of the keys without
+// any signature, the history of the validators at genesis
+func newSlashingProtectionInterchange(genesisValidatorsRoot []byte, keys []common.SecretKey) *SlashingProtectionInterchange {
+	interchange := &SlashingProtectionInterchange{Data: []*SlashingProtectionValidator{}}
+	interchange.Metadata.InterchangeFormatVersion = slashingProtectionVersion
+	interchange.Metadata.GenesisValidatorsRoot = hexutil.Encode(genesisValidatorsRoot)
+	for _, key := range keys {
+		interchange.Data = append(interchange.Data, &SlashingProtectionValidator{
+			Pubkey:             hexutil.Encode(key.PublicKey().Marshal()),
+			SignedBlocks:       []*SlashingProtectionBlock{},
+			SignedAttestations: []*SlashingProtectionAttestation{},
+		})
+	}
+	return interchange
+}
+
+// ParseSlashingProtectionInterchange decodes an interchange file and checks its version
+func ParseSlashingProtectionInterchange(data []byte) (*SlashingProtectionInterchange, error) {
+	var interchange SlashingProtectionInterchange
+	if err := json.Unmarshal(data, &interchange); err != nil {
+		return nil, fmt.Errorf("failed to decode the slashing protection interchange: %w", err)
+	}
+	if version := interchange.Metadata.InterchangeFormatVersion; version != slashingProtectionVersion {
+		return nil, fmt.Errorf("unsupported slashing protection interchange version '%s', expected %s", version, slashingProtectionVersion)
+	}
+	return &interchange, nil
+}
+
+// SlashingProtectionExporter is a validator client that exports its slashing protection database
+// in the interchange format (see ExportSlashingProtection)
+type SlashingProtectionExporter interface {
+	// SlashingProtectionExport returns the args of the entrypoint of the service that write the
+	// interchange file of its database to path, run while the validator client is stopped
+	SlashingProtectionExport(path string) []string
+}
+
+var _ SlashingProtectionExporter = &LighthouseValidator{}
+
+// ExportSlashingProtection stops the validator client of the service and exports its slashing
+// protection database with a one-off run of its entrypoint. The validator client stays stopped,
+// so that its keys can be moved to another validator client without signing anything else,
+// unless restart is set.
+func (d *LocalRunner) ExportSlashingProtection(name string, restart bool) (*SlashingProtectionInterchange, error) {
+	svc, ok := d.manifest.GetService(name)
+	if !ok {
+		return nil, fmt.Errorf("service '%s' not found", name)
+	}
+	exporter, ok := svc.component.(SlashingProtectionExporter)
+	if !ok {
+		return nil, fmt.Errorf("service '%s' is not a validator client with a slashing protection database", name)
+	}
+	if d.isHostService(name) {
+		return nil, fmt.Errorf("service '%s' runs on the host, its slashing protection database cannot be exported", name)
+	}
+
+	// the validator client must not sign while its database is exported, the exit of the
+	// container is expected
+	d.tasksMtx.Lock()
+	if task, ok := d.tasks[name]; ok {
+		task.expectRestart = true
+	}
+	d.tasksMtx.Unlock()
+	if err := d.composeService("stop", name); err != nil {
+		return nil, err
+	}
+	d.updateTaskStatus(name, taskStatusStopped)
+
+	path := filepath.Join("slashing_protection", name+".json")
+	args, err := d.resolveTemplates(svc, exporter.SlashingProtectionExport("{{.Dir}}/"+filepath.ToSlash(path)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply template on the export of service %s: %w", name, err)
+	}
+	if err := d.runOneOff(context.Background(), svc, args); err != nil {
+		return nil, fmt.Errorf("failed to export the slashing protection database of service %s: %w", name, err)
+	}
+
+	var data []byte
+	if d.remote != nil {
+		out, err := d.remote.run(context.Background(), nil, quoteShell([]string{"cat", filepath.Join(d.remote.Dir, path)}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the slashing protection interchange from the remote host: %w", err)
+		}
+		data = []byte(out)
+	} else if data, err = os.ReadFile(filepath.Join(d.out.dst, path)); err != nil {
+		return nil, fmt.Errorf("failed to read the slashing protection interchange: %w", err)
+	}
+	interchange, err := ParseSlashingProtectionInterchange(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if restart {
+		if err := d.composeService("start", name); err != nil {
+			return nil, err
+		}
+	}
+	return interchange, nil
+}
+
+// composeService runs a docker compose command (i.e. stop) on the service
+func (d *LocalRunner) composeService(command string, name string) error {
+	cmd := d.composeCommand(context.Background(), "-p", d.session.Name, "-f", filepath.Join(d.out.dst, "docker-compose.yaml"), command, name)
+
+	var errOut bytes.Buffer
+	cmd.Stderr = &errOut
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to %s service %s: %w, err: %s", command, name, err, errOut.String())
+	}
+	return nil
+}
+
+type slashingProtectionRequest struct {
+	Service string `json:"service"`
+	Restart bool   `json:"restart"`
+}
+
+type slashingProtectionResponse struct {
+	Interchange *SlashingProtectionInterchange `json:"interchange,omitempty"`
+	Error       string                         `json:"error,omitempty"`
+}
+
+// handleSlashingProtection exports the slashing protection database of a validator client
+func (c *ControlServer) handleSlashingProtection(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	resp := &slashingProtectionResponse{}
+
+	var req slashingProtectionRequest
+	if r.Method != http.MethodPost {
+		status, resp.Error = http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method)
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		status, resp.Error = http.StatusBadRequest, fmt.Sprintf("invalid request: %s", err)
+	} else if resp.Interchange, err = c.runner.ExportSlashingProtection(req.Service, req.Restart); err != nil {
+		status, resp.Error = http.StatusInternalServerError, err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ExportSessionSlashingProtection exports the slashing protection database of a validator client
+// of a running session (see ExportSlashingProtection)
+func ExportSessionSlashingProtection(ctx context.Context, session *Session, service string, restart bool) (*SlashingProtectionInterchange, error) {
+	if session.ControlURL == "" {
+		return nil, fmt.Errorf("session '%s' does not have a control server", session.Name)
+	}
+	data, _ := json.Marshal(&slashingProtectionRequest{Service: service, Restart: restart})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, session.ControlURL+"/slashing-protection", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var exportResp slashingProtectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&exportResp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if exportResp.Error != "" {
+		return nil, fmt.Errorf("%s", exportResp.Error)
+	}
+	return exportResp.Interchange, nil
+}
@@ -0,0 +1,197 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Snapshot is a normalized description of the manifest and the artifacts of a recipe. The
+// values that change between runs are left out (host ports, genesis time, keystore salts...)
+// and the templates of the args are not resolved, so the snapshot of a recipe is deterministic
+// and can be compared against golden files when components change.
+type Snapshot struct {
+	Recipe    string                     `json:"recipe"`
+	Services  []*ServiceSnapshot         `json:"services"`
+	Outputs   map[string]*OutputSnapshot `json:"outputs"`
+	Artifacts []string                   `json:"artifacts"`
+
+	// Validators is the number of validator keystores, which are not listed in the artifacts
+	Validators int `json:"validators"`
+}
+
+type ServiceSnapshot struct {
+	Name       string               `json:"name"`
+	Image      string               `json:"image"`
+	Tag        string               `json:"tag"`
+	Entrypoint string               `json:"entrypoint,omitempty"`
+	Args       []string             `json:"args"`
+	Init       []string             `json:"init,omitempty"`
+	Job        bool                 `json:"job,omitempty"`
+	Volumes    []string             `json:"volumes,omitempty"`
+	Env        map[string]string    `json:"env,omitempty"`
+	EnvFiles   []string             `json:"envFiles,omitempty"`
+	Labels     map[string]string    `json:"labels,omitempty"`
+	Files      map[string]string    `json:"files,omitempty"`
+	Platform   string               `json:"platform,omitempty"`
+	User       string               `json:"user,omitempty"`
+	Ports      []*topologyPort      `json:"ports"`
+	DependsOn  []*DependsOnSnapshot `json:"dependsOn,omitempty"`
+	ReadyCheck *topologyReadyCheck  `json:"readyCheck,omitempty"`
+}
+
+type DependsOnSnapshot struct {
+	Service   string             `json:"service"`
+	Condition DependsOnCondition `json:"condition"`
+}
+
+type OutputSnapshot struct {
+	Kind  OutputKind `json:"kind"`
+	Value string     `json:"value"`
+}
+
+// NewSnapshot creates the snapshot of a recipe applied to the manifest
+func NewSnapshot(recipe Recipe, manifest *Manifest) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Recipe:    recipe.Name(),
+		Services:  []*ServiceSnapshot{},
+		Outputs:   map[string]*OutputSnapshot{},
+		Artifacts: []string{},
+	}
+
+	for _, ss := range manifest.services {
+		svc := &ServiceSnapshot{
+			Name:       ss.Name,
+			Image:      ss.image,
+			Tag:        ss.tag,
+			Entrypoint: ss.entrypoint,
+			Args:       ss.args,
+			Init:       ss.initArgs,
+			Job:        ss.job,
+			Volumes:    ss.volumes,
+			Env:        ss.env,
+			EnvFiles:   ss.envFiles,
+			Labels:     ss.labels,
+			Files:      ss.files,
+			Platform:   ss.platform,
+			User:       ss.user,
+			Ports:      []*topologyPort{},
+		}
+		for _, p := range ss.ports {
+			svc.Ports = append(svc.Ports, &topologyPort{Name: p.Name, Port: p.Port, Protocol: p.Protocol})
+		}
+		sort.Slice(svc.Ports, func(i, j int) bool {
+			return svc.Ports[i].Name < svc.Ports[j].Name
+		})
+		for _, dep := range ss.dependsOn {
+			svc.DependsOn = append(svc.DependsOn, &DependsOnSnapshot{Service: dep.Service, Condition: dep.Condition})
+		}
+		sort.Slice(svc.DependsOn, func(i, j int) bool {
+			return svc.DependsOn[i].Service < svc.DependsOn[j].Service
+		})
+		if ss.readyCheck != nil {
+			svc.ReadyCheck = &topologyReadyCheck{Port: ss.readyCheck.PortLabel, Path: ss.readyCheck.Path}
+		}
+		snapshot.Services = append(snapshot.Services, svc)
+	}
+
+	for name, output := range recipe.Output(manifest) {
+		snapshot.Outputs[name] = &OutputSnapshot{Kind: output.Kind, Value: output.Value}
+	}
+
+	root := manifest.out.dst
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if rel == "data_validator" {
+				keystores, err := os.ReadDir(filepath.Join(path, "validators"))
+				if err != nil {
+					return err
+				}
+				snapshot.Validators = len(keystores)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		snapshot.Artifacts = append(snapshot.Artifacts, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(snapshot.Artifacts)
+
+	return snapshot, nil
+}
+
+// RenderSnapshot renders the recipe, with its current flag values, into a normalized JSON snapshot.
+// The artifacts are generated in a temporary folder that is removed afterwards.
+func RenderSnapshot(recipe Recipe) ([]byte, error) {
+	manifest, cleanup, err := applyRecipe(recipe)
+	if err != nil {
+		return nil, err
+	}
+	// the snapshot includes the list of artifacts, so the folder is removed afterwards
+	defer cleanup()
+
+	snapshot, err := NewSnapshot(recipe, manifest)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(snapshot, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ApplyRecipe builds the artifacts of the recipe in a temporary folder and returns the validated
+// manifest. The folder is removed afterwards, so the manifest cannot be deployed.
+func ApplyRecipe(recipe Recipe) (*Manifest, error) {
+	manifest, cleanup, err := applyRecipe(recipe)
+	if err != nil {
+		return nil, err
+	}
+	cleanup()
+	return manifest, nil
+}
+
+func applyRecipe(recipe Recipe) (*Manifest, func(), error) {
+	if err := ValidateRecipe(recipe); err != nil {
+		return nil, nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "playground-snapshot-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		os.RemoveAll(dir)
+	}
+
+	builder := recipe.Artifacts()
+	builder.OutputDir(dir)
+	// the keystores are not part of the snapshot, only their paths
+	builder.InsecureKeys(true)
+	artifacts, err := builder.Build(context.Background())
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to build artifacts: %w", err)
+	}
+
+	manifest := recipe.Apply(&ExContext{LogLevel: LevelInfo, SlotTime: artifacts.SlotTime}, artifacts)
+	if err := manifest.Validate(); err != nil {
+		cleanup()
+		return nil, nil, NewClassifiedError(ErrorClassUsage, fmt.Errorf("failed to validate manifest: %w", err))
+	}
+	return manifest, cleanup, nil
+}
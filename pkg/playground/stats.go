@@ -0,0 +1,229 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ServiceStats is the resource usage of a service during the run. The IO counters are the
+// totals of the run, including the containers of the service that were restarted.
+type ServiceStats struct {
+	NetRxBytes      uint64 `json:"netRxBytes"`
+	NetTxBytes      uint64 `json:"netTxBytes"`
+	BlockReadBytes  uint64 `json:"blockReadBytes"`
+	BlockWriteBytes uint64 `json:"blockWriteBytes"`
+
+	// MaxMemoryBytes is the peak memory usage of the samples
+	MaxMemoryBytes uint64 `json:"maxMemoryBytes"`
+
+	// DiskBytes is the size of the files written to the container filesystem (outside of
+	// the output folder) in the last sample
+	DiskBytes int64 `json:"diskBytes"`
+
+	// Samples is the number of samples taken
+	Samples int `json:"samples"`
+}
+
+// counter is a cumulative counter of a container that resets to zero when the container restarts
+type counter struct {
+	base uint64
+	last uint64
+}
+
+func (c *counter) update(value uint64) {
+	if value < c.last {
+		c.base += c.last
+	}
+	c.last = value
+}
+
+func (c *counter) value() uint64 {
+	return c.base + c.last
+}
+
+type containerStats struct {
+	netRx, netTx, blockRead, blockWrite counter
+}
+
+type serviceStats struct {
+	containers map[string]*containerStats
+	maxMemory  uint64
+	disk       int64
+	samples    int
+}
+
+// StatsCollector samples the network and block IO, the memory and the disk usage of the
+// containers of the session
+type StatsCollector struct {
+	runner *LocalRunner
+
+	lock     sync.Mutex
+	services map[string]*serviceStats
+}
+
+func NewStatsCollector(runner *LocalRunner) *StatsCollector {
+	return &StatsCollector{
+		runner:   runner,
+		services: map[string]*serviceStats{},
+	}
+}
+
+// Run samples the containers every interval until the context is done
+func (s *StatsCollector) Run(ctx context.Context, interval time.Duration) {
+	for {
+		if err := s.Sample(ctx); err != nil && ctx.Err() == nil {
+			runnerLog.Warn("failed to sample the container stats", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Sample takes a sample of the stats of all the running containers of the session
+func (s *StatsCollector) Sample(ctx context.Context) error {
+	containers, err := s.runner.client.ContainerList(ctx, container.ListOptions{
+		Filters: s.runner.sessionFilters(),
+	})
+	if err != nil {
+		return fmt.Errorf("error getting container list: %w", err)
+	}
+
+	for _, cont := range containers {
+		name := cont.Labels["com.docker.compose.service"]
+		if name == "" {
+			continue
+		}
+		stats, err := s.containerStats(ctx, cont.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get stats of %s: %w", name, err)
+		}
+		inspect, _, err := s.runner.client.ContainerInspectWithRaw(ctx, cont.ID, true)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s: %w", name, err)
+		}
+		s.add(name, cont.ID, stats, inspect.SizeRw)
+	}
+	return nil
+}
+
+func (s *StatsCollector) containerStats(ctx context.Context, id string) (*container.StatsResponse, error) {
+	resp, err := s.runner.client.ContainerStatsOneShot(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var stats container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (s *StatsCollector) add(name string, id string, stats *container.StatsResponse, sizeRw *int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	svc, ok := s.services[name]
+	if !ok {
+		svc = &serviceStats{containers: map[string]*containerStats{}}
+		s.services[name] = svc
+	}
+	cont, ok := svc.containers[id]
+	if !ok {
+		cont = &containerStats{}
+		svc.containers[id] = cont
+	}
+
+	var rx, tx uint64
+	for _, network := range stats.Networks {
+		rx += network.RxBytes
+		tx += network.TxBytes
+	}
+	cont.netRx.update(rx)
+	cont.netTx.update(tx)
+
+	var read, write uint64
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch entry.Op {
+		case "read", "Read":
+			read += entry.Value
+		case "write", "Write":
+			write += entry.Value
+		}
+	}
+	cont.blockRead.update(read)
+	cont.blockWrite.update(write)
+
+	if stats.MemoryStats.Usage > svc.maxMemory {
+		svc.maxMemory = stats.MemoryStats.Usage
+	}
+	if sizeRw != nil {
+		svc.disk = *sizeRw
+	}
+	svc.samples++
+}
+
+// Stats returns the stats of a service, nil if it was never sampled (i.e. it runs on the host)
+func (s *StatsCollector) Stats(name string) *ServiceStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	svc, ok := s.services[name]
+	if !ok {
+		return nil
+	}
+	stats := &ServiceStats{
+		MaxMemoryBytes: svc.maxMemory,
+		DiskBytes:      svc.disk,
+		Samples:        svc.samples,
+	}
+	for _, cont := range svc.containers {
+		stats.NetRxBytes += cont.netRx.value()
+		stats.NetTxBytes += cont.netTx.value()
+		stats.BlockReadBytes += cont.blockRead.value()
+		stats.BlockWriteBytes += cont.blockWrite.value()
+	}
+	return stats
+}
+
+// outputDiskUsage returns the disk usage of each entry of the output folder (the data
+// folders of the services, the logs...)
+func outputDiskUsage(root string) (map[string]int64, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	usage := map[string]int64{}
+	for _, entry := range entries {
+		var size int64
+		err := filepath.WalkDir(filepath.Join(root, entry.Name()), func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// the services might remove files while walking
+				return nil
+			}
+			if d.Type().IsRegular() {
+				if info, err := d.Info(); err == nil {
+					size += info.Size()
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		usage[entry.Name()] = size
+	}
+	return usage, nil
+}
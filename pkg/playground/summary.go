@@ -0,0 +1,224 @@
+package playground
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type ExitReason string
+
+var (
+	ExitReasonStartFailed      ExitReason = "start-failed"
+	ExitReasonNotReady         ExitReason = "not-ready"
+	ExitReasonDeploymentFailed ExitReason = "deployment-failed"
+	ExitReasonOutputFailed     ExitReason = "output-failed"
+	ExitReasonInterrupted      ExitReason = "interrupted"
+	ExitReasonServiceFailed    ExitReason = "service-failed"
+	ExitReasonWatchdogFailed   ExitReason = "watchdog-failed"
+	ExitReasonTimeout          ExitReason = "timeout"
+)
+
+// RunSummary describes the outcome of a session. It is written to summary.json
+// in the output folder when the session ends.
+type RunSummary struct {
+	Session    string     `json:"session"`
+	Recipe     string     `json:"recipe"`
+	StartedAt  time.Time  `json:"startedAt"`
+	EndedAt    time.Time  `json:"endedAt"`
+	ExitReason ExitReason `json:"exitReason"`
+	Error      string     `json:"error,omitempty"`
+
+	Watchdog *WatchdogSummary  `json:"watchdog,omitempty"`
+	Services []*ServiceSummary `json:"services"`
+	Outputs  map[string]string `json:"outputs,omitempty"`
+
+	// Disk is the disk usage in bytes of each entry of the output folder, if the
+	// stats are collected
+	Disk map[string]int64 `json:"disk,omitempty"`
+
+	// Bids is the summary of the builder bids received by the relay, if they are recorded
+	Bids *BidsSummary `json:"bids,omitempty"`
+
+	stats *StatsCollector
+	bids  *BidCollector
+}
+
+type WatchdogSummary struct {
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ServiceSummary struct {
+	Name   string         `json:"name"`
+	Image  string         `json:"image"`
+	Status string         `json:"status"`
+	Ports  map[string]int `json:"ports"`
+	Stats  *ServiceStats  `json:"stats,omitempty"`
+}
+
+func NewRunSummary(session *Session, watchdog bool) *RunSummary {
+	summary := &RunSummary{
+		Session:   session.Name,
+		Recipe:    session.Recipe,
+		StartedAt: time.Now(),
+		Services:  []*ServiceSummary{},
+	}
+	if watchdog {
+		summary.Watchdog = &WatchdogSummary{Passed: true}
+	}
+	return summary
+}
+
+// CollectStats includes in the summary the stats of the services sampled by the collector
+func (r *RunSummary) CollectStats(collector *StatsCollector) {
+	r.stats = collector
+}
+
+// CollectBids includes in the summary the summary of the bids recorded by the collector
+func (r *RunSummary) CollectBids(collector *BidCollector) {
+	r.bids = collector
+}
+
+// Finish records the exit reason and the status of the services. It must be called
+// before the services are stopped.
+func (r *RunSummary) Finish(manifest *Manifest, runner *LocalRunner, reason ExitReason, err error) {
+	r.EndedAt = time.Now()
+	r.ExitReason = reason
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if reason == ExitReasonWatchdogFailed && r.Watchdog != nil {
+		r.Watchdog.Passed = false
+		r.Watchdog.Error = r.Error
+	}
+
+	if r.stats != nil {
+		// a last sample with the final values of the counters
+		if err := r.stats.Sample(context.Background()); err != nil {
+			runnerLog.Warn("failed to sample the container stats", "err", err)
+		}
+		if root, err := manifest.out.AbsoluteDstPath(); err == nil {
+			if r.Disk, err = outputDiskUsage(root); err != nil {
+				runnerLog.Warn("failed to get the disk usage of the output folder", "err", err)
+			}
+		}
+	}
+
+	if r.bids != nil {
+		r.Bids = r.bids.Summary()
+	}
+
+	for _, svc := range manifest.Services() {
+		item := &ServiceSummary{
+			Name:   svc.Name,
+			Image:  fmt.Sprintf("%s:%s", svc.image, svc.tag),
+			Status: runner.TaskStatus(svc.Name),
+			Ports:  map[string]int{},
+		}
+		for _, p := range svc.ports {
+			item.Ports[p.Name] = p.HostPort
+		}
+		if r.stats != nil {
+			item.Stats = r.stats.Stats(svc.Name)
+		}
+		r.Services = append(r.Services, item)
+	}
+}
+
+// Write writes the summary to summary.json in the output folder
+func (r *RunSummary) Write(manifest *Manifest) error {
+	return manifest.out.WriteFile("summary.json", r)
+}
+
+// WriteBundle writes a tar.gz bundle with the contents of the output folder (logs, manifest,
+// genesis files, summary...) to be uploaded by CI pipelines. The databases of the
+// services (data_* folders) are not included.
+func WriteBundle(manifest *Manifest, path string) error {
+	root, err := manifest.out.AbsoluteDstPath()
+	if err != nil {
+		return err
+	}
+
+	bundlePath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer file.Close()
+
+	gw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gw)
+
+	var paths []string
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && strings.HasPrefix(info.Name(), "data_") {
+			return filepath.SkipDir
+		}
+		// skip directories and special files like the ipc sockets
+		if info.Mode().IsRegular() && p != bundlePath {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk output folder: %w", err)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err := addBundleFile(tw, root, p); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func addBundleFile(tw *tar.Writer, root, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(rel)
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// the log files might still be written, copy only the size in the header
+	if _, err := io.CopyN(tw, file, header.Size); err != nil {
+		return fmt.Errorf("failed to add %s to the bundle: %w", rel, err)
+	}
+	return nil
+}
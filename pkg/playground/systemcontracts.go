@@ -0,0 +1,148 @@
+package playground
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethparams "github.com/ethereum/go-ethereum/params"
+	"github.com/prysmaticlabs/prysm/v5/config/params"
+	"github.com/prysmaticlabs/prysm/v5/consensus-types/primitives"
+)
+
+// systemContract is a contract that the execution clients call at every block once its fork is active
+type systemContract struct {
+	name    string
+	eip     string
+	fork    string
+	address gethcommon.Address
+	code    []byte
+}
+
+var systemContracts = []*systemContract{
+	{"beacon-roots", "EIP-4788", "cancun", gethparams.BeaconRootsAddress, gethparams.BeaconRootsCode},
+	{"history-storage", "EIP-2935", "prague", gethparams.HistoryStorageAddress, gethparams.HistoryStorageCode},
+	{"withdrawal-requests", "EIP-7002", "prague", gethparams.WithdrawalQueueAddress, gethparams.WithdrawalQueueCode},
+	{"consolidation-requests", "EIP-7251", "prague", gethparams.ConsolidationQueueAddress, gethparams.ConsolidationQueueCode},
+}
+
+// SystemContractNames returns the names of the system contracts that can be disabled
+func SystemContractNames() []string {
+	names := []string{}
+	for _, contract := range systemContracts {
+		names = append(names, contract.name)
+	}
+	return names
+}
+
+func validateSystemContractNames(names []string) error {
+	for _, name := range names {
+		found := false
+		for _, contract := range systemContracts {
+			if contract.name == name {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown system contract '%s', expected one of %s", name, strings.Join(SystemContractNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// forkScheduled returns whether the fork of the system contract is active at some point of the chain
+func (s *systemContract) forkScheduled(config *gethparams.ChainConfig) bool {
+	switch s.fork {
+	case "cancun":
+		return config.CancunTime != nil
+	case "prague":
+		return config.PragueTime != nil
+	}
+	panic(fmt.Sprintf("BUG: unknown fork %s", s.fork))
+}
+
+// addSystemContracts deploys in the genesis the system contracts of the forks scheduled in the
+// chain config, except the disabled ones. The contracts already in the genesis with the same
+// code (i.e. from the state of a shadow fork) are kept with their storage.
+func addSystemContracts(gen *core.Genesis, disabled []string) {
+	for _, contract := range systemContracts {
+		if contains(disabled, contract.name) {
+			// the state of a shadow fork might have it
+			delete(gen.Alloc, contract.address)
+			continue
+		}
+		if !contract.forkScheduled(gen.Config) {
+			continue
+		}
+		if account, ok := gen.Alloc[contract.address]; ok && bytes.Equal(account.Code, contract.code) {
+			continue
+		}
+		gen.Alloc[contract.address] = types.Account{
+			Code:    contract.code,
+			Nonce:   1,
+			Balance: big.NewInt(0),
+		}
+	}
+}
+
+// validateGenesis cross-checks the execution genesis with the fork schedule of the beacon chain:
+// the execution forks must activate with their beacon chain forks, the deposit contract must be
+// deployed and every system contract of the scheduled forks must be deployed unless disabled.
+func validateGenesis(gen *core.Genesis, genesisTime uint64, config *params.BeaconChainConfig, disabled []string) error {
+	forks := []struct {
+		name      string
+		clName    string
+		epoch     primitives.Epoch
+		timestamp *uint64
+	}{
+		{"shanghai", "capella", config.CapellaForkEpoch, gen.Config.ShanghaiTime},
+		{"cancun", "deneb", config.DenebForkEpoch, gen.Config.CancunTime},
+		{"prague", "electra", config.ElectraForkEpoch, gen.Config.PragueTime},
+	}
+	for _, fork := range forks {
+		if fork.epoch == math.MaxUint64 {
+			if fork.timestamp != nil {
+				return fmt.Errorf("the %s fork is scheduled in the execution genesis but %s is not scheduled in the beacon chain", fork.name, fork.clName)
+			}
+			continue
+		}
+		expected := genesisTime + uint64(fork.epoch)*uint64(config.SlotsPerEpoch)*config.SecondsPerSlot
+		if fork.timestamp == nil || *fork.timestamp != expected {
+			return fmt.Errorf("the %s fork of the execution genesis does not activate with %s at %d", fork.name, fork.clName, expected)
+		}
+	}
+
+	depositContract := gethcommon.HexToAddress(config.DepositContractAddress)
+	if account, ok := gen.Alloc[depositContract]; !ok || len(account.Code) == 0 {
+		return fmt.Errorf("the deposit contract %s is not deployed in the genesis", depositContract)
+	}
+
+	for _, contract := range systemContracts {
+		if !contract.forkScheduled(gen.Config) {
+			continue
+		}
+		if contains(disabled, contract.name) {
+			artifactsLog.Warn("system contract disabled, the execution clients might reject the blocks after its fork", "contract", contract.name, "eip", contract.eip, "fork", contract.fork)
+			continue
+		}
+		account, ok := gen.Alloc[contract.address]
+		if !ok || !bytes.Equal(account.Code, contract.code) {
+			return fmt.Errorf("the %s system contract (%s) is not deployed at %s", contract.name, contract.eip, contract.address)
+		}
+	}
+	return nil
+}
+
+func contains(list []string, item string) bool {
+	for _, i := range list {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package playground
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	ecrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// templateExt is the extension of the template files, which is removed from the rendered file
+const templateExt = ".tmpl"
+
+// AddTemplate adds a config file, for the tools not managed by the playground, that is rendered
+// to the output folder once the services of the recipe are known (see RenderTemplates). The name
+// is the path of the rendered file relative to the output folder.
+func (s *Manifest) AddTemplate(name string, content string) {
+	if s.templates == nil {
+		s.templates = map[string]string{}
+	}
+	s.templates[name] = content
+}
+
+// LoadTemplates adds the *.tmpl files of a folder (and its subfolders) as templates. The rendered
+// files keep the path relative to the folder without the extension.
+func (s *Manifest) LoadTemplates(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, templateExt) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read template %s: %w", path, err)
+		}
+		s.AddTemplate(strings.TrimSuffix(rel, templateExt), string(data))
+		return nil
+	})
+}
+
+// RenderTemplates renders the templates to the output folder. The endpoints are the ones of the
+// docker network since the host ports are not assigned yet:
+// {{Service "service" "port"}} is the URL and {{Addr "service" "port"}} the host:port of a service,
+// {{JWTSecret "service"}} is the path of the JWT secret of a service,
+// {{PrefundedKey N}} and {{PrefundedAddress N}} are the private key and the address of the Nth
+// prefunded account, and {{.Dir}}, {{.L1ChainID}} and {{.L2ChainID}} are the absolute path of
+// the output folder and the chain ids.
+func (s *Manifest) RenderTemplates() error {
+	dir, err := s.out.AbsoluteDstPath()
+	if err != nil {
+		return err
+	}
+
+	addr := func(name string, portLabel string) (string, error) {
+		svc, ok := s.GetService(name)
+		if !ok {
+			return "", fmt.Errorf("service %s not found", name)
+		}
+		port, ok := svc.GetPort(portLabel)
+		if !ok {
+			return "", fmt.Errorf("service %s does not have port %s", name, portLabel)
+		}
+		return fmt.Sprintf("%s:%d", name, port.Port), nil
+	}
+	prefundedKey := func(index int) (string, error) {
+		if index < 0 || index >= len(prefundedAccounts) {
+			return "", fmt.Errorf("there are %d prefunded accounts", len(prefundedAccounts))
+		}
+		return prefundedAccounts[index], nil
+	}
+	funcs := template.FuncMap{
+		"Service": func(name string, portLabel string) (string, error) {
+			addr, err := addr(name, portLabel)
+			if err != nil {
+				return "", err
+			}
+			return "http://" + addr, nil
+		},
+		"Addr": addr,
+		"JWTSecret": func(name string) (string, error) {
+			owner, err := s.jwtSecretOwner(name)
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(dir, jwtSecretFile(owner)), nil
+		},
+		"PrefundedKey": prefundedKey,
+		"PrefundedAddress": func(index int) (string, error) {
+			key, err := prefundedKey(index)
+			if err != nil {
+				return "", err
+			}
+			priv, err := ecrypto.HexToECDSA(strings.TrimPrefix(key, "0x"))
+			if err != nil {
+				return "", err
+			}
+			return ecrypto.PubkeyToAddress(priv.PublicKey).Hex(), nil
+		},
+	}
+	input := map[string]interface{}{
+		"Dir":       dir,
+		"L1ChainID": l1ChainID,
+		"L2ChainID": s.l2ChainID,
+	}
+
+	names := make([]string, 0, len(s.templates))
+	for name := range s.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(s.templates[name])
+		if err != nil {
+			return fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		var out strings.Builder
+		if err := tpl.Execute(&out, input); err != nil {
+			return fmt.Errorf("failed to render template %s: %w", name, err)
+		}
+		if err := s.out.WriteFile(name, out.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
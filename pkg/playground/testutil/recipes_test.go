@@ -0,0 +1,105 @@
+package testutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ferranbt/builder-playground/pkg/playground"
+)
+
+// TestRecipeSnapshots compares the snapshot of each built-in recipe with its default flags
+// against the golden file in testdata (set UPDATE_GOLDEN=1 to update them)
+func TestRecipeSnapshots(t *testing.T) {
+	recipes := []struct {
+		recipe playground.Recipe
+		args   []string
+	}{
+		{recipe: &playground.L1Recipe{}},
+		{recipe: &playground.RelayRecipe{}},
+		{recipe: &playground.OpRecipe{}},
+		{recipe: &playground.OpInteropRecipe{}, args: []string{"--interop-dir", interopDir(t)}},
+		{recipe: &playground.DevRecipe{}},
+	}
+	for _, c := range recipes {
+		recipe := c.recipe
+		t.Run(recipe.Name(), func(t *testing.T) {
+			data, err := RenderWithArgs(recipe, c.args...)
+			if err != nil {
+				t.Fatalf("failed to render recipe: %v", err)
+			}
+			CompareGolden(t, filepath.Join("testdata", recipe.Name()+".json"), data)
+		})
+	}
+}
+
+// interopDir returns an op-deployer output folder with two chains, both with the chain
+// config embedded for the opstack recipe
+func interopDir(t *testing.T) string {
+	dir := t.TempDir()
+	copyFile := func(src, dst string) {
+		data, err := os.ReadFile(filepath.Join("..", "utils", src))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", src, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, dst), data, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", dst, err)
+		}
+	}
+	copyFile("state.json", "state.json")
+	for _, chainID := range []uint64{901, 902} {
+		copyFile("genesis.json", fmt.Sprintf("genesis-%d.json", chainID))
+		copyFile("rollup.json", fmt.Sprintf("rollup-%d.json", chainID))
+	}
+	return dir
+}
+
+// TestRecipeUsageErrors checks that the invalid flags of the built-in recipes are usage
+// errors, reported before the artifacts are built
+func TestRecipeUsageErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		recipe playground.Recipe
+		args   []string
+	}{
+		{name: "unknown builder", recipe: &playground.L1Recipe{}, args: []string{"--builder", "other"}},
+		{name: "unknown topology", recipe: &playground.L1Recipe{}, args: []string{"--topology", "other"}},
+		{name: "topology with native reth", recipe: &playground.L1Recipe{}, args: []string{"--topology", "ring", "--use-native-reth"}},
+		{name: "sentry nodes with geth-builder", recipe: &playground.L1Recipe{}, args: []string{"--builder", "geth-builder", "--sentry-nodes", "1"}},
+		{name: "private mempool with geth-builder", recipe: &playground.L1Recipe{}, args: []string{"--builder", "geth-builder", "--private-mempool"}},
+		{name: "sentry nodes with native reth", recipe: &playground.L1Recipe{}, args: []string{"--sentry-nodes", "1", "--use-native-reth"}},
+		{name: "malformed rpc gateway route", recipe: &playground.L1Recipe{}, args: []string{"--rpc-gateway-route", "eth_call"}},
+		{name: "rpc gateway route to unknown service", recipe: &playground.L1Recipe{}, args: []string{"--rpc-gateway-route", "eth_call=other"}},
+		{name: "invalid fee recipient", recipe: &playground.L1Recipe{}, args: []string{"--fee-recipient", "0x1"}},
+		{name: "zero gas limit", recipe: &playground.L1Recipe{}, args: []string{"--gas-limit", "0"}},
+		{name: "long extra data", recipe: &playground.L1Recipe{}, args: []string{"--extra-data", strings.Repeat("a", 33)}},
+		{name: "engine mux with secondary el", recipe: &playground.L1Recipe{}, args: []string{"--engine-mux", "reth", "--secondary-el", "8551"}},
+		{name: "unknown engine mux policy", recipe: &playground.L1Recipe{}, args: []string{"--engine-mux", "reth", "--engine-mux-policy", "other"}},
+		{name: "mev-boost relay without url", recipe: &playground.L1Recipe{}, args: []string{"--with-mev-boost=relay.example"}},
+		{name: "mev-boost relay without public key", recipe: &playground.L1Recipe{}, args: []string{"--with-mev-boost=https://relay.example"}},
+		{name: "optimistic relay without validation server", recipe: &playground.L1Recipe{}, args: []string{"--optimistic-relay"}},
+		{name: "optimistic flashbots relay", recipe: &playground.RelayRecipe{}, args: []string{"--optimistic-relay"}},
+		{name: "unknown pruning mode", recipe: &playground.L1Recipe{}, args: []string{"--el-pruning", "other"}},
+		{name: "static files with datadir", recipe: &playground.L1Recipe{}, args: []string{"--el-datadir", os.TempDir(), "--el-static-files", "static"}},
+		{name: "missing datadir", recipe: &playground.L1Recipe{}, args: []string{"--cl-datadir", filepath.Join(os.TempDir(), "playground-missing-datadir")}},
+		{name: "unknown datadir mode", recipe: &playground.L1Recipe{}, args: []string{"--datadir-mode", "other"}},
+		{name: "fault proofs with another chain id", recipe: &playground.OpRecipe{}, args: []string{"--with-fault-proofs", "--l2-chain-id", "1234"}},
+		{name: "unknown l2 el", recipe: &playground.OpRecipe{}, args: []string{"--l2-el", "other"}},
+		{name: "unknown batcher da type", recipe: &playground.OpRecipe{}, args: []string{"--batcher-da-type", "other"}},
+		{name: "unknown dev el", recipe: &playground.DevRecipe{}, args: []string{"--el", "other"}},
+		{name: "anvil fork without anvil", recipe: &playground.DevRecipe{}, args: []string{"--anvil-fork-block", "10"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := RenderWithArgs(c.recipe, c.args...)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if class := playground.ErrorClassOf(err); class != playground.ErrorClassUsage {
+				t.Fatalf("expected a usage error, got %s: %v", class, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Package testutil compares the snapshots of the recipes (see playground.RenderSnapshot)
+// against golden files in regression tests.
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ferranbt/builder-playground/pkg/playground"
+)
+
+// UpdateGoldenEnv is the environment variable that makes CompareGolden write the
+// golden files instead of comparing against them
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// RenderWithArgs resets the flags of the recipe to their defaults, parses the args
+// (i.e. --use-reth-for-validation) and renders the recipe
+func RenderWithArgs(recipe playground.Recipe, args ...string) ([]byte, error) {
+	if err := recipe.Flags().Parse(args); err != nil {
+		return nil, fmt.Errorf("failed to parse recipe flags: %w", err)
+	}
+	return playground.RenderSnapshot(recipe)
+}
+
+// TB is the subset of testing.TB used by the harness
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
+
+// CompareGolden compares the data with the golden file at path. If the UPDATE_GOLDEN
+// environment variable is set, the golden file is written instead.
+func CompareGolden(t TB, path string, data []byte) {
+	t.Helper()
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create golden folder: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (set %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(expected, data) {
+		t.Fatalf("snapshot does not match the golden file %s (set %s=1 to update it):\n%s", path, UpdateGoldenEnv, firstDiff(expected, data))
+	}
+}
+
+// firstDiff returns the first line that differs between the expected and the actual data
+func firstDiff(expected, actual []byte) string {
+	expectedLines := bytes.Split(expected, []byte("\n"))
+	actualLines := bytes.Split(actual, []byte("\n"))
+	for i := 0; i < max(len(expectedLines), len(actualLines)); i++ {
+		var e, a []byte
+		if i < len(expectedLines) {
+			e = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			a = actualLines[i]
+		}
+		if !bytes.Equal(e, a) {
+			return fmt.Sprintf("line %d:\n- %s\n+ %s", i+1, e, a)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,274 @@
+package playground
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gorilla/websocket"
+)
+
+var uiLog = Logger("ui")
+
+//go:embed ui.html
+var uiPage []byte
+
+// UIServer is a web dashboard that shows the state of the services deployed by the runner
+type UIServer struct {
+	manifest *Manifest
+	runner   *LocalRunner
+	server   *http.Server
+	upgrader websocket.Upgrader
+}
+
+func NewUIServer(addr string, manifest *Manifest, runner *LocalRunner) *UIServer {
+	u := &UIServer{
+		manifest: manifest,
+		runner:   runner,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", u.handleIndex)
+	mux.HandleFunc("/api/status", u.handleStatus)
+	mux.HandleFunc("/api/graph", u.handleGraph)
+	mux.HandleFunc("/ws/logs", u.handleLogs)
+
+	u.server = &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+	return u
+}
+
+// Run starts the HTTP server and blocks until it is closed
+func (u *UIServer) Run() error {
+	if err := u.server.ListenAndServe(); err != http.ErrServerClosed {
+		return fmt.Errorf("ui server error: %w", err)
+	}
+	return nil
+}
+
+func (u *UIServer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return u.server.Shutdown(ctx)
+}
+
+type uiPort struct {
+	Name     string `json:"name"`
+	Port     int    `json:"port"`
+	HostPort int    `json:"hostPort"`
+	URL      string `json:"url"`
+}
+
+type uiService struct {
+	Name    string    `json:"name"`
+	Image   string    `json:"image"`
+	Status  string    `json:"status"`
+	Healthy *bool     `json:"healthy,omitempty"`
+	Ports   []*uiPort `json:"ports"`
+}
+
+type uiChain struct {
+	Name    string `json:"name"`
+	Service string `json:"service"`
+	Head    uint64 `json:"head"`
+	Error   string `json:"error,omitempty"`
+}
+
+type uiStatus struct {
+	Services []*uiService `json:"services"`
+	Chains   []*uiChain   `json:"chains"`
+}
+
+func (u *UIServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(uiPage)
+}
+
+func (u *UIServer) handleGraph(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(u.manifest.GenerateDotGraph()))
+}
+
+func (u *UIServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := &uiStatus{
+		Services: []*uiService{},
+		Chains:   []*uiChain{},
+	}
+
+	for _, svc := range u.manifest.Services() {
+		item := &uiService{
+			Name:   svc.Name,
+			Image:  fmt.Sprintf("%s:%s", svc.image, svc.tag),
+			Status: u.runner.TaskStatus(svc.Name),
+			Ports:  []*uiPort{},
+		}
+		if svc.readyCheck != nil {
+			healthy := svc.readyCheck.probe(svc) == nil
+			item.Healthy = &healthy
+		}
+		for _, p := range svc.ports {
+			item.Ports = append(item.Ports, &uiPort{
+				Name:     p.Name,
+				Port:     p.Port,
+				HostPort: p.HostPort,
+				URL:      "http://" + svc.hostAddr(p.Name),
+			})
+		}
+		sort.Slice(item.Ports, func(i, j int) bool {
+			return item.Ports[i].Name < item.Ports[j].Name
+		})
+		status.Services = append(status.Services, item)
+
+		// report the chain head of the execution nodes
+		chainName, ok := serviceChainName(svc)
+		if !ok {
+			continue
+		}
+		chain := &uiChain{Name: chainName, Service: svc.Name}
+		if head, err := queryChainHead(r.Context(), "http://"+svc.hostAddr("http")); err != nil {
+			chain.Error = err.Error()
+		} else {
+			chain.Head = head
+		}
+		status.Chains = append(status.Chains, chain)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		uiLog.Warn("failed to encode ui status", "err", err)
+	}
+}
+
+// serviceChainName returns the chain (L1 or L2) of the execution nodes
+func serviceChainName(svc *ServiceSpec) (string, bool) {
+	switch svc.component.(type) {
+	case *RethEL, *GethDev, *Anvil:
+		return "L1", true
+	case *OpGeth, *OpReth:
+		return "L2", true
+	}
+	return "", false
+}
+
+func queryChainHead(ctx context.Context, elURL string) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	clt, err := ethclient.DialContext(ctx, elURL)
+	if err != nil {
+		return 0, err
+	}
+	defer clt.Close()
+
+	return clt.BlockNumber(ctx)
+}
+
+// handleLogs streams the logs of a service over a websocket connection. It sends
+// the full log file first and then any new content as it gets written.
+func (u *UIServer) handleLogs(w http.ResponseWriter, r *http.Request) {
+	svc, ok := u.manifest.GetService(r.URL.Query().Get("service"))
+	if !ok || svc.logs == nil {
+		http.Error(w, "service not found", http.StatusNotFound)
+		return
+	}
+
+	conn, err := u.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		uiLog.Warn("failed to upgrade ui logs connection", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	// detect when the client closes the connection
+	closeCh := make(chan struct{})
+	go func() {
+		defer close(closeCh)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	file, err := os.Open(svc.logs.path)
+	if err != nil {
+		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("failed to open logs: %v", err)))
+		return
+	}
+	defer func() {
+		file.Close()
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		// check for a rotation before reading so that the rest of the old file,
+		// which is not written anymore, is sent first
+		rotated, isRotated := reopenRotatedLog(file, svc.logs.path)
+		if err := sendLogs(conn, file, buf); err != nil {
+			if isRotated {
+				rotated.Close()
+			}
+			return
+		}
+		if isRotated {
+			file.Close()
+			file = rotated
+			continue
+		}
+
+		select {
+		case <-closeCh:
+			return
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// sendLogs sends the content of the file until the end over the websocket connection
+func sendLogs(conn *websocket.Conn, file *os.File, buf []byte) error {
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			if err := conn.WriteMessage(websocket.TextMessage, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// reopenRotatedLog opens the log file at path if it is not the same file as the
+// one being read anymore
+func reopenRotatedLog(file *os.File, path string) (*os.File, bool) {
+	current, err := file.Stat()
+	if err != nil {
+		return nil, false
+	}
+	latest, err := os.Stat(path)
+	if err != nil || os.SameFile(current, latest) {
+		return nil, false
+	}
+	rotated, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	return rotated, true
+}
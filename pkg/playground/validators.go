@@ -0,0 +1,513 @@
+package playground
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ecrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/prysmaticlabs/prysm/v5/beacon-chain/core/signing"
+	"github.com/prysmaticlabs/prysm/v5/config/params"
+	"github.com/prysmaticlabs/prysm/v5/consensus-types/primitives"
+	"github.com/prysmaticlabs/prysm/v5/crypto/bls"
+	ethpb "github.com/prysmaticlabs/prysm/v5/proto/prysm/v1alpha1"
+	"github.com/prysmaticlabs/prysm/v5/runtime/interop"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+)
+
+// depositorAccount is the prefunded account that sends the deposits of 'validators deposit'
+var depositorAccount = prefundedAccounts[8]
+
+// validatorKeyDirs are the folders with the keystores of the validator clients of the L1 recipe
+var validatorKeyDirs = []string{"data_validator", "data_validator_minority"}
+
+var depositContractABI = `[{"name":"deposit","type":"function","stateMutability":"payable","inputs":[{"name":"pubkey","type":"bytes"},{"name":"withdrawal_credentials","type":"bytes"},{"name":"signature","type":"bytes"},{"name":"deposit_data_root","type":"bytes32"}],"outputs":[]}]`
+
+// beaconValidator is a validator of the beacon chain state
+type beaconValidator struct {
+	Index           uint64
+	Status          string
+	Pubkey          string
+	ActivationEpoch uint64
+	ExitEpoch       uint64
+}
+
+// ValidatorExit is a voluntary exit submitted by 'validators exit'
+type ValidatorExit struct {
+	Index  uint64
+	Pubkey string
+	Epoch  uint64
+}
+
+// ExitValidators submits the voluntary exits of the last count active validators of the L1,
+// signed with the keys of the keystores in the output folder of the session
+func ExitValidators(ctx context.Context, session *Session, count uint64) ([]*ValidatorExit, error) {
+	beaconURL, err := sessionBeaconURL(session)
+	if err != nil {
+		return nil, err
+	}
+
+	validators, err := getValidators(ctx, beaconURL)
+	if err != nil {
+		return nil, err
+	}
+	active := []*beaconValidator{}
+	for _, validator := range validators {
+		if validator.Status == "active_ongoing" {
+			active = append(active, validator)
+		}
+	}
+	if uint64(len(active)) < count {
+		return nil, fmt.Errorf("there are only %d active validators", len(active))
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].Index > active[j].Index
+	})
+
+	var genesis struct {
+		Data struct {
+			GenesisValidatorsRoot string `json:"genesis_validators_root"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/beacon/genesis", &genesis); err != nil {
+		return nil, fmt.Errorf("failed to get the genesis: %w", err)
+	}
+	var spec struct {
+		Data struct {
+			CapellaForkVersion string `json:"CAPELLA_FORK_VERSION"`
+			SlotsPerEpoch      string `json:"SLOTS_PER_EPOCH"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/config/spec", &spec); err != nil {
+		return nil, fmt.Errorf("failed to get the beacon chain spec: %w", err)
+	}
+	head, err := getBeaconHead(ctx, beaconURL)
+	if err != nil {
+		return nil, err
+	}
+	slotsPerEpoch, err := strconv.ParseUint(spec.Data.SlotsPerEpoch, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slots per epoch in the spec: %w", err)
+	}
+	epoch := head.Slot / slotsPerEpoch
+
+	// since Deneb the exits are signed with the Capella fork version (EIP-7044)
+	forkVersion, err := hexutil.Decode(spec.Data.CapellaForkVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid capella fork version: %w", err)
+	}
+	validatorsRoot, err := hexutil.Decode(genesis.Data.GenesisValidatorsRoot)
+	if err != nil {
+		return nil, fmt.Errorf("invalid genesis validators root: %w", err)
+	}
+	domain, err := signing.ComputeDomain(params.BeaconConfig().DomainVoluntaryExit, forkVersion, validatorsRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	exits := []*ValidatorExit{}
+	for _, validator := range active[:count] {
+		key, err := readValidatorKey(session.Output, validator.Pubkey)
+		if err != nil {
+			return nil, err
+		}
+		exit := &ethpb.VoluntaryExit{
+			Epoch:          primitives.Epoch(epoch),
+			ValidatorIndex: primitives.ValidatorIndex(validator.Index),
+		}
+		root, err := signing.ComputeSigningRoot(exit, domain)
+		if err != nil {
+			return nil, err
+		}
+
+		body := map[string]interface{}{
+			"message": map[string]string{
+				"epoch":           strconv.FormatUint(epoch, 10),
+				"validator_index": strconv.FormatUint(validator.Index, 10),
+			},
+			"signature": hexutil.Encode(key.Sign(root[:]).Marshal()),
+		}
+		if err := postBeaconJSON(ctx, beaconURL+"/eth/v1/beacon/pool/voluntary_exits", body); err != nil {
+			return nil, fmt.Errorf("failed to submit the exit of validator %d: %w", validator.Index, err)
+		}
+		exits = append(exits, &ValidatorExit{Index: validator.Index, Pubkey: validator.Pubkey, Epoch: epoch})
+	}
+	return exits, nil
+}
+
+// readValidatorKey decrypts the keystore of the validator from the output folder
+func readValidatorKey(outputDir string, pubkey string) (bls.SecretKey, error) {
+	for _, dir := range validatorKeyDirs {
+		data, err := os.ReadFile(filepath.Join(outputDir, dir, "validators", pubkey, "voting-keystore.json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keystore of %s: %w", pubkey, err)
+		}
+		var keystore struct {
+			Crypto map[string]interface{} `json:"crypto"`
+		}
+		if err := json.Unmarshal(data, &keystore); err != nil {
+			return nil, fmt.Errorf("failed to decode keystore of %s: %w", pubkey, err)
+		}
+		buf, err := keystorev4.New().Decrypt(keystore.Crypto, secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt keystore of %s: %w", pubkey, err)
+		}
+		return bls.SecretKeyFromBytes(buf)
+	}
+	return nil, fmt.Errorf("keystore of validator %s not found in the output folder", pubkey)
+}
+
+// ValidatorDeposit is a deposit sent by 'validators deposit'
+type ValidatorDeposit struct {
+	Pubkey string
+	TxHash gethcommon.Hash
+}
+
+// DepositValidators creates count new validators: it writes their keystores for the validator
+// client of the L1 (which is restarted to load them) and sends their deposits to the deposit
+// contract. The keys are generated deterministically after the keys of the existing validators.
+func DepositValidators(ctx context.Context, session *Session, count uint64) ([]*ValidatorDeposit, error) {
+	if _, ok := session.Services["el"]; !ok {
+		return nil, fmt.Errorf("session '%s' has no L1 EL", session.Name)
+	}
+	elURL := sessionServiceURL(session, "el", "http")
+
+	config, err := loadCLConfig(filepath.Join(session.Output, "testnet", "config.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	// the deposits are signed with the genesis fork version of the active config
+	if err := params.SetActive(config); err != nil {
+		return nil, err
+	}
+
+	start := uint64(0)
+	for _, dir := range validatorKeyDirs {
+		entries, err := os.ReadDir(filepath.Join(session.Output, dir, "validators"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && strings.HasPrefix(entry.Name(), "0x") {
+				start++
+			}
+		}
+	}
+	priv, pub, err := interop.DeterministicallyGenerateKeys(start, count)
+	if err != nil {
+		return nil, err
+	}
+	depositData, roots, err := interop.DepositDataFromKeysWithExecCreds(priv, pub, count)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &output{dst: session.Output}
+	if err := out.WriteFile("data_validator/", &lighthouseKeystore{ctx: ctx, privKeys: priv}); err != nil {
+		return nil, fmt.Errorf("failed to write validator keystores: %w", err)
+	}
+
+	clt, err := ethclient.DialContext(ctx, elURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the EL: %w", err)
+	}
+	defer clt.Close()
+
+	key, err := ecrypto.HexToECDSA(strings.TrimPrefix(depositorAccount, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	from := ecrypto.PubkeyToAddress(key.PublicKey)
+	chainID, err := clt.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain id: %w", err)
+	}
+	nonce, err := clt.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	depositABI, err := abi.JSON(strings.NewReader(depositContractABI))
+	if err != nil {
+		return nil, err
+	}
+	contract := gethcommon.HexToAddress(config.DepositContractAddress)
+
+	deposits := []*ValidatorDeposit{}
+	txs := []*types.Transaction{}
+	for i, data := range depositData {
+		input, err := depositABI.Pack("deposit", data.PublicKey, data.WithdrawalCredentials, data.Signature, [32]byte(roots[i]))
+		if err != nil {
+			return nil, err
+		}
+		// the deposit amount is in gwei
+		value := new(big.Int).Mul(new(big.Int).SetUint64(data.Amount), big.NewInt(1e9))
+
+		tip, err := clt.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get gas tip: %w", err)
+		}
+		head, err := clt.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest block: %w", err)
+		}
+		gas, err := clt.EstimateGas(ctx, ethereum.CallMsg{From: from, To: &contract, Value: value, Data: input})
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate the gas of the deposit: %w", err)
+		}
+		tx, err := types.SignNewTx(key, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			GasTipCap: tip,
+			GasFeeCap: new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2))),
+			Gas:       gas,
+			To:        &contract,
+			Value:     value,
+			Data:      input,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := clt.SendTransaction(ctx, tx); err != nil {
+			return nil, fmt.Errorf("failed to send deposit: %w", err)
+		}
+		nonce++
+
+		txs = append(txs, tx)
+		deposits = append(deposits, &ValidatorDeposit{Pubkey: hexutil.Encode(data.PublicKey), TxHash: tx.Hash()})
+	}
+
+	for _, tx := range txs {
+		receipt, err := waitForReceipt(ctx, clt, tx.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			return nil, fmt.Errorf("deposit %s failed", tx.Hash())
+		}
+	}
+
+	// the validator client loads the new keystores when it starts
+	dockerClt, err := session.dockerClient()
+	if err != nil {
+		return nil, err
+	}
+	defer dockerClt.Close()
+
+	id, err := serviceContainer(ctx, dockerClt, session, "validator")
+	if err != nil {
+		return nil, err
+	}
+	if err := dockerClt.ContainerRestart(ctx, id, container.StopOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to restart the validator client: %w", err)
+	}
+	return deposits, nil
+}
+
+func waitForReceipt(ctx context.Context, clt *ethclient.Client, hash gethcommon.Hash) (*types.Receipt, error) {
+	timeoutCh := time.After(time.Minute)
+	for {
+		receipt, err := clt.TransactionReceipt(ctx, hash)
+		if err == nil {
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeoutCh:
+			return nil, fmt.Errorf("timeout waiting for transaction %s", hash)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func sessionBeaconURL(session *Session) (string, error) {
+	if _, ok := session.Services["beacon"]; !ok {
+		return "", fmt.Errorf("session '%s' has no L1 beacon node", session.Name)
+	}
+	return sessionServiceURL(session, "beacon", "http"), nil
+}
+
+func getValidators(ctx context.Context, beaconURL string) ([]*beaconValidator, error) {
+	var resp struct {
+		Data []struct {
+			Index     string `json:"index"`
+			Status    string `json:"status"`
+			Validator struct {
+				Pubkey          string `json:"pubkey"`
+				ActivationEpoch string `json:"activation_epoch"`
+				ExitEpoch       string `json:"exit_epoch"`
+			} `json:"validator"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/beacon/states/head/validators", &resp); err != nil {
+		return nil, fmt.Errorf("failed to get the validators: %w", err)
+	}
+
+	validators := []*beaconValidator{}
+	for _, item := range resp.Data {
+		validator := &beaconValidator{Status: item.Status, Pubkey: item.Validator.Pubkey}
+		validator.Index, _ = strconv.ParseUint(item.Index, 10, 64)
+		validator.ActivationEpoch, _ = strconv.ParseUint(item.Validator.ActivationEpoch, 10, 64)
+		validator.ExitEpoch, _ = strconv.ParseUint(item.Validator.ExitEpoch, 10, 64)
+		validators = append(validators, validator)
+	}
+	return validators, nil
+}
+
+func postBeaconJSON(ctx context.Context, url string, obj interface{}) error {
+	return postBeaconJSONResult(ctx, url, obj, nil)
+}
+
+// postBeaconJSONResult is like postBeaconJSON and decodes the response in result, if not nil
+func postBeaconJSONResult(ctx context.Context, url string, obj interface{}, result interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(msg))
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+// defaultFeeRecipient and defaultGasLimit are the fee recipient and the gas limit of the
+// devnet validators
+var (
+	defaultFeeRecipient        = "0x690B9A9E9aa1C9dB991C7721a92d351Db4FaC990"
+	defaultGasLimit     uint64 = 36_000_000
+)
+
+// ValidatorRegistration are the preferences that the validators of the devnet register with
+// the relay, the fee recipient of their blocks and the gas limit the builders target
+type ValidatorRegistration struct {
+	FeeRecipient string
+	GasLimit     uint64
+}
+
+func (v *ValidatorRegistration) Validate() error {
+	if !gethcommon.IsHexAddress(v.FeeRecipient) {
+		return fmt.Errorf("invalid fee recipient '%s', expected an address", v.FeeRecipient)
+	}
+	if v.GasLimit == 0 {
+		return fmt.Errorf("the gas limit must be greater than zero")
+	}
+	return nil
+}
+
+// registerValidators registers all the validators of the beacon chain with the builder API of
+// the relay (registerValidator). The keys of the validators are the deterministic keys of the
+// devnet. It retries until the relay accepts the registrations or the timeout expires, since
+// the relay rejects the validators it has not synced yet.
+func registerValidators(ctx context.Context, out io.Writer, beaconURL string, relayURL string, registration *ValidatorRegistration, timeout time.Duration) error {
+	var spec struct {
+		Data struct {
+			GenesisForkVersion string `json:"GENESIS_FORK_VERSION"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/config/spec", &spec); err != nil {
+		return fmt.Errorf("failed to get the beacon chain spec: %w", err)
+	}
+	// the registrations are signed with the genesis fork version and an empty validators root
+	forkVersion, err := hexutil.Decode(spec.Data.GenesisForkVersion)
+	if err != nil {
+		return fmt.Errorf("invalid genesis fork version: %w", err)
+	}
+	domain, err := signing.ComputeDomain(params.BeaconConfig().DomainApplicationBuilder, forkVersion, nil)
+	if err != nil {
+		return err
+	}
+	feeRecipient := gethcommon.HexToAddress(registration.FeeRecipient)
+
+	validators, err := getValidators(ctx, beaconURL)
+	if err != nil {
+		return err
+	}
+	privKeys, _, err := interop.DeterministicallyGenerateKeys(0, uint64(len(validators)))
+	if err != nil {
+		return err
+	}
+	keys := map[string]bls.SecretKey{}
+	for _, key := range privKeys {
+		keys[hexutil.Encode(key.PublicKey().Marshal())] = key
+	}
+
+	timestamp := uint64(time.Now().Unix())
+	registrations := []interface{}{}
+	for _, validator := range validators {
+		key, ok := keys[validator.Pubkey]
+		if !ok {
+			continue
+		}
+		message := &ethpb.ValidatorRegistrationV1{
+			FeeRecipient: feeRecipient.Bytes(),
+			GasLimit:     registration.GasLimit,
+			Timestamp:    timestamp,
+			Pubkey:       key.PublicKey().Marshal(),
+		}
+		root, err := signing.ComputeSigningRoot(message, domain)
+		if err != nil {
+			return err
+		}
+		registrations = append(registrations, map[string]interface{}{
+			"message": map[string]string{
+				"fee_recipient": feeRecipient.Hex(),
+				"gas_limit":     strconv.FormatUint(registration.GasLimit, 10),
+				"timestamp":     strconv.FormatUint(timestamp, 10),
+				"pubkey":        validator.Pubkey,
+			},
+			"signature": hexutil.Encode(key.Sign(root[:]).Marshal()),
+		})
+	}
+
+	timeoutCh := time.After(timeout)
+	for {
+		err := postBeaconJSON(ctx, relayURL+"/eth/v1/builder/validators", registrations)
+		if err == nil {
+			fmt.Fprintf(out, "registered %d validators with the relay %s\n", len(registrations), relayURL)
+			return nil
+		}
+		reportReadyProbe(ctx, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeoutCh:
+			return fmt.Errorf("failed to register the validators with the relay: %w", err)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
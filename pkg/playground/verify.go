@@ -0,0 +1,296 @@
+package playground
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type VerifyStatus string
+
+var (
+	VerifyStatusPass VerifyStatus = "pass"
+	VerifyStatusFail VerifyStatus = "fail"
+	VerifyStatusSkip VerifyStatus = "skip"
+)
+
+// VerifyCheck is the result of one of the checks of 'playground verify'
+type VerifyCheck struct {
+	Name    string       `json:"name"`
+	Service string       `json:"service"`
+	Status  VerifyStatus `json:"status"`
+	Message string       `json:"message,omitempty"`
+}
+
+// VerifyReport is the machine-readable report of 'playground verify'
+type VerifyReport struct {
+	Session string         `json:"session"`
+	Passed  bool           `json:"passed"`
+	Checks  []*VerifyCheck `json:"checks"`
+}
+
+// beaconAPIEndpoints are the endpoints of the Beacon API spec that every beacon node must serve
+var beaconAPIEndpoints = []string{
+	"/eth/v1/node/version",
+	"/eth/v1/node/syncing",
+	"/eth/v1/node/peers",
+	"/eth/v1/beacon/genesis",
+	"/eth/v1/beacon/headers/head",
+	"/eth/v1/beacon/states/head/finality_checkpoints",
+	"/eth/v1/config/spec",
+	"/eth/v1/config/fork_schedule",
+}
+
+// maxFinalityLag is the maximum number of epochs between the head and the finalized checkpoint.
+// With all the validators online, the checkpoint is finalized two epochs after it is proposed.
+const maxFinalityLag = 3
+
+// Verify runs a suite of consistency checks against the services of a running session:
+//   - the beacon nodes serve the Beacon API spec endpoints
+//   - the finality of the beacon chain advances
+//   - each execution node has the same head as its beacon node (el/beacon, el-N/beacon-N...)
+//   - the op-nodes derive new safe L2 blocks from the L1 within the timeout
+func Verify(ctx context.Context, session *Session, timeout time.Duration) *VerifyReport {
+	report := &VerifyReport{Session: session.Name, Checks: []*VerifyCheck{}}
+	add := func(name, service string, err error) {
+		check := &VerifyCheck{Name: name, Service: service, Status: VerifyStatusPass}
+		if err, ok := err.(*skipError); ok {
+			check.Status, check.Message = VerifyStatusSkip, err.reason
+		} else if err != nil {
+			check.Status, check.Message = VerifyStatusFail, err.Error()
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	beacons, opNodes := []string{}, []string{}
+	for name, component := range session.Components {
+		switch component {
+		case "lighthouse-beacon-node":
+			beacons = append(beacons, name)
+		case "op-node":
+			opNodes = append(opNodes, name)
+		}
+	}
+	sort.Strings(beacons)
+	sort.Strings(opNodes)
+
+	for _, name := range beacons {
+		beaconURL := sessionServiceURL(session, name, "http")
+		add("beacon-api", name, verifyBeaconAPI(ctx, beaconURL))
+
+		// the beacon node of the validators is the one that drives the finality
+		if name == "beacon" {
+			add("finality", name, verifyFinality(ctx, beaconURL))
+		}
+
+		el := "el" + strings.TrimPrefix(name, "beacon")
+		if _, ok := session.Services[el]; ok {
+			add("el-cl-head", name, verifyHead(ctx, beaconURL, sessionServiceURL(session, el, "http")))
+		}
+	}
+	for _, name := range opNodes {
+		add("op-node-derivation", name, verifyDerivation(ctx, sessionServiceURL(session, name, "http"), timeout))
+	}
+
+	report.Passed = true
+	for _, check := range report.Checks {
+		if check.Status == VerifyStatusFail {
+			report.Passed = false
+		}
+	}
+	return report
+}
+
+// skipError signals that a check does not apply to the current state of the devnet
+type skipError struct {
+	reason string
+}
+
+func (s *skipError) Error() string {
+	return s.reason
+}
+
+func verifyBeaconAPI(ctx context.Context, beaconURL string) error {
+	failed := []string{}
+	for _, endpoint := range beaconAPIEndpoints {
+		var resp struct {
+			Data interface{} `json:"data"`
+		}
+		if err := getBeaconJSON(ctx, beaconURL+endpoint, &resp); err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s)", endpoint, err))
+		} else if resp.Data == nil {
+			failed = append(failed, fmt.Sprintf("%s (no data)", endpoint))
+		}
+	}
+
+	// the health endpoint has no body, 200 is ready and 206 is syncing
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, beaconURL+"/eth/v1/node/health", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		failed = append(failed, fmt.Sprintf("/eth/v1/node/health (%s)", err))
+	} else {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+			failed = append(failed, fmt.Sprintf("/eth/v1/node/health (unexpected status code %d)", resp.StatusCode))
+		}
+	}
+
+	if len(failed) != 0 {
+		return fmt.Errorf("endpoints failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func verifyFinality(ctx context.Context, beaconURL string) error {
+	var spec struct {
+		Data struct {
+			SlotsPerEpoch string `json:"SLOTS_PER_EPOCH"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/config/spec", &spec); err != nil {
+		return fmt.Errorf("failed to get the beacon chain spec: %w", err)
+	}
+	slotsPerEpoch, err := strconv.ParseUint(spec.Data.SlotsPerEpoch, 10, 64)
+	if err != nil || slotsPerEpoch == 0 {
+		return fmt.Errorf("invalid slots per epoch '%s'", spec.Data.SlotsPerEpoch)
+	}
+
+	head, err := getBeaconHead(ctx, beaconURL)
+	if err != nil {
+		return err
+	}
+	var checkpoints struct {
+		Data struct {
+			Finalized struct {
+				Epoch string `json:"epoch"`
+			} `json:"finalized"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v1/beacon/states/head/finality_checkpoints", &checkpoints); err != nil {
+		return fmt.Errorf("failed to get the finality checkpoints: %w", err)
+	}
+	finalized, err := strconv.ParseUint(checkpoints.Data.Finalized.Epoch, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid finalized epoch: %w", err)
+	}
+
+	epoch := head.Slot / slotsPerEpoch
+	if epoch < maxFinalityLag {
+		return &skipError{reason: fmt.Sprintf("the chain is at epoch %d, the first checkpoints are not finalized yet", epoch)}
+	}
+	if epoch-finalized > maxFinalityLag {
+		return fmt.Errorf("the finalized epoch %d is %d epochs behind the head epoch %d", finalized, epoch-finalized, epoch)
+	}
+	return nil
+}
+
+func verifyHead(ctx context.Context, beaconURL string, elURL string) error {
+	clt, err := ethclient.DialContext(ctx, elURL)
+	if err != nil {
+		return err
+	}
+	defer clt.Close()
+
+	// the head might change between the requests, retry a few times before failing
+	for i := 0; ; i++ {
+		err := compareHead(ctx, beaconURL, clt)
+		if err == nil || i == 2 {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func compareHead(ctx context.Context, beaconURL string, clt *ethclient.Client) error {
+	var block struct {
+		Data struct {
+			Message struct {
+				Body struct {
+					ExecutionPayload struct {
+						BlockNumber string `json:"block_number"`
+						BlockHash   string `json:"block_hash"`
+					} `json:"execution_payload"`
+				} `json:"body"`
+			} `json:"message"`
+		} `json:"data"`
+	}
+	if err := getBeaconJSON(ctx, beaconURL+"/eth/v2/beacon/blocks/head", &block); err != nil {
+		return fmt.Errorf("failed to get the head block of the beacon node: %w", err)
+	}
+	payload := block.Data.Message.Body.ExecutionPayload
+	number, err := strconv.ParseUint(payload.BlockNumber, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid execution block number '%s'", payload.BlockNumber)
+	}
+
+	header, err := clt.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return fmt.Errorf("the EL does not have the execution block %d of the beacon head: %w", number, err)
+	}
+	if header.Hash().Hex() != payload.BlockHash {
+		return fmt.Errorf("the EL block %d is %s but the beacon head has %s", number, header.Hash().Hex(), payload.BlockHash)
+	}
+	latest, err := clt.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if latest != number {
+		return fmt.Errorf("the EL head %d is not the execution block %d of the beacon head", latest, number)
+	}
+	return nil
+}
+
+func verifyDerivation(ctx context.Context, opNodeURL string, timeout time.Duration) error {
+	clt, err := rpc.DialContext(ctx, opNodeURL)
+	if err != nil {
+		return err
+	}
+	defer clt.Close()
+
+	type syncStatus struct {
+		UnsafeL2 struct {
+			Number uint64 `json:"number"`
+		} `json:"unsafe_l2"`
+		SafeL2 struct {
+			Number uint64 `json:"number"`
+		} `json:"safe_l2"`
+	}
+	var start syncStatus
+	if err := clt.CallContext(ctx, &start, "optimism_syncStatus"); err != nil {
+		return fmt.Errorf("failed to get the sync status: %w", err)
+	}
+
+	timeoutCh := time.After(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeoutCh:
+			return fmt.Errorf("the safe L2 head did not advance from %d in %s", start.SafeL2.Number, timeout)
+		case <-time.After(2 * time.Second):
+		}
+
+		var status syncStatus
+		if err := clt.CallContext(ctx, &status, "optimism_syncStatus"); err != nil {
+			return fmt.Errorf("failed to get the sync status: %w", err)
+		}
+		if status.SafeL2.Number > start.SafeL2.Number && status.UnsafeL2.Number > start.UnsafeL2.Number {
+			return nil
+		}
+	}
+}
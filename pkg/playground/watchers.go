@@ -0,0 +1,624 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/flashbots/mev-boost-relay/beaconclient"
+	mevRCommon "github.com/flashbots/mev-boost-relay/common"
+)
+
+var watchdogLog = Logger("watchdog")
+
+func waitForChainAlive(ctx context.Context, logOutput io.Writer, beaconNodeURL string, timeout time.Duration) error {
+	// Test that blocks are being produced
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "waitForChainAlive")
+	log.Logger.Out = logOutput
+
+	clt := beaconclient.NewProdBeaconInstance(log, beaconNodeURL, beaconNodeURL)
+
+	// Subscribe to head events right away even if the connection has not been established yet
+	// That is handled internally in the function already.
+	// Otherwise, if we connect only when the first head slot happens we might miss some initial slots.
+	ch := make(chan beaconclient.PayloadAttributesEvent)
+	go clt.SubscribeToPayloadAttributesEvents(ch)
+
+	{
+		// If the chain has not started yet, wait for it to start.
+		// Otherwise, the subscription will not return any data.
+		bClient := beaconclient.NewMultiBeaconClient(log, []beaconclient.IBeaconInstance{
+			clt,
+		})
+
+		isReady := func() bool {
+			sync, err := bClient.BestSyncStatus()
+			if err != nil {
+				reportReadyProbe(ctx, err)
+				return false
+			}
+			if sync.HeadSlot < 1 {
+				reportReadyProbe(ctx, fmt.Errorf("the chain has not started yet (head slot %d)", sync.HeadSlot))
+				return false
+			}
+			return true
+		}
+
+		if !isReady() {
+			syncTimeoutCh := time.After(timeout)
+			for {
+				if isReady() {
+					break
+				}
+				select {
+				case <-syncTimeoutCh:
+					return fmt.Errorf("beacon client failed to start after %s", timeout)
+				case <-ctx.Done():
+					return fmt.Errorf("timeout waiting for chain to start")
+				default:
+					time.Sleep(1 * time.Second)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateProposerPayloads validates that payload attribute events are being broadcasted by the beacon node
+// in the correct order without any missing slots.
+func validateProposerPayloads(logOutput io.Writer, beaconNodeURL string) error {
+	// Test that blocks are being produced
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "validateProposerPayloads")
+	log.Logger.Out = logOutput
+
+	clt := beaconclient.NewProdBeaconInstance(log, beaconNodeURL, beaconNodeURL)
+
+	// We run this after 'waitForChainAlive' to ensure that the beacon node is ready to receive payloads.
+	ch := make(chan beaconclient.PayloadAttributesEvent)
+	go clt.SubscribeToPayloadAttributesEvents(ch)
+
+	log.Infof("Chain is alive. Subscribing to head events")
+
+	var lastSlot uint64
+	for {
+		select {
+		case head := <-ch:
+			log.Infof("Slot: %d Parent block number: %d", head.Data.ProposalSlot, head.Data.ParentBlockNumber)
+
+			// If we are being notified of a new slot, validate that the slots are contiguous
+			// Note that lighthouse might send multiple updates for the same slot.
+			if lastSlot != 0 && lastSlot != head.Data.ProposalSlot && lastSlot+1 != head.Data.ProposalSlot {
+				return fmt.Errorf("slot mismatch, expected %d, got %d", lastSlot+1, head.Data.ProposalSlot)
+			}
+			// if the network did not miss any initial slots, lighthouse will send payload attribute updates
+			// of the form: (slot = slot, parent block number = slot - 2), (slot, slot - 1).
+			// The -2 is in case we want to handle reorgs in the chain.
+			// We need to validate that at least the difference between the parent block number and the slot is 2.
+			if head.Data.ProposalSlot-head.Data.ParentBlockNumber > 2 {
+				return fmt.Errorf("parent block too big %d", head.Data.ParentBlockNumber)
+			}
+
+			lastSlot = head.Data.ProposalSlot
+		case <-time.After(20 * time.Second):
+			return fmt.Errorf("timeout waiting for block")
+		}
+	}
+}
+
+func watchProposerPayloads(beaconNodeURL string) error {
+	getProposerPayloadDelivered := func() ([]*mevRCommon.BidTraceV2JSON, error) {
+		resp, err := http.Get(fmt.Sprintf("%s/relay/v1/data/bidtraces/proposer_payload_delivered", beaconNodeURL))
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		var payloadDeliveredList []*mevRCommon.BidTraceV2JSON
+		if err := json.Unmarshal(data, &payloadDeliveredList); err != nil {
+			return nil, err
+		}
+		return payloadDeliveredList, nil
+	}
+
+	// Wait for at least 10 seconds for Mev-boost to start
+	timerC := time.After(10 * time.Second)
+LOOP:
+	for {
+		select {
+		case <-timerC:
+			break
+		case <-time.After(2 * time.Second):
+			if _, err := getProposerPayloadDelivered(); err == nil {
+				break LOOP
+			}
+		}
+	}
+
+	// This is not the most efficient solution since we are querying the endpoint for the full list of payloads
+	// every 2 seconds. It should be fine for the kind of workloads expected to run.
+
+	lastSlot := uint64(0)
+
+	for {
+		time.Sleep(2 * time.Second)
+
+		vals, err := getProposerPayloadDelivered()
+		if err != nil {
+			watchdogLog.Error("failed to get the proposer payloads", "err", err)
+			continue
+		}
+
+		for _, val := range vals {
+			if val.Slot <= lastSlot {
+				continue
+			}
+
+			watchdogLog.Info("block proposed", "slot", val.Slot, "builder", val.BuilderPubkey, "block", val.BlockNumber)
+			lastSlot = val.Slot
+		}
+	}
+}
+
+// maxSlotsWithoutBids is the number of consecutive slots without builder bids tolerated
+// by watchRelayBids. It gives the builder some time to start submitting blocks.
+const maxSlotsWithoutBids = 3
+
+func getRelayBidTraces(relayURL string, path string, query url.Values) ([]*mevRCommon.BidTraceV2JSON, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/relay/v1/data/bidtraces/%s?%s", relayURL, path, query.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d for %s: %s", resp.StatusCode, path, string(data))
+	}
+
+	var traces []*mevRCommon.BidTraceV2JSON
+	if err := json.Unmarshal(data, &traces); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// watchRelayBids queries the data API of the relay every slot and asserts that the relay
+// received builder bids that passed the validation, and that the payload delivered to
+// the proposer is one of them.
+func watchRelayBids(logOutput io.Writer, relayURL string, slotTime time.Duration) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchRelayBids")
+	log.Logger.Out = logOutput
+
+	// find the current slot from the payload attributes known by the relay
+	clt := beaconclient.NewProdBeaconInstance(log, relayURL, relayURL)
+	ch := make(chan beaconclient.PayloadAttributesEvent)
+	go clt.SubscribeToPayloadAttributesEvents(ch)
+
+	var lastSlot uint64
+	slotsWithoutBids := 0
+
+	for {
+		var slot uint64
+		select {
+		case head := <-ch:
+			slot = head.Data.ProposalSlot
+		case <-time.After(2 * slotTime):
+			return fmt.Errorf("timeout waiting for payload attributes from the relay")
+		}
+
+		// check the previous slot once the next one starts, which is when the
+		// proposer has already requested the payload
+		if slot <= lastSlot+1 {
+			continue
+		}
+		checkSlot := slot - 1
+		lastSlot = checkSlot
+
+		query := url.Values{"slot": []string{fmt.Sprintf("%d", checkSlot)}}
+
+		bids, err := getRelayBidTraces(relayURL, "builder_blocks_received", query)
+		if err != nil {
+			return fmt.Errorf("failed to get builder bids for slot %d: %w", checkSlot, err)
+		}
+		delivered, err := getRelayBidTraces(relayURL, "proposer_payload_delivered", query)
+		if err != nil {
+			return fmt.Errorf("failed to get delivered payloads for slot %d: %w", checkSlot, err)
+		}
+
+		log.Infof("Slot: %d Bids: %d Delivered: %d", checkSlot, len(bids), len(delivered))
+
+		if len(bids) == 0 {
+			slotsWithoutBids++
+			if slotsWithoutBids >= maxSlotsWithoutBids {
+				return fmt.Errorf("relay did not receive any valid builder bids in the last %d slots", slotsWithoutBids)
+			}
+			continue
+		}
+		slotsWithoutBids = 0
+
+		if len(delivered) == 0 {
+			return fmt.Errorf("relay received %d bids for slot %d but did not deliver any payload", len(bids), checkSlot)
+		}
+
+		validated := map[string]struct{}{}
+		for _, bid := range bids {
+			validated[bid.BlockHash] = struct{}{}
+		}
+		for _, payload := range delivered {
+			if _, ok := validated[payload.BlockHash]; !ok {
+				return fmt.Errorf("relay delivered payload %s for slot %d that is not in the validated bids", payload.BlockHash, checkSlot)
+			}
+		}
+	}
+}
+
+// watchBuilderSubmissions asserts that the relay keeps receiving valid block submissions from
+// the builder. It fails if there are no new submissions for maxSlotsWithoutBids slots.
+func watchBuilderSubmissions(logOutput io.Writer, relayURL string, builderPubkey string, slotTime time.Duration) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchBuilderSubmissions").WithField("builder", builderPubkey)
+	log.Logger.Out = logOutput
+
+	query := url.Values{"builder_pubkey": []string{builderPubkey}}
+
+	var lastSlot uint64
+	lastSubmission := time.Now()
+
+	for {
+		time.Sleep(slotTime)
+
+		bids, err := getRelayBidTraces(relayURL, "builder_blocks_received", query)
+		if err != nil {
+			return fmt.Errorf("failed to get builder submissions: %w", err)
+		}
+
+		for _, bid := range bids {
+			if bid.Slot > lastSlot {
+				log.Infof("Builder submission: Slot: %d, Block: %d, Value: %s", bid.Slot, bid.BlockNumber, bid.Value)
+				lastSlot = bid.Slot
+				lastSubmission = time.Now()
+			}
+		}
+
+		if time.Since(lastSubmission) > maxSlotsWithoutBids*slotTime {
+			return fmt.Errorf("builder did not submit any valid block to the relay in the last %d slots", maxSlotsWithoutBids)
+		}
+	}
+}
+
+// watchBlockParams checks the gas limit and the extra data of the new blocks of the EL. The gas
+// limit of a block can only change by less than 1/1024 of the gas limit of its parent, so it has
+// to move towards the target gas limit (or stay at it) without overshooting it. The extra data
+// is only checked if it is set.
+func watchBlockParams(logOutput io.Writer, elURL string, gasLimit uint64, extraData string) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchBlockParams").WithField("el", elURL)
+	log.Logger.Out = logOutput
+
+	clt, err := ethclient.Dial(elURL)
+	if err != nil {
+		return err
+	}
+	defer clt.Close()
+
+	var parent *types.Header
+	for {
+		time.Sleep(500 * time.Millisecond)
+
+		num, err := clt.BlockNumber(context.Background())
+		if err != nil {
+			return err
+		}
+		if parent == nil {
+			if parent, err = clt.HeaderByNumber(context.Background(), new(big.Int).SetUint64(num)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for next := parent.Number.Uint64() + 1; next <= num; next++ {
+			header, err := clt.HeaderByNumber(context.Background(), new(big.Int).SetUint64(next))
+			if err != nil {
+				return err
+			}
+			if err := checkBlockParams(parent, header, gasLimit, extraData); err != nil {
+				return err
+			}
+			if header.GasLimit != parent.GasLimit {
+				log.Infof("Gas limit of block %d: %d (target %d)", next, header.GasLimit, gasLimit)
+			}
+			parent = header
+		}
+	}
+}
+
+func checkBlockParams(parent, header *types.Header, gasLimit uint64, extraData string) error {
+	if extraData != "" && string(header.Extra) != extraData {
+		return fmt.Errorf("block %d has extra data %q, expected %q", header.Number, header.Extra, extraData)
+	}
+	if gasLimit == 0 {
+		return nil
+	}
+
+	from, to := parent.GasLimit, header.GasLimit
+	var ok bool
+	switch {
+	case from < gasLimit:
+		ok = to > from && to <= gasLimit
+	case from > gasLimit:
+		ok = to < from && to >= gasLimit
+	default:
+		ok = to == gasLimit
+	}
+	// the maximum change of the gas limit of a block
+	if ok && max(from, to)-min(from, to) >= from/1024 {
+		ok = false
+	}
+	if !ok {
+		return fmt.Errorf("gas limit of block %d is %d after %d, it does not move towards the target %d", header.Number, to, from, gasLimit)
+	}
+	return nil
+}
+
+// maxSlotsWithMissingPeers is the number of consecutive slots that a node can miss some of its
+// expected peers in watchPeers. It gives the nodes time to connect after they start.
+const maxSlotsWithMissingPeers = 5
+
+// watchPeers checks every slot that a node is connected to at least minPeers peers and, if
+// getPeers returns the connected peers by service name, to all the expected ones. It fails
+// if the node misses peers for maxSlotsWithMissingPeers slots, which signals a partition of
+// the network or a failure of the discovery.
+func watchPeers(logOutput io.Writer, node string, slotTime time.Duration, minPeers int, expected []string, getPeers func() (int, map[string]bool, error)) error {
+	if minPeers == 0 {
+		return nil
+	}
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchPeers").WithField("node", node)
+	log.Logger.Out = logOutput
+
+	slotsWithMissingPeers := 0
+	for {
+		time.Sleep(slotTime)
+
+		count, connected, err := getPeers()
+		if err != nil {
+			return fmt.Errorf("failed to get the peers of %s: %w", node, err)
+		}
+		missing := []string{}
+		if connected != nil {
+			for _, peer := range expected {
+				if !connected[peer] {
+					missing = append(missing, peer)
+				}
+			}
+		}
+		if count >= minPeers && len(missing) == 0 {
+			if slotsWithMissingPeers != 0 {
+				log.Infof("Peers: %d", count)
+			}
+			slotsWithMissingPeers = 0
+			continue
+		}
+
+		slotsWithMissingPeers++
+		log.Warnf("Peers: %d, expected at least %d, missing: %v", count, minPeers, missing)
+		if slotsWithMissingPeers >= maxSlotsWithMissingPeers {
+			if len(missing) != 0 {
+				return fmt.Errorf("%s is not connected to %s in the last %d slots", node, strings.Join(missing, ", "), slotsWithMissingPeers)
+			}
+			return fmt.Errorf("%s has %d peers in the last %d slots, expected at least %d", node, count, slotsWithMissingPeers, minPeers)
+		}
+	}
+}
+
+// getELPeers returns the peer count of an EL (net_peerCount) and which of the services are its
+// peers (admin_peers), from their deterministic p2p keys
+func getELPeers(elURL string, services []string) (int, map[string]bool, error) {
+	clt, err := rpc.Dial(elURL)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer clt.Close()
+
+	var count hexutil.Uint64
+	if err := clt.Call(&count, "net_peerCount"); err != nil {
+		return 0, nil, err
+	}
+	var peers []struct {
+		Enode string `json:"enode"`
+	}
+	if err := clt.Call(&peers, "admin_peers"); err != nil {
+		return 0, nil, err
+	}
+	pubkeys := map[string]bool{}
+	for _, peer := range peers {
+		pubkey, _, _ := strings.Cut(strings.TrimPrefix(peer.Enode, "enode://"), "@")
+		pubkeys[pubkey] = true
+	}
+	connected := map[string]bool{}
+	for _, name := range services {
+		connected[name] = pubkeys[nodePubkeyHex(name)]
+	}
+	return int(count), connected, nil
+}
+
+// getBeaconPeerCount returns the number of connected peers of a beacon node
+func getBeaconPeerCount(beaconURL string) (int, error) {
+	var resp struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := getBeaconJSON(context.Background(), beaconURL+"/eth/v1/node/peers?state=connected", &resp); err != nil {
+		return 0, err
+	}
+	return len(resp.Data), nil
+}
+
+// watchChainHead watches the chain head and ensures that it is advancing
+func watchChainHead(logOutput io.Writer, elURL string, blockTime time.Duration) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchChainHead").WithField("el", elURL)
+	log.Logger.Out = logOutput
+
+	// add some wiggle room to block time
+	blockTime = blockTime + 1*time.Second
+
+	rpcClient, err := rpc.Dial(elURL)
+	if err != nil {
+		return err
+	}
+
+	var latestBlock *uint64
+	clt := ethclient.NewClient(rpcClient)
+
+	timeout := time.NewTimer(blockTime)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-time.After(500 * time.Millisecond):
+			num, err := clt.BlockNumber(context.Background())
+			if err != nil {
+				return err
+			}
+			if latestBlock != nil && num <= *latestBlock {
+				continue
+			}
+			log.Infof("Chain head: %d", num)
+			latestBlock = &num
+
+			// Reset timeout since we saw a new block
+			if !timeout.Stop() {
+				<-timeout.C
+			}
+			timeout.Reset(blockTime)
+
+		case <-timeout.C:
+			return fmt.Errorf("chain head for %s not advancing", elURL)
+		}
+	}
+}
+
+// watchSupervisorSafeHead watches the sync status of op-supervisor and ensures that the cross-safe
+// head of the dependency set is advancing. The cross-safe head only advances once the cross-chain
+// messages of the blocks have been validated against their initiating chains.
+func watchSupervisorSafeHead(logOutput io.Writer, supervisorURL string, timeout time.Duration) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchSupervisorSafeHead").WithField("supervisor", supervisorURL)
+	log.Logger.Out = logOutput
+
+	clt, err := rpc.Dial(supervisorURL)
+	if err != nil {
+		return err
+	}
+	defer clt.Close()
+
+	var latestSafe, latestFinalized uint64
+	lastProgress := time.Now()
+
+	for {
+		time.Sleep(2 * time.Second)
+
+		var status struct {
+			SafeTimestamp      uint64 `json:"safeTimestamp"`
+			FinalizedTimestamp uint64 `json:"finalizedTimestamp"`
+		}
+		if err := clt.Call(&status, "supervisor_syncStatus"); err != nil {
+			return fmt.Errorf("failed to get supervisor sync status: %w", err)
+		}
+
+		if status.SafeTimestamp > latestSafe {
+			log.Infof("Cross-safe head: %d", status.SafeTimestamp)
+			latestSafe = status.SafeTimestamp
+			lastProgress = time.Now()
+		}
+		if status.FinalizedTimestamp > latestFinalized {
+			log.Infof("Finalized head: %d", status.FinalizedTimestamp)
+			latestFinalized = status.FinalizedTimestamp
+		}
+
+		if time.Since(lastProgress) > timeout {
+			return fmt.Errorf("cross-safe head of the dependency set not advancing")
+		}
+	}
+}
+
+// watchValidatorLifecycle tracks the validators with a keystore in keysDir (including the ones
+// added later with 'validators deposit') and logs the changes of their status in the beacon
+// chain, i.e. once a deposit is processed, the validator is activated or it exits. It fails if
+// any of the validators is slashed.
+func watchValidatorLifecycle(logOutput io.Writer, beaconNodeURL string, keysDir string, slotTime time.Duration) error {
+	log := mevRCommon.LogSetup(false, "info").WithField("context", "watchValidatorLifecycle")
+	log.Logger.Out = logOutput
+
+	statuses := map[string]string{}
+	for {
+		time.Sleep(slotTime)
+
+		entries, err := os.ReadDir(keysDir)
+		if err != nil {
+			return fmt.Errorf("failed to read validator keys: %w", err)
+		}
+		managed := map[string]struct{}{}
+		for _, entry := range entries {
+			if entry.IsDir() && strings.HasPrefix(entry.Name(), "0x") {
+				managed[entry.Name()] = struct{}{}
+			}
+		}
+
+		validators, err := getValidators(context.Background(), beaconNodeURL)
+		if err != nil {
+			return err
+		}
+		for _, validator := range validators {
+			if _, ok := managed[validator.Pubkey]; !ok {
+				continue
+			}
+			if prev, ok := statuses[validator.Pubkey]; ok && prev != validator.Status {
+				log.Infof("Validator %d: %s -> %s (activation epoch: %d, exit epoch: %d)", validator.Index, prev, validator.Status, validator.ActivationEpoch, validator.ExitEpoch)
+			} else if !ok && len(statuses) != 0 {
+				// the first time a deposit is included in the beacon state
+				log.Infof("Validator %d: deposit processed (%s)", validator.Index, validator.Status)
+			}
+			statuses[validator.Pubkey] = validator.Status
+
+			if strings.HasSuffix(validator.Status, "_slashed") {
+				return fmt.Errorf("validator %d (%s) was slashed", validator.Index, validator.Pubkey)
+			}
+		}
+	}
+}
+
+type watchGroup struct {
+	errCh chan error
+}
+
+func newWatchGroup() *watchGroup {
+	return &watchGroup{
+		errCh: make(chan error, 1),
+	}
+}
+
+func (wg *watchGroup) watch(watch func() error) {
+	go func() {
+		wg.errCh <- watch()
+	}()
+}
+
+func (wg *watchGroup) wait() error {
+	return <-wg.errCh
+}
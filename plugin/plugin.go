@@ -0,0 +1,187 @@
+// Package plugin is the interface of the recipes shipped as external binaries. The playground
+// discovers the binaries in ~/.playground/plugins and adds their recipes under 'cook', so a
+// custom service does not require a fork of the repository.
+//
+// A plugin is a binary that calls Serve with its recipe:
+//
+//	func main() {
+//		plugin.Serve(&myRecipe{})
+//	}
+//
+// The recipe describes its services with the same schema as the YAML recipes, the services
+// are either built-in components or low level services with an image and args.
+package plugin
+
+import (
+	"net/rpc"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"gopkg.in/yaml.v2"
+)
+
+// Handshake is the handshake between the playground and the plugins. The protocol version
+// changes when the interface of the recipes is not compatible anymore.
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "BUILDER_PLAYGROUND_PLUGIN",
+	MagicCookieValue: "recipe",
+}
+
+// PluginName is the name of the recipe in the plugin set of the binaries
+const PluginName = "recipe"
+
+// Recipe is the recipe implemented by a plugin
+type Recipe interface {
+	// Info returns the name, the description and the flags of the recipe
+	Info() (*Info, error)
+
+	// Apply returns the services of the recipe for the values of the flags
+	Apply(req *ApplyRequest) (*Spec, error)
+}
+
+type Info struct {
+	Name        string
+	Description string
+	Flags       []*Flag
+}
+
+// Flag is a string flag of the recipe, available as --<name> under 'cook <recipe>'
+type Flag struct {
+	Name    string
+	Usage   string
+	Default string
+}
+
+type ApplyRequest struct {
+	// Flags are the values of the flags of the recipe by name
+	Flags map[string]string
+}
+
+// Spec is the description of the services of the recipe. It uses the schema of the YAML
+// recipes (see the README).
+type Spec struct {
+	Artifacts *Artifacts          `yaml:"artifacts,omitempty"`
+	Services  map[string]*Service `yaml:"services"`
+}
+
+type Artifacts struct {
+	// LatestFork enables the latest L1 fork at genesis
+	LatestFork bool `yaml:"latest_fork,omitempty"`
+
+	// Files are extra files to write in the output folder, by path relative to the output folder
+	Files map[string]string `yaml:"files,omitempty"`
+}
+
+type Service struct {
+	// Component is the name of a built-in component (i.e. reth or lighthouse-beacon-node).
+	// If set, Config is decoded into the component fields.
+	Component string                 `yaml:"component,omitempty"`
+	Config    map[string]interface{} `yaml:"config,omitempty"`
+
+	Image      string            `yaml:"image,omitempty"`
+	Tag        string            `yaml:"tag,omitempty"`
+	Entrypoint string            `yaml:"entrypoint,omitempty"`
+	Args       []string          `yaml:"args,omitempty"`
+	Env        map[string]string `yaml:"env,omitempty"`
+	EnvFiles   []string          `yaml:"env_file,omitempty"`
+
+	// DependsOn maps a service name to the condition (started, healthy or completed)
+	DependsOn  map[string]string `yaml:"depends_on,omitempty"`
+	ReadyCheck *ReadyCheck       `yaml:"ready_check,omitempty"`
+
+	// Restart is the restart policy (never, always, on-failure or on-failure:<retries>)
+	Restart string `yaml:"restart,omitempty"`
+
+	// Volumes are the shared volumes mounted by the service
+	Volumes []string `yaml:"volumes,omitempty"`
+
+	// Job marks a service that runs to completion
+	Job bool `yaml:"job,omitempty"`
+
+	// User is the <uid>[:<gid>] the container runs as, or host for the host user
+	User string `yaml:"user,omitempty"`
+}
+
+type ReadyCheck struct {
+	Port string `yaml:"port"`
+	Path string `yaml:"path,omitempty"`
+
+	// Timeout is the time to wait for the service to be ready (i.e. 2m)
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// Serve serves the recipe to the playground. It must be called from the main function of the
+// plugin binary and blocks until the playground is done with it.
+func Serve(recipe Recipe) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins: map[string]goplugin.Plugin{
+			PluginName: &RecipePlugin{Impl: recipe},
+		},
+	})
+}
+
+// RecipePlugin is the go-plugin of the recipes, over net/rpc
+type RecipePlugin struct {
+	Impl Recipe
+}
+
+func (p *RecipePlugin) Server(*goplugin.MuxBroker) (interface{}, error) {
+	return &recipeRPCServer{impl: p.Impl}, nil
+}
+
+func (p *RecipePlugin) Client(_ *goplugin.MuxBroker, c *rpc.Client) (interface{}, error) {
+	return &recipeRPC{client: c}, nil
+}
+
+// recipeRPC is the client side of the recipe. The spec is sent as YAML since gob does not
+// encode the arbitrary values of the component configs.
+type recipeRPC struct {
+	client *rpc.Client
+}
+
+func (r *recipeRPC) Info() (*Info, error) {
+	var info Info
+	if err := r.client.Call("Plugin.Info", new(interface{}), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (r *recipeRPC) Apply(req *ApplyRequest) (*Spec, error) {
+	var data []byte
+	if err := r.client.Call("Plugin.Apply", req, &data); err != nil {
+		return nil, err
+	}
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+type recipeRPCServer struct {
+	impl Recipe
+}
+
+func (r *recipeRPCServer) Info(_ interface{}, resp *Info) error {
+	info, err := r.impl.Info()
+	if err != nil {
+		return err
+	}
+	*resp = *info
+	return nil
+}
+
+func (r *recipeRPCServer) Apply(req *ApplyRequest, resp *[]byte) error {
+	spec, err := r.impl.Apply(req)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	*resp = data
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	rpcgateway "github.com/ferranbt/builder-playground/rpc-gateway"
+	"github.com/spf13/cobra"
+)
+
+var (
+	port          int
+	defaultTarget string
+	routes        []string
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "rpc-gateway",
+	Short: "",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRpcGateway()
+	},
+}
+
+func main() {
+	rootCmd.Flags().IntVar(&port, "port", 8545, "")
+	rootCmd.Flags().StringVar(&defaultTarget, "default", "http://localhost:8545", "")
+	rootCmd.Flags().StringArrayVar(&routes, "route", []string{}, "")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func runRpcGateway() error {
+	cfg := &rpcgateway.Config{
+		LogOutput: os.Stdout,
+		Port:      uint64(port),
+		Default:   defaultTarget,
+		Routes:    map[string]string{},
+	}
+	for _, route := range routes {
+		method, target, ok := strings.Cut(route, "=")
+		if !ok {
+			return fmt.Errorf("invalid route '%s', expected method=url", route)
+		}
+		cfg.Routes[method] = target
+	}
+
+	gateway, err := rpcgateway.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create rpc gateway: %w", err)
+	}
+	return gateway.Run()
+}
@@ -0,0 +1,278 @@
+package rpcgateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flashbots/mev-boost-relay/common"
+	"github.com/sirupsen/logrus"
+)
+
+type Config struct {
+	LogOutput io.Writer
+	Port      uint64
+
+	// Default is the URL of the JSON-RPC endpoint of the methods without a route
+	Default string
+
+	// Routes are the URLs of the endpoints by JSON-RPC method. A method ending with '*' is a
+	// prefix (i.e. eth_* or mev_*), the longest match wins.
+	Routes map[string]string
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		LogOutput: os.Stdout,
+		Port:      8545,
+		Routes:    map[string]string{},
+	}
+}
+
+// RpcGateway aggregates multiple JSON-RPC endpoints behind one URL and routes each request to
+// an endpoint by its method, i.e. the bundles to the builder and the rest to the full node.
+// The batches are split by endpoint and the responses are merged in the order of the requests.
+type RpcGateway struct {
+	config *Config
+	log    *logrus.Entry
+	server *http.Server
+	client *http.Client
+
+	// prefixes are the methods of the prefix routes, sorted from the longest one
+	prefixes []string
+}
+
+func New(config *Config) (*RpcGateway, error) {
+	log := common.LogSetup(false, "info")
+	log.Logger.SetOutput(config.LogOutput)
+
+	if config.Default == "" {
+		return nil, fmt.Errorf("default endpoint is required")
+	}
+
+	prefixes := []string{}
+	for method := range config.Routes {
+		if strings.HasSuffix(method, "*") {
+			prefixes = append(prefixes, method)
+		}
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+
+	gateway := &RpcGateway{
+		config:   config,
+		log:      log,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		prefixes: prefixes,
+	}
+	return gateway, nil
+}
+
+// Run starts the HTTP server
+func (s *RpcGateway) Run() error {
+	mux := http.NewServeMux()
+	s.server = &http.Server{
+		Addr:         fmt.Sprintf(":%d", s.config.Port),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 60 * time.Second,
+		Handler:      mux,
+	}
+
+	mux.HandleFunc("/", s.handleRequest)
+
+	for method, target := range s.config.Routes {
+		s.log.Infof("Route %s to %s", method, target)
+	}
+	s.log.Infof("Starting server on port %d, default route to %s", s.config.Port, s.config.Default)
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return fmt.Errorf("server error: %v", err)
+	}
+	return nil
+}
+
+// Close gracefully shuts down the server
+func (s *RpcGateway) Close() error {
+	s.log.Info("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("server shutdown error: %v", err)
+	}
+	return nil
+}
+
+// Route returns the URL of the endpoint of the JSON-RPC method
+func (s *RpcGateway) Route(method string) string {
+	if target, ok := s.config.Routes[method]; ok {
+		return target
+	}
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(method, strings.TrimSuffix(prefix, "*")) {
+			return s.config.Routes[prefix]
+		}
+	}
+	return s.config.Default
+}
+
+type jsonrpcMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+}
+
+func (s *RpcGateway) handleRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		// the requests without a JSON-RPC body (i.e. health checks) go to the default endpoint
+		s.proxy(w, r, s.config.Default, nil)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		var msg jsonrpcMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			writeError(w, nil, -32700, "parse error")
+			return
+		}
+		target := s.Route(msg.Method)
+		s.log.WithField("method", msg.Method).Debugf("Routing to %s", target)
+		s.proxy(w, r, target, data)
+		return
+	}
+	s.handleBatch(w, r, data)
+}
+
+// handleBatch splits the batch by endpoint and merges the responses in the order of the requests
+func (s *RpcGateway) handleBatch(w http.ResponseWriter, r *http.Request, data []byte) {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(data, &batch); err != nil {
+		writeError(w, nil, -32700, "parse error")
+		return
+	}
+
+	targets := []string{}
+	byTarget := map[string][]int{}
+	for i, raw := range batch {
+		var msg jsonrpcMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			writeError(w, nil, -32600, "invalid request in batch")
+			return
+		}
+		target := s.Route(msg.Method)
+		if _, ok := byTarget[target]; !ok {
+			targets = append(targets, target)
+		}
+		byTarget[target] = append(byTarget[target], i)
+	}
+
+	// the responses are matched to the requests by id
+	responses := map[string]json.RawMessage{}
+	for _, target := range targets {
+		sub := []json.RawMessage{}
+		for _, i := range byTarget[target] {
+			sub = append(sub, batch[i])
+		}
+		body, err := json.Marshal(sub)
+		if err != nil {
+			writeError(w, nil, -32603, err.Error())
+			return
+		}
+
+		resp, err := s.forward(r.Context(), target, r.Header, body)
+		if err != nil {
+			s.log.WithError(err).Warnf("Failed to forward batch to %s", target)
+			writeError(w, nil, -32603, fmt.Sprintf("failed to forward batch to %s", target))
+			return
+		}
+		var results []json.RawMessage
+		if err := json.Unmarshal(resp, &results); err != nil {
+			writeError(w, nil, -32603, fmt.Sprintf("invalid batch response from %s", target))
+			return
+		}
+		for _, result := range results {
+			var msg jsonrpcMessage
+			if err := json.Unmarshal(result, &msg); err == nil {
+				responses[string(msg.ID)] = result
+			}
+		}
+	}
+
+	merged := []json.RawMessage{}
+	for _, raw := range batch {
+		var msg jsonrpcMessage
+		json.Unmarshal(raw, &msg)
+		if result, ok := responses[string(msg.ID)]; ok {
+			merged = append(merged, result)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merged)
+}
+
+func (s *RpcGateway) forward(ctx context.Context, target string, header http.Header, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header.Clone()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (s *RpcGateway) proxy(w http.ResponseWriter, r *http.Request, target string, body []byte) {
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, strings.TrimSuffix(target, "/")+r.URL.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	req.Header = r.Header.Clone()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.log.WithError(err).Warnf("Failed to forward request to %s", target)
+		http.Error(w, "Bad gateway", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func writeError(w http.ResponseWriter, id json.RawMessage, code int, message string) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	})
+}